@@ -0,0 +1,87 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "os/signal"
+
+    conf "lawmap/internal/config"
+    "lawmap/internal/etl"
+    "lawmap/internal/etl/sources"
+)
+
+// etl runs a configured source through fetch, parse, normalize and emit, keeping
+// per-source state and a content-addressed cache of raw downloads between runs.
+//
+//    go run ./cmd/etl -list
+//    go run ./cmd/etl -source "GovInfo CFR" -url https://www.govinfo.gov/.../CFR-2023-title28-vol1.xml -out cfr.jsonl
+//    go run ./cmd/etl -source "CA LegInfo" -url https://downloads.leginfo.legislature.ca.gov/pubinfo_2025.zip -full -out ca.jsonl
+func main() {
+    sourcesFile := flag.String("sources", "", "sources config (default configs/sources.json, else configs/sources.example.json)")
+    name := flag.String("source", "", "descriptor name of the source to run")
+    out := flag.String("out", "", "output JSONL path (default stdout)")
+    stateDir := flag.String("state", "var/etl/state", "directory for per-source incremental state")
+    cacheDir := flag.String("cache", "var/etl/cache", "directory for the raw artifact cache")
+    full := flag.Bool("full", false, "emit every node and edge, not only changes since the last run")
+    force := flag.Bool("force", false, "parse even if no download changed")
+    list := flag.Bool("list", false, "list configured sources and whether they have an importer")
+    var urls []string
+    flag.Func("url", "URL to fetch instead of the descriptor's (repeatable)", func(s string) error { urls = append(urls, s); return nil })
+    flag.Parse()
+
+    descs, err := loadSources(*sourcesFile)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "sources: %v\n", err)
+        os.Exit(1)
+    }
+    reg := sources.Registry()
+    if *list {
+        known := map[string]bool{}
+        for _, n := range reg.Names() { known[n] = true }
+        for _, d := range descs {
+            mark := "-"
+            if known[d.Name] { mark = "+" }
+            fmt.Printf("%s %s (%s)\n", mark, d.Name, d.Kind)
+        }
+        return
+    }
+    var desc *conf.SourceDescriptor
+    for i := range descs {
+        if descs[i].Name == *name { desc = &descs[i] }
+    }
+    if desc == nil {
+        fmt.Fprintf(os.Stderr, "unknown source %q (see -list)\n", *name)
+        os.Exit(2)
+    }
+    dst := os.Stdout
+    if *out != "" {
+        f, err := os.Create(*out)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "create %s: %v\n", *out, err)
+            os.Exit(1)
+        }
+        defer f.Close()
+        dst = f
+    }
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+    runner := &etl.Runner{Registry: reg, Cache: &etl.Cache{Dir: *cacheDir}, StateDir: *stateDir}
+    rep, err := runner.Run(ctx, *desc, dst, etl.RunOptions{URLs: urls, Full: *full, Force: *force})
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    b, _ := json.Marshal(rep)
+    fmt.Fprintln(os.Stderr, string(b))
+}
+
+func loadSources(path string) ([]conf.SourceDescriptor, error) {
+    if path == "" {
+        path = "configs/sources.example.json"
+        if _, err := os.Stat("configs/sources.json"); err == nil { path = "configs/sources.json" }
+    }
+    return conf.LoadSources(path)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "lawmap/internal/export"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+// export writes the whole store in a graph interchange or linked-data format,
+// as neo4j-admin import CSVs, as a Cypher MERGE script, or one subtree as Akoma Ntoso.
+func main() {
+    in := flag.String("in", "docs/EXAMPLES.graph.jsonl", "graph JSONL to export")
+    format := flag.String("format", "graphml", "output format: graphml|gexf|dot|jsonld|turtle|ntriples|neo4j|cypher|akn")
+    base := flag.String("base", "http://localhost:8080", "public base URL for node IRIs in RDF formats")
+    root := flag.String("root", "", "CODE, TITLE or CHAPTER ID to export with -format akn")
+    out := flag.String("out", "", "output path (default stdout); a directory for -format neo4j")
+    flag.Parse()
+
+    f, ok := export.ParseFormat(*format)
+    if !ok && *format != "neo4j" && *format != "cypher" && *format != "akn" {
+        fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+        os.Exit(2)
+    }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(*in); err != nil {
+        fmt.Fprintf(os.Stderr, "load %s: %v\n", *in, err)
+        os.Exit(1)
+    }
+    if *format == "neo4j" {
+        if *out == "" {
+            fmt.Fprintln(os.Stderr, "-out directory is required for -format neo4j")
+            os.Exit(2)
+        }
+        files, err := export.WriteNeo4jCSV(*out, store.Nodes(), store.Edges())
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "export: %v\n", err)
+            os.Exit(1)
+        }
+        for _, name := range files { fmt.Println(name) }
+        return
+    }
+    dst := os.Stdout
+    if *out != "" {
+        file, err := os.Create(*out)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "create %s: %v\n", *out, err)
+            os.Exit(1)
+        }
+        defer file.Close()
+        dst = file
+    }
+    var err error
+    switch *format {
+    case "cypher":
+        err = export.WriteCypher(dst, store.Nodes(), store.Edges())
+    case "akn":
+        ns, es, serr := store.Subtree(*root)
+        if serr != nil {
+            fmt.Fprintf(os.Stderr, "subtree %q: %v\n", *root, serr)
+            os.Exit(1)
+        }
+        err = export.WriteAkomaNtoso(dst, *root, ns, es, export.Options{BaseURL: *base})
+    default:
+        err = export.Write(dst, f, store.Nodes(), store.Edges(), export.Options{BaseURL: *base})
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "export: %v\n", err)
+        os.Exit(1)
+    }
+}
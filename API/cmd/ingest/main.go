@@ -0,0 +1,127 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "lawmap/internal/etl"
+    "lawmap/internal/etl/cfr"
+    "lawmap/internal/etl/courts"
+    "lawmap/internal/etl/fedreg"
+    "lawmap/internal/etl/leginfo"
+    "lawmap/internal/etl/uscode"
+    graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/citations"
+)
+
+// ingest converts local bulk downloads into graph JSONL the API can load.
+//
+//    go run ./cmd/ingest -source uscode -out usc.jsonl xml_usc18.xml xml_usc42.xml
+//    go run ./cmd/ingest -source uscode -out usc.jsonl ./uscode-xml/
+//    go run ./cmd/ingest -source cfr -out cfr.jsonl CFR-2023-title28-vol1.xml title28_ecfr_2024-06-05.xml
+//    go run ./cmd/ingest -source leginfo -codes CIV,PEN,CONS,BPC -out ca.jsonl ./pubinfo_2025/
+//    go run ./cmd/ingest -source fedreg -out fr.jsonl documents.json FR-2024-02-21.xml
+//    go run ./cmd/ingest -source courtlistener -graph usc.jsonl,ca.jsonl -out opinions.jsonl ./courtlistener-bulk/
+func main() {
+    source := flag.String("source", "uscode", "source to ingest: uscode, cfr, leginfo, fedreg, courtlistener")
+    out := flag.String("out", "", "output JSONL path (default stdout)")
+    fetchedAt := flag.String("fetched-at", "", "version.fetched_at for emitted nodes (RFC 3339, default now)")
+    effective := flag.String("effective-date", "", "version.effective_date for emitted nodes (YYYY-MM-DD, default from the files)")
+    codes := flag.String("codes", "", "leginfo: comma-separated law codes to import (default all)")
+    graphs := flag.String("graph", "", "courtlistener: comma-separated graph JSONL files to resolve statute citations against")
+    flag.Parse()
+
+    // leginfo reads one extracted pubinfo directory; the other sources take files or directories
+    paths, err := flag.Args(), error(nil)
+    switch *source {
+    case "leginfo":
+    case "courtlistener":
+        paths, err = inputs(paths, ".csv", ".csv.gz", ".csv.bz2", ".json", ".jsonl")
+    case "fedreg":
+        paths, err = inputs(paths, ".json", ".xml")
+    default:
+        paths, err = inputs(paths, ".xml")
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "inputs: %v\n", err)
+        os.Exit(1)
+    }
+    if len(paths) == 0 {
+        fmt.Fprintln(os.Stderr, "no input files")
+        os.Exit(2)
+    }
+    dst := os.Stdout
+    if *out != "" {
+        f, err := os.Create(*out)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "create %s: %v\n", *out, err)
+            os.Exit(1)
+        }
+        defer f.Close()
+        dst = f
+    }
+    var res *etl.Result
+    switch *source {
+    case "uscode":
+        res, err = uscode.Import(paths, uscode.Options{FetchedAt: *fetchedAt, EffectiveDate: *effective})
+    case "cfr":
+        res, err = cfr.Import(paths, cfr.Options{FetchedAt: *fetchedAt, EffectiveDate: *effective})
+    case "leginfo":
+        var list []string
+        if *codes != "" { list = strings.Split(*codes, ",") }
+        if len(paths) != 1 {
+            fmt.Fprintln(os.Stderr, "leginfo: pass one pubinfo directory")
+            os.Exit(2)
+        }
+        res, err = leginfo.Import(paths[0], leginfo.Options{FetchedAt: *fetchedAt, Codes: list})
+    case "fedreg":
+        res, err = fedreg.Import(paths, fedreg.Options{FetchedAt: *fetchedAt})
+    case "courtlistener":
+        opts := courts.Options{FetchedAt: *fetchedAt}
+        if *graphs != "" {
+            store := graphrepo.NewMemoryStore()
+            for _, g := range strings.Split(*graphs, ",") {
+                if err := store.LoadJSONL(g); err != nil {
+                    fmt.Fprintf(os.Stderr, "graph %s: %v\n", g, err)
+                    os.Exit(1)
+                }
+            }
+            opts.Statutes = citations.NewIndex(store.Nodes())
+        }
+        res, err = courts.Import(paths, opts)
+    default:
+        fmt.Fprintf(os.Stderr, "unknown source %q\n", *source)
+        os.Exit(2)
+    }
+    if err == nil { err = res.WriteJSONL(dst) }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", *source, err)
+        os.Exit(1)
+    }
+    fmt.Fprintf(os.Stderr, "wrote %d nodes, %d edges\n", len(res.Nodes), len(res.Edges))
+}
+
+// inputs expands directories to the files in them with one of the given extensions, sorted.
+func inputs(args []string, exts ...string) ([]string, error) {
+    var out []string
+    for _, a := range args {
+        fi, err := os.Stat(a)
+        if err != nil { return nil, err }
+        if !fi.IsDir() { out = append(out, a); continue }
+        entries, err := os.ReadDir(a)
+        if err != nil { return nil, err }
+        var matches []string
+        for _, e := range entries {
+            for _, ext := range exts {
+                if !e.IsDir() && strings.HasSuffix(e.Name(), ext) { matches = append(matches, filepath.Join(a, e.Name())); break }
+            }
+        }
+        sort.Strings(matches)
+        out = append(out, matches...)
+    }
+    return out, nil
+}
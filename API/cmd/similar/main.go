@@ -0,0 +1,53 @@
+package main
+
+import (
+    "bufio"
+    "flag"
+    "fmt"
+    "os"
+
+    graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/repo/index"
+)
+
+// similar proposes SAME_AS / SIMILAR_TO edges between lexically similar nodes.
+// Output is JSONL in the loader's edge format with props.status "proposed" so a
+// reviewer can accept lines into the graph by hand.
+func main() {
+    in := flag.String("in", "docs/EXAMPLES.graph.jsonl", "graph JSONL to index")
+    out := flag.String("out", "", "output JSONL path (default stdout)")
+    threshold := flag.Float64("threshold", 0.5, "minimum cosine score to propose an edge")
+    sameAs := flag.Float64("same-as", 0.95, "minimum cosine score to propose SAME_AS instead of SIMILAR_TO")
+    flag.Parse()
+
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(*in); err != nil {
+        fmt.Fprintf(os.Stderr, "load %s: %v\n", *in, err)
+        os.Exit(1)
+    }
+    edges := index.NewSimilarity(store.Nodes()).ProposeEdges(*threshold, *sameAs)
+
+    dst := os.Stdout
+    if *out != "" {
+        f, err := os.Create(*out)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "create %s: %v\n", *out, err)
+            os.Exit(1)
+        }
+        defer f.Close()
+        dst = f
+    }
+    bw := bufio.NewWriter(dst)
+    jw := graphrepo.NewJSONLWriter(bw)
+    for _, e := range edges {
+        if err := jw.WriteEdge(e); err != nil {
+            fmt.Fprintf(os.Stderr, "write: %v\n", err)
+            os.Exit(1)
+        }
+    }
+    if err := bw.Flush(); err != nil {
+        fmt.Fprintf(os.Stderr, "write: %v\n", err)
+        os.Exit(1)
+    }
+    fmt.Fprintf(os.Stderr, "proposed %d edges\n", len(edges))
+}
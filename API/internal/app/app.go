@@ -1,15 +1,23 @@
 package app
 
 import (
+    "context"
     "fmt"
     "os"
+    "strings"
+    "lawmap/internal/etl/scheduler"
+    "lawmap/internal/etl/sources"
     httpapi "lawmap/internal/http"
     graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/auth"
+    "lawmap/internal/services/webhooks"
     conf "lawmap/internal/config"
 )
 
 type App struct {
-    Server *httpapi.Server
+    Server    *httpapi.Server
+    Scheduler *scheduler.Scheduler
+    Webhooks  *webhooks.Service
 }
 
 func New() (*App, error) {
@@ -20,11 +28,15 @@ func New() (*App, error) {
         // Assume the working dir is API/; if not, allow override via EXAMPLES_FILE
         examples = "docs/EXAMPLES.graph.jsonl"
     }
-    if err := store.LoadJSONL(examples); err != nil {
-        return nil, fmt.Errorf("load examples: %w", err)
+    // A comma-separated list loads several graphs into one store, e.g. a code and the
+    // opinions citing it.
+    for _, path := range strings.Split(examples, ",") {
+        if err := store.LoadJSONL(strings.TrimSpace(path)); err != nil {
+            return nil, fmt.Errorf("load examples: %w", err)
+        }
     }
     // Load sources config if available
-    var sources []conf.SourceDescriptor
+    var srcs []conf.SourceDescriptor
     spath := os.Getenv("SOURCES_FILE")
     if spath == "" {
         // prefer project config if present
@@ -36,16 +48,49 @@ func New() (*App, error) {
     }
     if spath != "" {
         if ss, err := conf.LoadSources(spath); err == nil {
-            sources = ss
-            fmt.Printf("Loaded %d sources from %s\n", len(sources), spath)
+            srcs = ss
+            fmt.Printf("Loaded %d sources from %s\n", len(srcs), spath)
         } else {
             fmt.Printf("warn: could not load sources from %s: %v\n", spath, err)
         }
     }
-    server := httpapi.NewServer(store, sources)
-    return &App{Server: server}, nil
+    server := httpapi.NewServer(store, srcs)
+    // Share one CURSOR_SECRET between replicas so list cursors verify on any of them
+    if secret := os.Getenv("CURSOR_SECRET"); secret != "" { server.SetCursorSecret(secret) }
+    app := &App{Server: server}
+    // Scheduled refresh and webhooks, when configs/config.yaml (or CONFIG_FILE) enables them
+    cpath := os.Getenv("CONFIG_FILE")
+    if cpath == "" {
+        if _, err := os.Stat("configs/config.yaml"); err == nil { cpath = "configs/config.yaml" }
+    }
+    if cpath != "" {
+        cfg, err := conf.LoadConfig(cpath)
+        if err != nil { return nil, err }
+        if cfg.Scheduler.Enabled {
+            sch, err := scheduler.New(cfg.Scheduler, srcs, sources.Registry(), store)
+            if err != nil { return nil, err }
+            server.SetScheduler(sch)
+            app.Scheduler = sch
+            fmt.Printf("Scheduling %d sources from %s\n", len(sch.Jobs()), cpath)
+        }
+        if cfg.Auth.Enabled {
+            a, err := auth.New(cfg.Auth)
+            if err != nil { return nil, err }
+            server.SetAuth(a)
+            fmt.Printf("Authentication on (%d API keys)\n", len(cfg.Auth.APIKeys))
+        }
+        if cfg.Webhooks.Enabled {
+            wh, err := webhooks.New(cfg.Webhooks, store)
+            if err != nil { return nil, err }
+            server.SetWebhooks(wh)
+            app.Webhooks = wh
+        }
+    }
+    return app, nil
 }
 
 func (a *App) Start() error {
+    if a.Scheduler != nil { a.Scheduler.Start(context.Background()) }
+    if a.Webhooks != nil { a.Webhooks.Start(context.Background()) }
     return a.Server.Start()
 }
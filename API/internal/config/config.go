@@ -0,0 +1,89 @@
+package config
+
+import (
+    "fmt"
+    "os"
+    "reflect"
+)
+
+// Config corresponds to configs/config.yaml.
+type Config struct {
+    Scheduler SchedulerConfig `json:"scheduler"`
+    Webhooks  WebhooksConfig  `json:"webhooks"`
+    Auth      AuthConfig      `json:"auth"`
+}
+
+// SchedulerConfig drives the refresh daemon (internal/etl/scheduler).
+type SchedulerConfig struct {
+    Enabled  bool   `json:"enabled"`
+    Default  string `json:"default"`   // cron expression for sources without their own
+    StateDir string `json:"state_dir"` // per-source incremental state
+    CacheDir string `json:"cache_dir"` // raw artifact cache
+    RunsFile string `json:"runs_file"` // run log (JSONL); empty keeps it in memory only
+    Sources  []ScheduledSource `json:"sources"`
+}
+
+// ScheduledSource overrides the schedule of one source, by descriptor name.
+type ScheduledSource struct {
+    Name     string   `json:"name"`
+    Schedule string   `json:"schedule"`
+    URLs     []string `json:"urls"` // fetch these instead of the descriptor's URLs
+    Paused   bool     `json:"paused"`
+}
+
+// WebhooksConfig drives subscription delivery (internal/services/webhooks).
+// Durations use Go syntax ("30s", "1h").
+type WebhooksConfig struct {
+    Enabled     bool     `json:"enabled"`
+    File        string   `json:"file"`         // subscriptions and dead letters (JSON); empty keeps them in memory only
+    MaxAttempts int      `json:"max_attempts"` // deliveries failing this often go to the dead-letter list
+    MaxQueue    int      `json:"max_queue"`    // pending deliveries; beyond it new events go to the dead-letter list
+    Backoff     string   `json:"backoff"`      // wait before the first retry, doubled after each failure
+    MaxBackoff  string   `json:"max_backoff"`
+    Timeout     string   `json:"timeout"`      // per request
+    AllowHosts  []string `json:"allow_hosts"`  // hosts, IPs or CIDRs callbacks may reach although loopback, private or link-local
+}
+
+// AuthConfig drives authentication (internal/services/auth). Disabled, every route
+// is open.
+type AuthConfig struct {
+    Enabled bool      `json:"enabled"`
+    APIKeys []APIKey  `json:"api_keys"`
+    JWT     JWTConfig `json:"jwt"`
+}
+
+// APIKey is one static key. Jurisdictions and codes, when set, scope what it may read.
+type APIKey struct {
+    Name          string   `json:"name"`
+    Key           string   `json:"key"`
+    KeySHA256     string   `json:"key_sha256"` // hex digest, instead of key, to keep the secret out of the file
+    Role          string   `json:"role"`       // reader|editor|admin
+    Jurisdictions []string `json:"jurisdictions"`
+    Codes         []string `json:"codes"`
+}
+
+// JWTConfig accepts bearer tokens signed by a key in a local JWKS file. An empty
+// jwks_file turns JWTs off.
+type JWTConfig struct {
+    JWKSFile  string `json:"jwks_file"`
+    Issuer    string `json:"issuer"`     // required iss, when set
+    Audience  string `json:"audience"`   // required in aud, when set
+    RoleClaim string `json:"role_claim"` // default "role"
+}
+
+// LoadConfig reads a YAML config file.
+func LoadConfig(path string) (*Config, error) {
+    b, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("read config: %w", err)
+    }
+    v, err := parseYAML(string(b))
+    if err != nil {
+        return nil, fmt.Errorf("parse config: %w", err)
+    }
+    cfg := &Config{}
+    if err := decodeYAML(v, reflect.ValueOf(cfg).Elem(), ""); err != nil {
+        return nil, fmt.Errorf("parse config: %w", err)
+    }
+    return cfg, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestLoadExampleConfig(t *testing.T) {
+    cfg, err := LoadConfig("../../configs/config.example.yaml")
+    if err != nil { t.Fatal(err) }
+    s := cfg.Scheduler
+    if s.Enabled || s.Default != "0 3 1 * *" || s.RunsFile != "var/etl/runs.jsonl" || len(s.Sources) != 4 { t.Fatalf("scheduler = %+v", s) }
+    fr := s.Sources[0]
+    if fr.Name != "Federal Register" || fr.Schedule != "30 6 * * 1-5" || len(fr.URLs) != 1 { t.Errorf("first source = %+v", fr) }
+    if w := cfg.Webhooks; w.Enabled || w.MaxAttempts != 6 || w.Backoff != "30s" || w.MaxBackoff != "1h" || w.File != "var/webhooks.json" { t.Errorf("webhooks = %+v", w) }
+    if a := cfg.Auth; a.Enabled || len(a.APIKeys) != 3 || a.APIKeys[1].Role != "reader" || len(a.APIKeys[1].Codes) != 2 || a.JWT.Audience != "lawmap" { t.Errorf("auth = %+v", a) }
+    if cl := s.Sources[3]; cl.Name != "CourtListener (opinions/RECAP)" || !cl.Paused || cl.Schedule != "" { t.Errorf("last source = %+v", cl) }
+}
+
+func TestParseYAML(t *testing.T) {
+    src := `
+a: 1
+b: "x # not a comment"   # a comment
+c:
+  - one
+  - 'two'
+d: [x, 2, true]
+e:
+- k: v
+  n:
+    deep: yes
+-
+  k: w
+f:
+`
+    got, err := parseYAML(src)
+    if err != nil { t.Fatal(err) }
+    want := map[string]any{
+        "a": "1", "b": "x # not a comment", "c": []any{"one", "two"}, "d": []any{"x", "2", "true"},
+        "e": []any{map[string]any{"k": "v", "n": map[string]any{"deep": "yes"}}, map[string]any{"k": "w"}},
+        "f": nil,
+    }
+    if !reflect.DeepEqual(got, want) { t.Errorf("got %#v", got) }
+}
+
+func TestLoadConfigTypesScalarsByField(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    src := `
+webhooks:
+  enabled: yes
+  max_attempts: 3
+  backoff: 30s
+auth:
+  api_keys:
+    - name: on
+      key: 123456
+      role: reader
+      codes: [2024, CIV]
+  jwt:
+    audience: 1.5
+`
+    if err := os.WriteFile(path, []byte(src), 0o644); err != nil { t.Fatal(err) }
+    cfg, err := LoadConfig(path)
+    if err != nil { t.Fatal(err) }
+    if w := cfg.Webhooks; !w.Enabled || w.MaxAttempts != 3 || w.Backoff != "30s" { t.Errorf("webhooks = %+v", w) }
+    if k := cfg.Auth.APIKeys[0]; k.Name != "on" || k.Key != "123456" || !reflect.DeepEqual(k.Codes, []string{"2024", "CIV"}) { t.Errorf("key = %+v", k) }
+    if cfg.Auth.JWT.Audience != "1.5" { t.Errorf("audience = %q", cfg.Auth.JWT.Audience) }
+
+    if err := os.WriteFile(path, []byte("webhooks:\n  max_attempts: many\n"), 0o644); err != nil { t.Fatal(err) }
+    if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "webhooks.max_attempts") { t.Errorf("bad integer: %v", err) }
+}
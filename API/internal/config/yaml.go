@@ -0,0 +1,196 @@
+package config
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// parseYAML reads the block-style YAML subset the config files use: nested
+// mappings, "- " sequences (of scalars or mappings), flow sequences of scalars
+// ("[a, b]"), plain or quoted scalars and "#" comments. Values come back as
+// map[string]any, []any, string or nil (null, ~); decodeYAML gives scalars their types.
+func parseYAML(src string) (any, error) {
+    var lines []yamlLine
+    for i, raw := range strings.Split(strings.ReplaceAll(src, "\t", "    "), "\n") {
+        text := stripComment(raw)
+        if strings.TrimSpace(text) == "" || strings.TrimSpace(text) == "---" { continue }
+        lines = append(lines, yamlLine{no: i + 1, indent: len(text) - len(strings.TrimLeft(text, " ")), text: strings.TrimSpace(text)})
+    }
+    if len(lines) == 0 { return map[string]any{}, nil }
+    p := &yamlParser{lines: lines}
+    v, err := p.block(lines[0].indent)
+    if err != nil { return nil, err }
+    if p.pos < len(p.lines) { return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].no) }
+    return v, nil
+}
+
+type yamlLine struct {
+    no, indent int
+    text       string
+}
+
+type yamlParser struct {
+    lines []yamlLine
+    pos   int
+}
+
+func (p *yamlParser) block(indent int) (any, error) {
+    if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" { return p.sequence(indent) }
+    return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+    out := map[string]any{}
+    for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+        l := p.lines[p.pos]
+        if strings.HasPrefix(l.text, "- ") { break }
+        key, rest, ok := splitKey(l.text)
+        if !ok { return nil, fmt.Errorf("yaml line %d: expected key: value", l.no) }
+        p.pos++
+        if rest != "" { out[key] = scalar(rest); continue }
+        // nested block: deeper, or a sequence at the same indentation
+        if p.pos < len(p.lines) && (p.lines[p.pos].indent > indent || p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "- ")) {
+            v, err := p.block(p.lines[p.pos].indent)
+            if err != nil { return nil, err }
+            out[key] = v
+            continue
+        }
+        out[key] = nil
+    }
+    return out, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+    out := []any{}
+    for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && (strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-") {
+        l := p.lines[p.pos]
+        item := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+        if item == "" {
+            p.pos++
+            if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent { out = append(out, nil); continue }
+            v, err := p.block(p.lines[p.pos].indent)
+            if err != nil { return nil, err }
+            out = append(out, v)
+            continue
+        }
+        if _, _, ok := splitKey(item); ok && !isQuoted(item) {
+            // "- key: value" starts a mapping indented past the dash
+            p.lines[p.pos] = yamlLine{no: l.no, indent: indent + 2, text: item}
+            v, err := p.mapping(indent + 2)
+            if err != nil { return nil, err }
+            out = append(out, v)
+            continue
+        }
+        p.pos++
+        out = append(out, scalar(item))
+    }
+    return out, nil
+}
+
+// splitKey splits "key: value" (or "key:") outside quotes.
+func splitKey(s string) (string, string, bool) {
+    if isQuoted(s) || strings.HasPrefix(s, "[") { return "", "", false }
+    i := strings.Index(s, ": ")
+    if i < 0 && strings.HasSuffix(s, ":") { i = len(s) - 1 }
+    if i <= 0 { return "", "", false }
+    return strings.Trim(strings.TrimSpace(s[:i]), `"'`), strings.TrimSpace(s[i+1:]), true
+}
+
+func scalar(s string) any {
+    if isQuoted(s) { return unquote(s) }
+    if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+        out := []any{}
+        for _, part := range strings.Split(s[1:len(s)-1], ",") {
+            if part = strings.TrimSpace(part); part != "" { out = append(out, scalar(part)) }
+        }
+        return out
+    }
+    if s == "null" || s == "~" { return nil }
+    return s
+}
+
+// decodeYAML stores a parseYAML value in dst, matching struct fields by their json
+// tags and converting scalars to the field's type, so a key of 2024 or a name of on
+// stay strings. Unknown keys are ignored, as encoding/json does.
+func decodeYAML(v any, dst reflect.Value, path string) error {
+    if v == nil { return nil }
+    bad := func(want string) error { return fmt.Errorf("%s: want %s, got %v", path, want, v) }
+    switch dst.Kind() {
+    case reflect.Struct:
+        m, ok := v.(map[string]any)
+        if !ok { return bad("a mapping") }
+        t := dst.Type()
+        for i := 0; i < t.NumField(); i++ {
+            name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+            if name == "" || name == "-" { continue }
+            if err := decodeYAML(m[name], dst.Field(i), joinPath(path, name)); err != nil { return err }
+        }
+        return nil
+    case reflect.Slice:
+        list, ok := v.([]any)
+        if !ok { return bad("a list") }
+        out := reflect.MakeSlice(dst.Type(), len(list), len(list))
+        for i, item := range list {
+            if err := decodeYAML(item, out.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil { return err }
+        }
+        dst.Set(out)
+        return nil
+    }
+    str, ok := v.(string)
+    if !ok { return bad("a scalar") }
+    switch dst.Kind() {
+    case reflect.String:
+        dst.SetString(str)
+    case reflect.Bool:
+        switch strings.ToLower(str) {
+        case "true", "yes", "on": dst.SetBool(true)
+        case "false", "no", "off": dst.SetBool(false)
+        default: return bad("true or false")
+        }
+    case reflect.Int, reflect.Int64:
+        n, err := strconv.ParseInt(str, 10, 64)
+        if err != nil { return bad("an integer") }
+        dst.SetInt(n)
+    case reflect.Float64:
+        f, err := strconv.ParseFloat(str, 64)
+        if err != nil { return bad("a number") }
+        dst.SetFloat(f)
+    default:
+        return fmt.Errorf("%s: cannot decode into %s", path, dst.Type())
+    }
+    return nil
+}
+
+func joinPath(path, key string) string {
+    if path == "" { return key }
+    return path + "." + key
+}
+
+func isQuoted(s string) bool {
+    return len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'')
+}
+
+func unquote(s string) string {
+    if s[0] == '\'' { return strings.ReplaceAll(s[1:len(s)-1], "''", "'") }
+    if u, err := strconv.Unquote(s); err == nil { return u }
+    return s[1 : len(s)-1]
+}
+
+// stripComment drops a "#" comment that is not inside quotes.
+func stripComment(line string) string {
+    var quote byte
+    for i := 0; i < len(line); i++ {
+        c := line[i]
+        switch {
+        case quote != 0:
+            if c == quote { quote = 0 }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '#' && (i == 0 || line[i-1] == ' '):
+            return strings.TrimRight(line[:i], " ")
+        }
+    }
+    return strings.TrimRight(line, " \r")
+}
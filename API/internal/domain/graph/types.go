@@ -62,8 +62,21 @@ type GraphSliceDTO struct {
 }
 
 type PathDTO struct {
-    Nodes []string `json:"nodes"`
-    Edges []string `json:"edges,omitempty"`
+    Nodes []string  `json:"nodes"`
+    Edges []string  `json:"edges,omitempty"`
+    Items []NodeDTO `json:"items,omitempty"` // ancestor DTOs in path order when expand=nodes
+}
+
+type PathsDTO struct {
+    Paths   map[string]PathDTO `json:"paths"`
+    Missing []string           `json:"missing,omitempty"`
+}
+
+type NeighborsDTO struct {
+    ID    string    `json:"id"`
+    Dir   string    `json:"dir"`
+    Scope string    `json:"scope"`
+    Nodes []NodeDTO `json:"nodes"`
 }
 
 type SearchItem struct {
@@ -76,6 +89,18 @@ type SearchItem struct {
 type SearchResultDTO struct {
     Query      string       `json:"query,omitempty"`
     Items      []SearchItem `json:"items"`
+    Total      int          `json:"total"`
     NextCursor string       `json:"next_cursor,omitempty"`
 }
 
+type SimilarItem struct {
+    ID       string  `json:"id"`
+    Title    string  `json:"title,omitempty"`
+    Citation string  `json:"citation,omitempty"`
+    Score    float64 `json:"score"`
+}
+
+type SimilarResultDTO struct {
+    ID    string        `json:"id"`
+    Items []SimilarItem `json:"items"`
+}
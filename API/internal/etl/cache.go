@@ -0,0 +1,59 @@
+package etl
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// Cache stores raw artifacts on disk by content: a body with digest ab12… lives at
+// <dir>/sha256/ab/ab12…, so a download that did not change is stored once and an
+// artifact can be re-parsed without fetching it again.
+type Cache struct {
+    Dir string
+}
+
+// Put stores the contents of r and returns its "sha256:" hash and path.
+func (c *Cache) Put(r io.Reader) (string, string, error) {
+    tmpDir := filepath.Join(c.Dir, "tmp")
+    if err := os.MkdirAll(tmpDir, 0o755); err != nil { return "", "", err }
+    tmp, err := os.CreateTemp(tmpDir, "put-*")
+    if err != nil { return "", "", err }
+    defer os.Remove(tmp.Name())
+    h := sha256.New()
+    if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+        tmp.Close()
+        return "", "", err
+    }
+    if err := tmp.Close(); err != nil { return "", "", err }
+    hash := "sha256:" + hex.EncodeToString(h.Sum(nil))
+    path := c.Path(hash)
+    if _, err := os.Stat(path); err == nil { return hash, path, nil }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return "", "", err }
+    if err := os.Rename(tmp.Name(), path); err != nil { return "", "", err }
+    return hash, path, nil
+}
+
+// Path is where the artifact with the given hash is (or would be) stored.
+func (c *Cache) Path(hash string) string {
+    hex := strings.TrimPrefix(hash, "sha256:")
+    if len(hex) < 2 { return filepath.Join(c.Dir, "sha256", hex) }
+    return filepath.Join(c.Dir, "sha256", hex[:2], hex)
+}
+
+// Has reports whether the artifact with the given hash is cached.
+func (c *Cache) Has(hash string) bool {
+    _, err := os.Stat(c.Path(hash))
+    return err == nil
+}
+
+// Open opens a cached artifact.
+func (c *Cache) Open(hash string) (*os.File, error) {
+    f, err := os.Open(c.Path(hash))
+    if err != nil { return nil, fmt.Errorf("cache %s: %w", hash, err) }
+    return f, nil
+}
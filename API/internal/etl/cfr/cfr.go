@@ -0,0 +1,324 @@
+// Package cfr imports Code of Federal Regulations XML into graph nodes and edges.
+// Two layouts are read from local files:
+//
+//   - GovInfo annual editions (CFRDOC: TITLE/CHAPTER/SUBCHAP/PART/SUBPART/SECTION,
+//     dated by the "Revised as of" line of the title page);
+//   - eCFR point-in-time XML (DIV1…DIV8 with TYPE and N attributes, dated by
+//     AMDDATE or by a YYYY-MM-DD in the file name).
+//
+// Levels map onto the canonical ladder CODE → TITLE → CHAPTER → REGULATION:
+// subtitles stay TITLE, chapters, subchapters, parts and subparts become CHAPTER
+// and sections become REGULATION. Parts and sections are numbered per title, so
+// they hang off the title ID (US:CFR:T28:PT600, US:CFR:T28:§600.4); chapters do
+// too (US:CFR:T28:CHVI) while subtitles, subchapters and subparts extend their
+// parent's ID (US:CFR:T28:PT0:SPTA).
+//
+// Every input file is one edition. Editions are emitted oldest first and a node
+// is repeated only when its content hash changes, so the store keeps each
+// distinct text as a version of the same node.
+package cfr
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl"
+    "lawmap/internal/pkg/parse"
+)
+
+const (
+    // CodeID is the canonical ID of the Code of Federal Regulations.
+    CodeID = "US:CFR"
+    // AnnualSource and ECFRSource match the GovInfo CFR and eCFR source descriptors.
+    AnnualSource = "GovInfo CFR"
+    ECFRSource   = "eCFR"
+    // AnnualURL and ECFRURL are recorded in sources[] for the two layouts.
+    AnnualURL = "https://www.govinfo.gov/app/collection/CFR"
+    ECFRURL   = "https://www.ecfr.gov/"
+)
+
+// level describes one CFR hierarchy level.
+type level struct {
+    seg   string // ID segment prefix
+    prop  string // props key for the level number
+    name  string // display name
+    label string
+    cite  string // citation abbreviation
+}
+
+var levels = map[string]level{
+    "title":      {"T", "title_num", "Title", "TITLE", ""},
+    "subtitle":   {"ST", "subtitle_num", "Subtitle", "TITLE", "subtit."},
+    "chapter":    {"CH", "chapter_num", "Chapter", "CHAPTER", "ch."},
+    "subchapter": {"SCH", "subchapter_num", "Subchapter", "CHAPTER", "subch."},
+    "part":       {"PT", "part_num", "Part", "CHAPTER", "pt."},
+    "subpart":    {"SPT", "subpart_num", "Subpart", "CHAPTER", "subpt."},
+}
+
+// Annual element names and eCFR DIV TYPE values share a vocabulary; subject groups
+// have no number and are flattened into their parent.
+var kinds = map[string]string{
+    "TITLE": "title", "SUBTITLE": "subtitle", "CHAPTER": "chapter", "SUBCHAP": "subchapter",
+    "PART": "part", "SUBPART": "subpart", "SECTION": "section", "SUBJGRP": "group",
+}
+
+// Section children that are not body text.
+var skipInBody = map[string]bool{
+    "SECTNO": true, "SUBJECT": true, "HEAD": true, "CITA": true, "SECAUTH": true, "EAR": true, "PRTPAGE": true,
+}
+
+var fileDate = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Options control provenance and versioning of emitted nodes.
+type Options struct {
+    // FetchedAt is recorded as version.fetched_at and sources[].retrieved_at (RFC 3339); defaults to now.
+    FetchedAt string
+    // EffectiveDate (YYYY-MM-DD) is used for files that carry no date of their own.
+    EffectiveDate string
+}
+
+// unit is one level of either layout after normalization.
+type unit struct {
+    kind, num, heading string
+    lines              []string
+    authority, source  string
+    kids               []*unit
+}
+
+// Edition is one parsed input file.
+type Edition struct {
+    Layout        string // "annual" or "ecfr"
+    EffectiveDate string
+    title         *unit
+}
+
+// Import reads CFR XML files and returns the CODE node, its PARENT_OF edge from the
+// US jurisdiction and the title subtrees, with one node version per changed edition.
+func Import(paths []string, opts Options) (*etl.Result, error) {
+    if opts.FetchedAt == "" { opts.FetchedAt = time.Now().UTC().Format(time.RFC3339) }
+    var eds []*Edition
+    for _, p := range paths {
+        f, err := os.Open(p)
+        if err != nil { return nil, err }
+        doc, err := parse.ParseXML(f)
+        f.Close()
+        if err == nil {
+            var ed *Edition
+            if ed, err = ParseEdition(doc); err == nil {
+                if ed.EffectiveDate == "" { ed.EffectiveDate = fileDate.FindString(filepath.Base(p)) }
+                if ed.EffectiveDate == "" { ed.EffectiveDate = opts.EffectiveDate }
+                if ed.EffectiveDate == "" { err = fmt.Errorf("no edition date; pass an effective date") }
+                eds = append(eds, ed)
+            }
+        }
+        if err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+    }
+    sort.SliceStable(eds, func(i, j int) bool { return eds[i].EffectiveDate < eds[j].EffectiveDate })
+
+    em := &emitter{opts: opts, res: &etl.Result{}, hashes: map[string]string{}, edgeAt: map[string]int{}}
+    em.res.Nodes = append(em.res.Nodes, &dgraph.Node{ID: CodeID, Labels: []string{"CODE"}, Title: "Code of Federal Regulations",
+        Props: map[string]any{"jurisdiction": "US", "code": "CFR"}})
+    em.edge(etl.ParentOf("US", CodeID, 2))
+    for _, ed := range eds {
+        em.ed = ed
+        em.level(ed.title, CodeID, map[string]any{"jurisdiction": "US", "code": "CFR"}, orderOf(ed.title.num))
+    }
+    return em.res, nil
+}
+
+// ParseEdition normalizes a GovInfo annual (CFRDOC) or eCFR (DIV1) document.
+// EffectiveDate is empty when the document does not state it.
+func ParseEdition(doc *parse.Element) (*Edition, error) {
+    if tp := doc.Find("TITLEPG"); doc.Name == "CFRDOC" || tp != nil {
+        t := doc.Find("TITLE")
+        if t == nil { return nil, fmt.Errorf("no <TITLE> in <%s>", doc.Name) }
+        title := annualUnit(t, "title")
+        title.num = lastField(tp.Child("TITLENUM").Text())
+        title.heading = tp.Child("SUBJECT").Text()
+        return &Edition{Layout: "annual", EffectiveDate: parseDate(strings.TrimPrefix(tp.Child("REVISED").Text(), "Revised as of ")), title: title}, nil
+    }
+    d := doc.Find("DIV1")
+    if d == nil { return nil, fmt.Errorf("neither CFRDOC nor DIV1 in <%s>", doc.Name) }
+    return &Edition{Layout: "ecfr", EffectiveDate: parseDate(doc.Find("AMDDATE").Text()), title: ecfrUnit(d, "title")}, nil
+}
+
+func annualUnit(e *parse.Element, kind string) *unit {
+    u := &unit{kind: kind}
+    switch kind {
+    case "section":
+        u.num = strings.TrimSpace(strings.TrimPrefix(e.Child("SECTNO").Text(), "§"))
+        u.heading = e.Child("SUBJECT").Text()
+        u.lines = bodyLines(e)
+    case "title":
+    default:
+        hd := e.Child("HD")
+        if hd == nil { hd = e.Child("TOC").Child("TOCHD").Child("HD") }
+        u.num, u.heading = splitHeading(hd.Text())
+    }
+    u.authority, u.source = noteText(e.Child("AUTH")), noteText(e.Child("SOURCE"))
+    if kind == "section" { u.source = e.Child("CITA").Text() }
+    for _, c := range e.Elements() {
+        k, ok := kinds[c.Name]
+        if !ok { continue }
+        if k == "group" { u.kids = append(u.kids, annualUnit(c, k).kids...); continue }
+        u.kids = append(u.kids, annualUnit(c, k))
+    }
+    return u
+}
+
+func ecfrUnit(e *parse.Element, kind string) *unit {
+    u := &unit{kind: kind, num: strings.TrimSpace(strings.TrimPrefix(e.Attr("N"), "§"))}
+    head := e.Child("HEAD").Text()
+    switch kind {
+    case "section":
+        u.heading = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(head, "§")), u.num))
+        u.lines = bodyLines(e)
+        u.source = e.Child("CITA").Text()
+    default:
+        num, h := splitHeading(head)
+        u.heading = h
+        if u.num == "" { u.num = num }
+    }
+    if kind != "section" { u.authority, u.source = noteText(e.Child("AUTH")), noteText(e.Child("SOURCE")) }
+    for _, c := range e.Elements() {
+        if !strings.HasPrefix(c.Name, "DIV") { continue }
+        k, ok := kinds[c.Attr("TYPE")]
+        if !ok { continue }
+        if k == "group" { u.kids = append(u.kids, ecfrUnit(c, k).kids...); continue }
+        u.kids = append(u.kids, ecfrUnit(c, k))
+    }
+    return u
+}
+
+// bodyLines returns one line per paragraph-level child of a section.
+func bodyLines(e *parse.Element) []string {
+    var out []string
+    for _, c := range e.Elements() {
+        if skipInBody[c.Name] { continue }
+        if t := c.Text(); t != "" { out = append(out, t) }
+    }
+    return out
+}
+
+// noteText is the body of an AUTH or SOURCE block without its "Authority:" heading.
+func noteText(e *parse.Element) string {
+    var parts []string
+    for _, c := range e.Elements() {
+        if c.Name == "HD" || c.Name == "HED" { continue }
+        if t := c.Text(); t != "" { parts = append(parts, t) }
+    }
+    return strings.Join(parts, " ")
+}
+
+type emitter struct {
+    opts    Options
+    res     *etl.Result
+    ed      *Edition
+    hashes  map[string]string // node ID -> last emitted version hash
+    edgeAt  map[string]int    // edge ID -> index in res.Edges
+    titleID string
+    title   string // title number as written, for citations
+}
+
+func (em *emitter) level(u *unit, parentID string, inherited map[string]any, order int) {
+    if u.kind == "section" { em.section(u, parentID, inherited, order); return }
+    lv := levels[u.kind]
+    props := etl.CopyProps(inherited)
+    props[lv.prop] = etl.Numeric(u.num)
+    var id, cite string
+    switch u.kind {
+    case "title":
+        id = CodeID + ":T" + etl.Pad2(u.num)
+        em.titleID, em.title = id, u.num
+        cite = u.num + " CFR"
+    case "chapter", "part":
+        id = em.titleID + ":" + lv.seg + strings.ToUpper(u.num)
+        cite = em.title + " CFR " + lv.cite + " " + u.num
+    default:
+        id = parentID + ":" + lv.seg + strings.ToUpper(u.num)
+        cite = em.title + " CFR " + lv.cite + " " + u.num
+        if p, ok := inherited["part_num"]; ok && u.kind == "subpart" { cite = fmt.Sprintf("%s CFR pt. %v, subpt. %s", em.title, p, u.num) }
+        if c, ok := inherited["chapter_num"]; ok && u.kind == "subchapter" { cite = fmt.Sprintf("%s CFR ch. %v, subch. %s", em.title, c, u.num) }
+    }
+    title := lv.name + " " + u.num
+    if u.heading != "" { title += " - " + u.heading }
+    n := &dgraph.Node{ID: id, Labels: []string{lv.label}, Title: title, Citation: cite, Props: props}
+    em.node(n, u, "")
+    em.edge(etl.ParentOf(parentID, id, order))
+    childProps := etl.CopyProps(props)
+    for i, k := range u.kids { em.level(k, id, childProps, i+1) }
+}
+
+func (em *emitter) section(u *unit, parentID string, inherited map[string]any, order int) {
+    props := etl.CopyProps(inherited)
+    props["section_num"] = u.num
+    id := em.titleID + ":§" + u.num
+    title := "§ " + u.num
+    if h := strings.TrimSuffix(u.heading, "."); h != "" { title += " " + h }
+    text := strings.Join(u.lines, "\n")
+    n := &dgraph.Node{ID: id, Labels: []string{"REGULATION"}, Title: title, Citation: em.title + " CFR § " + u.num, Text: text, Props: props}
+    em.node(n, u, text)
+    em.edge(etl.ParentOf(parentID, id, order))
+}
+
+// node attaches notes, version and sources and emits n unless its content is unchanged
+// since the previous edition.
+func (em *emitter) node(n *dgraph.Node, u *unit, text string) {
+    if u.authority != "" { n.Props["authority"] = u.authority }
+    if u.source != "" { n.Props["source_note"] = u.source }
+    hash := etl.ContentHash(n.Title, text, u.authority, u.source)
+    if em.hashes[n.ID] == hash { return }
+    em.hashes[n.ID] = hash
+    n.Props["edition"] = em.ed.Layout
+    name, url := AnnualSource, AnnualURL
+    if em.ed.Layout == "ecfr" { name, url = ECFRSource, ECFRURL }
+    n.Version = &dgraph.Version{FetchedAt: em.opts.FetchedAt, EffectiveDate: em.ed.EffectiveDate, Hash: hash}
+    n.Sources = []dgraph.SourceMeta{{Name: name, URL: url, RetrievedAt: em.opts.FetchedAt}}
+    em.res.Nodes = append(em.res.Nodes, n)
+}
+
+// edge emits e once; a later edition's edge with the same ID replaces its order.
+func (em *emitter) edge(e *dgraph.Edge) {
+    if i, ok := em.edgeAt[e.ID]; ok { em.res.Edges[i] = e; return }
+    em.edgeAt[e.ID] = len(em.res.Edges)
+    em.res.Edges = append(em.res.Edges, e)
+}
+
+// splitHeading splits "PART 600—GENERAL POWERS OF SPECIAL COUNSEL" into "600" and the heading.
+func splitHeading(s string) (string, string) {
+    for _, sep := range []string{"—", "--", " - "} {
+        if i := strings.Index(s, sep); i >= 0 { return lastField(s[:i]), strings.TrimSpace(s[i+len(sep):]) }
+    }
+    return lastField(s), ""
+}
+
+func lastField(s string) string {
+    f := strings.Fields(s)
+    if len(f) == 0 { return "" }
+    return f[len(f)-1]
+}
+
+// parseDate reads "July 1, 2023" or "Jun. 5, 2024" as YYYY-MM-DD; "" when it does not parse.
+func parseDate(s string) string {
+    s = strings.TrimSpace(s)
+    for _, layout := range []string{"January 2, 2006", "Jan. 2, 2006", "Jan 2, 2006", "2006-01-02"} {
+        if t, err := time.Parse(layout, s); err == nil { return t.Format("2006-01-02") }
+    }
+    return ""
+}
+
+// orderOf uses a title's number as its order under the code, as the example graph does.
+func orderOf(num string) int {
+    n := 0
+    for _, c := range num {
+        if c < '0' || c > '9' { break }
+        n = n*10 + int(c-'0')
+    }
+    return n
+}
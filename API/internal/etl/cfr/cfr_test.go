@@ -0,0 +1,80 @@
+package cfr
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+// The eCFR snapshot is listed first: editions are ordered by date, not by argument order.
+var fixtures = []string{"../../test/fixtures/cfr/title28_ecfr_2024-06-05.xml", "../../test/fixtures/cfr/title28_annual_2023.xml"}
+
+func TestImportLadderAndEditions(t *testing.T) {
+    res, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    versions := map[string][]*dgraph.Node{}
+    for _, n := range res.Nodes { versions[n.ID] = append(versions[n.ID], n) }
+    labels := map[string]string{
+        "US:CFR": "CODE", "US:CFR:T28": "TITLE", "US:CFR:T28:CHI": "CHAPTER", "US:CFR:T28:PT0": "CHAPTER",
+        "US:CFR:T28:PT0:SPTA": "CHAPTER", "US:CFR:T28:§0.1": "REGULATION", "US:CFR:T28:CHVI": "CHAPTER", "US:CFR:T28:§600.4": "REGULATION",
+    }
+    for id, want := range labels {
+        vs := versions[id]
+        if len(vs) == 0 { t.Fatalf("missing %s", id) }
+        if vs[0].Labels[0] != want { t.Errorf("%s label = %s, want %s", id, vs[0].Labels[0], want) }
+    }
+    // unchanged content is emitted once, from the annual edition
+    for _, id := range []string{"US:CFR:T28", "US:CFR:T28:PT600", "US:CFR:T28:§0.1", "US:CFR:T28:§600.1"} {
+        if vs := versions[id]; len(vs) != 1 || vs[0].Version.EffectiveDate != "2023-07-01" || vs[0].Sources[0].Name != AnnualSource {
+            t.Errorf("%s versions = %d", id, len(vs))
+        }
+    }
+    vs := versions["US:CFR:T28:§600.4"]
+    if len(vs) != 2 { t.Fatalf("§600.4 versions = %d", len(vs)) }
+    old, cur := vs[0], vs[1]
+    if old.Version.EffectiveDate != "2023-07-01" || cur.Version.EffectiveDate != "2024-06-05" || old.Version.Hash == cur.Version.Hash {
+        t.Errorf("versions = %+v / %+v", old.Version, cur.Version)
+    }
+    if cur.Sources[0].Name != ECFRSource || cur.Props["edition"] != "ecfr" || old.Props["edition"] != "annual" { t.Errorf("provenance = %v / %v", cur.Sources, cur.Props) }
+    if cur.Title != "§ 600.4 Jurisdiction" || cur.Citation != "28 CFR § 600.4" || !strings.Contains(cur.Text, "set out in a written order.\n(b) Additional jurisdiction.") {
+        t.Errorf("section = %q / %q / %q", cur.Title, cur.Citation, cur.Text)
+    }
+    if cur.Props["title_num"] != float64(28) || cur.Props["chapter_num"] != "VI" || cur.Props["part_num"] != float64(600) || cur.Props["section_num"] != "600.4" {
+        t.Errorf("inherited props = %v", cur.Props)
+    }
+    sp := versions["US:CFR:T28:PT0:SPTA"][0]
+    if sp.Citation != "28 CFR pt. 0, subpt. A" || sp.Title != "Subpart A - Organizational Structure of the Department of Justice" { t.Errorf("subpart = %q / %q", sp.Citation, sp.Title) }
+    pt := versions["US:CFR:T28:PT600"][0]
+    if pt.Props["authority"] != "5 U.S.C. 301; 28 U.S.C. 509, 510, 515–519." || pt.Props["source_note"] != "64 FR 37041, July 9, 1999, unless otherwise noted." { t.Errorf("part notes = %v", pt.Props) }
+    if s := versions["US:CFR:T28:§0.1"][0]; strings.Count(s.Text, "\n") != 2 || s.Props["source_note"] != "[Order No. 2865-2007, 72 FR 10064, Mar. 7, 2007]" { t.Errorf("§0.1 = %q / %v", s.Text, s.Props) }
+    seen := map[string]bool{}
+    for _, e := range res.Edges {
+        if seen[e.ID] { t.Errorf("duplicate edge %s", e.ID) }
+        seen[e.ID] = true
+    }
+}
+
+func TestImportLoadsAsVersions(t *testing.T) {
+    res, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    var buf bytes.Buffer
+    if err := res.WriteJSONL(&buf); err != nil { t.Fatal(err) }
+    path := filepath.Join(t.TempDir(), "cfr.jsonl")
+    if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(path); err != nil { t.Fatal(err) }
+    hist := store.History("US:CFR:T28:§600.4")
+    if len(hist) != 2 || hist[0].Version.EffectiveDate != "2023-07-01" || hist[1].Version.EffectiveDate != "2024-06-05" { t.Fatalf("history = %v", hist) }
+    if n, _ := store.GetNode("US:CFR:T28:§600.4"); !strings.Contains(n.Text, "written order") { t.Errorf("current text = %q", n.Text) }
+    kids, _ := store.GetChildren("US:CFR:T28:PT600")
+    if len(kids) != 2 || kids[0].ID != "US:CFR:T28:§600.1" || kids[1].ID != "US:CFR:T28:§600.4" { t.Fatalf("children = %v", kids) }
+    // loading the same editions again adds neither versions nor edges
+    if err := store.LoadJSONL(path); err != nil { t.Fatal(err) }
+    if len(store.History("US:CFR:T28:§600.4")) != 2 { t.Errorf("reload duplicated versions") }
+    if kids, _ := store.GetChildren("US:CFR:T28:PT600"); len(kids) != 2 { t.Errorf("reload duplicated edges: %v", kids) }
+}
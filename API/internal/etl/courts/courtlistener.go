@@ -0,0 +1,329 @@
+// Package courts imports court opinions. CourtListener bulk data
+// (https://www.courtlistener.com/help/api/bulk-data/) is read from local files:
+// courts, dockets, opinion-clusters, opinions and citation-map, as the CSV dumps
+// (optionally .gz or .bz2) or as JSON/JSONL records with the same field names.
+//
+// Each opinion becomes an OPINION node carrying the court, filing date, docket and
+// judges of its cluster. Rows of the citation map become opinion-to-opinion CITES
+// edges. Statutory citations found in the opinion text are resolved against a
+// citations.Index and linked with CITES (pin cite and mention count in props) and,
+// where the court construes the provision, INTERPRETS.
+package courts
+
+import (
+    "compress/bzip2"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "html"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl"
+    "lawmap/internal/pkg/parse"
+    "lawmap/internal/services/citations"
+)
+
+// SourceName matches the CourtListener source descriptor.
+const SourceName = "CourtListener (opinions/RECAP)"
+
+// Options control provenance and statute linking.
+type Options struct {
+    // FetchedAt is recorded as version.fetched_at and sources[].retrieved_at (RFC 3339); defaults to now.
+    FetchedAt string
+    // Statutes resolves citations found in opinion text; nil keeps them in props.statutes only.
+    Statutes *citations.Index
+}
+
+// record is one row keyed by column or JSON field name.
+type record map[string]string
+
+// Opinion text sources in order of preference; HTML is reduced to text.
+var textFields = []string{"plain_text", "html_with_citations", "html", "html_lawbox", "html_columbia", "xml_harvard", "html_anon_2020"}
+
+// interpretive marks a sentence in which the court construes the statute it cites.
+var interpretive = regexp.MustCompile(`(?i)\b(interpret|constru|meaning of|we read|as used in|defin)`)
+
+// interpretMentions is how many mentions of one provision make an opinion INTERPRETS it regardless of wording.
+const interpretMentions = 3
+
+// Import reads CourtListener bulk files, classified by file name prefix, and returns
+// OPINION nodes with their CITES and INTERPRETS edges.
+func Import(paths []string, opts Options) (*etl.Result, error) {
+    if opts.FetchedAt == "" { opts.FetchedAt = time.Now().UTC().Format(time.RFC3339) }
+    tables := map[string][]record{}
+    for _, p := range paths {
+        kind := tableOf(filepath.Base(p))
+        if kind == "" { return nil, fmt.Errorf("%s: not a CourtListener bulk file (courts, dockets, opinion-clusters, opinions, citation-map)", p) }
+        recs, err := readRecords(p)
+        if err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+        tables[kind] = append(tables[kind], recs...)
+    }
+    courts, dockets, clusters := byID(tables["courts"]), byID(tables["dockets"]), byID(tables["opinion-clusters"])
+
+    res := &etl.Result{}
+    ids := map[string]string{} // CourtListener opinion id -> node ID
+    ops := tables["opinions"]
+    sort.SliceStable(ops, func(i, j int) bool { return atoi(ops[i]["id"]) < atoi(ops[j]["id"]) })
+    for _, op := range ops {
+        cl := clusters[refID(op["cluster_id"], op["cluster"])]
+        dk := dockets[refID(cl["docket_id"], cl["docket"])]
+        court := refID(dk["court_id"], dk["court"])
+        n := opinionNode(op, cl, dk, courts[court], court, opts)
+        ids[op["id"]] = n.ID
+        res.Nodes = append(res.Nodes, n)
+        res.Edges = append(res.Edges, statuteEdges(n, opts.Statutes)...)
+    }
+    for _, c := range tables["citation-map"] {
+        from, ok1 := ids[refID(c["citing_opinion_id"], c["citing_opinion"])]
+        to, ok2 := ids[refID(c["cited_opinion_id"], c["cited_opinion"])]
+        if !ok1 || !ok2 || from == to { continue }
+        props := map[string]any{}
+        if d := atoi(c["depth"]); d > 0 { props["depth"] = float64(d) }
+        res.Edges = append(res.Edges, &dgraph.Edge{ID: "cites:" + from + "->" + to, EdgeType: "CITES", FromID: from, ToID: to, Props: props})
+    }
+    return res, nil
+}
+
+func opinionNode(op, cl, dk, court record, courtID string, opts Options) *dgraph.Node {
+    j := jurisdictionOf(courtID)
+    seg := strings.ToUpper(courtID)
+    if seg == "" { seg = "UNKNOWN" }
+    id := j + ":OPN:" + seg + ":CL" + op["id"]
+
+    name := first(cl["case_name"], cl["case_name_short"], cl["case_name_full"], dk["case_name"])
+    filed := cl["date_filed"]
+    title := name
+    if len(filed) >= 4 { title += " (" + filed[:4] + ")" }
+    typ := opinionType(op["type"])
+    if typ != "" && typ != "lead" && typ != "combined" {
+        title += " - " + typ
+        if a := op["author_str"]; a != "" { title += " (" + a + ")" }
+    }
+    text := ""
+    for _, f := range textFields {
+        if v := op[f]; strings.TrimSpace(v) != "" {
+            if f == "plain_text" { text = strings.TrimSpace(v) } else { text = stripTags(v) }
+            break
+        }
+    }
+    props := map[string]any{"jurisdiction": j, "court": courtID, "case_name": name, "cluster_id": etl.Numeric(cl["id"]), "opinion_id": etl.Numeric(op["id"])}
+    set := func(k, v string) { if v != "" { props[k] = v } }
+    set("court_name", court["full_name"])
+    set("date_filed", filed)
+    set("docket_number", dk["docket_number"])
+    set("opinion_type", typ)
+    set("author", op["author_str"])
+    set("precedential_status", cl["precedential_status"])
+    if d := refID(cl["docket_id"], cl["docket"]); d != "" { props["docket_id"] = etl.Numeric(d) }
+    if js := splitNames(cl["judges"]); len(js) > 0 { props["judges"] = js }
+    if js := splitNames(op["joined_by_str"]); len(js) > 0 { props["joined_by"] = js }
+    if op["per_curiam"] == "t" || op["per_curiam"] == "true" || op["per_curiam"] == "True" { props["per_curiam"] = true }
+
+    url := "https://www.courtlistener.com/opinion/" + cl["id"] + "/"
+    if s := cl["slug"]; s != "" { url += s + "/" }
+    return &dgraph.Node{ID: id, Labels: []string{"OPINION"}, Title: title, Text: text, Props: props,
+        Version: &dgraph.Version{FetchedAt: opts.FetchedAt, EffectiveDate: filed, Hash: etl.ContentHash(title, text)},
+        Sources: []dgraph.SourceMeta{{Name: SourceName, URL: url, RetrievedAt: opts.FetchedAt}}}
+}
+
+// statuteEdges extracts statutory citations from n's text, records them in
+// props.statutes and links the resolvable ones.
+func statuteEdges(n *dgraph.Node, ix *citations.Index) []*dgraph.Edge {
+    cites := citations.Extract(n.Text)
+    if len(cites) == 0 { return nil }
+    type link struct {
+        pin       string
+        mentions  int
+        construed bool
+    }
+    var seen []string
+    found := map[string]bool{}
+    links := map[string]*link{}
+    var order []string
+    for _, c := range cites {
+        if s := c.String(); !found[s] { found[s] = true; seen = append(seen, s) }
+        if ix == nil { continue }
+        to, ok := ix.Resolve(c)
+        if !ok { continue }
+        l := links[to]
+        if l == nil { l = &link{pin: c.String()}; links[to] = l; order = append(order, to) }
+        l.mentions++
+        if interpretive.MatchString(sentenceAt(n.Text, c.Start, c.End)) { l.construed = true }
+    }
+    n.Props["statutes"] = seen
+    var out []*dgraph.Edge
+    for _, to := range order {
+        l := links[to]
+        out = append(out, &dgraph.Edge{ID: "cites:" + n.ID + "->" + to, EdgeType: "CITES", FromID: n.ID, ToID: to,
+            Props: map[string]any{"pin_cite": l.pin, "mentions": float64(l.mentions)}})
+        if l.construed || l.mentions >= interpretMentions {
+            out = append(out, &dgraph.Edge{ID: "interprets:" + n.ID + "->" + to, EdgeType: "INTERPRETS", FromID: n.ID, ToID: to})
+        }
+    }
+    return out
+}
+
+// sentenceAt returns the sentence of text around [start, end).
+func sentenceAt(text string, start, end int) string {
+    s, e := 0, len(text)
+    for i := start - 1; i >= 0; i-- {
+        if isBoundary(text, i) { s = i + 1; break }
+    }
+    for i := end; i < len(text); i++ {
+        if isBoundary(text, i) { e = i; break }
+    }
+    return text[s:e]
+}
+
+// isBoundary reports whether text[i] ends a sentence: a semicolon, a line break, or a
+// period followed by a space and a capital letter (so "U.S.C. §" does not end one).
+func isBoundary(text string, i int) bool {
+    switch text[i] {
+    case ';', '\n':
+        return true
+    case '.':
+        return i+2 < len(text) && text[i+1] == ' ' && text[i+2] >= 'A' && text[i+2] <= 'Z'
+    }
+    return false
+}
+
+// readRecords reads a CSV file with a header row, a JSON array or JSON lines.
+func readRecords(path string) ([]record, error) {
+    f, err := os.Open(path)
+    if err != nil { return nil, err }
+    defer f.Close()
+    var r io.Reader = f
+    name := strings.ToLower(path)
+    switch {
+    case strings.HasSuffix(name, ".gz"):
+        gz, err := gzip.NewReader(f)
+        if err != nil { return nil, err }
+        defer gz.Close()
+        r, name = gz, strings.TrimSuffix(name, ".gz")
+    case strings.HasSuffix(name, ".bz2"):
+        r, name = bzip2.NewReader(f), strings.TrimSuffix(name, ".bz2")
+    }
+    if strings.HasSuffix(name, ".csv") {
+        rows, err := parse.ReadCSV(r)
+        if err != nil || len(rows) == 0 { return nil, err }
+        var out []record
+        for _, row := range rows[1:] {
+            rec := record{}
+            for i, h := range rows[0] {
+                if i < len(row) { rec[strings.TrimSpace(h)] = row[i] }
+            }
+            out = append(out, rec)
+        }
+        return out, nil
+    }
+    var out []record
+    dec := json.NewDecoder(r)
+    for {
+        var v any
+        if err := dec.Decode(&v); err == io.EOF { break } else if err != nil { return nil, err }
+        items, ok := v.([]any)
+        if !ok { items = []any{v} }
+        for _, it := range items {
+            if m, ok := it.(map[string]any); ok { out = append(out, flatten(m)) }
+        }
+    }
+    return out, nil
+}
+
+// flatten stringifies JSON fields the way the CSV dumps write them.
+func flatten(m map[string]any) record {
+    rec := record{}
+    for k, v := range m {
+        switch x := v.(type) {
+        case nil:
+        case string:
+            rec[k] = x
+        case bool:
+            rec[k] = strconv.FormatBool(x)
+        case float64:
+            rec[k] = strconv.FormatFloat(x, 'f', -1, 64)
+        default:
+            b, _ := json.Marshal(x)
+            rec[k] = string(b)
+        }
+    }
+    return rec
+}
+
+// tableOf names the bulk table a file holds from its name, e.g. opinions-2024-12-31.csv.bz2.
+func tableOf(base string) string {
+    for _, t := range []string{"opinion-clusters", "citation-map", "opinions", "dockets", "courts"} {
+        if strings.HasPrefix(base, t) { return t }
+    }
+    if strings.HasPrefix(base, "clusters") { return "opinion-clusters" }
+    return ""
+}
+
+func byID(recs []record) map[string]record {
+    m := make(map[string]record, len(recs))
+    for _, r := range recs { m[r["id"]] = r }
+    return m
+}
+
+// refID returns the id column, or the trailing ID of an API URL such as
+// https://www.courtlistener.com/api/rest/v4/clusters/123/ in JSON records.
+func refID(id, url string) string {
+    if id != "" { return id }
+    parts := strings.Split(strings.Trim(url, "/"), "/")
+    return parts[len(parts)-1]
+}
+
+// jurisdictionOf maps California courts to CA and everything else to US.
+func jurisdictionOf(courtID string) string {
+    if strings.HasPrefix(courtID, "cal") { return "CA" }
+    return "US"
+}
+
+// opinionType turns CourtListener's "040dissent" into "dissent".
+func opinionType(t string) string {
+    return strings.TrimLeft(t, "0123456789")
+}
+
+var (
+    tags   = regexp.MustCompile(`<[^>]*>`)
+    blocks = regexp.MustCompile(`(?i)</(p|div|blockquote|h\d)>|<br\s*/?>`)
+)
+
+// stripTags reduces opinion HTML to text with one line per block.
+func stripTags(s string) string {
+    s = blocks.ReplaceAllString(s, "\n")
+    s = html.UnescapeString(tags.ReplaceAllString(s, ""))
+    var lines []string
+    for _, l := range strings.Split(s, "\n") {
+        if l = parse.Collapse(l); l != "" { lines = append(lines, l) }
+    }
+    return strings.Join(lines, "\n")
+}
+
+func splitNames(s string) []string {
+    var out []string
+    for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+        if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+    }
+    return out
+}
+
+func first(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
+
+func atoi(s string) int {
+    n, _ := strconv.Atoi(strings.TrimSpace(s))
+    return n
+}
@@ -0,0 +1,69 @@
+package courts
+
+import (
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/citations"
+)
+
+func importFixtures(t *testing.T) *graphResult {
+    t.Helper()
+    paths, err := filepath.Glob("../../test/fixtures/courtlistener/*-2024-12-31.*")
+    if err != nil || len(paths) != 5 { t.Fatalf("fixtures: %v %v", paths, err) }
+    sort.Strings(paths)
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    res, err := Import(paths, Options{FetchedAt: "2025-01-01T00:00:00Z", Statutes: citations.NewIndex(store.Nodes())})
+    if err != nil { t.Fatal(err) }
+    g := &graphResult{nodes: map[string]*dgraph.Node{}, edges: map[string]*dgraph.Edge{}}
+    for _, n := range res.Nodes { g.nodes[n.ID] = n }
+    for _, e := range res.Edges { g.edges[e.ID] = e }
+    return g
+}
+
+type graphResult struct {
+    nodes map[string]*dgraph.Node
+    edges map[string]*dgraph.Edge
+}
+
+func TestImportOpinions(t *testing.T) {
+    g := importFixtures(t)
+    if len(g.nodes) != 4 { t.Fatalf("nodes = %d", len(g.nodes)) }
+    j := g.nodes["US:OPN:SCOTUS:CL7001"]
+    if j == nil { t.Fatal("missing Johnson lead opinion") }
+    if j.Title != "Johnson v. United States (2015)" || j.Labels[0] != "OPINION" { t.Errorf("title = %q", j.Title) }
+    if j.Props["court"] != "scotus" || j.Props["court_name"] != "Supreme Court of the United States" || j.Props["date_filed"] != "2015-06-26" ||
+        j.Props["docket_number"] != "13-7120" || j.Props["author"] != "Scalia" || j.Version.EffectiveDate != "2015-06-26" {
+        t.Errorf("props = %v", j.Props)
+    }
+    if js, _ := j.Props["judges"].([]string); len(js) != 9 || js[0] != "Scalia" { t.Errorf("judges = %v", j.Props["judges"]) }
+    if !strings.Contains(j.Text, `a "violent felony," a term`) || !strings.Contains(j.Text, "\nFederal law forbids") { t.Errorf("text = %q", j.Text) }
+    if d := g.nodes["US:OPN:SCOTUS:CL7002"]; d.Title != "Johnson v. United States (2015) - dissent (Alito)" || d.Props["opinion_type"] != "dissent" { t.Errorf("dissent = %q %v", d.Title, d.Props) }
+    ca := g.nodes["CA:OPN:CALCTAPP:CL7201"]
+    if ca == nil || !strings.HasPrefix(ca.Text, "Plaintiff was bitten by defendant’s dog") || !strings.Contains(ca.Text, "\nCivil Code section 3342") { t.Fatalf("CA opinion = %+v", ca) }
+    if st, _ := ca.Props["statutes"].([]string); strings.Join(st, "|") != "CIV § 3342|PEN § 187" { t.Errorf("statutes = %v", ca.Props["statutes"]) }
+}
+
+func TestImportCitationEdges(t *testing.T) {
+    g := importFixtures(t)
+    // opinion citation map; the row citing an opinion outside the import is dropped
+    if e := g.edges["cites:US:OPN:SCOTUS:CL7101->US:OPN:SCOTUS:CL7001"]; e == nil || e.Props["depth"] != float64(12) { t.Errorf("Welch -> Johnson = %+v", e) }
+    if g.edges["cites:US:OPN:SCOTUS:CL7002->US:OPN:SCOTUS:CL7001"] == nil { t.Errorf("dissent -> lead missing") }
+    // statute links: pin cites resolve to the most specific node, 922(g) is not in the graph
+    acca := "US:USC:T18:§924(e)"
+    e := g.edges["cites:US:OPN:SCOTUS:CL7001->"+acca]
+    if e == nil || e.Props["pin_cite"] != "18 USC § 924(e)(2)(B)" || e.Props["mentions"] != float64(1) { t.Fatalf("Johnson CITES = %+v", e) }
+    if g.edges["interprets:US:OPN:SCOTUS:CL7001->"+acca] == nil { t.Errorf("Johnson should INTERPRET 924(e) (\"a term defined\")") }
+    if g.edges["interprets:US:OPN:SCOTUS:CL7002->"+acca] != nil { t.Errorf("a single neutral mention should only CITE") }
+    civ := "CA:CIV:T02:CH02:§3342"
+    if e := g.edges["cites:CA:OPN:CALCTAPP:CL7201->"+civ]; e == nil || e.Props["mentions"] != float64(2) { t.Errorf("CA CITES = %+v", e) }
+    if g.edges["interprets:CA:OPN:CALCTAPP:CL7201->"+civ] == nil { t.Errorf("CA opinion construes § 3342") }
+    for id, e := range g.edges {
+        if _, ok := g.nodes[e.FromID]; !ok { t.Errorf("%s from unknown node", id) }
+    }
+}
@@ -0,0 +1,70 @@
+// Package etl holds what the source importers share: the Result they produce and
+// helpers for deterministic IDs, content hashes and ordered PARENT_OF edges.
+package etl
+
+import (
+    "bufio"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+// Result is the graph produced by an import.
+type Result struct {
+    Nodes []*dgraph.Node
+    Edges []*dgraph.Edge
+}
+
+// WriteJSONL writes the result in the loader's item format, nodes first.
+func (res *Result) WriteJSONL(w io.Writer) error {
+    bw := bufio.NewWriter(w)
+    jw := graphrepo.NewJSONLWriter(bw)
+    for _, n := range res.Nodes {
+        if err := jw.WriteNode(n); err != nil { return err }
+    }
+    for _, e := range res.Edges {
+        if err := jw.WriteEdge(e); err != nil { return err }
+    }
+    return bw.Flush()
+}
+
+// Append adds another result's nodes and edges.
+func (res *Result) Append(o *Result) {
+    res.Nodes = append(res.Nodes, o.Nodes...)
+    res.Edges = append(res.Edges, o.Edges...)
+}
+
+// ParentOf builds a PARENT_OF edge with a stable ID so re-imports replace rather than duplicate it.
+func ParentOf(from, to string, order int) *dgraph.Edge {
+    return &dgraph.Edge{ID: "po:" + from + "->" + to, EdgeType: "PARENT_OF", FromID: from, ToID: to, Props: map[string]any{"order": float64(order)}}
+}
+
+// ContentHash is the version.hash of normalized content: "sha256:" + hex digest of parts joined by newlines.
+func ContentHash(parts ...string) string {
+    sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+    return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Numeric stores plain integers as numbers, as props.title_num / chapter_num expect.
+func Numeric(s string) any {
+    if n, err := strconv.Atoi(s); err == nil { return float64(n) }
+    return s
+}
+
+// Pad2 zero-pads single-digit numbers for ID segments (T02); other values are upper-cased.
+func Pad2(s string) string {
+    if len(s) == 1 && s[0] >= '0' && s[0] <= '9' { return "0" + s }
+    return strings.ToUpper(s)
+}
+
+// CopyProps returns a shallow copy of m for a child to extend.
+func CopyProps(m map[string]any) map[string]any {
+    out := make(map[string]any, len(m)+2)
+    for k, v := range m { out[k] = v }
+    return out
+}
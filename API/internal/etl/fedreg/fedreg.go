@@ -0,0 +1,399 @@
+// Package fedreg imports Federal Register documents from local files: API JSON
+// (a document, an array of documents or a search response with "results") and the
+// GovInfo daily issue XML (FEDREG with RULES/PRORULES/NOTICES/PRESDOCS) or a single
+// document's full-text XML. JSON and XML for the same document number are merged:
+// JSON supplies the metadata, XML the amendatory instructions.
+//
+// Each document becomes an FR_DOCUMENT node under its volume
+// (US:FR → US:FR:V89 → US:FR:2024-03412) with agencies, dockets, RINs and
+// publication, effective and comment dates in props. Rules and proposed rules get
+// AMENDS edges to the CFR sections named in their amendatory instructions (AMDPAR),
+// and to the parts in cfr_references that no instruction narrows down to sections.
+// Target IDs follow the cfr importer (US:CFR:T28:§600.4, US:CFR:T28:PT600), so a
+// regulation's incoming AMENDS edges are its rulemaking history.
+package fedreg
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl"
+    "lawmap/internal/pkg/parse"
+)
+
+const (
+    // CodeID is the canonical ID of the Federal Register.
+    CodeID = "US:FR"
+    // SourceName matches the Federal Register source descriptor.
+    SourceName = "Federal Register"
+    // DocumentURL is the federalregister.gov short link for a document number.
+    DocumentURL = "https://www.federalregister.gov/d/"
+)
+
+// Document types by XML element and by API "type".
+var xmlTypes = map[string]string{"RULE": "rule", "PRORULE": "proposed_rule", "NOTICE": "notice", "PRESDOCU": "presidential_document"}
+var apiTypes = map[string]string{"Rule": "rule", "Proposed Rule": "proposed_rule", "Notice": "notice", "Presidential Document": "presidential_document"}
+
+// Options control provenance.
+type Options struct {
+    // FetchedAt is recorded as version.fetched_at and sources[].retrieved_at (RFC 3339); defaults to now.
+    FetchedAt string
+}
+
+// Amendment is one affected CFR unit of a document.
+type Amendment struct {
+    Title, Part, Section string // Section is empty for a whole part
+    Action               string // revise, add, remove, redesignate, amend, ...
+    Instruction          string // the AMDPAR text
+}
+
+// document collects what JSON and XML say about one document number.
+type document struct {
+    number, docType, title, action, abstract, url   string
+    published, effective, commentsClose, signing    string
+    volume, startPage, endPage                      int
+    agencies, dockets, rins                         []string
+    parts                                           [][2]string // cfr_references: title, part
+    amendments                                      []Amendment
+}
+
+// Import reads FR JSON and XML files and returns the FR CODE node, volume nodes,
+// documents and their AMENDS edges to CFR parts and sections.
+func Import(paths []string, opts Options) (*etl.Result, error) {
+    if opts.FetchedAt == "" { opts.FetchedAt = time.Now().UTC().Format(time.RFC3339) }
+    docs := map[string]*document{}
+    get := func(num string) *document {
+        d := docs[num]
+        if d == nil { d = &document{number: num}; docs[num] = d }
+        return d
+    }
+    for _, p := range paths {
+        var err error
+        if strings.HasSuffix(strings.ToLower(p), ".json") { err = readJSON(p, get) } else { err = readXML(p, get) }
+        if err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+    }
+
+    var list []*document
+    for _, d := range docs { list = append(list, d) }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].published != list[j].published { return list[i].published < list[j].published }
+        return list[i].number < list[j].number
+    })
+    res := &etl.Result{}
+    res.Nodes = append(res.Nodes, &dgraph.Node{ID: CodeID, Labels: []string{"CODE"}, Title: "Federal Register", Props: map[string]any{"jurisdiction": "US", "code": "FR"}})
+    res.Edges = append(res.Edges, etl.ParentOf("US", CodeID, 8))
+    volumes := map[int]bool{}
+    for _, d := range list {
+        parent := CodeID
+        if d.volume > 0 {
+            parent = fmt.Sprintf("%s:V%d", CodeID, d.volume)
+            if !volumes[d.volume] {
+                volumes[d.volume] = true
+                title := fmt.Sprintf("Volume %d", d.volume)
+                if len(d.published) >= 4 { title += " (" + d.published[:4] + ")" }
+                res.Nodes = append(res.Nodes, &dgraph.Node{ID: parent, Labels: []string{"TITLE"}, Title: title,
+                    Props: map[string]any{"jurisdiction": "US", "code": "FR", "volume": float64(d.volume)}})
+                res.Edges = append(res.Edges, etl.ParentOf(CodeID, parent, d.volume))
+            }
+        }
+        n := d.node(opts)
+        res.Nodes = append(res.Nodes, n)
+        res.Edges = append(res.Edges, etl.ParentOf(parent, n.ID, d.startPage))
+        res.Edges = append(res.Edges, d.amendsEdges(n.ID)...)
+    }
+    return res, nil
+}
+
+func (d *document) node(opts Options) *dgraph.Node {
+    props := map[string]any{"jurisdiction": "US", "code": "FR", "document_number": d.number}
+    set := func(k, v string) { if v != "" { props[k] = v } }
+    set("document_type", d.docType)
+    set("action", d.action)
+    set("publication_date", d.published)
+    set("effective_date", d.effective)
+    set("comments_close_on", d.commentsClose)
+    set("signing_date", d.signing)
+    if len(d.agencies) > 0 { props["agencies"] = d.agencies }
+    if len(d.dockets) > 0 { props["docket_ids"] = d.dockets }
+    if len(d.rins) > 0 { props["rins"] = d.rins }
+    if d.volume > 0 { props["volume"] = float64(d.volume) }
+    if d.startPage > 0 { props["start_page"] = float64(d.startPage) }
+    if d.endPage > 0 { props["end_page"] = float64(d.endPage) }
+    var refs []string
+    for _, p := range d.parts { refs = append(refs, p[0]+" CFR "+p[1]) }
+    if len(refs) > 0 { props["cfr_references"] = refs }
+    cite := ""
+    if d.volume > 0 && d.startPage > 0 { cite = fmt.Sprintf("%d FR %d", d.volume, d.startPage) }
+    url := d.url
+    if url == "" { url = DocumentURL + d.number }
+    return &dgraph.Node{ID: CodeID + ":" + d.number, Labels: []string{"FR_DOCUMENT"}, Title: d.title, Citation: cite, Text: d.abstract, Props: props,
+        Version: &dgraph.Version{FetchedAt: opts.FetchedAt, EffectiveDate: d.published, Hash: etl.ContentHash(d.title, d.abstract, d.action, d.effective)},
+        Sources: []dgraph.SourceMeta{{Name: SourceName, URL: url, RetrievedAt: opts.FetchedAt}}}
+}
+
+// amendsEdges links rules and proposed rules to the CFR units they change. Parts
+// whose sections are named by an instruction are not linked as a whole.
+func (d *document) amendsEdges(from string) []*dgraph.Edge {
+    if d.docType != "rule" && d.docType != "proposed_rule" { return nil }
+    var out []*dgraph.Edge
+    seen := map[string]bool{}
+    narrowed := map[string]bool{}
+    add := func(a Amendment) {
+        to := fmt.Sprintf("US:CFR:T%s:PT%s", etl.Pad2(a.Title), a.Part)
+        if a.Section != "" { to = fmt.Sprintf("US:CFR:T%s:§%s", etl.Pad2(a.Title), a.Section); narrowed[a.Title+"|"+a.Part] = true }
+        id := "amends:" + from + "->" + to
+        if seen[id] { return }
+        seen[id] = true
+        props := map[string]any{"action": a.Action, "document_type": d.docType}
+        if a.Instruction != "" { props["instruction"] = a.Instruction }
+        if d.published != "" { props["publication_date"] = d.published }
+        if d.effective != "" { props["effective_date"] = d.effective }
+        if d.docType == "proposed_rule" { props["status"] = "proposed" }
+        out = append(out, &dgraph.Edge{ID: id, EdgeType: "AMENDS", FromID: from, ToID: to, Props: props})
+    }
+    for _, a := range d.amendments {
+        if a.Section != "" { add(a) }
+    }
+    for _, a := range d.amendments {
+        if a.Section == "" && !narrowed[a.Title+"|"+a.Part] { add(a) }
+    }
+    for _, p := range d.parts {
+        if !narrowed[p[0]+"|"+p[1]] { add(Amendment{Title: p[0], Part: p[1], Action: "amend"}) }
+    }
+    return out
+}
+
+// apiDoc is the subset of the federalregister.gov API document used here.
+type apiDoc struct {
+    DocumentNumber       string `json:"document_number"`
+    Type                 string `json:"type"`
+    Title                string `json:"title"`
+    Action               string `json:"action"`
+    Abstract             string `json:"abstract"`
+    HTMLURL              string `json:"html_url"`
+    PublicationDate      string `json:"publication_date"`
+    EffectiveOn          string `json:"effective_on"`
+    CommentsCloseOn      string `json:"comments_close_on"`
+    SigningDate          string `json:"signing_date"`
+    Citation             string `json:"citation"`
+    StartPage            int    `json:"start_page"`
+    EndPage              int    `json:"end_page"`
+    Volume               int    `json:"volume"`
+    DocketIDs            []string `json:"docket_ids"`
+    RINs                 []string `json:"regulation_id_numbers"`
+    Agencies             []struct {
+        Name    string `json:"name"`
+        RawName string `json:"raw_name"`
+    } `json:"agencies"`
+    CFRReferences []struct {
+        Title json.Number `json:"title"`
+        Part  json.Number `json:"part"`
+    } `json:"cfr_references"`
+}
+
+func readJSON(path string, get func(string) *document) error {
+    b, err := os.ReadFile(path)
+    if err != nil { return err }
+    var items []apiDoc
+    trimmed := strings.TrimSpace(string(b))
+    switch {
+    case strings.HasPrefix(trimmed, "["):
+        err = json.Unmarshal(b, &items)
+    case strings.Contains(trimmed, `"results"`):
+        var page struct{ Results []apiDoc `json:"results"` }
+        err = json.Unmarshal(b, &page)
+        items = page.Results
+    default:
+        var one apiDoc
+        err = json.Unmarshal(b, &one)
+        items = []apiDoc{one}
+    }
+    if err != nil { return err }
+    for _, it := range items {
+        if it.DocumentNumber == "" { continue }
+        d := get(it.DocumentNumber)
+        d.docType = first(apiTypes[it.Type], d.docType)
+        d.title, d.action, d.abstract, d.url = first(it.Title, d.title), first(it.Action, d.action), first(it.Abstract, d.abstract), first(it.HTMLURL, d.url)
+        d.published, d.effective = first(it.PublicationDate, d.published), first(it.EffectiveOn, d.effective)
+        d.commentsClose, d.signing = first(it.CommentsCloseOn, d.commentsClose), first(it.SigningDate, d.signing)
+        if it.Volume > 0 { d.volume, d.startPage, d.endPage = it.Volume, it.StartPage, it.EndPage }
+        if d.volume == 0 { d.volume, d.startPage = parseCitation(it.Citation) }
+        if len(it.Agencies) > 0 {
+            d.agencies = nil
+            for _, a := range it.Agencies { d.agencies = append(d.agencies, first(a.Name, a.RawName)) }
+        }
+        if len(it.DocketIDs) > 0 { d.dockets = it.DocketIDs }
+        if len(it.RINs) > 0 { d.rins = it.RINs }
+        for _, r := range it.CFRReferences { d.addPart(r.Title.String(), r.Part.String()) }
+    }
+    return nil
+}
+
+func (d *document) addPart(title, part string) {
+    if title == "" || part == "" { return }
+    for _, p := range d.parts {
+        if p[0] == title && p[1] == part { return }
+    }
+    d.parts = append(d.parts, [2]string{title, part})
+}
+
+// readXML reads a daily issue or a single document, tracking PRTPAGE markers for page ranges.
+func readXML(path string, get func(string) *document) error {
+    f, err := os.Open(path)
+    if err != nil { return err }
+    defer f.Close()
+    root, err := parse.ParseXML(f)
+    if err != nil { return err }
+    volume, _ := strconv.Atoi(root.Child("VOL").Text())
+    published := parseDate(root.Child("DATE").Text())
+    page := 0
+    var walk func(e *parse.Element)
+    walk = func(e *parse.Element) {
+        if e.Name == "PRTPAGE" {
+            if p, err := strconv.Atoi(e.Attr("P")); err == nil { page = p }
+            return
+        }
+        if typ, ok := xmlTypes[e.Name]; ok {
+            start := page
+            if start == 0 {
+                if pp := e.Find("PRTPAGE"); pp != nil { start, _ = strconv.Atoi(pp.Attr("P")) }
+            }
+            for _, c := range e.Elements() { walk(c) }
+            readDocument(e, typ, get, volume, published, start, page)
+            return
+        }
+        for _, c := range e.Elements() { walk(c) }
+    }
+    walk(root)
+    return nil
+}
+
+var (
+    frDoc      = regexp.MustCompile(`FR Doc\.\s*([\dE]+-\d+)`)
+    cfrHeading = regexp.MustCompile(`(\d+)\s+CFR\s+Parts?\s+([\d,\sand]+)`)
+    number     = regexp.MustCompile(`\d+`)
+    sectionRun = regexp.MustCompile(`(?:§§?|\b[Ss]ections?)\s*((?:\d+\.\d+[a-z]?(?:\([a-zA-Z0-9]+\))*(?:,\s*|\s+and\s+|\s+through\s+)?)+)`)
+    sectionNum = regexp.MustCompile(`\d+\.\d+[a-z]?`)
+    partRef    = regexp.MustCompile(`\b[Pp]art\s+(\d+)`)
+    longDate   = regexp.MustCompile(`(January|February|March|April|May|June|July|August|September|October|November|December)\s+\d{1,2},\s+\d{4}`)
+)
+
+// verbs map the gerunds of amendatory instructions to actions, most specific first.
+var verbs = []struct{ word, action string }{
+    {"removing and reserving", "remove_and_reserve"}, {"remove and reserve", "remove_and_reserve"},
+    {"redesignat", "redesignate"}, {"revis", "revise"}, {"adding", "add"}, {"add ", "add"},
+    {"removing", "remove"}, {"remove ", "remove"}, {"suspend", "suspend"}, {"republish", "republish"},
+}
+
+func readDocument(e *parse.Element, typ string, get func(string) *document, volume int, published string, start, end int) {
+    m := frDoc.FindStringSubmatch(e.Find("FRDOC").Text())
+    if m == nil { return }
+    d := get(m[1])
+    pre := e.Child("PREAMB")
+    d.docType = first(d.docType, typ)
+    d.title = first(d.title, pre.Child("SUBJECT").Text())
+    d.action = first(d.action, strings.TrimSuffix(bodyOf(pre.Child("ACT")), "."))
+    d.abstract = first(d.abstract, bodyOf(pre.Child("SUM")))
+    d.published = first(d.published, published)
+    d.effective = first(d.effective, parseDate(longDate.FindString(bodyOf(pre.Child("EFFDATE")))))
+    if d.volume == 0 { d.volume, d.startPage, d.endPage = volume, start, end }
+    if len(d.agencies) == 0 {
+        for _, a := range pre.Elements("AGENCY", "SUBAGY") { if t := a.Text(); t != "" { d.agencies = append(d.agencies, t) } }
+    }
+    if len(d.dockets) == 0 {
+        for _, p := range strings.Split(strings.Trim(pre.Child("DEPDOC").Text(), "[] "), ";") {
+            if p = strings.TrimSpace(p); p != "" { d.dockets = append(d.dockets, p) }
+        }
+    }
+    if len(d.rins) == 0 {
+        for _, r := range pre.Elements("RIN") { d.rins = append(d.rins, strings.TrimSpace(strings.TrimPrefix(r.Text(), "RIN"))) }
+    }
+    for _, c := range pre.Elements("CFR") {
+        if m := cfrHeading.FindStringSubmatch(c.Text()); m != nil {
+            for _, p := range number.FindAllString(m[2], -1) { d.addPart(m[1], p) }
+        }
+    }
+    d.amendments = nil
+    for _, rt := range regtexts(e) {
+        title, part := rt.Attr("TITLE"), rt.Attr("PART")
+        d.addPart(title, part)
+        for _, ap := range rt.Elements("AMDPAR") {
+            d.amendments = append(d.amendments, instruction(ap.Text(), title, part)...)
+        }
+    }
+}
+
+// instruction reads one AMDPAR: the sections it names (or its part) and the action.
+func instruction(text, title, part string) []Amendment {
+    low := strings.ToLower(text)
+    // "The authority citation for part 600 continues to read as follows" changes nothing
+    if strings.Contains(low, "authority citation") && strings.Contains(low, "continues") { return nil }
+    action := "amend"
+    for _, v := range verbs {
+        if strings.Contains(low, v.word) { action = v.action; break }
+    }
+    var out []Amendment
+    for _, run := range sectionRun.FindAllStringSubmatch(text, -1) {
+        for _, s := range sectionNum.FindAllString(run[1], -1) {
+            p := part
+            if i := strings.Index(s, "."); i > 0 { p = s[:i] }
+            out = append(out, Amendment{Title: title, Part: p, Section: s, Action: action, Instruction: text})
+        }
+    }
+    if len(out) == 0 {
+        if m := partRef.FindStringSubmatch(text); m != nil { part = m[1] }
+        if part != "" { out = append(out, Amendment{Title: title, Part: part, Action: action, Instruction: text}) }
+    }
+    return out
+}
+
+// regtexts returns the REGTEXT blocks of a document in order.
+func regtexts(e *parse.Element) []*parse.Element {
+    var out []*parse.Element
+    for _, c := range e.Elements() {
+        if c.Name == "REGTEXT" { out = append(out, c); continue }
+        out = append(out, regtexts(c)...)
+    }
+    return out
+}
+
+// bodyOf is the text of a preamble block without its "SUMMARY:" style heading.
+func bodyOf(e *parse.Element) string {
+    var parts []string
+    for _, c := range e.Elements() {
+        if c.Name == "HD" { continue }
+        if t := c.Text(); t != "" { parts = append(parts, t) }
+    }
+    return strings.Join(parts, "\n")
+}
+
+// parseCitation reads "89 FR 12845" as volume and page.
+func parseCitation(s string) (int, int) {
+    f := strings.Fields(s)
+    if len(f) != 3 || f[1] != "FR" { return 0, 0 }
+    v, _ := strconv.Atoi(f[0])
+    p, _ := strconv.Atoi(f[2])
+    return v, p
+}
+
+// parseDate reads "Wednesday, February 21, 2024" or "March 22, 2024" as YYYY-MM-DD.
+func parseDate(s string) string {
+    if m := longDate.FindString(s); m != "" {
+        if t, err := time.Parse("January 2, 2006", m); err == nil { return t.Format("2006-01-02") }
+    }
+    return ""
+}
+
+func first(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
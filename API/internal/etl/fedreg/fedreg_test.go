@@ -0,0 +1,85 @@
+package fedreg
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl/cfr"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+var fixtures = []string{"../../test/fixtures/fedreg/documents_2024-02-21.json", "../../test/fixtures/fedreg/FR-2024-02-21.xml"}
+
+func TestImportDocumentsAndAmendments(t *testing.T) {
+    res, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    nodes := map[string]*dgraph.Node{}
+    for _, n := range res.Nodes { nodes[n.ID] = n }
+    if v := nodes["US:FR:V89"]; v == nil || v.Title != "Volume 89 (2024)" { t.Fatalf("volume = %+v", v) }
+    rule := nodes["US:FR:2024-03412"]
+    if rule == nil { t.Fatal("missing rule") }
+    if rule.Labels[0] != "FR_DOCUMENT" || rule.Citation != "89 FR 12845" || rule.Title != "General Powers of Special Counsel" { t.Errorf("rule = %q / %q", rule.Citation, rule.Title) }
+    p := rule.Props
+    if p["document_type"] != "rule" || p["publication_date"] != "2024-02-21" || p["effective_date"] != "2024-03-22" || p["action"] != "Final rule." { t.Errorf("rule props = %v", p) }
+    if a, _ := p["agencies"].([]string); len(a) != 1 || a[0] != "Justice Department" { t.Errorf("agencies = %v", p["agencies"]) }
+    if d, _ := p["docket_ids"].([]string); len(d) != 2 || d[0] != "Docket No. OAG 100" { t.Errorf("dockets = %v", p["docket_ids"]) }
+    if r, _ := p["rins"].([]string); len(r) != 1 || r[0] != "1105-AB12" { t.Errorf("rins = %v", p["rins"]) }
+    if rule.Version.EffectiveDate != "2024-02-21" || !strings.Contains(rule.Sources[0].URL, "2024-03412") { t.Errorf("provenance = %+v / %v", rule.Version, rule.Sources) }
+    if n := nodes["US:FR:2024-03455"]; n == nil || n.Props["document_type"] != "notice" || n.Props["comments_close_on"] != "2024-03-22" { t.Errorf("notice = %+v", n) }
+
+    amends := map[string]*dgraph.Edge{}
+    for _, e := range res.Edges {
+        if e.EdgeType == "AMENDS" { amends[e.FromID+" "+e.ToID] = e }
+    }
+    want := map[string]string{
+        "US:FR:2024-03412 US:CFR:T28:§600.4":  "revise",
+        "US:FR:2024-03412 US:CFR:T28:§600.11": "add",
+        "US:FR:2024-03390 US:CFR:T28:§0.1":    "revise",
+        "US:FR:2024-03390 US:CFR:T28:§0.23":   "revise",
+    }
+    for k, action := range want {
+        e := amends[k]
+        if e == nil { t.Errorf("missing AMENDS %s", k); continue }
+        if e.Props["action"] != action { t.Errorf("%s action = %v", k, e.Props["action"]) }
+    }
+    // parts narrowed to sections by an instruction are not linked as a whole; notices amend nothing
+    if len(amends) != len(want) { t.Errorf("AMENDS = %v", amends) }
+    e := amends["US:FR:2024-03412 US:CFR:T28:§600.4"]
+    if e.Props["instruction"] != "2. Amend § 600.4 by revising paragraph (b) to read as follows:" || e.Props["effective_date"] != "2024-03-22" { t.Errorf("props = %v", e.Props) }
+    if amends["US:FR:2024-03390 US:CFR:T28:§0.1"].Props["status"] != "proposed" { t.Errorf("proposed rule status missing") }
+}
+
+func TestImportJSONOnlyLinksParts(t *testing.T) {
+    res, err := Import(fixtures[:1], Options{})
+    if err != nil { t.Fatal(err) }
+    var got []string
+    for _, e := range res.Edges {
+        if e.EdgeType == "AMENDS" { got = append(got, e.ToID) }
+    }
+    if len(got) != 2 || got[0] != "US:CFR:T28:PT0" || got[1] != "US:CFR:T28:PT600" { t.Errorf("AMENDS targets = %v", got) }
+}
+
+// Loaded next to the CFR, a regulation's incoming AMENDS edges are its rulemaking history.
+func TestAmendmentsResolveAgainstCFR(t *testing.T) {
+    regs, err := cfr.Import([]string{"../../test/fixtures/cfr/title28_annual_2023.xml"}, cfr.Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    docs, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    regs.Append(docs)
+    var buf bytes.Buffer
+    if err := regs.WriteJSONL(&buf); err != nil { t.Fatal(err) }
+    path := filepath.Join(t.TempDir(), "cfr_fr.jsonl")
+    if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(path); err != nil { t.Fatal(err) }
+    var history []string
+    for _, e := range store.EdgesOf("US:CFR:T28:§600.4") {
+        if e.EdgeType == "AMENDS" && e.ToID == "US:CFR:T28:§600.4" { history = append(history, e.FromID) }
+    }
+    if len(history) != 1 || history[0] != "US:FR:2024-03412" { t.Errorf("history = %v", history) }
+    if kids, _ := store.GetChildren("US:FR:V89"); len(kids) != 3 || kids[0].ID != "US:FR:2024-03412" || kids[2].ID != "US:FR:2024-03455" { t.Errorf("volume children = %v", kids) }
+}
@@ -0,0 +1,147 @@
+package etl
+
+import (
+    "archive/zip"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+)
+
+// Artifact is one raw download as kept in the cache.
+type Artifact struct {
+    URL       string
+    Name      string // file name from the URL, which importers dispatch on
+    Hash      string // "sha256:" content hash, the cache key
+    Path      string // cached file
+    FetchedAt string
+    Changed   bool // the content differs from the last run's (or there was none)
+}
+
+// Fetcher downloads URLs for a source into the cache. It sends the ETag and
+// Last-Modified seen last time and records what came back in the source's state.
+type Fetcher struct {
+    Client    *http.Client
+    Cache     *Cache
+    State     *State
+    URLs      []string // what to fetch: the descriptor's URLs unless the run overrides them
+    FetchedAt string   // RFC 3339 timestamp of this run
+}
+
+// FetchAll fetches every URL in f.URLs.
+func (f *Fetcher) FetchAll(ctx context.Context) ([]Artifact, error) {
+    var out []Artifact
+    for _, u := range f.URLs {
+        a, err := f.Get(ctx, u)
+        if err != nil { return nil, err }
+        out = append(out, a)
+    }
+    return out, nil
+}
+
+// Get fetches one URL. A 304 answer, or a body with the hash seen last time, yields
+// the cached artifact with Changed false.
+func (f *Fetcher) Get(ctx context.Context, u string) (Artifact, error) {
+    prev, known := f.State.Artifacts[u]
+    if known && !f.Cache.Has(prev.Hash) { known = false }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+    if err != nil { return Artifact{}, err }
+    if known {
+        if prev.ETag != "" { req.Header.Set("If-None-Match", prev.ETag) }
+        if prev.LastModified != "" { req.Header.Set("If-Modified-Since", prev.LastModified) }
+    }
+    client := f.Client
+    if client == nil { client = http.DefaultClient }
+    resp, err := client.Do(req)
+    if err != nil { return Artifact{}, err }
+    defer resp.Body.Close()
+    a := Artifact{URL: u, Name: artifactName(u, resp), FetchedAt: f.FetchedAt}
+    if resp.StatusCode == http.StatusNotModified && known {
+        a.Hash, a.Path = prev.Hash, f.Cache.Path(prev.Hash)
+        f.State.LastFetched = f.FetchedAt
+        return a, nil
+    }
+    if resp.StatusCode != http.StatusOK { return Artifact{}, fmt.Errorf("GET %s: %s", u, resp.Status) }
+    a.Hash, a.Path, err = f.Cache.Put(resp.Body)
+    if err != nil { return Artifact{}, fmt.Errorf("GET %s: %w", u, err) }
+    a.Changed = !known || prev.Hash != a.Hash
+    f.State.Artifacts[u] = ArtifactState{Hash: a.Hash, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), FetchedAt: f.FetchedAt}
+    f.State.LastFetched = f.FetchedAt
+    return a, nil
+}
+
+// artifactName is the file name to stage an artifact under: the Content-Disposition
+// filename if any, else the last path segment of the URL.
+func artifactName(u string, resp *http.Response) string {
+    if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+        if i := strings.Index(cd, "filename="); i >= 0 {
+            if n := path.Base(strings.Trim(cd[i+len("filename="):], `"; `)); n != "." && n != "/" { return n }
+        }
+    }
+    if pu, err := url.Parse(u); err == nil {
+        if n := path.Base(pu.Path); n != "." && n != "/" { return n }
+    }
+    return "index"
+}
+
+// Stage lays artifacts out in dir under their names, as the file-based importers
+// expect. Zip archives are extracted into dir instead of being copied.
+func Stage(dir string, arts []Artifact) ([]string, error) {
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    var out []string
+    for _, a := range arts {
+        if strings.HasSuffix(strings.ToLower(a.Name), ".zip") {
+            files, err := unzip(a.Path, dir)
+            if err != nil { return nil, fmt.Errorf("%s: %w", a.Name, err) }
+            out = append(out, files...)
+            continue
+        }
+        dst := filepath.Join(dir, a.Name)
+        if err := os.Link(a.Path, dst); err != nil {
+            if err := copyFile(a.Path, dst); err != nil { return nil, err }
+        }
+        out = append(out, dst)
+    }
+    return out, nil
+}
+
+func unzip(src, dir string) ([]string, error) {
+    zr, err := zip.OpenReader(src)
+    if err != nil { return nil, err }
+    defer zr.Close()
+    var out []string
+    for _, zf := range zr.File {
+        if zf.FileInfo().IsDir() { continue }
+        // flatten, and never write outside dir
+        dst := filepath.Join(dir, filepath.Base(zf.Name))
+        rc, err := zf.Open()
+        if err != nil { return nil, err }
+        err = writeFile(dst, rc)
+        rc.Close()
+        if err != nil { return nil, err }
+        out = append(out, dst)
+    }
+    return out, nil
+}
+
+func copyFile(src, dst string) error {
+    in, err := os.Open(src)
+    if err != nil { return err }
+    defer in.Close()
+    return writeFile(dst, in)
+}
+
+func writeFile(dst string, r io.Reader) error {
+    out, err := os.Create(dst)
+    if err != nil { return err }
+    if _, err := io.Copy(out, r); err != nil {
+        out.Close()
+        return err
+    }
+    return out.Close()
+}
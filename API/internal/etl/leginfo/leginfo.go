@@ -0,0 +1,326 @@
+// Package leginfo imports California codes from the LegInfo bulk database export
+// (the pubinfo archives at https://downloads.leginfo.legislature.ca.gov/), read from an
+// extracted directory holding LAW_TOC_TBL.dat, LAW_TOC_SECTIONS_TBL.dat,
+// LAW_SECTION_TBL.dat and the .lob files with section XML.
+//
+// LAW_TOC_TBL rebuilds each code's outline. The layer of a TOC node (division,
+// part, title, chapter or article) is the column it sets over its parent, found by
+// node_treepath, since codes nest them differently (CIV: Division → Part → Title,
+// GOV: Title → Division). Layers map onto the canonical ladder CODE → TITLE →
+// CHAPTER → SECTION: division, part and title become TITLE, chapter and article
+// become CHAPTER. Native numbers are kept in props (division_num, part_num, ...,
+// inherited by descendants) and props.layer names the native layer.
+//
+// IDs extend the parent's ID one segment per layer (CA:CIV:D4:PT1:T02:CH02:Art3:§3342,
+// CA:CONS:ArtI:§13). Every LAW_SECTION_TBL row is a version of its section; rows are
+// emitted oldest effective date first and op_statues, op_chapter, op_section and
+// history travel with each version in props.
+package leginfo
+
+import (
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl"
+    "lawmap/internal/pkg/parse"
+)
+
+const (
+    // SourceName matches the CA LegInfo source descriptor.
+    SourceName = "CA LegInfo"
+    // SectionURL is the display page of one section; %s are the code and section number.
+    SectionURL = "https://leginfo.legislature.ca.gov/faces/codes_displaySection.xhtml?lawCode=%s&sectionNum=%s."
+)
+
+// CodeNames are the LegInfo law codes and their display titles.
+var CodeNames = map[string]string{
+    "BPC": "Business and Professions Code", "CCP": "Code of Civil Procedure", "CIV": "Civil Code",
+    "COM": "Commercial Code", "CONS": "California Constitution", "CORP": "Corporations Code",
+    "EDC": "Education Code", "ELEC": "Elections Code", "EVID": "Evidence Code",
+    "FAC": "Food and Agricultural Code", "FAM": "Family Code", "FGC": "Fish and Game Code",
+    "FIN": "Financial Code", "GOV": "Government Code", "HNC": "Harbors and Navigation Code",
+    "HSC": "Health and Safety Code", "INS": "Insurance Code", "LAB": "Labor Code",
+    "MVC": "Military and Veterans Code", "PCC": "Public Contract Code", "PEN": "Penal Code",
+    "PRC": "Public Resources Code", "PROB": "Probate Code", "PUC": "Public Utilities Code",
+    "RTC": "Revenue and Taxation Code", "SHC": "Streets and Highways Code",
+    "UIC": "Unemployment Insurance Code", "VEH": "Vehicle Code", "WAT": "Water Code",
+    "WIC": "Welfare and Institutions Code",
+}
+
+// layer describes one LegInfo outline column.
+type layer struct {
+    name  string // props.layer and display name
+    seg   string // ID segment prefix
+    prop  string // props key for the number
+    label string
+    pad   bool   // zero-pad single digits, as the canonical T02/CH02 do
+}
+
+// layers in LAW_TOC_TBL column order (division, title, part, chapter, article).
+var layers = []layer{
+    {"division", "D", "division_num", "TITLE", false},
+    {"title", "T", "title_num", "TITLE", true},
+    {"part", "PT", "part_num", "TITLE", false},
+    {"chapter", "CH", "chapter_num", "CHAPTER", true},
+    {"article", "Art", "article_num", "CHAPTER", false},
+}
+
+// Column positions in the pubinfo tables (capublic schema).
+const (
+    tocCode, tocDivision, tocHeading            = 0, 1, 6
+    tocActive, tocPosition, tocTreepath         = 7, 12, 13
+    tsCode, tsTreepath, tsSection, tsOrder      = 1, 2, 3, 4
+    secCode, secNum, secOpStatues, secOpChapter = 1, 2, 3, 4
+    secOpSection, secEffective, secVersionID    = 5, 6, 7
+    secDivision, secHistory, secContent         = 8, 13, 14
+    secActive                                   = 15
+)
+
+// Options select codes and set provenance.
+type Options struct {
+    // FetchedAt is recorded as version.fetched_at and sources[].retrieved_at (RFC 3339); defaults to now.
+    FetchedAt string
+    // Codes limits the import to these law codes (e.g. CIV, PEN); empty means every code in the export.
+    Codes []string
+}
+
+// tocNode is one LAW_TOC_TBL row placed in the outline.
+type tocNode struct {
+    id, treepath string
+    cols         [5]string // division, title, part, chapter, article
+    props        map[string]any
+    order        int
+}
+
+// Import reads an extracted pubinfo directory and returns CODE nodes with PARENT_OF
+// edges from CA, the outline nodes and every section version.
+func Import(dir string, opts Options) (*etl.Result, error) {
+    if opts.FetchedAt == "" { opts.FetchedAt = time.Now().UTC().Format(time.RFC3339) }
+    want := map[string]bool{}
+    for _, c := range opts.Codes { want[strings.ToUpper(c)] = true }
+    keep := func(code string) bool { return len(want) == 0 || want[code] }
+
+    toc, err := readTable(dir, "LAW_TOC_TBL.dat")
+    if err != nil { return nil, err }
+    tocSecs, err := readTable(dir, "LAW_TOC_SECTIONS_TBL.dat")
+    if err != nil { return nil, err }
+    secs, err := readTable(dir, "LAW_SECTION_TBL.dat")
+    if err != nil { return nil, err }
+
+    im := &importer{dir: dir, opts: opts, res: &etl.Result{}, nodes: map[string]*tocNode{}, byID: map[string]*tocNode{}, byCols: map[string]*tocNode{},
+        sectionAt: map[string]string{}, sectionOrder: map[string]int{}, lastOrder: map[string]int{}, hashes: map[string]string{}, edges: map[string]bool{}}
+    codes := map[string]bool{}
+    for _, r := range toc {
+        if code := col(r, tocCode); keep(code) && col(r, tocActive) != "N" { codes[code] = true }
+    }
+    for _, r := range secs {
+        if code := col(r, secCode); keep(code) { codes[code] = true }
+    }
+    im.codes(codes)
+
+    // parents sort before children by treepath depth, siblings by node position
+    sort.SliceStable(toc, func(i, j int) bool {
+        a, b := col(toc[i], tocTreepath), col(toc[j], tocTreepath)
+        if da, db := strings.Count(a, "."), strings.Count(b, "."); da != db { return da < db }
+        return atoi(col(toc[i], tocPosition)) < atoi(col(toc[j], tocPosition))
+    })
+    for _, r := range toc {
+        if code := col(r, tocCode); keep(code) && col(r, tocActive) != "N" { im.outline(r) }
+    }
+    for _, r := range tocSecs {
+        code := col(r, tsCode)
+        if !keep(code) { continue }
+        num := trimNum(col(r, tsSection))
+        if n := im.nodes[code+"|"+col(r, tsTreepath)]; n != nil {
+            im.sectionAt[code+"|"+num] = n.id
+            o := int(parseFloat(col(r, tsOrder)))
+            im.sectionOrder[code+"|"+num] = o
+            if o > im.lastOrder[n.id] { im.lastOrder[n.id] = o }
+        }
+    }
+    // versions oldest first so the store keeps the latest effective one current
+    sort.SliceStable(secs, func(i, j int) bool { return col(secs[i], secEffective) < col(secs[j], secEffective) })
+    for _, r := range secs {
+        if keep(col(r, secCode)) {
+            if err := im.section(r); err != nil { return nil, err }
+        }
+    }
+    return im.res, nil
+}
+
+type importer struct {
+    dir          string
+    opts         Options
+    res          *etl.Result
+    nodes        map[string]*tocNode // code|treepath
+    byID         map[string]*tocNode
+    byCols       map[string]*tocNode // code|division|title|part|chapter|article
+    sectionAt    map[string]string   // code|section -> parent ID from LAW_TOC_SECTIONS_TBL
+    sectionOrder map[string]int
+    lastOrder    map[string]int    // parent ID -> highest section order so far
+    hashes       map[string]string // section ID -> last emitted version hash
+    edges        map[string]bool
+}
+
+func (im *importer) codes(set map[string]bool) {
+    var list []string
+    for c := range set { list = append(list, c) }
+    sort.Strings(list)
+    for i, c := range list {
+        title := CodeNames[c]
+        if title == "" { title = c }
+        if c != "CONS" { title = "California " + title }
+        im.res.Nodes = append(im.res.Nodes, &dgraph.Node{ID: "CA:" + c, Labels: []string{"CODE"}, Title: title,
+            Props: map[string]any{"jurisdiction": "CA", "code": c}})
+        order := i + 1
+        if c == "CONS" { order = 0 }
+        im.edge(etl.ParentOf("CA", "CA:"+c, order))
+    }
+}
+
+// outline places one LAW_TOC_TBL row under its parent (the row whose treepath is its
+// prefix, or the code) and emits its node.
+func (im *importer) outline(r []string) {
+    code, tp := col(r, tocCode), col(r, tocTreepath)
+    n := &tocNode{treepath: tp, order: atoi(col(r, tocPosition))}
+    for i := range n.cols { n.cols[i] = trimNum(col(r, tocDivision+i)) }
+    parentID, parentProps := "CA:"+code, map[string]any{"jurisdiction": "CA", "code": code}
+    var parentCols [5]string
+    if i := strings.LastIndex(tp, "."); i > 0 {
+        if p := im.nodes[code+"|"+tp[:i]]; p != nil { parentID, parentProps, parentCols = p.id, p.props, p.cols }
+    }
+    li := -1
+    for i := range layers {
+        if n.cols[i] != "" && n.cols[i] != parentCols[i] { li = i }
+    }
+    if li < 0 { return }
+    lv := layers[li]
+    num := n.cols[li]
+    seg := num
+    if lv.pad { seg = etl.Pad2(num) }
+    n.id = parentID + ":" + lv.seg + seg
+    n.props = etl.CopyProps(parentProps)
+    delete(n.props, "layer")
+    n.props[lv.prop] = etl.Numeric(num)
+    im.nodes[code+"|"+tp] = n
+    im.byID[n.id] = n
+    im.byCols[code+"|"+strings.Join(n.cols[:], "|")] = n
+
+    heading := col(r, tocHeading)
+    if i := strings.LastIndex(heading, "["); i > 0 && strings.HasSuffix(heading, "]") { heading = strings.TrimSpace(heading[:i]) }
+    props := etl.CopyProps(n.props)
+    props["layer"] = lv.name
+    title := strings.ToUpper(lv.name[:1]) + lv.name[1:] + " " + num
+    if heading != "" { title += " - " + heading }
+    node := &dgraph.Node{ID: n.id, Labels: []string{lv.label}, Title: title, Props: props,
+        Version: &dgraph.Version{FetchedAt: im.opts.FetchedAt, Hash: etl.ContentHash(title)},
+        Sources: []dgraph.SourceMeta{{Name: SourceName, URL: "https://leginfo.legislature.ca.gov/faces/codesTOCSelected.xhtml?tocCode=" + code, RetrievedAt: im.opts.FetchedAt}}}
+    im.res.Nodes = append(im.res.Nodes, node)
+    im.edge(etl.ParentOf(parentID, n.id, n.order))
+}
+
+// section emits one LAW_SECTION_TBL row as a version of its section node.
+func (im *importer) section(r []string) error {
+    code, num := col(r, secCode), trimNum(col(r, secNum))
+    parentID := im.sectionAt[code+"|"+num]
+    if parentID == "" {
+        // fall back to the outline node named by the row's own columns
+        var cols [5]string
+        for i := range cols { cols[i] = trimNum(col(r, secDivision+i)) }
+        if n := im.byCols[code+"|"+strings.Join(cols[:], "|")]; n != nil { parentID = n.id } else { parentID = "CA:" + code }
+    }
+    props := map[string]any{"jurisdiction": "CA", "code": code}
+    if n := im.byID[parentID]; n != nil { props = etl.CopyProps(n.props) }
+    props["section_num"] = num
+    for key, i := range map[string]int{"op_statues": secOpStatues, "op_chapter": secOpChapter, "op_section": secOpSection, "history": secHistory, "law_section_version_id": secVersionID} {
+        if v := col(r, i); v != "" { props[key] = v }
+    }
+    if col(r, secActive) == "N" { props["status"] = "inactive" }
+    text, err := im.content(col(r, secContent))
+    if err != nil { return fmt.Errorf("%s § %s: %w", code, num, err) }
+    id := parentID + ":§" + num
+    title := "Section " + num
+    cite := code + " § " + num
+    if code == "CONS" { cite = fmt.Sprintf("Cal. Const. art. %v, § %s", props["article_num"], num) }
+    hash := etl.ContentHash(title, text)
+    if im.hashes[id] == hash { return nil }
+    im.hashes[id] = hash
+    effective := col(r, secEffective)
+    if len(effective) > 10 { effective = effective[:10] }
+    n := &dgraph.Node{ID: id, Labels: []string{"SECTION"}, Title: title, Citation: cite, Text: text, Props: props,
+        Version: &dgraph.Version{FetchedAt: im.opts.FetchedAt, EffectiveDate: effective, Hash: hash},
+        Sources: []dgraph.SourceMeta{{Name: SourceName, URL: fmt.Sprintf(SectionURL, code, url.QueryEscape(num)), RetrievedAt: im.opts.FetchedAt}}}
+    im.res.Nodes = append(im.res.Nodes, n)
+    if !im.edges[etl.ParentOf(parentID, id, 0).ID] {
+        order, ok := im.sectionOrder[code+"|"+num]
+        if !ok { im.lastOrder[parentID]++; order = im.lastOrder[parentID] }
+        im.edge(etl.ParentOf(parentID, id, order))
+    }
+    return nil
+}
+
+// content returns the section text, one line per paragraph. The column holds either
+// the name of a .lob file next to the tables or the XML itself.
+func (im *importer) content(v string) (string, error) {
+    if v == "" { return "", nil }
+    raw := v
+    if strings.HasSuffix(strings.ToLower(v), ".lob") {
+        b, err := os.ReadFile(filepath.Join(im.dir, filepath.Base(v)))
+        if err != nil { return "", err }
+        raw = string(b)
+    }
+    doc, err := parse.ParseXML(strings.NewReader(raw))
+    if err != nil { return "", err }
+    var lines []string
+    var walk func(e *parse.Element)
+    walk = func(e *parse.Element) {
+        for _, c := range e.Elements() {
+            if c.Name == "p" { if t := c.Text(); t != "" { lines = append(lines, t) }; continue }
+            walk(c)
+        }
+    }
+    walk(doc)
+    if len(lines) == 0 { return doc.Text(), nil }
+    return strings.Join(lines, "\n"), nil
+}
+
+// edge emits e once per ID.
+func (im *importer) edge(e *dgraph.Edge) {
+    if im.edges[e.ID] { return }
+    im.edges[e.ID] = true
+    im.res.Edges = append(im.res.Edges, e)
+}
+
+func readTable(dir, name string) ([][]string, error) {
+    f, err := os.Open(filepath.Join(dir, name))
+    if err != nil { return nil, err }
+    defer f.Close()
+    rows, err := parse.ReadDat(f)
+    if err != nil { return nil, fmt.Errorf("%s: %w", name, err) }
+    return rows, nil
+}
+
+func col(r []string, i int) string {
+    if i < len(r) { return r[i] }
+    return ""
+}
+
+// trimNum drops the trailing period LegInfo writes after numbers ("3.", "3342.").
+func trimNum(s string) string { return strings.TrimSuffix(strings.TrimSpace(s), ".") }
+
+func atoi(s string) int {
+    n, _ := strconv.Atoi(strings.TrimSpace(s))
+    return n
+}
+
+func parseFloat(s string) float64 {
+    f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+    return f
+}
@@ -0,0 +1,73 @@
+package leginfo
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+const fixtureDir = "../../test/fixtures/leginfo"
+
+func TestImportOutlineAndVersions(t *testing.T) {
+    res, err := Import(fixtureDir, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    versions := map[string][]*dgraph.Node{}
+    for _, n := range res.Nodes { versions[n.ID] = append(versions[n.ID], n) }
+    labels := map[string]string{
+        "CA:CIV": "CODE", "CA:CIV:D4": "TITLE", "CA:CIV:D4:PT1": "TITLE", "CA:CIV:D4:PT1:T02": "TITLE",
+        "CA:CIV:D4:PT1:T02:CH02": "CHAPTER", "CA:CIV:D4:PT1:T02:CH02:Art3": "CHAPTER",
+        "CA:CIV:D4:PT1:T02:CH02:Art3:§3342": "SECTION", "CA:PEN:PT1:T08:CH01:§187": "SECTION",
+        "CA:CONS:ArtI:§13": "SECTION", "CA:BPC:D7:PT2:CH05:§17200": "SECTION",
+    }
+    for id, want := range labels {
+        vs := versions[id]
+        if len(vs) == 0 { t.Fatalf("missing %s", id) }
+        if vs[0].Labels[0] != want { t.Errorf("%s label = %s, want %s", id, vs[0].Labels[0], want) }
+    }
+    if n := versions["CA:CIV:D4:PT1:T02"][0]; n.Title != "Title 2 - COMPENSATORY RELIEF" || n.Props["layer"] != "title" || n.Props["division_num"] != float64(4) {
+        t.Errorf("title node = %q %v", n.Title, n.Props)
+    }
+    vs := versions["CA:CIV:D4:PT1:T02:CH02:Art3:§3342"]
+    if len(vs) != 2 { t.Fatalf("§3342 versions = %d", len(vs)) }
+    old, cur := vs[0], vs[1]
+    if old.Version.EffectiveDate != "1970-11-10" || cur.Version.EffectiveDate != "1989-01-01" || old.Props["status"] != "inactive" { t.Errorf("versions = %+v / %+v", old.Version, cur.Version) }
+    if cur.Props["op_statues"] != "1988" || cur.Props["op_chapter"] != "1030" || cur.Props["history"] != "Amended by Stats. 1988, Ch. 1030, Sec. 1." || old.Props["op_statues"] != "1969" {
+        t.Errorf("version metadata = %v / %v", cur.Props, old.Props)
+    }
+    if cur.Citation != "CIV § 3342" || !strings.HasPrefix(cur.Text, "(a) The owner of any dog") || !strings.Contains(cur.Text, "owner’s knowledge of such viciousness.\n(b) Nothing") {
+        t.Errorf("section = %q / %q", cur.Citation, cur.Text)
+    }
+    if cur.Props["article_num"] != float64(3) || cur.Props["part_num"] != float64(1) || cur.Props["section_num"] != "3342" || cur.Props["layer"] != nil { t.Errorf("inherited props = %v", cur.Props) }
+    if cur.Sources[0].Name != SourceName || !strings.HasSuffix(cur.Sources[0].URL, "lawCode=CIV&sectionNum=3342.") { t.Errorf("sources = %v", cur.Sources) }
+    if c := versions["CA:CONS:ArtI:§13"][0]; c.Citation != "Cal. Const. art. I, § 13" { t.Errorf("CONS citation = %q", c.Citation) }
+    // § 3343 is not in LAW_TOC_SECTIONS_TBL: placed by its own outline columns
+    if len(versions["CA:CIV:D4:PT1:T02:CH02:Art3:§3343"]) != 1 { t.Errorf("§3343 not placed under its article") }
+
+    only, err := Import(fixtureDir, Options{FetchedAt: "2025-01-01T00:00:00Z", Codes: []string{"pen"}})
+    if err != nil { t.Fatal(err) }
+    for _, n := range only.Nodes {
+        if !strings.HasPrefix(n.ID, "CA:PEN") { t.Errorf("code filter let through %s", n.ID) }
+    }
+}
+
+func TestImportJSONLLoads(t *testing.T) {
+    res, err := Import(fixtureDir, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    var buf bytes.Buffer
+    if err := res.WriteJSONL(&buf); err != nil { t.Fatal(err) }
+    path := filepath.Join(t.TempDir(), "leginfo.jsonl")
+    if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(path); err != nil { t.Fatal(err) }
+    if h := store.History("CA:CIV:D4:PT1:T02:CH02:Art3:§3342"); len(h) != 2 || h[1].Props["op_statues"] != "1988" { t.Fatalf("history = %v", h) }
+    kids, _ := store.GetChildren("CA:CIV:D4:PT1:T02:CH02:Art3")
+    if len(kids) != 2 || kids[0].ID != "CA:CIV:D4:PT1:T02:CH02:Art3:§3342" { t.Fatalf("children = %v", kids) }
+    if path, _ := store.GetParentsPath("CA:PEN:PT1:T08:CH01:§187"); strings.Join(path, " > ") != "CA > CA:PEN > CA:PEN:PT1 > CA:PEN:PT1:T08 > CA:PEN:PT1:T08:CH01 > CA:PEN:PT1:T08:CH01:§187" {
+        t.Fatalf("breadcrumb = %v", path)
+    }
+}
@@ -0,0 +1,144 @@
+package etl
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    conf "lawmap/internal/config"
+)
+
+// Runner runs sources through Fetch → Parse → Normalize → Emit with their state
+// and the shared artifact cache.
+type Runner struct {
+    Registry *Registry
+    Cache    *Cache
+    StateDir string
+    Client   *http.Client     // defaults to http.DefaultClient
+    Now      func() time.Time // defaults to time.Now
+}
+
+// RunOptions adjust a single run.
+type RunOptions struct {
+    URLs  []string // fetch these instead of the descriptor's URLs
+    Full  bool     // emit every node and edge, not only what changed since the last run
+    Force bool     // parse even when no artifact changed upstream
+}
+
+// Report summarizes a run. Counts are of node IDs: added ones are new, changed ones
+// have a version hash not emitted before, removed ones were emitted last run and not this one.
+type Report struct {
+    Source     string   `json:"source"`
+    StartedAt  string   `json:"started_at"`
+    FinishedAt string   `json:"finished_at"`
+    Artifacts  int      `json:"artifacts"`
+    Downloaded int      `json:"downloaded"` // artifacts whose content changed
+    Skipped    bool     `json:"skipped"`    // nothing changed upstream, nothing parsed
+    Nodes      int      `json:"nodes"`      // node versions emitted
+    Edges      int      `json:"edges"`      // edges emitted
+    Added      int      `json:"added"`
+    Changed    int      `json:"changed"`
+    Removed    int      `json:"removed"`
+    Unchanged  int      `json:"unchanged"`
+    RemovedIDs []string `json:"removed_ids,omitempty"`
+}
+
+// Run runs the source registered for d and writes its output to w. Unless
+// opts.Full is set, or this is the source's first run, only new node versions and
+// new or changed edges are emitted. State is saved only after a successful emit.
+func (r *Runner) Run(ctx context.Context, d conf.SourceDescriptor, w io.Writer, opts RunOptions) (*Report, error) {
+    now := time.Now
+    if r.Now != nil { now = r.Now }
+    rep := &Report{Source: d.Name, StartedAt: now().UTC().Format(time.RFC3339)}
+    src, err := r.Registry.New(d)
+    if err != nil { return nil, err }
+    st, err := LoadState(r.StateDir, d.Name)
+    if err != nil { return nil, err }
+    urls := opts.URLs
+    if len(urls) == 0 { urls = d.URLs }
+    f := &Fetcher{Client: r.Client, Cache: r.Cache, State: st, URLs: urls, FetchedAt: rep.StartedAt}
+    arts, err := src.Fetch(ctx, f)
+    if err != nil { return nil, fmt.Errorf("%s: fetch: %w", d.Name, err) }
+    rep.Artifacts = len(arts)
+    for _, a := range arts {
+        if a.Changed { rep.Downloaded++ }
+    }
+    firstRun := st.LastRun == ""
+    if rep.Downloaded == 0 && !firstRun && !opts.Force {
+        rep.Skipped = true
+        st.LastRun = rep.StartedAt
+        rep.FinishedAt = now().UTC().Format(time.RFC3339)
+        return rep, st.Save(r.StateDir)
+    }
+    res, err := src.Parse(ctx, arts)
+    if err != nil { return nil, fmt.Errorf("%s: parse: %w", d.Name, err) }
+    if err := src.Normalize(res); err != nil { return nil, fmt.Errorf("%s: normalize: %w", d.Name, err) }
+
+    out := &Result{}
+    full := opts.Full || firstRun
+    seen := map[string]bool{}
+    status := map[string]string{}
+    for _, n := range res.Nodes {
+        h := NodeHash(n)
+        switch {
+        case st.HasVersion(n.ID, h):
+            if status[n.ID] == "" { status[n.ID] = "unchanged" }
+        case len(st.Nodes[n.ID]) > 0:
+            status[n.ID] = "changed"
+        default:
+            if status[n.ID] != "changed" { status[n.ID] = "added" }
+        }
+        if full || !st.HasVersion(n.ID, h) { out.Nodes = append(out.Nodes, n) }
+        seen[n.ID] = true
+    }
+    edges := make(map[string]string, len(res.Edges))
+    for _, e := range res.Edges {
+        h := EdgeHash(e)
+        if e.ID != "" { edges[e.ID] = h }
+        if full || e.ID == "" || st.Edges[e.ID] != h { out.Edges = append(out.Edges, e) }
+    }
+    for id := range st.Nodes {
+        if !seen[id] { rep.RemovedIDs = append(rep.RemovedIDs, id) }
+    }
+    sort.Strings(rep.RemovedIDs)
+    for _, s := range status {
+        switch s {
+        case "added":
+            rep.Added++
+        case "changed":
+            rep.Changed++
+        default:
+            rep.Unchanged++
+        }
+    }
+    rep.Removed = len(rep.RemovedIDs)
+    rep.Nodes, rep.Edges = len(out.Nodes), len(out.Edges)
+    if err := src.Emit(out, w); err != nil { return nil, fmt.Errorf("%s: emit: %w", d.Name, err) }
+
+    for _, n := range res.Nodes {
+        if h := NodeHash(n); !st.HasVersion(n.ID, h) { st.Nodes[n.ID] = append(st.Nodes[n.ID], h) }
+    }
+    for _, id := range rep.RemovedIDs { delete(st.Nodes, id) }
+    st.Edges = edges
+    st.LastRun = rep.StartedAt
+    rep.FinishedAt = now().UTC().Format(time.RFC3339)
+    return rep, st.Save(r.StateDir)
+}
+
+// NodeHash is a node's version.hash, or a hash of its content when it has none.
+func NodeHash(n *dgraph.Node) string {
+    if n.Version != nil && n.Version.Hash != "" { return n.Version.Hash }
+    props, _ := json.Marshal(n.Props)
+    return ContentHash(n.Title, n.Citation, n.Text, string(props))
+}
+
+// EdgeHash identifies an edge's endpoints, type and props.
+func EdgeHash(e *dgraph.Edge) string {
+    props, _ := json.Marshal(e.Props)
+    return ContentHash(e.EdgeType, e.FromID, e.ToID, string(props))
+}
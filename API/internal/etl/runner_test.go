@@ -0,0 +1,126 @@
+package etl
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    conf "lawmap/internal/config"
+)
+
+// listSource reads "id=text" lines into nodes under a root.
+type listSource struct{ Defaults }
+
+func (listSource) Fetch(ctx context.Context, f *Fetcher) ([]Artifact, error) { return f.FetchAll(ctx) }
+
+func (listSource) Parse(ctx context.Context, arts []Artifact) (*Result, error) {
+    res := &Result{Nodes: []*dgraph.Node{{ID: "X", Labels: []string{"CODE"}, Title: "X"}}}
+    for _, a := range arts {
+        b, err := os.ReadFile(a.Path)
+        if err != nil { return nil, err }
+        sc := bufio.NewScanner(bytes.NewReader(b))
+        for i := 0; sc.Scan(); i++ {
+            id, text, _ := strings.Cut(sc.Text(), "=")
+            res.Nodes = append(res.Nodes, &dgraph.Node{ID: "X:" + id, Labels: []string{"SECTION"}, Text: text,
+                Version: &dgraph.Version{FetchedAt: a.FetchedAt, Hash: ContentHash(text)}})
+            res.Edges = append(res.Edges, ParentOf("X", "X:"+id, i))
+        }
+    }
+    // repeats are dropped by Normalize
+    res.Edges = append(res.Edges, res.Edges...)
+    return res, nil
+}
+
+// fixtureServer serves a mutable body with an ETag and honors If-None-Match.
+type fixtureServer struct {
+    mu       sync.Mutex
+    body     string
+    requests int
+}
+
+func (fs *fixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    fs.mu.Lock()
+    defer fs.mu.Unlock()
+    fs.requests++
+    etag := fmt.Sprintf(`"%s"`, ContentHash(fs.body)[7:19])
+    if r.Header.Get("If-None-Match") == etag { w.WriteHeader(http.StatusNotModified); return }
+    w.Header().Set("ETag", etag)
+    fmt.Fprint(w, fs.body)
+}
+
+func (fs *fixtureServer) set(body string) { fs.mu.Lock(); fs.body = body; fs.mu.Unlock() }
+
+func decode(t *testing.T, b []byte) (nodes, edges []string) {
+    t.Helper()
+    sc := bufio.NewScanner(bytes.NewReader(b))
+    for sc.Scan() {
+        var item struct{ Type, ID string }
+        if err := json.Unmarshal(sc.Bytes(), &item); err != nil { t.Fatal(err) }
+        if item.Type == "node" { nodes = append(nodes, item.ID) } else { edges = append(edges, item.ID) }
+    }
+    return nodes, edges
+}
+
+func TestRunnerIncremental(t *testing.T) {
+    fs := &fixtureServer{body: "1=one\n2=two\n3=three\n"}
+    srv := httptest.NewServer(fs)
+    defer srv.Close()
+    reg := NewRegistry()
+    reg.Register("List", func(conf.SourceDescriptor) (Source, error) { return listSource{}, nil })
+    dir := t.TempDir()
+    r := &Runner{Registry: reg, Cache: &Cache{Dir: filepath.Join(dir, "cache")}, StateDir: filepath.Join(dir, "state"), Client: srv.Client()}
+    d := conf.SourceDescriptor{Name: "List", Kind: "api", URLs: []string{srv.URL + "/list.txt"}}
+    run := func(opts RunOptions) (*Report, []string, []string) {
+        var buf bytes.Buffer
+        rep, err := r.Run(context.Background(), d, &buf, opts)
+        if err != nil { t.Fatal(err) }
+        n, e := decode(t, buf.Bytes())
+        return rep, n, e
+    }
+
+    rep, nodes, edges := run(RunOptions{})
+    if rep.Added != 4 || rep.Downloaded != 1 || len(nodes) != 4 || len(edges) != 3 { t.Fatalf("first run = %+v, %v, %v", rep, nodes, edges) }
+
+    // the server answers 304: nothing is parsed or emitted
+    rep, nodes, _ = run(RunOptions{})
+    if !rep.Skipped || len(nodes) != 0 || fs.requests != 2 { t.Fatalf("unchanged run = %+v, %v", rep, nodes) }
+
+    // one section changes, one is dropped, one is new
+    fs.set("1=one\n2=TWO\n4=four\n")
+    rep, nodes, edges = run(RunOptions{})
+    if rep.Added != 1 || rep.Changed != 1 || rep.Removed != 1 || rep.Unchanged != 2 || strings.Join(rep.RemovedIDs, ",") != "X:3" { t.Fatalf("report = %+v", rep) }
+    if strings.Join(nodes, ",") != "X:2,X:4" || strings.Join(edges, ",") != "po:X->X:4" { t.Fatalf("emitted %v / %v", nodes, edges) }
+
+    // -full re-emits everything; -force parses an unchanged download
+    rep, nodes, _ = run(RunOptions{Full: true, Force: true})
+    if rep.Skipped || len(nodes) != 4 || rep.Unchanged != 4 { t.Fatalf("full run = %+v, %v", rep, nodes) }
+
+    st, err := LoadState(r.StateDir, "List")
+    if err != nil { t.Fatal(err) }
+    if len(st.Nodes["X:2"]) != 2 || st.Artifacts[d.URLs[0]].ETag == "" || st.LastRun == "" { t.Errorf("state = %+v", st) }
+    if _, err := os.Stat(filepath.Join(r.StateDir, "list.json")); err != nil { t.Errorf("state file: %v", err) }
+}
+
+func TestCacheIsContentAddressed(t *testing.T) {
+    c := &Cache{Dir: t.TempDir()}
+    h1, p1, err := c.Put(strings.NewReader("same"))
+    if err != nil { t.Fatal(err) }
+    h2, p2, _ := c.Put(strings.NewReader("same"))
+    h3, _, _ := c.Put(strings.NewReader("other"))
+    if h1 != h2 || p1 != p2 || h1 == h3 || h1 != ContentHash("same") { t.Fatalf("hashes %s %s %s", h1, h2, h3) }
+    if !c.Has(h1) || filepath.Base(filepath.Dir(p1)) != h1[7:9] { t.Errorf("path = %s", p1) }
+}
+
+func TestRegistryUnknownSource(t *testing.T) {
+    if _, err := NewRegistry().New(conf.SourceDescriptor{Name: "Nope"}); err == nil { t.Fatal("expected error") }
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Schedule is a parsed five-field cron expression (minute hour day-of-month month
+// day-of-week). As in cron, when both day fields are restricted a day matching
+// either one is due.
+type Schedule struct {
+    spec                          string
+    minute, hour, dom, month, dow uint64 // bit sets
+    domAny, dowAny                bool
+}
+
+var shorthands = map[string]string{
+    "@yearly": "0 0 1 1 *", "@annually": "0 0 1 1 *", "@monthly": "0 0 1 * *",
+    "@weekly": "0 0 * * 0", "@daily": "0 0 * * *", "@midnight": "0 0 * * *", "@hourly": "0 * * * *",
+}
+
+// ParseCron parses "m h dom mon dow" with *, lists, ranges and steps, or a shorthand such as @monthly.
+func ParseCron(spec string) (*Schedule, error) {
+    expr := strings.TrimSpace(spec)
+    if s, ok := shorthands[expr]; ok { expr = s }
+    f := strings.Fields(expr)
+    if len(f) != 5 { return nil, fmt.Errorf("cron %q: want 5 fields", spec) }
+    s := &Schedule{spec: spec, domAny: f[2] == "*", dowAny: f[4] == "*"}
+    var err error
+    bounds := []struct {
+        dst      *uint64
+        min, max int
+    }{{&s.minute, 0, 59}, {&s.hour, 0, 23}, {&s.dom, 1, 31}, {&s.month, 1, 12}, {&s.dow, 0, 7}}
+    for i, b := range bounds {
+        if *b.dst, err = parseField(f[i], b.min, b.max); err != nil { return nil, fmt.Errorf("cron %q: %w", spec, err) }
+    }
+    // 7 is Sunday too
+    if s.dow&(1<<7) != 0 { s.dow |= 1 }
+    if !s.domAny && s.dowAny && !s.someDayExists() { return nil, fmt.Errorf("cron %q: no chosen month has the chosen day of month, so it never runs", spec) }
+    return s, nil
+}
+
+// monthDays is the longest each month gets, Feb 29 included.
+var monthDays = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// someDayExists reports whether a chosen day of month occurs in a chosen month
+// (0 0 31 2 * asks for Feb 31).
+func (s *Schedule) someDayExists() bool {
+    for m := 1; m <= 12; m++ {
+        if s.month&(1<<uint(m)) == 0 { continue }
+        for d := 1; d <= monthDays[m]; d++ {
+            if s.dom&(1<<uint(d)) != 0 { return true }
+        }
+    }
+    return false
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+    var bits uint64
+    for _, part := range strings.Split(field, ",") {
+        rng, step := part, 1
+        if i := strings.Index(part, "/"); i >= 0 {
+            n, err := strconv.Atoi(part[i+1:])
+            if err != nil || n <= 0 { return 0, fmt.Errorf("bad step in %q", part) }
+            rng, step = part[:i], n
+        }
+        lo, hi := min, max
+        if rng != "*" {
+            a, b, isRange := strings.Cut(rng, "-")
+            var err error
+            if lo, err = strconv.Atoi(a); err != nil { return 0, fmt.Errorf("bad value %q", part) }
+            hi = lo
+            if isRange {
+                if hi, err = strconv.Atoi(b); err != nil { return 0, fmt.Errorf("bad value %q", part) }
+            } else if step > 1 {
+                hi = max
+            }
+        }
+        if lo < min || hi > max || lo > hi { return 0, fmt.Errorf("%q out of range %d-%d", part, min, max) }
+        for v := lo; v <= hi; v += step { bits |= 1 << uint(v) }
+    }
+    return bits, nil
+}
+
+// String returns the expression as configured.
+func (s *Schedule) String() string { return s.spec }
+
+// Next returns the first minute strictly after t that matches, in t's location, or
+// the zero time if none does (ParseCron rejects such schedules).
+func (s *Schedule) Next(t time.Time) time.Time {
+    t = t.Truncate(time.Minute).Add(time.Minute)
+    // every schedule ParseCron accepts matches within eight years (Feb 29 around 2100 at worst)
+    for limit := t.AddDate(9, 0, 0); t.Before(limit); {
+        if s.month&(1<<uint(t.Month())) == 0 {
+            t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+            continue
+        }
+        if !s.dayMatches(t) {
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+            continue
+        }
+        if s.hour&(1<<uint(t.Hour())) == 0 {
+            t = t.Truncate(time.Hour).Add(time.Hour)
+            continue
+        }
+        if s.minute&(1<<uint(t.Minute())) == 0 {
+            t = t.Add(time.Minute)
+            continue
+        }
+        return t
+    }
+    return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+    dom := s.dom&(1<<uint(t.Day())) != 0
+    dow := s.dow&(1<<uint(t.Weekday())) != 0
+    switch {
+    case s.domAny && s.dowAny:
+        return true
+    case s.domAny:
+        return dow
+    case s.dowAny:
+        return dom
+    }
+    return dom || dow
+}
@@ -0,0 +1,340 @@
+// Package scheduler refreshes sources on cron schedules. Each run goes through the
+// etl.Runner, compares the emitted node versions with the live store by
+// version.hash and applies only what is new or changed, so a refresh adds versions
+// rather than reloading the graph. Every run is recorded in the run log.
+package scheduler
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    conf "lawmap/internal/config"
+    "lawmap/internal/etl"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+// maxRuns bounds the run log kept in memory.
+const maxRuns = 1000
+
+// Run is one entry of the run log. Node counts are by ID, as in etl.Report; removed
+// IDs are reported but stay in the store, since a provision missing from one
+// download is more often a partial file than a repeal.
+type Run struct {
+    ID         int      `json:"id"`
+    Source     string   `json:"source"`
+    Trigger    string   `json:"trigger"` // schedule|manual
+    Status     string   `json:"status"`  // running|ok|skipped|error
+    StartedAt  string   `json:"started_at"`
+    FinishedAt string   `json:"finished_at,omitempty"`
+    Error      string   `json:"error,omitempty"`
+    Added      int      `json:"added"`
+    Changed    int      `json:"changed"`
+    Removed    int      `json:"removed"`
+    Unchanged  int      `json:"unchanged"`
+    Edges      int      `json:"edges"` // new or changed edges applied
+    RemovedIDs []string `json:"removed_ids,omitempty"`
+}
+
+// Job is a scheduled source as reported by GET /admin/sources.
+type Job struct {
+    Source   string `json:"source"`
+    Schedule string `json:"schedule"`
+    Paused   bool   `json:"paused"`
+    Running  bool   `json:"running"`
+    NextRun  string `json:"next_run,omitempty"`
+    LastRun  *Run   `json:"last_run,omitempty"`
+}
+
+type job struct {
+    desc    conf.SourceDescriptor
+    sched   *Schedule
+    urls    []string
+    paused  bool
+    running bool
+    next    time.Time
+    last    *Run
+}
+
+// Scheduler owns the jobs and the run log.
+type Scheduler struct {
+    Runner *etl.Runner
+    Now    func() time.Time // defaults to time.Now
+
+    store    *graphrepo.MemoryStore
+    runsFile string
+    mu       sync.Mutex
+    jobs     map[string]*job
+    order    []string
+    runs     []Run
+    nextID   int
+    wg       sync.WaitGroup
+    ctx      context.Context // from Start; manual runs outlive the request that triggered them
+}
+
+// ErrUnknownSource is returned for a name that is not scheduled.
+var ErrUnknownSource = errors.New("source is not scheduled")
+
+// ErrRunning is returned when a source is triggered while it runs.
+var ErrRunning = errors.New("source is already running")
+
+// New schedules the sources listed in cfg. Each must name a descriptor with a
+// registered importer; sources without a schedule use cfg.Default (else @monthly).
+func New(cfg conf.SchedulerConfig, descs []conf.SourceDescriptor, reg *etl.Registry, store *graphrepo.MemoryStore) (*Scheduler, error) {
+    stateDir, cacheDir := cfg.StateDir, cfg.CacheDir
+    if stateDir == "" { stateDir = "var/etl/state" }
+    if cacheDir == "" { cacheDir = "var/etl/cache" }
+    s := &Scheduler{
+        Runner: &etl.Runner{Registry: reg, Cache: &etl.Cache{Dir: cacheDir}, StateDir: stateDir},
+        store: store, runsFile: cfg.RunsFile, jobs: map[string]*job{},
+    }
+    byName := map[string]conf.SourceDescriptor{}
+    for _, d := range descs { byName[d.Name] = d }
+    registered := map[string]bool{}
+    for _, n := range reg.Names() { registered[n] = true }
+    for _, src := range cfg.Sources {
+        d, ok := byName[src.Name]
+        if !ok { return nil, fmt.Errorf("scheduler: no source descriptor named %q", src.Name) }
+        if !registered[src.Name] { return nil, fmt.Errorf("scheduler: no importer for %q", src.Name) }
+        spec := src.Schedule
+        if spec == "" { spec = cfg.Default }
+        if spec == "" { spec = "@monthly" }
+        sched, err := ParseCron(spec)
+        if err != nil { return nil, fmt.Errorf("scheduler: %s: %w", src.Name, err) }
+        s.jobs[src.Name] = &job{desc: d, sched: sched, urls: src.URLs, paused: src.Paused}
+        s.order = append(s.order, src.Name)
+    }
+    if err := s.loadRuns(); err != nil { return nil, err }
+    now := s.now()
+    for _, j := range s.jobs { j.next = j.sched.Next(now) }
+    return s, nil
+}
+
+func (s *Scheduler) now() time.Time {
+    if s.Now != nil { return s.Now().UTC() }
+    return time.Now().UTC()
+}
+
+// Start checks for due jobs every minute until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+    s.mu.Lock()
+    s.ctx = ctx
+    s.mu.Unlock()
+    go func() {
+        t := time.NewTicker(time.Minute)
+        defer t.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+                s.Tick(ctx)
+            }
+        }
+    }()
+}
+
+// Tick starts every unpaused job whose next run time has passed. A job without a
+// next run time never runs on schedule.
+func (s *Scheduler) Tick(ctx context.Context) {
+    now := s.now()
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, name := range s.order {
+        j := s.jobs[name]
+        if j.paused || j.running || j.next.IsZero() || now.Before(j.next) { continue }
+        j.next = j.sched.Next(now)
+        s.startLocked(ctx, j, "schedule")
+    }
+}
+
+// Trigger starts a run of the named source now, paused or not. The run uses the
+// context given to Start, not the caller's.
+func (s *Scheduler) Trigger(name string) (Run, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    j, ok := s.jobs[name]
+    if !ok { return Run{}, ErrUnknownSource }
+    if j.running { return Run{}, ErrRunning }
+    ctx := s.ctx
+    if ctx == nil { ctx = context.Background() }
+    return s.startLocked(ctx, j, "manual"), nil
+}
+
+// Pause stops (or, with false, resumes) the scheduled runs of a source.
+func (s *Scheduler) Pause(name string, paused bool) (Job, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    j, ok := s.jobs[name]
+    if !ok { return Job{}, ErrUnknownSource }
+    j.paused = paused
+    if !paused { j.next = j.sched.Next(s.now()) }
+    return s.jobLocked(j), nil
+}
+
+// Wait blocks until the runs started so far have finished.
+func (s *Scheduler) Wait() { s.wg.Wait() }
+
+// Jobs lists the scheduled sources in config order.
+func (s *Scheduler) Jobs() []Job {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Job, 0, len(s.order))
+    for _, name := range s.order { out = append(out, s.jobLocked(s.jobs[name])) }
+    return out
+}
+
+// Runs returns the run log newest first, optionally for one source.
+func (s *Scheduler) Runs(source string, limit int) []Run {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []Run
+    for i := len(s.runs) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+        if source == "" || s.runs[i].Source == source { out = append(out, s.runs[i]) }
+    }
+    return out
+}
+
+func (s *Scheduler) jobLocked(j *job) Job {
+    out := Job{Source: j.desc.Name, Schedule: j.sched.String(), Paused: j.paused, Running: j.running, LastRun: j.last}
+    if !j.paused && !j.next.IsZero() { out.NextRun = j.next.Format(time.RFC3339) }
+    return out
+}
+
+// startLocked records a running entry and runs the job in the background.
+func (s *Scheduler) startLocked(ctx context.Context, j *job, trigger string) Run {
+    s.nextID++
+    run := Run{ID: s.nextID, Source: j.desc.Name, Trigger: trigger, Status: "running", StartedAt: s.now().Format(time.RFC3339)}
+    j.running = true
+    s.appendLocked(run)
+    s.wg.Add(1)
+    go func() {
+        defer s.wg.Done()
+        s.finish(j, s.execute(ctx, j, run))
+    }()
+    return run
+}
+
+// execute runs the source and applies the changes to the store.
+func (s *Scheduler) execute(ctx context.Context, j *job, run Run) Run {
+    var buf bytes.Buffer
+    // Forced, full output: what counts as changed is decided against the store, not the
+    // runner's state, which outlives the store across restarts (the store is reloaded
+    // from the dataset, without versions applied by earlier runs).
+    rep, err := s.Runner.Run(ctx, j.desc, &buf, etl.RunOptions{URLs: j.urls, Full: true, Force: true})
+    if err != nil {
+        run.Status, run.Error = "error", err.Error()
+        return run
+    }
+    var nodes []*dgraph.Node
+    var edges []*dgraph.Edge
+    status := map[string]string{}
+    err = graphrepo.ReadJSONL(&buf,
+        func(n *dgraph.Node) error {
+            st := s.compare(n)
+            if st != "unchanged" { nodes = append(nodes, n) }
+            if prev := status[n.ID]; prev == "" || prev == "unchanged" || st == "changed" { status[n.ID] = st }
+            return nil
+        },
+        func(e *dgraph.Edge) error {
+            if old, ok := s.store.Edge(e.ID); ok && e.ID != "" && etl.EdgeHash(old) == etl.EdgeHash(e) { return nil }
+            edges = append(edges, e)
+            return nil
+        })
+    if err != nil {
+        run.Status, run.Error = "error", err.Error()
+        return run
+    }
+    if len(nodes)+len(edges) > 0 { s.store.Apply(nodes, edges) }
+    for _, st := range status {
+        switch st {
+        case "added":
+            run.Added++
+        case "changed":
+            run.Changed++
+        default:
+            run.Unchanged++
+        }
+    }
+    run.Edges = len(edges)
+    run.Removed, run.RemovedIDs = rep.Removed, rep.RemovedIDs
+    run.Status = "ok"
+    if rep.Downloaded == 0 && len(nodes)+len(edges) == 0 { run.Status = "skipped" }
+    return run
+}
+
+// compare classifies a node version against the store by version.hash (by content for
+// nodes without one).
+func (s *Scheduler) compare(n *dgraph.Node) string {
+    cur, ok := s.store.GetNode(n.ID)
+    if !ok { return "added" }
+    if n.Version != nil && n.Version.Hash != "" {
+        if s.store.HasVersion(n.ID, n.Version.Hash) { return "unchanged" }
+        return "changed"
+    }
+    if etl.NodeHash(cur) == etl.NodeHash(n) { return "unchanged" }
+    return "changed"
+}
+
+func (s *Scheduler) finish(j *job, run Run) {
+    run.FinishedAt = s.now().Format(time.RFC3339)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    j.running = false
+    last := run
+    j.last = &last
+    for i := len(s.runs) - 1; i >= 0; i-- {
+        if s.runs[i].ID == run.ID { s.runs[i] = run; break }
+    }
+    if err := s.persistLocked(run); err != nil { fmt.Fprintf(os.Stderr, "scheduler: run log: %v\n", err) }
+}
+
+func (s *Scheduler) appendLocked(run Run) {
+    s.runs = append(s.runs, run)
+    if len(s.runs) > maxRuns { s.runs = s.runs[len(s.runs)-maxRuns:] }
+}
+
+// persistLocked appends a finished run to the run log file.
+func (s *Scheduler) persistLocked(run Run) error {
+    if s.runsFile == "" { return nil }
+    if err := os.MkdirAll(filepath.Dir(s.runsFile), 0o755); err != nil { return err }
+    f, err := os.OpenFile(s.runsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+    if err != nil { return err }
+    b, _ := json.Marshal(run)
+    if _, err := f.Write(append(b, '\n')); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// loadRuns reads earlier runs from the run log file and sets each job's last run.
+func (s *Scheduler) loadRuns() error {
+    if s.runsFile == "" { return nil }
+    f, err := os.Open(s.runsFile)
+    if errors.Is(err, os.ErrNotExist) { return nil }
+    if err != nil { return err }
+    defer f.Close()
+    sc := bufio.NewScanner(f)
+    sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+    for sc.Scan() {
+        var run Run
+        if json.Unmarshal(sc.Bytes(), &run) != nil { continue }
+        s.appendLocked(run)
+        if run.ID > s.nextID { s.nextID = run.ID }
+    }
+    sort.SliceStable(s.runs, func(i, k int) bool { return s.runs[i].ID < s.runs[k].ID })
+    for i := range s.runs {
+        if j, ok := s.jobs[s.runs[i].Source]; ok { r := s.runs[i]; j.last = &r }
+    }
+    return sc.Err()
+}
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    conf "lawmap/internal/config"
+    "lawmap/internal/etl/sources"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+func TestCronNext(t *testing.T) {
+    from := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC) // a Wednesday
+    cases := []struct{ spec, want string }{
+        {"@monthly", "2025-02-01T00:00:00Z"},
+        {"0 3 1 * *", "2025-02-01T03:00:00Z"},
+        {"*/15 * * * *", "2025-01-15T10:45:00Z"},
+        {"30 6 * * 1-5", "2025-01-16T06:30:00Z"},
+        {"0 2 * * 0", "2025-01-19T02:00:00Z"},
+        {"0 2 * * 7", "2025-01-19T02:00:00Z"},
+        {"0 0 29 2 *", "2028-02-29T00:00:00Z"},
+        {"0 12 1,20 * 1", "2025-01-20T12:00:00Z"}, // day of month or Monday
+        {"0 0 31 2 1", "2025-02-03T00:00:00Z"},    // no Feb 31, but Mondays in February
+    }
+    for _, c := range cases {
+        s, err := ParseCron(c.spec)
+        if err != nil { t.Fatalf("%s: %v", c.spec, err) }
+        if got := s.Next(from).Format(time.RFC3339); got != c.want { t.Errorf("%s: next = %s, want %s", c.spec, got, c.want) }
+    }
+    for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 31 2 *", "0 0 30,31 2 *", "0 0 31 4,6,9,11 *"} {
+        if _, err := ParseCron(bad); err == nil { t.Errorf("%q: expected error", bad) }
+    }
+    // a schedule that never matches has no next run, and Tick does not take that as due
+    never := &Schedule{spec: "never", minute: 1, hour: 1, dom: 1 << 31, month: 1 << 2, dowAny: true}
+    if next := never.Next(from); !next.IsZero() { t.Errorf("never: next = %s", next) }
+}
+
+// edition serves one CFR fixture at a fixed URL and can be switched to another.
+// No validators are sent, so every run downloads and the cache decides; the
+// Content-Disposition name carries the edition date the importer needs.
+type edition struct {
+    mu   sync.Mutex
+    file string
+}
+
+func (e *edition) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    b, err := os.ReadFile(filepath.Join("../../test/fixtures/cfr", e.file))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Disposition", `attachment; filename="`+e.file+`"`)
+    w.Write(b)
+}
+
+func serve(t *testing.T, ed *edition) *httptest.Server {
+    srv := httptest.NewServer(ed)
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// newScheduler runs srv's source into a fresh store, with state, cache and run log under dir.
+func newScheduler(t *testing.T, srv *httptest.Server, dir string) (*Scheduler, *graphrepo.MemoryStore) {
+    t.Helper()
+    cfg := conf.SchedulerConfig{
+        Default: "0 3 1 * *", StateDir: filepath.Join(dir, "state"), CacheDir: filepath.Join(dir, "cache"), RunsFile: filepath.Join(dir, "runs.jsonl"),
+        Sources: []conf.ScheduledSource{{Name: "GovInfo CFR", URLs: []string{srv.URL + "/title28.xml"}}},
+    }
+    descs := []conf.SourceDescriptor{{Name: "GovInfo CFR", Jurisdictions: []string{"US"}, Codes: []string{"CFR"}, Kind: "bulk"}}
+    store := graphrepo.NewMemoryStore()
+    s, err := New(cfg, descs, sources.Registry(), store)
+    if err != nil { t.Fatal(err) }
+    s.Runner.Client = srv.Client()
+    return s, store
+}
+
+func TestRunsApplyOnlyChanges(t *testing.T) {
+    ed := &edition{file: "title28_annual_2023.xml"}
+    s, store := newScheduler(t, serve(t, ed), t.TempDir())
+    trigger := func() Run {
+        t.Helper()
+        if _, err := s.Trigger("GovInfo CFR"); err != nil { t.Fatal(err) }
+        s.Wait()
+        return s.Runs("", 1)[0]
+    }
+    first := trigger()
+    if first.Status != "ok" || first.Added == 0 || first.Changed != 0 || first.Trigger != "manual" { t.Fatalf("first run = %+v", first) }
+    if _, ok := store.GetNode("US:CFR:T28:§600.4"); !ok { t.Fatal("not applied") }
+
+    // same download, nothing new for the store
+    if r := trigger(); r.Status != "skipped" || r.Added+r.Changed != 0 { t.Fatalf("second run = %+v", r) }
+
+    // the eCFR snapshot changes only § 600.4, which becomes a second version
+    ed.mu.Lock()
+    ed.file = "title28_ecfr_2024-06-05.xml"
+    ed.mu.Unlock()
+    third := trigger()
+    if third.Status != "ok" || third.Changed != 1 || third.Added != 0 || third.Unchanged == 0 { t.Fatalf("third run = %+v", third) }
+    if h := store.History("US:CFR:T28:§600.4"); len(h) != 2 { t.Errorf("history = %d versions", len(h)) }
+
+    // the run log survives a restart
+    b, err := os.ReadFile(s.runsFile)
+    if err != nil || len(b) == 0 { t.Fatalf("runs file: %v", err) }
+    s2, _ := newScheduler(t, serve(t, ed), t.TempDir())
+    s2.runsFile = s.runsFile
+    if err := s2.loadRuns(); err != nil { t.Fatal(err) }
+    if runs := s2.Runs("GovInfo CFR", 0); len(runs) != 3 || runs[0].Changed != 1 { t.Errorf("reloaded runs = %+v", runs) }
+}
+
+func TestRestartReappliesToFreshStore(t *testing.T) {
+    ed := &edition{file: "title28_annual_2023.xml"}
+    srv, dir := serve(t, ed), t.TempDir()
+    s, _ := newScheduler(t, srv, dir)
+    if _, err := s.Trigger("GovInfo CFR"); err != nil { t.Fatal(err) }
+    s.Wait()
+    ed.mu.Lock()
+    ed.file = "title28_ecfr_2024-06-05.xml"
+    ed.mu.Unlock()
+    if _, err := s.Trigger("GovInfo CFR"); err != nil { t.Fatal(err) }
+    s.Wait()
+
+    // after a restart the store is reloaded without what the runs applied, while the
+    // runner's state remembers the download as unchanged; the run still fills the store
+    s2, store := newScheduler(t, srv, dir)
+    if _, err := s2.Trigger("GovInfo CFR"); err != nil { t.Fatal(err) }
+    s2.Wait()
+    r := s2.Runs("", 1)[0]
+    if r.Status != "ok" || r.Added == 0 { t.Fatalf("run after restart = %+v", r) }
+    n, ok := store.GetNode("US:CFR:T28:§600.4")
+    if !ok || n.Version == nil || n.Version.EffectiveDate != "2024-06-05" { t.Fatalf("current version after restart = %+v", n) }
+}
+
+func TestTickHonorsScheduleAndPause(t *testing.T) {
+    ed := &edition{file: "title28_annual_2023.xml"}
+    s, _ := newScheduler(t, serve(t, ed), t.TempDir())
+    now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+    s.Now = func() time.Time { return now }
+    s.jobs["GovInfo CFR"].next = s.jobs["GovInfo CFR"].sched.Next(now)
+    s.Tick(context.Background())
+    s.Wait()
+    if len(s.Runs("", 0)) != 0 { t.Fatal("ran before its time") }
+
+    now = time.Date(2025, 2, 1, 3, 0, 0, 0, time.UTC)
+    if _, err := s.Pause("GovInfo CFR", true); err != nil { t.Fatal(err) }
+    s.Tick(context.Background())
+    s.Wait()
+    if len(s.Runs("", 0)) != 0 { t.Fatal("paused source ran") }
+
+    job, _ := s.Pause("GovInfo CFR", false)
+    if job.NextRun != "2025-03-01T03:00:00Z" { t.Fatalf("next run after resume = %s", job.NextRun) }
+    now = time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC)
+    s.Tick(context.Background())
+    s.Wait()
+    runs := s.Runs("", 0)
+    if len(runs) != 1 || runs[0].Trigger != "schedule" || runs[0].Status != "ok" { t.Fatalf("runs = %+v", runs) }
+    if j := s.Jobs()[0]; j.NextRun != "2025-04-01T03:00:00Z" || j.LastRun == nil { t.Errorf("job = %+v", j) }
+
+    s.jobs["GovInfo CFR"].next = time.Time{} // as for a schedule that never matches
+    now = time.Date(2025, 5, 1, 3, 0, 0, 0, time.UTC)
+    s.Tick(context.Background())
+    s.Wait()
+    if len(s.Runs("", 0)) != 1 { t.Error("a job without a next run time ran") }
+}
+
+func TestNewRejectsUnknownSources(t *testing.T) {
+    descs := []conf.SourceDescriptor{{Name: "CA OAL / CCR"}}
+    for _, name := range []string{"Nope", "CA OAL / CCR"} {
+        cfg := conf.SchedulerConfig{Sources: []conf.ScheduledSource{{Name: name}}}
+        if _, err := New(cfg, descs, sources.Registry(), graphrepo.NewMemoryStore()); err == nil { t.Errorf("%s: expected error", name) }
+    }
+}
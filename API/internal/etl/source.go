@@ -0,0 +1,103 @@
+package etl
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "sort"
+    "sync"
+
+    dgraph "lawmap/internal/domain/graph"
+    conf "lawmap/internal/config"
+)
+
+// Source is one ingestion pipeline: Fetch downloads raw artifacts into the cache,
+// Parse turns them into graph items, Normalize cleans the result up and Emit writes
+// it. The Runner drives the four steps and keeps the source's incremental state.
+type Source interface {
+    Fetch(ctx context.Context, f *Fetcher) ([]Artifact, error)
+    Parse(ctx context.Context, arts []Artifact) (*Result, error)
+    Normalize(res *Result) error
+    Emit(res *Result, w io.Writer) error
+}
+
+// Factory builds a Source for a descriptor from configs/sources.json.
+type Factory func(d conf.SourceDescriptor) (Source, error)
+
+// Registry maps descriptor names ("GovInfo CFR", "CA LegInfo") to source factories.
+type Registry struct {
+    mu        sync.RWMutex
+    factories map[string]Factory
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry { return &Registry{factories: make(map[string]Factory)} }
+
+// Register adds f under a descriptor name, replacing any earlier registration.
+func (r *Registry) Register(name string, f Factory) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.factories[name] = f
+}
+
+// New builds the source registered for d.Name.
+func (r *Registry) New(d conf.SourceDescriptor) (Source, error) {
+    r.mu.RLock()
+    f, ok := r.factories[d.Name]
+    r.mu.RUnlock()
+    if !ok { return nil, fmt.Errorf("no source registered for %q", d.Name) }
+    return f(d)
+}
+
+// Names lists the registered descriptor names, sorted.
+func (r *Registry) Names() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    out := make([]string, 0, len(r.factories))
+    for n := range r.factories { out = append(out, n) }
+    sort.Strings(out)
+    return out
+}
+
+// Defaults gives a Source the usual Normalize and Emit steps; embed it and
+// implement Fetch and Parse.
+type Defaults struct{}
+
+// Normalize drops nodes and edges without IDs and repeated (ID, hash) versions and
+// edge IDs, keeping the first of each. Versions of a node stay in emitted order.
+func (Defaults) Normalize(res *Result) error {
+    Dedupe(res)
+    return nil
+}
+
+// Emit writes the result as loader JSONL.
+func (Defaults) Emit(res *Result, w io.Writer) error { return res.WriteJSONL(w) }
+
+// Dedupe removes nodes without an ID, repeated node versions and repeated edge IDs in place.
+func Dedupe(res *Result) {
+    seen := map[string]bool{}
+    nodes := res.Nodes[:0]
+    for _, n := range res.Nodes {
+        k := n.ID + "|" + versionHash(n)
+        if n.ID == "" || seen[k] { continue }
+        seen[k] = true
+        nodes = append(nodes, n)
+    }
+    res.Nodes = nodes
+    seenEdge := map[string]bool{}
+    edges := res.Edges[:0]
+    for _, e := range res.Edges {
+        if e.FromID == "" || e.ToID == "" { continue }
+        if e.ID != "" {
+            if seenEdge[e.ID] { continue }
+            seenEdge[e.ID] = true
+        }
+        edges = append(edges, e)
+    }
+    res.Edges = edges
+}
+
+func versionHash(n *dgraph.Node) string {
+    if n.Version == nil { return "" }
+    return n.Version.Hash
+}
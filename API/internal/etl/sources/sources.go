@@ -0,0 +1,89 @@
+// Package sources registers the file importers as etl.Sources under their
+// descriptor names, so configs/sources.json entries can be run by name. Each
+// source fetches its URLs into the artifact cache, stages the files under their
+// names (extracting zip archives) and hands them to the importer.
+package sources
+
+import (
+    "context"
+    "os"
+    "strings"
+
+    conf "lawmap/internal/config"
+    "lawmap/internal/etl"
+    "lawmap/internal/etl/cfr"
+    "lawmap/internal/etl/courts"
+    "lawmap/internal/etl/fedreg"
+    "lawmap/internal/etl/leginfo"
+    "lawmap/internal/etl/uscode"
+)
+
+// Registry returns a registry with every built-in source.
+func Registry() *etl.Registry {
+    r := etl.NewRegistry()
+    files := func(exts []string, imp func(paths []string, fetchedAt string, d conf.SourceDescriptor) (*etl.Result, error)) etl.Factory {
+        return func(d conf.SourceDescriptor) (etl.Source, error) { return &fileSource{d: d, exts: exts, imp: imp}, nil }
+    }
+    r.Register(uscode.SourceName, files([]string{".xml"}, func(paths []string, at string, _ conf.SourceDescriptor) (*etl.Result, error) {
+        return uscode.Import(paths, uscode.Options{FetchedAt: at})
+    }))
+    cfrFactory := files([]string{".xml"}, func(paths []string, at string, _ conf.SourceDescriptor) (*etl.Result, error) {
+        return cfr.Import(paths, cfr.Options{FetchedAt: at})
+    })
+    r.Register(cfr.AnnualSource, cfrFactory)
+    r.Register(cfr.ECFRSource, cfrFactory)
+    r.Register(fedreg.SourceName, files([]string{".json", ".xml"}, func(paths []string, at string, _ conf.SourceDescriptor) (*etl.Result, error) {
+        return fedreg.Import(paths, fedreg.Options{FetchedAt: at})
+    }))
+    r.Register(courts.SourceName, files([]string{".csv", ".csv.gz", ".csv.bz2", ".json", ".jsonl"}, func(paths []string, at string, _ conf.SourceDescriptor) (*etl.Result, error) {
+        return courts.Import(paths, courts.Options{FetchedAt: at})
+    }))
+    // pubinfo is a directory of tables; the staging directory is that directory
+    r.Register(leginfo.SourceName, func(d conf.SourceDescriptor) (etl.Source, error) { return &leginfoSource{d: d}, nil })
+    return r
+}
+
+// fileSource fetches its URLs and imports the staged files with the given extensions.
+type fileSource struct {
+    etl.Defaults
+    d    conf.SourceDescriptor
+    exts []string
+    imp  func(paths []string, fetchedAt string, d conf.SourceDescriptor) (*etl.Result, error)
+}
+
+func (s *fileSource) Fetch(ctx context.Context, f *etl.Fetcher) ([]etl.Artifact, error) { return f.FetchAll(ctx) }
+
+func (s *fileSource) Parse(ctx context.Context, arts []etl.Artifact) (*etl.Result, error) {
+    dir, err := os.MkdirTemp("", "lawmap-etl-*")
+    if err != nil { return nil, err }
+    defer os.RemoveAll(dir)
+    staged, err := etl.Stage(dir, arts)
+    if err != nil { return nil, err }
+    var paths []string
+    for _, p := range staged {
+        for _, ext := range s.exts {
+            if strings.HasSuffix(strings.ToLower(p), ext) { paths = append(paths, p); break }
+        }
+    }
+    return s.imp(paths, fetchedAt(arts), s.d)
+}
+
+type leginfoSource struct {
+    etl.Defaults
+    d conf.SourceDescriptor
+}
+
+func (s *leginfoSource) Fetch(ctx context.Context, f *etl.Fetcher) ([]etl.Artifact, error) { return f.FetchAll(ctx) }
+
+func (s *leginfoSource) Parse(ctx context.Context, arts []etl.Artifact) (*etl.Result, error) {
+    dir, err := os.MkdirTemp("", "lawmap-etl-*")
+    if err != nil { return nil, err }
+    defer os.RemoveAll(dir)
+    if _, err := etl.Stage(dir, arts); err != nil { return nil, err }
+    return leginfo.Import(dir, leginfo.Options{FetchedAt: fetchedAt(arts), Codes: s.d.Codes})
+}
+
+func fetchedAt(arts []etl.Artifact) string {
+    if len(arts) == 0 { return "" }
+    return arts[0].FetchedAt
+}
@@ -0,0 +1,83 @@
+package sources
+
+import (
+    "archive/zip"
+    "bytes"
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    conf "lawmap/internal/config"
+    "lawmap/internal/etl"
+)
+
+const fixtures = "../../test/fixtures"
+
+// fixtureServer serves the test fixtures, plus the LegInfo tables as /pubinfo.zip.
+func fixtureServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    var zbuf bytes.Buffer
+    zw := zip.NewWriter(&zbuf)
+    entries, err := os.ReadDir(filepath.Join(fixtures, "leginfo"))
+    if err != nil { t.Fatal(err) }
+    for _, e := range entries {
+        b, err := os.ReadFile(filepath.Join(fixtures, "leginfo", e.Name()))
+        if err != nil { t.Fatal(err) }
+        w, _ := zw.Create("pubinfo/" + e.Name())
+        w.Write(b)
+    }
+    if err := zw.Close(); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    mux.Handle("/", http.FileServer(http.Dir(fixtures)))
+    mux.HandleFunc("/pubinfo.zip", func(w http.ResponseWriter, r *http.Request) { w.Write(zbuf.Bytes()) })
+    return httptest.NewServer(mux)
+}
+
+func run(t *testing.T, srv *httptest.Server, d conf.SourceDescriptor) (*etl.Report, string) {
+    t.Helper()
+    dir := t.TempDir()
+    r := &etl.Runner{Registry: Registry(), Cache: &etl.Cache{Dir: filepath.Join(dir, "cache")}, StateDir: filepath.Join(dir, "state"), Client: srv.Client()}
+    var buf bytes.Buffer
+    rep, err := r.Run(context.Background(), d, &buf, etl.RunOptions{})
+    if err != nil { t.Fatal(err) }
+    return rep, buf.String()
+}
+
+func TestBuiltinSources(t *testing.T) {
+    srv := fixtureServer(t)
+    defer srv.Close()
+    cases := []struct {
+        desc conf.SourceDescriptor
+        want []string
+    }{
+        {conf.SourceDescriptor{Name: "GovInfo CFR", URLs: []string{srv.URL + "/cfr/title28_annual_2023.xml", srv.URL + "/cfr/title28_ecfr_2024-06-05.xml"}},
+            []string{`"id":"US:CFR:T28:§600.4"`, `"edition":"ecfr"`}},
+        {conf.SourceDescriptor{Name: "Federal Register", URLs: []string{srv.URL + "/fedreg/documents_2024-02-21.json", srv.URL + "/fedreg/FR-2024-02-21.xml"}},
+            []string{`"id":"US:FR:2024-03412"`, `"to_id":"US:CFR:T28:§600.11"`}},
+        {conf.SourceDescriptor{Name: "CA LegInfo", Codes: []string{"CIV"}, URLs: []string{srv.URL + "/pubinfo.zip"}},
+            []string{`"id":"CA:CIV:D4:PT1:T02:CH02:Art3:§3342"`}},
+        {conf.SourceDescriptor{Name: "CourtListener (opinions/RECAP)", URLs: []string{
+            srv.URL + "/courtlistener/courts-2024-12-31.csv", srv.URL + "/courtlistener/dockets-2024-12-31.csv",
+            srv.URL + "/courtlistener/opinion-clusters-2024-12-31.csv", srv.URL + "/courtlistener/opinions-2024-12-31.csv"}},
+            []string{`"labels":["OPINION"]`}},
+    }
+    for _, c := range cases {
+        rep, out := run(t, srv, c.desc)
+        if rep.Added == 0 || rep.Artifacts != len(c.desc.URLs) { t.Errorf("%s: report = %+v", c.desc.Name, rep) }
+        for _, w := range c.want {
+            if !strings.Contains(out, w) { t.Errorf("%s: output lacks %s", c.desc.Name, w) }
+        }
+        if strings.Contains(out, `"id":"CA:PEN`) { t.Errorf("%s: descriptor codes not applied", c.desc.Name) }
+    }
+}
+
+func TestRegistryCoversImporters(t *testing.T) {
+    names := strings.Join(Registry().Names(), "|")
+    for _, n := range []string{"US Code (OLRC)", "GovInfo CFR", "eCFR", "Federal Register", "CA LegInfo", "CourtListener (opinions/RECAP)"} {
+        if !strings.Contains(names, n) { t.Errorf("missing %s", n) }
+    }
+}
@@ -0,0 +1,77 @@
+package etl
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// State is what a source remembers between runs: when it last fetched, what each
+// URL returned (for conditional requests and to skip unchanged downloads) and the
+// hashes of the node versions and edges it emitted (to emit only what changed).
+type State struct {
+    Source      string                   `json:"source"`
+    LastFetched string                   `json:"last_fetched,omitempty"`
+    LastRun     string                   `json:"last_run,omitempty"`
+    Artifacts   map[string]ArtifactState `json:"artifacts,omitempty"` // by URL
+    Nodes       map[string][]string      `json:"nodes,omitempty"`     // node ID → version hashes
+    Edges       map[string]string        `json:"edges,omitempty"`     // edge ID → content hash
+}
+
+// ArtifactState is the last response seen for a URL.
+type ArtifactState struct {
+    Hash         string `json:"hash"`
+    ETag         string `json:"etag,omitempty"`
+    LastModified string `json:"last_modified,omitempty"`
+    FetchedAt    string `json:"fetched_at,omitempty"`
+}
+
+// LoadState reads a source's state from dir; a missing file is an empty state.
+func LoadState(dir, source string) (*State, error) {
+    st := &State{Source: source, Artifacts: map[string]ArtifactState{}, Nodes: map[string][]string{}, Edges: map[string]string{}}
+    b, err := os.ReadFile(statePath(dir, source))
+    if errors.Is(err, os.ErrNotExist) { return st, nil }
+    if err != nil { return nil, err }
+    if err := json.Unmarshal(b, st); err != nil { return nil, fmt.Errorf("state %s: %w", source, err) }
+    if st.Artifacts == nil { st.Artifacts = map[string]ArtifactState{} }
+    if st.Nodes == nil { st.Nodes = map[string][]string{} }
+    if st.Edges == nil { st.Edges = map[string]string{} }
+    return st, nil
+}
+
+// Save writes the state to dir atomically.
+func (st *State) Save(dir string) error {
+    if err := os.MkdirAll(dir, 0o755); err != nil { return err }
+    b, err := json.MarshalIndent(st, "", "  ")
+    if err != nil { return err }
+    path := statePath(dir, st.Source)
+    if err := os.WriteFile(path+".tmp", b, 0o644); err != nil { return err }
+    return os.Rename(path+".tmp", path)
+}
+
+// HasVersion reports whether the node version was emitted by an earlier run.
+func (st *State) HasVersion(id, hash string) bool {
+    for _, h := range st.Nodes[id] {
+        if h == hash { return true }
+    }
+    return false
+}
+
+// statePath derives a file name from the descriptor name: "GovInfo CFR" → govinfo-cfr.json.
+func statePath(dir, source string) string {
+    var b strings.Builder
+    dash := false
+    for _, r := range strings.ToLower(source) {
+        if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+            b.WriteRune(r)
+            dash = false
+        } else if !dash && b.Len() > 0 {
+            b.WriteByte('-')
+            dash = true
+        }
+    }
+    return filepath.Join(dir, strings.TrimSuffix(b.String(), "-")+".json")
+}
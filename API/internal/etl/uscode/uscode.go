@@ -0,0 +1,257 @@
+// Package uscode imports USLM XML title files from the OLRC bulk download
+// (https://uscode.house.gov/download/download.shtml) into graph nodes and edges.
+//
+// USLM levels map onto the canonical ladder CODE → TITLE → CHAPTER → SECTION:
+// levels above the first chapter (subtitle, part in Title 18) become TITLE,
+// chapter and everything below it down to the section (subchapter, part in
+// Title 42) become CHAPTER. Native level numbers are kept in props
+// (subtitle_num, part_num, subchapter_num, ...) and inherited by descendants.
+//
+// IDs follow the canonical scheme with USC numbering scopes: chapters and
+// sections are unique within a title, so they hang off the title ID
+// (US:USC:T18:CH44, US:USC:T18:§924) while subtitles, parts and subchapters
+// extend their parent's ID (US:USC:T18:PTI, US:USC:T42:CH7:SCHII).
+package uscode
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/etl"
+    "lawmap/internal/pkg/parse"
+)
+
+const (
+    // CodeID is the canonical ID of the United States Code.
+    CodeID = "US:USC"
+    // SourceName matches the OLRC source descriptor.
+    SourceName = "US Code (OLRC)"
+    // DefaultSourceURL is recorded in sources[] when Options.SourceURL is empty.
+    DefaultSourceURL = "https://uscode.house.gov/download/download.shtml"
+)
+
+// level describes one USLM hierarchy element.
+type level struct {
+    seg  string // ID segment prefix
+    prop string // props key for the level number
+    name string // display name
+    cite string // citation abbreviation
+}
+
+var levels = map[string]level{
+    "title":       {"T", "title_num", "Title", ""},
+    "subtitle":    {"ST", "subtitle_num", "Subtitle", "subtit."},
+    "part":        {"PT", "part_num", "Part", "pt."},
+    "subpart":     {"SPT", "subpart_num", "Subpart", "subpt."},
+    "division":    {"D", "division_num", "Division", "div."},
+    "subdivision": {"SD", "subdivision_num", "Subdivision", "subdiv."},
+    "chapter":     {"CH", "chapter_num", "Chapter", "ch."},
+    "subchapter":  {"SCH", "subchapter_num", "Subchapter", "subch."},
+}
+
+// Section body levels; each starts a new line of text.
+var bodyLevels = map[string]bool{
+    "subsection": true, "paragraph": true, "subparagraph": true, "clause": true,
+    "subclause": true, "item": true, "subitem": true, "subsubitem": true,
+}
+
+// Options control provenance and versioning of emitted nodes.
+type Options struct {
+    // FetchedAt is recorded as version.fetched_at and sources[].retrieved_at (RFC 3339); defaults to now.
+    FetchedAt string
+    // EffectiveDate (YYYY-MM-DD) defaults to the file's dcterms:created date.
+    EffectiveDate string
+    // SourceURL defaults to DefaultSourceURL.
+    SourceURL string
+}
+
+// Note is one editorial or statutory note attached to a node (props.notes).
+type Note struct {
+    Topic   string `json:"topic,omitempty"`
+    Heading string `json:"heading,omitempty"`
+    Text    string `json:"text"`
+}
+
+// Import reads USLM title files and returns the CODE node, its PARENT_OF edge from
+// the US jurisdiction, and every title subtree.
+func Import(paths []string, opts Options) (*etl.Result, error) {
+    res := &etl.Result{}
+    res.Nodes = append(res.Nodes, &dgraph.Node{ID: CodeID, Labels: []string{"CODE"}, Title: "United States Code",
+        Props: map[string]any{"jurisdiction": "US", "code": "USC"}})
+    res.Edges = append(res.Edges, etl.ParentOf("US", CodeID, 1))
+    for _, p := range paths {
+        f, err := os.Open(p)
+        if err != nil { return nil, err }
+        t, err := ParseTitle(f, opts)
+        f.Close()
+        if err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+        res.Append(t)
+    }
+    return res, nil
+}
+
+// ParseTitle converts one USLM document into the title's nodes and ordered PARENT_OF
+// edges, including the edge from the CODE node to the title.
+func ParseTitle(r io.Reader, opts Options) (*etl.Result, error) {
+    doc, err := parse.ParseXML(r)
+    if err != nil { return nil, err }
+    if opts.FetchedAt == "" { opts.FetchedAt = time.Now().UTC().Format(time.RFC3339) }
+    if opts.SourceURL == "" { opts.SourceURL = DefaultSourceURL }
+    if opts.EffectiveDate == "" {
+        if c := doc.Child("meta").Child("created").Text(); len(c) >= 10 { opts.EffectiveDate = c[:10] }
+    }
+    im := &importer{opts: opts, res: &etl.Result{}}
+    main := doc.Child("main")
+    if main == nil { main = doc }
+    found := false
+    for _, e := range main.Elements() {
+        if e.Name != "title" { continue }
+        found = true
+        im.level(e, CodeID, "", map[string]any{"jurisdiction": "US", "code": "USC"}, false, orderOf(numOf(e)))
+    }
+    if !found { return nil, fmt.Errorf("no <title> element in <%s>", main.Name) }
+    return im.res, nil
+}
+
+type importer struct {
+    opts    Options
+    res     *etl.Result
+    titleID string
+    title   string // title number as written, for citations
+}
+
+// level emits e and its descendants. inherited carries the ancestors' *_num props.
+func (im *importer) level(e *parse.Element, parentID, parentCite string, inherited map[string]any, inChapter bool, order int) {
+    lv := levels[e.Name]
+    num := numOf(e)
+    props := etl.CopyProps(inherited)
+    props[lv.prop] = etl.Numeric(num)
+    var id, label, cite string
+    switch e.Name {
+    case "title":
+        id = CodeID + ":T" + etl.Pad2(num)
+        im.titleID, im.title = id, num
+        label, cite = "TITLE", num+" USC"
+    case "chapter":
+        id = im.titleID + ":CH" + strings.ToUpper(num)
+        label, cite, inChapter = "CHAPTER", parentCite, true
+    default:
+        id = parentID + ":" + lv.seg + strings.ToUpper(num)
+        label, cite = "TITLE", parentCite
+        if inChapter { label = "CHAPTER" }
+    }
+    if lv.cite != "" { cite = im.title + " USC " + lv.cite + " " + num }
+    title := lv.name + " " + num
+    if h := e.Child("heading").Text(); h != "" { title += " - " + h }
+    n := &dgraph.Node{ID: id, Labels: []string{label}, Title: title, Citation: cite, Props: props}
+    im.finish(n, e, "")
+    im.res.Edges = append(im.res.Edges, etl.ParentOf(parentID, id, order))
+
+    childProps := etl.CopyProps(props)
+    idx := 0
+    for _, c := range e.Elements() {
+        if _, ok := levels[c.Name]; ok {
+            idx++
+            im.level(c, id, cite, childProps, inChapter, idx)
+        } else if c.Name == "section" {
+            idx++
+            im.section(c, id, childProps, idx)
+        }
+    }
+}
+
+func (im *importer) section(e *parse.Element, parentID string, inherited map[string]any, order int) {
+    num := numOf(e)
+    props := etl.CopyProps(inherited)
+    props["section_num"] = num
+    if s := e.Attr("status"); s != "" { props["status"] = s }
+    id := im.titleID + ":§" + num
+    title := "§ " + num + "."
+    if h := e.Child("heading").Text(); h != "" { title += " " + h }
+    var lines []string
+    bodyText(e, &lines, true)
+    n := &dgraph.Node{ID: id, Labels: []string{"SECTION"}, Title: title, Citation: im.title + " USC § " + num,
+        Text: strings.Join(lines, "\n"), Props: props}
+    im.finish(n, e, strings.Join(lines, "\n"))
+    im.res.Edges = append(im.res.Edges, etl.ParentOf(parentID, id, order))
+}
+
+// finish attaches identifier, source credit, notes, version and sources, then emits n.
+func (im *importer) finish(n *dgraph.Node, e *parse.Element, text string) {
+    if v := e.Attr("identifier"); v != "" { n.Props["identifier"] = v }
+    credit := e.Child("sourceCredit").Text()
+    if credit != "" { n.Props["source_credit"] = credit }
+    var notes []Note
+    for _, c := range e.Elements("notes", "note") {
+        list := []*parse.Element{c}
+        if c.Name == "notes" { list = c.Elements("note") }
+        for _, ne := range list {
+            var body []string
+            for _, p := range ne.Elements() {
+                if p.Name != "heading" && p.Name != "num" { if t := p.Text(); t != "" { body = append(body, t) } }
+            }
+            notes = append(notes, Note{Topic: ne.Attr("topic"), Heading: ne.Child("heading").Text(), Text: strings.Join(body, "\n")})
+        }
+    }
+    if len(notes) > 0 { n.Props["notes"] = notes }
+    n.Version = &dgraph.Version{FetchedAt: im.opts.FetchedAt, EffectiveDate: im.opts.EffectiveDate, Hash: etl.ContentHash(n.Title, text, credit)}
+    n.Sources = []dgraph.SourceMeta{{Name: SourceName, URL: im.opts.SourceURL, RetrievedAt: im.opts.FetchedAt}}
+    im.res.Nodes = append(im.res.Nodes, n)
+}
+
+// bodyText renders a section body as one line per block: "(a) Heading. text".
+// The section's own num and heading are skipped (they are in the node title), as are
+// source credits, notes and tables of contents.
+func bodyText(e *parse.Element, lines *[]string, top bool) {
+    var cur strings.Builder
+    flush := func() {
+        if s := parse.Collapse(cur.String()); s != "" { *lines = append(*lines, s) }
+        cur.Reset()
+    }
+    for _, k := range e.Kids {
+        switch x := k.(type) {
+        case string:
+            cur.WriteString(x)
+        case *parse.Element:
+            switch {
+            case x.Name == "sourceCredit" || x.Name == "notes" || x.Name == "note" || x.Name == "toc":
+            case (x.Name == "num" || x.Name == "heading") && top:
+            case x.Name == "num" || x.Name == "heading":
+                cur.WriteString(" " + x.Text() + " ")
+            case x.Name == "content" || x.Name == "chapeau":
+                // joins the num/heading of its level; each <p> inside starts a new line
+                ps := x.Elements("p")
+                if len(ps) == 0 { cur.WriteString(" " + x.Text() + " "); continue }
+                for _, p := range ps {
+                    cur.WriteString(" " + p.Text())
+                    flush()
+                }
+            case bodyLevels[x.Name] || x.Name == "continuation" || x.Name == "p":
+                flush()
+                bodyText(x, lines, false)
+            default:
+                cur.WriteString(x.Text())
+            }
+        }
+    }
+    flush()
+}
+
+func numOf(e *parse.Element) string {
+    n := e.Child("num")
+    if v := strings.TrimSpace(n.Attr("value")); v != "" { return v }
+    f := strings.Fields(n.Text())
+    if len(f) == 0 { return "" }
+    return strings.Trim(f[len(f)-1], "§.—-– ")
+}
+
+// orderOf uses a title's number as its order under the code, as the example graph does.
+func orderOf(num string) int {
+    n, _ := strconv.Atoi(strings.TrimRight(num, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+    return n
+}
@@ -0,0 +1,64 @@
+package uscode
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+var fixtures = []string{"../../test/fixtures/uscode/usc18_excerpt.xml", "../../test/fixtures/uscode/usc42_excerpt.xml"}
+
+func TestImportLadderAndProps(t *testing.T) {
+    res, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    byID := map[string]*dgraph.Node{}
+    for _, n := range res.Nodes { byID[n.ID] = n }
+    labels := map[string]string{
+        "US:USC": "CODE", "US:USC:T18": "TITLE", "US:USC:T18:PTI": "TITLE", "US:USC:T18:CH44": "CHAPTER",
+        "US:USC:T18:§924": "SECTION", "US:USC:T42:CH7:SCHII": "CHAPTER", "US:USC:T42:CH7:SCHII:PTA": "CHAPTER", "US:USC:T42:§401": "SECTION",
+    }
+    for id, want := range labels {
+        n := byID[id]
+        if n == nil { t.Fatalf("missing %s", id); continue }
+        if n.Labels[0] != want { t.Errorf("%s label = %s, want %s", id, n.Labels[0], want) }
+    }
+    s := byID["US:USC:T18:§924"]
+    if s.Citation != "18 USC § 924" || s.Title != "§ 924. Penalties" { t.Errorf("section = %q / %q", s.Citation, s.Title) }
+    if s.Props["title_num"] != float64(18) || s.Props["chapter_num"] != float64(44) || s.Props["part_num"] != "I" || s.Props["section_num"] != "924" {
+        t.Errorf("inherited props = %v", s.Props)
+    }
+    if !strings.HasPrefix(s.Text, "(a) Except as otherwise provided in this subsection—\n(1) whoever") || !strings.Contains(s.Text, "violates section 922(g) and has three previous convictions & more") {
+        t.Errorf("text = %q", s.Text)
+    }
+    if !strings.HasPrefix(s.Props["source_credit"].(string), "(Added Pub. L. 90–351") { t.Errorf("source_credit = %v", s.Props["source_credit"]) }
+    if notes, _ := s.Props["notes"].([]Note); len(notes) != 1 || notes[0].Topic != "amendments" || !strings.HasPrefix(notes[0].Text, "2022—Subsec.") {
+        t.Errorf("notes = %v", s.Props["notes"])
+    }
+    if v := s.Version; v == nil || v.EffectiveDate != "2024-03-15" || !strings.HasPrefix(v.Hash, "sha256:") { t.Errorf("version = %+v", v) }
+    if byID["US:USC:T18:§1029"].Props["status"] != "repealed" { t.Errorf("status not kept") }
+    if !strings.Contains(byID["US:USC:T18:§1028A"].Text, "another person\nshall be sentenced") { t.Errorf("paragraphs not split: %q", byID["US:USC:T18:§1028A"].Text) }
+}
+
+func TestImportJSONLLoads(t *testing.T) {
+    res, err := Import(fixtures, Options{FetchedAt: "2025-01-01T00:00:00Z"})
+    if err != nil { t.Fatal(err) }
+    var buf bytes.Buffer
+    if err := res.WriteJSONL(&buf); err != nil { t.Fatal(err) }
+    path := filepath.Join(t.TempDir(), "usc.jsonl")
+    if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(path); err != nil { t.Fatal(err) }
+    kids, _ := store.GetChildren("US:USC:T18:CH47")
+    if len(kids) != 2 || kids[0].ID != "US:USC:T18:§1028A" || kids[1].ID != "US:USC:T18:§1029" { t.Fatalf("children = %v", kids) }
+    if path, _ := store.GetParentsPath("US:USC:T42:§401"); strings.Join(path, " > ") != "US > US:USC > US:USC:T42 > US:USC:T42:CH7 > US:USC:T42:CH7:SCHII > US:USC:T42:CH7:SCHII:PTA > US:USC:T42:§401" {
+        t.Fatalf("breadcrumb = %v", path)
+    }
+    // hashes are stable across runs so unchanged sections can be skipped
+    again, _ := Import(fixtures, Options{FetchedAt: "2025-02-01T00:00:00Z"})
+    if again.Nodes[4].Version.Hash != res.Nodes[4].Version.Hash { t.Errorf("hash changed with fetch time") }
+}
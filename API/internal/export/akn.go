@@ -0,0 +1,328 @@
+package export
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Akoma Ntoso (OASIS LegalDocML 3.0) output for statutory subtrees.
+const nsAKN = "http://docs.oasis-open.org/legaldocml/ns/akn/3.0"
+
+// AkomaNtosoContentType is the media type written by WriteAkomaNtoso.
+const AkomaNtosoContentType = "application/akn+xml; charset=utf-8"
+
+// aknLayers are the hierarchy elements, keyed by the native layer number prop, with
+// the eId prefix from the AKN naming convention.
+var aknLayers = []struct{ key, elem, abbr string }{
+    {"division_num", "division", "dvs"},
+    {"subdivision_num", "subdivision", "subdvs"},
+    {"title_num", "title", "title"},
+    {"subtitle_num", "subtitle", "subtitle"},
+    {"part_num", "part", "part"},
+    {"subpart_num", "subpart", "subpart"},
+    {"chapter_num", "chapter", "chp"},
+    {"subchapter_num", "subchapter", "subchp"},
+    {"article_num", "article", "art"},
+    {"section_num", "section", "sec"},
+}
+
+func aknLayerIndex(elem string) int {
+    for i, l := range aknLayers {
+        if l.elem == elem { return i }
+    }
+    return -1
+}
+
+// aknNode is the element chosen for one node of the subtree.
+type aknNode struct {
+    elem, name, num, eID string
+    kids                 []string
+}
+
+// aknLayerOf picks the hierarchy element for n. An explicit props.layer wins; otherwise
+// the native *_num prop that n adds over its parent decides, preferring the one matching
+// n's label, so a CA Division normalized to TITLE still becomes <division>. Nodes with
+// no recognizable layer become <hcontainer name="label">.
+func aknLayerOf(n, parent *dgraph.Node) aknNode {
+    if l, ok := n.Props["layer"].(string); ok {
+        if i := aknLayerIndex(strings.ToLower(l)); i >= 0 { return aknNode{elem: aknLayers[i].elem, num: propString(n.Props[aknLayers[i].key])} }
+    }
+    label := ""
+    if len(n.Labels) > 0 { label = strings.ToLower(n.Labels[0]) }
+    li := aknLayerIndex(label)
+    pick := -1
+    for i, l := range aknLayers {
+        v, ok := n.Props[l.key]
+        if !ok { continue }
+        if parent != nil {
+            if pv, ok := parent.Props[l.key]; ok && propString(pv) == propString(v) { continue }
+        }
+        pick = i
+        if i == li { break }
+    }
+    if pick < 0 { pick = li }
+    if pick < 0 { return aknNode{elem: "hcontainer", name: label} }
+    return aknNode{elem: aknLayers[pick].elem, num: propString(n.Props[aknLayers[pick].key])}
+}
+
+func propString(v any) string {
+    switch x := v.(type) {
+    case nil:
+        return ""
+    case string:
+        return x
+    case float64:
+        return formatValue(x, valueType(x))
+    }
+    return fmt.Sprint(v)
+}
+
+// eidSafe keeps letters, digits and dots and turns everything else into single dashes.
+func eidSafe(s string) string {
+    var b strings.Builder
+    dash := false
+    for _, r := range s {
+        if r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+            b.WriteRune(r)
+            dash = false
+        } else if !dash && b.Len() > 0 {
+            b.WriteByte('-')
+            dash = true
+        }
+    }
+    return strings.TrimSuffix(b.String(), "-")
+}
+
+// aknHeading drops a leading "Section 3342." style prefix that <num> already carries.
+func aknHeading(title, elem, num string) string {
+    if num == "" { return title }
+    for _, p := range []string{elem + " " + num, "§" + num, "§ " + num} {
+        if len(title) >= len(p) && strings.EqualFold(title[:len(p)], p) { return strings.TrimLeft(title[len(p):], " .:-–—") }
+    }
+    return title
+}
+
+func edgeOrder(e *dgraph.Edge) int {
+    if v, ok := e.Props["order"].(float64); ok { return int(v) }
+    return 0
+}
+
+// WriteAkomaNtoso renders the subtree under root as an Akoma Ntoso <act>. nodes and
+// edges are the subtree with its PARENT_OF edges and the CITES edges leaving it
+// (see MemoryStore.Subtree); cited nodes outside the subtree only label references.
+// The root must be a CODE, TITLE or CHAPTER.
+func WriteAkomaNtoso(w io.Writer, root string, nodes []*dgraph.Node, edges []*dgraph.Edge, opts Options) error {
+    base := opts.BaseURL
+    if base == "" { base = "http://localhost:8080" }
+    byID := make(map[string]*dgraph.Node, len(nodes))
+    for _, n := range nodes { byID[n.ID] = n }
+    rn, ok := byID[root]
+    if !ok { return fmt.Errorf("root %q not found", root) }
+    if !hasAnyLabel(rn, "CODE", "TITLE", "CHAPTER") { return fmt.Errorf("akoma ntoso export needs a CODE, TITLE or CHAPTER root, got %q", root) }
+
+    kids := map[string][]*dgraph.Edge{}
+    cites := map[string][]*dgraph.Edge{}
+    for _, e := range edges {
+        switch e.EdgeType {
+        case "PARENT_OF":
+            kids[e.FromID] = append(kids[e.FromID], e)
+        case "CITES":
+            cites[e.FromID] = append(cites[e.FromID], e)
+        }
+    }
+    for _, es := range kids { sort.SliceStable(es, func(i, j int) bool { return edgeOrder(es[i]) < edgeOrder(es[j]) }) }
+
+    // Assign elements and eIds in document order. A CODE root is the act itself and
+    // gets no eId; its children are top-level body elements.
+    doc := map[string]*aknNode{}
+    var order []string
+    used := map[string]int{}
+    var assign func(id, parentEID string, parent *dgraph.Node)
+    assign = func(id, parentEID string, parent *dgraph.Node) {
+        n, ok := byID[id]
+        if _, seen := doc[id]; !ok || seen { return }
+        an := aknLayerOf(n, parent)
+        if id == root && hasAnyLabel(n, "CODE") {
+            an = aknNode{elem: "act"}
+        } else {
+            abbr := an.name
+            if i := aknLayerIndex(an.elem); i >= 0 { abbr = aknLayers[i].abbr }
+            part := eidSafe(an.num)
+            if part == "" { part = eidSafe(id[strings.LastIndex(id, ":")+1:]) }
+            an.eID = abbr + "_" + part
+            if parentEID != "" { an.eID = parentEID + "__" + an.eID }
+            if used[an.eID]++; used[an.eID] > 1 { an.eID += fmt.Sprintf("-%d", used[an.eID]) }
+        }
+        doc[id] = &an
+        order = append(order, id)
+        for _, e := range kids[id] {
+            if _, seen := doc[e.ToID]; !seen && byID[e.ToID] != nil {
+                an.kids = append(an.kids, e.ToID)
+                assign(e.ToID, an.eID, n)
+            }
+        }
+    }
+    assign(root, "", nil)
+
+    // FRBR dates: the latest effective date and fetch in the subtree.
+    var eff, fetched string
+    var sources []dgraph.SourceMeta
+    seenSrc := map[string]bool{}
+    for _, id := range order {
+        n := byID[id]
+        if v := n.Version; v != nil {
+            if v.EffectiveDate > eff { eff = v.EffectiveDate }
+            if len(v.FetchedAt) >= 10 && v.FetchedAt[:10] > fetched { fetched = v.FetchedAt[:10] }
+        }
+        for _, s := range n.Sources {
+            key := s.URL + "|" + s.Name
+            if !seenSrc[key] { seenSrc[key] = true; sources = append(sources, s) }
+        }
+    }
+    if eff == "" { eff = fetched }
+    if eff == "" { eff = time.Now().UTC().Format("2006-01-02") }
+    if fetched == "" { fetched = eff }
+    country := "us"
+    if j := strings.ToLower(propString(rn.Props["jurisdiction"])); j != "" && j != "us" { country = "us-" + j }
+    code := strings.ToLower(eidSafe(propString(rn.Props["code"])))
+    if code == "" { code = strings.ToLower(eidSafe(root)) }
+    work := "/akn/" + country + "/act/code/" + code
+    expr := work + "/eng@" + eff
+    component := "/main"
+    if e := doc[root].eID; e != "" { component += "~" + e }
+    srcIDs := make([]string, len(sources))
+    for i, s := range sources {
+        srcIDs[i] = "src_" + strings.ToLower(eidSafe(s.Name))
+        if s.Name == "" { srcIDs[i] = fmt.Sprintf("src_%d", i+1) }
+    }
+    author := "#lawmap"
+    if len(srcIDs) > 0 { author = "#" + srcIDs[0] }
+
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<akomaNtoso xmlns=\"%s\" xmlns:lawmap=\"%s\">\n", nsAKN, esc(vocab(base)))
+    fmt.Fprintf(bw, "  <act name=\"%s\">\n    <meta>\n      <identification source=\"#lawmap\">\n", esc(code))
+    fmt.Fprintf(bw, "        <FRBRWork>\n          <FRBRthis value=\"%s\"/>\n          <FRBRuri value=\"%s\"/>\n", esc(work+component), esc(work))
+    fmt.Fprintf(bw, "          <FRBRdate date=\"%s\" name=\"effective\"/>\n          <FRBRauthor href=\"%s\"/>\n          <FRBRcountry value=\"%s\"/>\n        </FRBRWork>\n", esc(eff), esc(author), esc(country))
+    fmt.Fprintf(bw, "        <FRBRExpression>\n          <FRBRthis value=\"%s\"/>\n          <FRBRuri value=\"%s\"/>\n", esc(expr+component), esc(expr))
+    fmt.Fprintf(bw, "          <FRBRdate date=\"%s\" name=\"effective\"/>\n          <FRBRauthor href=\"%s\"/>\n          <FRBRlanguage language=\"eng\"/>\n        </FRBRExpression>\n", esc(eff), esc(author))
+    fmt.Fprintf(bw, "        <FRBRManifestation>\n          <FRBRthis value=\"%s\"/>\n          <FRBRuri value=\"%s\"/>\n", esc(expr+component+".xml"), esc(expr+".akn"))
+    fmt.Fprintf(bw, "          <FRBRdate date=\"%s\" name=\"fetched\"/>\n          <FRBRauthor href=\"#lawmap\"/>\n        </FRBRManifestation>\n      </identification>\n", esc(fetched))
+    fmt.Fprintf(bw, "      <references source=\"#lawmap\">\n        <TLCOrganization eId=\"lawmap\" href=\"%s\" showAs=\"LawMap\"/>\n", esc(strings.TrimSuffix(base, "/")))
+    for i, s := range sources {
+        href := s.URL
+        if href == "" { href = "/" + srcIDs[i] }
+        fmt.Fprintf(bw, "        <TLCOrganization eId=\"%s\" href=\"%s\" showAs=\"%s\"/>\n", esc(srcIDs[i]), esc(href), esc(s.Name))
+    }
+    bw.WriteString("      </references>\n      <proprietary source=\"#lawmap\">\n")
+    for _, id := range order {
+        n, an := byID[id], doc[id]
+        bw.WriteString("        <lawmap:node")
+        if an.eID != "" { fmt.Fprintf(bw, " eId=\"%s\"", esc(an.eID)) }
+        fmt.Fprintf(bw, " id=\"%s\"", esc(id))
+        if v := n.Version; v != nil {
+            if v.EffectiveDate != "" { fmt.Fprintf(bw, " effective_date=\"%s\"", esc(v.EffectiveDate)) }
+            if v.FetchedAt != "" { fmt.Fprintf(bw, " fetched_at=\"%s\"", esc(v.FetchedAt)) }
+            if v.Hash != "" { fmt.Fprintf(bw, " hash=\"%s\"", esc(v.Hash)) }
+        }
+        bw.WriteString("/>\n")
+    }
+    bw.WriteString("      </proprietary>\n    </meta>\n")
+    if rn.Title != "" { fmt.Fprintf(bw, "    <preface>\n      <p><docTitle>%s</docTitle></p>\n    </preface>\n", esc(rn.Title)) }
+    bw.WriteString("    <body>\n")
+
+    ref := func(e *dgraph.Edge) (href, text string) {
+        href = NodeURI(base, e.ToID)
+        if an, ok := doc[e.ToID]; ok && an.eID != "" { href = "#" + an.eID }
+        text, _ = e.Props["pin_cite"].(string)
+        if t := byID[e.ToID]; text == "" && t != nil {
+            text = t.Citation
+            if text == "" { text = t.Title }
+        }
+        if text == "" { text = e.ToID }
+        return href, text
+    }
+    // blocks writes n's text as <p> paragraphs, wrapping the first mention of each cited
+    // provision in <ref>; citations not found in the text get a trailing paragraph.
+    blocks := func(n *dgraph.Node, ind string) bool {
+        type seg struct{ text, href string }
+        var paras [][]seg
+        for _, line := range strings.Split(n.Text, "\n") {
+            if line = strings.TrimSpace(line); line != "" { paras = append(paras, []seg{{text: line}}) }
+        }
+        var rest []seg
+        for _, e := range cites[n.ID] {
+            href, text := ref(e)
+            placed := false
+            for pi := 0; pi < len(paras) && !placed; pi++ {
+                for si, s := range paras[pi] {
+                    k := strings.Index(s.text, text)
+                    if s.href != "" || k < 0 { continue }
+                    split := []seg{{text: s.text[:k]}, {text: text, href: href}, {text: s.text[k+len(text):]}}
+                    paras[pi] = append(paras[pi][:si], append(split, paras[pi][si+1:]...)...)
+                    placed = true
+                    break
+                }
+            }
+            if !placed {
+                if len(rest) > 0 { rest = append(rest, seg{text: "; "}) }
+                rest = append(rest, seg{text: text, href: href})
+            }
+        }
+        if len(rest) > 0 { paras = append(paras, rest) }
+        for _, p := range paras {
+            bw.WriteString(ind + "<p>")
+            for _, s := range p {
+                if s.href != "" { fmt.Fprintf(bw, "<ref href=\"%s\">%s</ref>", esc(s.href), esc(s.text)) } else { bw.WriteString(esc(s.text)) }
+            }
+            bw.WriteString("</p>\n")
+        }
+        return len(paras) > 0
+    }
+    var write func(id string, depth int)
+    write = func(id string, depth int) {
+        n, an := byID[id], doc[id]
+        ind := strings.Repeat("  ", depth)
+        if an.elem == "hcontainer" {
+            fmt.Fprintf(bw, "%s<hcontainer name=\"%s\" eId=\"%s\">\n", ind, esc(an.name), esc(an.eID))
+        } else {
+            fmt.Fprintf(bw, "%s<%s eId=\"%s\">\n", ind, an.elem, esc(an.eID))
+        }
+        if an.num != "" { fmt.Fprintf(bw, "%s  <num>%s</num>\n", ind, esc(an.num)) }
+        if h := aknHeading(n.Title, an.elem, an.num); h != "" { fmt.Fprintf(bw, "%s  <heading>%s</heading>\n", ind, esc(h)) }
+        if len(an.kids) == 0 {
+            bw.WriteString(ind + "  <content>\n")
+            if !blocks(n, ind+"    ") { bw.WriteString(ind + "    <p/>\n") }
+            bw.WriteString(ind + "  </content>\n")
+        } else {
+            if n.Text != "" || len(cites[id]) > 0 {
+                bw.WriteString(ind + "  <intro>\n")
+                blocks(n, ind+"    ")
+                bw.WriteString(ind + "  </intro>\n")
+            }
+            for _, k := range an.kids { write(k, depth+1) }
+        }
+        fmt.Fprintf(bw, "%s</%s>\n", ind, an.elem)
+    }
+    if doc[root].elem == "act" {
+        for _, k := range doc[root].kids { write(k, 3) }
+    } else {
+        write(root, 3)
+    }
+    bw.WriteString("    </body>\n  </act>\n</akomaNtoso>\n")
+    return bw.Flush()
+}
+
+func hasAnyLabel(n *dgraph.Node, labels ...string) bool {
+    for _, l := range n.Labels {
+        for _, want := range labels {
+            if l == want { return true }
+        }
+    }
+    return false
+}
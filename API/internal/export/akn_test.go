@@ -0,0 +1,59 @@
+package export
+
+import (
+    "bytes"
+    "encoding/xml"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestAkomaNtosoHierarchy(t *testing.T) {
+    nodes := []*dgraph.Node{
+        {ID: "CA:FAM", Labels: []string{"CODE"}, Title: "Family Code", Props: map[string]any{"jurisdiction": "CA", "code": "FAM"},
+            Sources: []dgraph.SourceMeta{{Name: "LegInfo", URL: "https://leginfo.legislature.ca.gov/"}}},
+        {ID: "CA:FAM:D10", Labels: []string{"TITLE"}, Title: "Division 10. Prevention of Domestic Violence", Props: map[string]any{"jurisdiction": "CA", "code": "FAM", "division_num": "10"}},
+        {ID: "CA:FAM:D10:A1", Labels: []string{"CHAPTER"}, Title: "Article 1. General", Props: map[string]any{"jurisdiction": "CA", "code": "FAM", "division_num": "10", "article_num": float64(1)}},
+        {ID: "CA:FAM:D10:A1:§6200", Labels: []string{"SECTION"}, Title: "Section 6200. Short title", Text: "This division may be cited as the Domestic Violence Prevention Act.\nSee Section 6211.",
+            Props: map[string]any{"jurisdiction": "CA", "code": "FAM", "division_num": "10", "article_num": float64(1), "section_num": "6200"},
+            Version: &dgraph.Version{EffectiveDate: "2023-01-01", FetchedAt: "2025-02-03T00:00:00Z", Hash: "sha256:a"}},
+        {ID: "CA:FAM:D10:A1:§6211", Labels: []string{"SECTION"}, Title: "Section 6211. Definition", Text: "Domestic violence is abuse <perpetrated> against...",
+            Props: map[string]any{"jurisdiction": "CA", "code": "FAM", "division_num": "10", "article_num": float64(1), "section_num": "6211"},
+            Version: &dgraph.Version{EffectiveDate: "2024-07-01", Hash: "sha256:b"}},
+        {ID: "CA:PEN:§273.5", Labels: []string{"SECTION"}, Citation: "PEN § 273.5"},
+    }
+    edges := []*dgraph.Edge{
+        {EdgeType: "PARENT_OF", FromID: "CA:FAM", ToID: "CA:FAM:D10", Props: map[string]any{"order": float64(1)}},
+        {EdgeType: "PARENT_OF", FromID: "CA:FAM:D10", ToID: "CA:FAM:D10:A1", Props: map[string]any{"order": float64(1)}},
+        {EdgeType: "PARENT_OF", FromID: "CA:FAM:D10:A1", ToID: "CA:FAM:D10:A1:§6211", Props: map[string]any{"order": float64(2)}},
+        {EdgeType: "PARENT_OF", FromID: "CA:FAM:D10:A1", ToID: "CA:FAM:D10:A1:§6200", Props: map[string]any{"order": float64(1)}},
+        {EdgeType: "CITES", FromID: "CA:FAM:D10:A1:§6200", ToID: "CA:FAM:D10:A1:§6211", Props: map[string]any{"pin_cite": "Section 6211"}},
+        {EdgeType: "CITES", FromID: "CA:FAM:D10:A1:§6211", ToID: "CA:PEN:§273.5"},
+    }
+    var buf bytes.Buffer
+    if err := WriteAkomaNtoso(&buf, "CA:FAM", nodes, edges, Options{BaseURL: "https://lawmap.example"}); err != nil { t.Fatal(err) }
+    out := buf.String()
+    var doc struct{ XMLName xml.Name }
+    if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil || doc.XMLName.Local != "akomaNtoso" { t.Fatalf("invalid xml: %v\n%s", err, out) }
+    for _, want := range []string{
+        `<division eId="dvs_10">`,
+        `<heading>Prevention of Domestic Violence</heading>`,
+        `<article eId="dvs_10__art_1">`,
+        `<section eId="dvs_10__art_1__sec_6200">`,
+        `<p>See <ref href="#dvs_10__art_1__sec_6211">Section 6211</ref>.</p>`,
+        `<p><ref href="https://lawmap.example/nodes/CA:PEN:%C2%A7273.5">PEN § 273.5</ref></p>`,
+        `abuse &lt;perpetrated&gt;`,
+        `<FRBRdate date="2024-07-01" name="effective"/>`,
+        `<FRBRdate date="2025-02-03" name="fetched"/>`,
+        `<FRBRauthor href="#src_leginfo"/>`,
+        `<FRBRcountry value="us-ca"/>`,
+        `<lawmap:node eId="dvs_10__art_1__sec_6211" id="CA:FAM:D10:A1:§6211" effective_date="2024-07-01" hash="sha256:b"/>`,
+    } {
+        if !strings.Contains(out, want) { t.Errorf("missing %s", want) }
+    }
+    if strings.Index(out, "sec_6200\">") > strings.Index(out, "sec_6211\">") { t.Errorf("sections out of order") }
+    if strings.Contains(out, "CA:PEN:§273.5\"") { t.Errorf("cited node outside the subtree was rendered") }
+
+    if err := WriteAkomaNtoso(&buf, "CA:FAM:D10:A1:§6200", nodes, edges, Options{}); err == nil { t.Errorf("expected error for SECTION root") }
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+    "bufio"
+    "io"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// writeDOT emits a Graphviz digraph. DOT has no attribute types, so numeric and
+// boolean values are written unquoted and everything else as quoted strings.
+func writeDOT(w io.Writer, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    nattrs, nrows := nodeRows(nodes)
+    eattrs, erows := edgeRows(edges)
+    bw.WriteString("digraph lawmap {\n")
+    for i, n := range nodes {
+        label := n.Title
+        if label == "" { label = n.ID }
+        bw.WriteString("  " + dotQuote(n.ID) + " [label=" + dotQuote(label))
+        writeDOTAttrs(bw, nattrs, nrows[i])
+        bw.WriteString("];\n")
+    }
+    for i, e := range edges {
+        bw.WriteString("  " + dotQuote(e.FromID) + " -> " + dotQuote(e.ToID) + " [label=" + dotQuote(e.EdgeType))
+        writeDOTAttrs(bw, eattrs, erows[i])
+        bw.WriteString("];\n")
+    }
+    bw.WriteString("}\n")
+    return bw.Flush()
+}
+
+func writeDOTAttrs(bw *bufio.Writer, attrs []attr, r row) {
+    for _, a := range attrs {
+        v, ok := r[a.Name]
+        if !ok { continue }
+        if a.Type == "string" { v = dotQuote(v) }
+        bw.WriteString(", " + dotQuote(a.Name) + "=" + v)
+    }
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+    return `"` + dotEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,254 @@
+package export
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "mime"
+    "sort"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Format is a graph serialization supported by Write.
+type Format string
+
+const (
+    GraphML  Format = "graphml"
+    GEXF     Format = "gexf"
+    DOT      Format = "dot"
+    JSONLD   Format = "jsonld"
+    Turtle   Format = "turtle"
+    NTriples Format = "ntriples"
+)
+
+var contentTypes = map[Format]string{
+    GraphML:  "application/graphml+xml",
+    GEXF:     "application/gexf+xml",
+    DOT:      "text/vnd.graphviz",
+    JSONLD:   "application/ld+json",
+    Turtle:   "text/turtle",
+    NTriples: "application/n-triples",
+}
+
+// Options carries settings that only some formats use.
+type Options struct {
+    // BaseURL prefixes node IRIs in RDF output ({BaseURL}/nodes/{id}).
+    BaseURL string
+}
+
+// ParseFormat recognizes a format=... value. "ttl", "nt" and "json-ld" are accepted as aliases.
+func ParseFormat(s string) (Format, bool) {
+    f := Format(strings.ToLower(s))
+    switch f {
+    case "ttl":
+        f = Turtle
+    case "nt":
+        f = NTriples
+    case "json-ld":
+        f = JSONLD
+    }
+    _, ok := contentTypes[f]
+    return f, ok
+}
+
+// FromAccept picks the export format an Accept header prefers: the media range with
+// the highest q (the first on ties), if that is an export type. q=0 refuses a type,
+// and a client that ranks application/json or */* higher gets no export.
+func FromAccept(accept string) (Format, bool) {
+    var best Format
+    bestQ := 0.0
+    for _, part := range strings.Split(accept, ",") {
+        mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+        if err != nil { continue }
+        q := 1.0
+        if v, ok := params["q"]; ok {
+            if q, err = strconv.ParseFloat(v, 64); err != nil { continue }
+        }
+        if q <= bestQ { continue }
+        best, bestQ = "", q
+        for f, ct := range contentTypes {
+            if mt == ct { best = f }
+        }
+    }
+    return best, best != ""
+}
+
+// ContentType is the media type written for f.
+func (f Format) ContentType() string {
+    return contentTypes[f] + "; charset=utf-8"
+}
+
+// IsRDF reports whether f is a linked-data serialization.
+func (f Format) IsRDF() bool {
+    return f == JSONLD || f == Turtle || f == NTriples
+}
+
+// Write serializes nodes and edges to w.
+func Write(w io.Writer, f Format, nodes []*dgraph.Node, edges []*dgraph.Edge, opts Options) error {
+    base := opts.BaseURL
+    if base == "" { base = "http://localhost:8080" }
+    // a graph file cannot hold an edge without both ends; RDF can point outside itself
+    if f == GraphML || f == GEXF || f == DOT { edges = connected(nodes, edges) }
+    switch f {
+    case GraphML:
+        return writeGraphML(w, nodes, edges)
+    case GEXF:
+        return writeGEXF(w, nodes, edges)
+    case DOT:
+        return writeDOT(w, nodes, edges)
+    case JSONLD:
+        return writeJSONLD(w, base, nodes, edges)
+    case Turtle:
+        return writeTurtle(w, base, nodes, edges)
+    case NTriples:
+        return writeNTriples(w, base, nodes, edges)
+    }
+    return fmt.Errorf("unsupported export format %q", f)
+}
+
+// attr is a typed column shared by every node (or edge) in an export.
+type attr struct {
+    Name string
+    Type string // string|long|double|boolean
+}
+
+// row holds one element's attribute values already formatted for output.
+type row map[string]string
+
+// nodeRows flattens nodes into typed attribute columns. Fixed fields come first
+// (labels, title, citation, text, version.*), then props as "props.<key>".
+func nodeRows(nodes []*dgraph.Node) ([]attr, []row) {
+    fixed := []string{"labels", "title", "citation", "text", "version.fetched_at", "version.effective_date", "version.hash"}
+    props := make([]map[string]any, len(nodes))
+    rows := make([]row, len(nodes))
+    for i, n := range nodes {
+        r := row{}
+        if len(n.Labels) > 0 { r["labels"] = strings.Join(n.Labels, ";") }
+        if n.Title != "" { r["title"] = n.Title }
+        if n.Citation != "" { r["citation"] = n.Citation }
+        if n.Text != "" { r["text"] = n.Text }
+        if v := n.Version; v != nil {
+            if v.FetchedAt != "" { r["version.fetched_at"] = v.FetchedAt }
+            if v.EffectiveDate != "" { r["version.effective_date"] = v.EffectiveDate }
+            if v.Hash != "" { r["version.hash"] = v.Hash }
+        }
+        rows[i] = r
+        props[i] = n.Props
+    }
+    attrs := make([]attr, 0, len(fixed))
+    for _, name := range fixed { attrs = append(attrs, attr{Name: name, Type: "string"}) }
+    return append(attrs, propAttrs(props, rows)...), rows
+}
+
+// edgeRows flattens edges into a "type" column plus typed props columns.
+func edgeRows(edges []*dgraph.Edge) ([]attr, []row) {
+    props := make([]map[string]any, len(edges))
+    rows := make([]row, len(edges))
+    for i, e := range edges {
+        rows[i] = row{"type": e.EdgeType}
+        props[i] = e.Props
+    }
+    return append([]attr{{Name: "type", Type: "string"}}, propAttrs(props, rows)...), rows
+}
+
+// propAttrs infers one type per props key across all elements and writes the
+// formatted values into rows. Keys with mixed or structured values become strings.
+func propAttrs(props []map[string]any, rows []row) []attr {
+    types := map[string]string{}
+    for _, p := range props {
+        for k, v := range p {
+            t := valueType(v)
+            if prev, ok := types[k]; ok && prev != t {
+                if (prev == "long" && t == "double") || (prev == "double" && t == "long") { t = "double" } else { t = "string" }
+            }
+            types[k] = t
+        }
+    }
+    keys := make([]string, 0, len(types))
+    for k := range types { keys = append(keys, k) }
+    sort.Strings(keys)
+    attrs := make([]attr, 0, len(keys))
+    for _, k := range keys { attrs = append(attrs, attr{Name: "props." + k, Type: types[k]}) }
+    for i, p := range props {
+        for k, v := range p { rows[i]["props."+k] = formatValue(v, types[k]) }
+    }
+    return attrs
+}
+
+func valueType(v any) string {
+    switch x := v.(type) {
+    case bool:
+        return "boolean"
+    case float64:
+        if x == math.Trunc(x) && math.Abs(x) < 1<<53 { return "long" }
+        return "double"
+    case int, int64:
+        return "long"
+    case string:
+        return "string"
+    }
+    return "string"
+}
+
+func formatValue(v any, typ string) string {
+    switch x := v.(type) {
+    case string:
+        return x
+    case bool:
+        return strconv.FormatBool(x)
+    case float64:
+        if typ == "long" { return strconv.FormatInt(int64(x), 10) }
+        return strconv.FormatFloat(x, 'g', -1, 64)
+    case int:
+        return strconv.Itoa(x)
+    case int64:
+        return strconv.FormatInt(x, 10)
+    }
+    b, _ := json.Marshal(v)
+    return string(b)
+}
+
+// connected keeps the edges whose ends are both among nodes.
+func connected(nodes []*dgraph.Node, edges []*dgraph.Edge) []*dgraph.Edge {
+    in := make(map[string]struct{}, len(nodes))
+    for _, n := range nodes { in[n.ID] = struct{}{} }
+    out := make([]*dgraph.Edge, 0, len(edges))
+    for _, e := range edges {
+        _, from := in[e.FromID]
+        _, to := in[e.ToID]
+        if from && to { out = append(out, e) }
+    }
+    return out
+}
+
+// edgeIDs gives every edge a distinct ID: its own, or for an edge loaded without one
+// (or repeating an earlier one) "from->TYPE->to", suffixed "~2", "~3", ... until it
+// clashes with no other edge's ID.
+func edgeIDs(edges []*dgraph.Edge) []string {
+    own := make(map[string]struct{}, len(edges))
+    for _, e := range edges {
+        if e.ID != "" { own[e.ID] = struct{}{} }
+    }
+    taken := make(map[string]struct{}, len(edges))
+    ids := make([]string, len(edges))
+    for i, e := range edges {
+        id := e.ID
+        if _, dup := taken[id]; id == "" || dup {
+            base := e.FromID + "->" + e.EdgeType + "->" + e.ToID
+            id = base
+            for n := 2; ; n++ {
+                _, clash := own[id]
+                _, used := taken[id]
+                if !clash && !used { break }
+                id = base + "~" + strconv.Itoa(n)
+            }
+        }
+        taken[id] = struct{}{}
+        ids[i] = id
+    }
+    return ids
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+    "bytes"
+    "encoding/xml"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func sample() ([]*dgraph.Node, []*dgraph.Edge) {
+    nodes := []*dgraph.Node{
+        {ID: "CA:CIV:T02:CH02", Labels: []string{"CHAPTER"}, Title: "Chapter 2", Props: map[string]any{"jurisdiction": "CA", "chapter_num": float64(2)}},
+        {ID: "CA:CIV:T02:CH02:§3342", Labels: []string{"SECTION"}, Title: `Section "3342" & more`, Citation: "CIV § 3342",
+            Props: map[string]any{"jurisdiction": "CA", "section_num": "3342"}, Version: &dgraph.Version{Hash: "sha256:x"}},
+    }
+    edges := []*dgraph.Edge{
+        {ID: "e4", EdgeType: "PARENT_OF", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH02:§3342", Props: map[string]any{"order": float64(10)}},
+        {EdgeType: "CITES", FromID: "CA:CIV:T02:CH02:§3342", ToID: "CA:CIV:T02:CH02", Props: map[string]any{"pin_cite": "(b)", "weight": 0.5}},
+    }
+    return nodes, edges
+}
+
+func TestGraphMLTypedAttributes(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := Write(&buf, GraphML, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+    var doc struct {
+        Keys []struct{ ID string `xml:"id,attr"`; For string `xml:"for,attr"`; Name string `xml:"attr.name,attr"`; Type string `xml:"attr.type,attr"` } `xml:"key"`
+        Graph struct {
+            Nodes []struct{ ID string `xml:"id,attr"` } `xml:"node"`
+            Edges []struct{ ID string `xml:"id,attr"`; Source string `xml:"source,attr"` } `xml:"edge"`
+        } `xml:"graph"`
+    }
+    if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatalf("invalid xml: %v\n%s", err, buf.String()) }
+    types := map[string]string{}
+    for _, k := range doc.Keys { types[k.For+":"+k.Name] = k.Type }
+    want := map[string]string{"node:props.chapter_num": "long", "node:props.section_num": "string", "edge:props.order": "long", "edge:props.weight": "double", "edge:type": "string"}
+    for k, v := range want {
+        if types[k] != v { t.Fatalf("key %s type=%q want %q", k, types[k], v) }
+    }
+    if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 2 { t.Fatalf("unexpected counts") }
+    if want := "CA:CIV:T02:CH02:§3342->CITES->CA:CIV:T02:CH02"; doc.Graph.Edges[1].ID != want { t.Fatalf("expected synthetic id %q for unnamed edge, got %q", want, doc.Graph.Edges[1].ID) }
+}
+
+func TestEdgeIDsAreUnique(t *testing.T) {
+    edges := []*dgraph.Edge{
+        {EdgeType: "CITES", FromID: "a", ToID: "b"},
+        {ID: "a->CITES->b", EdgeType: "CITES", FromID: "x", ToID: "y"},
+        {EdgeType: "CITES", FromID: "a", ToID: "b"},
+        {ID: "e4", EdgeType: "CITES", FromID: "c", ToID: "d"},
+        {ID: "e4", EdgeType: "CITES", FromID: "c", ToID: "d"},
+    }
+    want := []string{"a->CITES->b~2", "a->CITES->b", "a->CITES->b~3", "e4", "c->CITES->d"}
+    got := edgeIDs(edges)
+    for i := range want {
+        if got[i] != want[i] { t.Errorf("edge %d: id %q, want %q", i, got[i], want[i]) }
+    }
+}
+
+func TestGraphFormatsDropDanglingEdges(t *testing.T) {
+    nodes, edges := sample()
+    edges = append(edges, &dgraph.Edge{ID: "c9", EdgeType: "CITES", FromID: "CA:CIV:T02:CH02:§3342", ToID: "US:USC:T18:§1028A"})
+    for _, f := range []Format{GraphML, GEXF, DOT} {
+        var buf bytes.Buffer
+        if err := Write(&buf, f, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+        if strings.Contains(buf.String(), "1028A") { t.Errorf("%s keeps an edge to a node it does not export:\n%s", f, buf.String()) }
+    }
+    var buf bytes.Buffer
+    if err := Write(&buf, NTriples, nodes, edges, Options{}); err != nil || !strings.Contains(buf.String(), "1028A") { t.Errorf("RDF may point outside the export: %v", err) }
+}
+
+func TestGEXFParses(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := Write(&buf, GEXF, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+    var doc struct {
+        Graph struct {
+            Nodes []struct{ Label string `xml:"label,attr"` } `xml:"nodes>node"`
+            Edges []struct{ Label string `xml:"label,attr"` } `xml:"edges>edge"`
+        } `xml:"graph"`
+    }
+    if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatalf("invalid xml: %v", err) }
+    if doc.Graph.Nodes[1].Label != `Section "3342" & more` || doc.Graph.Edges[0].Label != "PARENT_OF" { t.Fatalf("unexpected labels: %+v", doc.Graph) }
+}
+
+func TestDOT(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := Write(&buf, DOT, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+    out := buf.String()
+    for _, want := range []string{`digraph lawmap {`, `label="Section \"3342\" & more"`, `"CA:CIV:T02:CH02" -> "CA:CIV:T02:CH02:§3342" [label="PARENT_OF", "type"="PARENT_OF", "props.order"=10]`} {
+        if !strings.Contains(out, want) { t.Fatalf("dot missing %q:\n%s", want, out) }
+    }
+}
+
+func TestFromAccept(t *testing.T) {
+    if f, ok := FromAccept("application/json;q=0.5, text/vnd.graphviz"); !ok || f != DOT { t.Fatalf("expected dot, got %q", f) }
+    if _, ok := FromAccept("application/json"); ok { t.Fatalf("json is not an export format") }
+    cases := []struct {
+        accept string
+        want   Format
+    }{
+        {"application/graphml+xml;q=0", ""},
+        {"application/json, text/turtle;q=0.9", ""},
+        {"text/turtle;q=0.5, application/ld+json;q=0.8", JSONLD},
+        {"text/turtle, application/ld+json", Turtle},
+        {"text/html,application/xhtml+xml,*/*;q=0.8", ""},
+        {"application/n-triples, */*;q=0.1", NTriples},
+    }
+    for _, c := range cases {
+        if f, _ := FromAccept(c.accept); f != c.want { t.Errorf("%q: got %q, want %q", c.accept, f, c.want) }
+    }
+}
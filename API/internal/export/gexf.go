@@ -0,0 +1,58 @@
+package export
+
+import (
+    "bufio"
+    "encoding/xml"
+    "fmt"
+    "io"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// gexfType maps attribute types to GEXF 1.3 attribute types.
+var gexfType = map[string]string{"string": "string", "long": "long", "double": "double", "boolean": "boolean"}
+
+func writeGEXF(w io.Writer, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    nattrs, nrows := nodeRows(nodes)
+    eattrs, erows := edgeRows(edges)
+    bw.WriteString(xml.Header)
+    bw.WriteString("<gexf xmlns=\"http://gexf.net/1.3\" version=\"1.3\">\n")
+    bw.WriteString("  <graph defaultedgetype=\"directed\" mode=\"static\">\n")
+    writeGEXFAttributes(bw, "node", nattrs)
+    writeGEXFAttributes(bw, "edge", eattrs)
+    bw.WriteString("    <nodes>\n")
+    for i, n := range nodes {
+        label := n.Title
+        if label == "" { label = n.ID }
+        fmt.Fprintf(bw, "      <node id=\"%s\" label=\"%s\">\n", esc(n.ID), esc(label))
+        writeGEXFValues(bw, nattrs, nrows[i])
+        bw.WriteString("      </node>\n")
+    }
+    bw.WriteString("    </nodes>\n    <edges>\n")
+    ids := edgeIDs(edges)
+    for i, e := range edges {
+        fmt.Fprintf(bw, "      <edge id=\"%s\" source=\"%s\" target=\"%s\" label=\"%s\">\n", esc(ids[i]), esc(e.FromID), esc(e.ToID), esc(e.EdgeType))
+        writeGEXFValues(bw, eattrs, erows[i])
+        bw.WriteString("      </edge>\n")
+    }
+    bw.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+    return bw.Flush()
+}
+
+func writeGEXFAttributes(bw *bufio.Writer, class string, attrs []attr) {
+    fmt.Fprintf(bw, "    <attributes class=\"%s\">\n", class)
+    for i, a := range attrs {
+        fmt.Fprintf(bw, "      <attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, esc(a.Name), gexfType[a.Type])
+    }
+    bw.WriteString("    </attributes>\n")
+}
+
+func writeGEXFValues(bw *bufio.Writer, attrs []attr, r row) {
+    if len(r) == 0 { return }
+    bw.WriteString("        <attvalues>\n")
+    for i, a := range attrs {
+        if v, ok := r[a.Name]; ok { fmt.Fprintf(bw, "          <attvalue for=\"%d\" value=\"%s\"/>\n", i, esc(v)) }
+    }
+    bw.WriteString("        </attvalues>\n")
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+    "bufio"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// graphMLType maps attribute types to GraphML attr.type values.
+var graphMLType = map[string]string{"string": "string", "long": "long", "double": "double", "boolean": "boolean"}
+
+func writeGraphML(w io.Writer, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    nattrs, nrows := nodeRows(nodes)
+    eattrs, erows := edgeRows(edges)
+    bw.WriteString(xml.Header)
+    bw.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+    for i, a := range nattrs {
+        fmt.Fprintf(bw, "  <key id=\"n%d\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, esc(a.Name), graphMLType[a.Type])
+    }
+    for i, a := range eattrs {
+        fmt.Fprintf(bw, "  <key id=\"e%d\" for=\"edge\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, esc(a.Name), graphMLType[a.Type])
+    }
+    bw.WriteString("  <graph id=\"lawmap\" edgedefault=\"directed\">\n")
+    for i, n := range nodes {
+        fmt.Fprintf(bw, "    <node id=\"%s\">\n", esc(n.ID))
+        for k, a := range nattrs {
+            if v, ok := nrows[i][a.Name]; ok { fmt.Fprintf(bw, "      <data key=\"n%d\">%s</data>\n", k, esc(v)) }
+        }
+        bw.WriteString("    </node>\n")
+    }
+    ids := edgeIDs(edges)
+    for i, e := range edges {
+        fmt.Fprintf(bw, "    <edge id=\"%s\" source=\"%s\" target=\"%s\">\n", esc(ids[i]), esc(e.FromID), esc(e.ToID))
+        for k, a := range eattrs {
+            if v, ok := erows[i][a.Name]; ok { fmt.Fprintf(bw, "      <data key=\"e%d\">%s</data>\n", k, esc(v)) }
+        }
+        bw.WriteString("    </edge>\n")
+    }
+    bw.WriteString("  </graph>\n</graphml>\n")
+    return bw.Flush()
+}
+
+// esc escapes s for use in XML text and double-quoted attributes.
+func esc(s string) string {
+    var b strings.Builder
+    _ = xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
@@ -0,0 +1,325 @@
+package export
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Neo4j bulk-import layout: one nodes_<LABEL>.csv per primary label and one
+// rels_<TYPE>.csv per edge type, with neo4j-admin typed headers
+// (id:ID, :LABEL, :START_ID, :END_ID, :TYPE, name:long, ...). Load with
+//
+//    neo4j-admin database import full --multiline-fields=true \
+//        --nodes=nodes_SECTION.csv ... --relationships=rels_PARENT_OF.csv ...
+//
+// Props become top-level properties; a prop whose key clashes with a fixed
+// column is written as "props.<key>". Structured prop values are stored as JSON strings.
+
+// nodeColumns are the fixed node properties after id:ID and :LABEL.
+var nodeColumns = []string{"title", "citation", "text", "version_fetched_at", "version_effective_date", "version_hash", "sources"}
+
+var neo4jType = map[string]string{"string": "string", "long": "long", "double": "double", "boolean": "boolean"}
+
+// primaryLabel picks the file a node is written to.
+func primaryLabel(n *dgraph.Node) string {
+    if len(n.Labels) > 0 && n.Labels[0] != "" { return n.Labels[0] }
+    return "NODE"
+}
+
+// propColumn names a props key, prefixing keys that clash with fixed columns.
+func propColumn(key string, reserved []string) string {
+    for _, r := range reserved {
+        if key == r { return "props." + key }
+    }
+    return key
+}
+
+// WriteNeo4jCSV writes neo4j-admin import files into dir (created if needed) and
+// returns the file names written, nodes first.
+func WriteNeo4jCSV(dir string, nodes []*dgraph.Node, edges []*dgraph.Edge) ([]string, error) {
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    var files []string
+
+    byLabel := map[string][]*dgraph.Node{}
+    for _, n := range nodes { byLabel[primaryLabel(n)] = append(byLabel[primaryLabel(n)], n) }
+    for _, label := range sortedKeys(byLabel) {
+        group := byLabel[label]
+        props := make([]map[string]any, len(group))
+        rows := make([]row, len(group))
+        for i, n := range group { props[i], rows[i] = n.Props, row{} }
+        pattrs := propAttrs(props, rows)
+        header := []string{"id:ID", ":LABEL"}
+        header = append(header, nodeColumns...)
+        for _, a := range pattrs {
+            header = append(header, propColumn(strings.TrimPrefix(a.Name, "props."), append([]string{"id"}, nodeColumns...))+":"+neo4jType[a.Type])
+        }
+        records := make([][]string, 0, len(group))
+        for i, n := range group {
+            rec := []string{n.ID, strings.Join(n.Labels, ";"), n.Title, n.Citation, n.Text, "", "", "", ""}
+            if v := n.Version; v != nil { rec[5], rec[6], rec[7] = v.FetchedAt, v.EffectiveDate, v.Hash }
+            if len(n.Sources) > 0 {
+                b, err := json.Marshal(n.Sources)
+                if err != nil { return nil, err }
+                rec[8] = string(b)
+            }
+            for _, a := range pattrs { rec = append(rec, rows[i][a.Name]) }
+            records = append(records, rec)
+        }
+        name := "nodes_" + fileSafe(label) + ".csv"
+        if err := writeCSV(filepath.Join(dir, name), header, records); err != nil { return nil, err }
+        files = append(files, name)
+    }
+
+    byType := map[string][]*dgraph.Edge{}
+    for _, e := range edges { byType[e.EdgeType] = append(byType[e.EdgeType], e) }
+    for _, et := range sortedKeys(byType) {
+        group := byType[et]
+        props := make([]map[string]any, len(group))
+        rows := make([]row, len(group))
+        for i, e := range group { props[i], rows[i] = e.Props, row{} }
+        pattrs := propAttrs(props, rows)
+        header := []string{":START_ID", ":END_ID", ":TYPE", "id"}
+        for _, a := range pattrs {
+            header = append(header, propColumn(strings.TrimPrefix(a.Name, "props."), []string{"id"})+":"+neo4jType[a.Type])
+        }
+        records := make([][]string, 0, len(group))
+        for i, e := range group {
+            rec := []string{e.FromID, e.ToID, e.EdgeType, e.ID}
+            for _, a := range pattrs { rec = append(rec, rows[i][a.Name]) }
+            records = append(records, rec)
+        }
+        name := "rels_" + fileSafe(et) + ".csv"
+        if err := writeCSV(filepath.Join(dir, name), header, records); err != nil { return nil, err }
+        files = append(files, name)
+    }
+    return files, nil
+}
+
+// ReadNeo4jCSV loads the nodes_*.csv and rels_*.csv files written by WriteNeo4jCSV.
+func ReadNeo4jCSV(dir string) ([]*dgraph.Node, []*dgraph.Edge, error) {
+    nodeFiles, err := filepath.Glob(filepath.Join(dir, "nodes_*.csv"))
+    if err != nil { return nil, nil, err }
+    relFiles, err := filepath.Glob(filepath.Join(dir, "rels_*.csv"))
+    if err != nil { return nil, nil, err }
+    var nodes []*dgraph.Node
+    var edges []*dgraph.Edge
+    for _, f := range nodeFiles {
+        header, records, err := readCSV(f)
+        if err != nil { return nil, nil, err }
+        for _, rec := range records {
+            n := &dgraph.Node{}
+            var ver dgraph.Version
+            for i, col := range header {
+                name, typ := splitHeader(col)
+                val := rec[i]
+                switch {
+                case typ == "ID":
+                    n.ID = val
+                case typ == "LABEL":
+                    if val != "" { n.Labels = strings.Split(val, ";") }
+                case name == "title":
+                    n.Title = val
+                case name == "citation":
+                    n.Citation = val
+                case name == "text":
+                    n.Text = val
+                case name == "version_fetched_at":
+                    ver.FetchedAt = val
+                case name == "version_effective_date":
+                    ver.EffectiveDate = val
+                case name == "version_hash":
+                    ver.Hash = val
+                case name == "sources":
+                    if val != "" {
+                        if err := json.Unmarshal([]byte(val), &n.Sources); err != nil { return nil, nil, fmt.Errorf("%s: sources: %w", f, err) }
+                    }
+                default:
+                    if err := setProp(&n.Props, strings.TrimPrefix(name, "props."), typ, val); err != nil { return nil, nil, fmt.Errorf("%s: %w", f, err) }
+                }
+            }
+            if ver != (dgraph.Version{}) { n.Version = &ver }
+            nodes = append(nodes, n)
+        }
+    }
+    for _, f := range relFiles {
+        header, records, err := readCSV(f)
+        if err != nil { return nil, nil, err }
+        for _, rec := range records {
+            e := &dgraph.Edge{}
+            for i, col := range header {
+                name, typ := splitHeader(col)
+                val := rec[i]
+                switch {
+                case typ == "START_ID":
+                    e.FromID = val
+                case typ == "END_ID":
+                    e.ToID = val
+                case typ == "TYPE":
+                    e.EdgeType = val
+                case name == "id":
+                    e.ID = val
+                default:
+                    if err := setProp(&e.Props, strings.TrimPrefix(name, "props."), typ, val); err != nil { return nil, nil, fmt.Errorf("%s: %w", f, err) }
+                }
+            }
+            edges = append(edges, e)
+        }
+    }
+    return nodes, edges, nil
+}
+
+// WriteCypher writes an idempotent MERGE script for incremental loads. Nodes are
+// merged on id under their primary label (with a uniqueness constraint per label)
+// and edges on (start, type, end, id), or on (start, type, end) when the edge has no ID.
+func WriteCypher(w io.Writer, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    labelOf := map[string]string{}
+    labels := map[string]struct{}{}
+    for _, n := range nodes {
+        labelOf[n.ID] = primaryLabel(n)
+        labels[primaryLabel(n)] = struct{}{}
+    }
+    for _, l := range sortedKeys(labels) {
+        fmt.Fprintf(bw, "CREATE CONSTRAINT IF NOT EXISTS FOR (n:%s) REQUIRE n.id IS UNIQUE;\n", cypherName(l))
+    }
+    for _, n := range nodes {
+        set := map[string]any{"labels": n.Labels}
+        if n.Title != "" { set["title"] = n.Title }
+        if n.Citation != "" { set["citation"] = n.Citation }
+        if n.Text != "" { set["text"] = n.Text }
+        if v := n.Version; v != nil {
+            if v.FetchedAt != "" { set["version_fetched_at"] = v.FetchedAt }
+            if v.EffectiveDate != "" { set["version_effective_date"] = v.EffectiveDate }
+            if v.Hash != "" { set["version_hash"] = v.Hash }
+        }
+        if len(n.Sources) > 0 {
+            b, _ := json.Marshal(n.Sources)
+            set["sources"] = string(b)
+        }
+        reserved := append([]string{"id", "labels"}, nodeColumns...)
+        for k, v := range n.Props { set[propColumn(k, reserved)] = v }
+        fmt.Fprintf(bw, "MERGE (n:%s {id: %s}) SET n += %s", cypherName(labelOf[n.ID]), cypherValue(n.ID), cypherMap(set))
+        for _, l := range n.Labels[min(1, len(n.Labels)):] { fmt.Fprintf(bw, ", n:%s", cypherName(l)) }
+        bw.WriteString(";\n")
+    }
+    for _, e := range edges {
+        props := map[string]any{}
+        for k, v := range e.Props { props[propColumn(k, []string{"id"})] = v }
+        key := ""
+        if e.ID != "" { key = " {id: " + cypherValue(e.ID) + "}" }
+        fmt.Fprintf(bw, "MATCH (a%s {id: %s}), (b%s {id: %s}) MERGE (a)-[r:%s%s]->(b) SET r += %s;\n",
+            labelMatch(labelOf, e.FromID), cypherValue(e.FromID), labelMatch(labelOf, e.ToID), cypherValue(e.ToID),
+            cypherName(e.EdgeType), key, cypherMap(props))
+    }
+    return bw.Flush()
+}
+
+func labelMatch(labelOf map[string]string, id string) string {
+    if l, ok := labelOf[id]; ok { return ":" + cypherName(l) }
+    return ""
+}
+
+// cypherName backtick-quotes a label, relationship type or property key.
+func cypherName(s string) string {
+    return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// cypherValue renders a literal; JSON string escapes are valid Cypher escapes.
+func cypherValue(v any) string {
+    switch x := v.(type) {
+    case string:
+        var b strings.Builder
+        enc := json.NewEncoder(&b)
+        enc.SetEscapeHTML(false)
+        _ = enc.Encode(x)
+        return strings.TrimSuffix(b.String(), "\n")
+    case bool:
+        return strconv.FormatBool(x)
+    case float64:
+        if valueType(x) == "long" { return strconv.FormatInt(int64(x), 10) }
+        return strconv.FormatFloat(x, 'g', -1, 64)
+    case []string:
+        parts := make([]string, len(x))
+        for i, s := range x { parts[i] = cypherValue(s) }
+        return "[" + strings.Join(parts, ", ") + "]"
+    case nil:
+        return "null"
+    }
+    b, _ := json.Marshal(v)
+    return cypherValue(string(b))
+}
+
+func cypherMap(m map[string]any) string {
+    keys := sortedKeys(m)
+    parts := make([]string, len(keys))
+    for i, k := range keys { parts[i] = cypherName(k) + ": " + cypherValue(m[k]) }
+    return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func splitHeader(col string) (name, typ string) {
+    if i := strings.LastIndex(col, ":"); i >= 0 { return col[:i], col[i+1:] }
+    return col, ""
+}
+
+func setProp(props *map[string]any, key, typ, val string) error {
+    if val == "" { return nil }
+    var v any
+    switch typ {
+    case "long", "int", "double", "float":
+        f, err := strconv.ParseFloat(val, 64)
+        if err != nil { return fmt.Errorf("%s: %w", key, err) }
+        v = f
+    case "boolean":
+        b, err := strconv.ParseBool(val)
+        if err != nil { return fmt.Errorf("%s: %w", key, err) }
+        v = b
+    default:
+        v = val
+    }
+    if *props == nil { *props = map[string]any{} }
+    (*props)[key] = v
+    return nil
+}
+
+func writeCSV(path string, header []string, records [][]string) error {
+    f, err := os.Create(path)
+    if err != nil { return err }
+    cw := csv.NewWriter(f)
+    if err := cw.Write(header); err != nil { f.Close(); return err }
+    if err := cw.WriteAll(records); err != nil { f.Close(); return err }
+    return f.Close()
+}
+
+func readCSV(path string) ([]string, [][]string, error) {
+    f, err := os.Open(path)
+    if err != nil { return nil, nil, err }
+    defer f.Close()
+    all, err := csv.NewReader(f).ReadAll()
+    if err != nil { return nil, nil, fmt.Errorf("%s: %w", path, err) }
+    if len(all) == 0 { return nil, nil, fmt.Errorf("%s: missing header", path) }
+    return all[0], all[1:], nil
+}
+
+func fileSafe(s string) string {
+    return strings.Map(func(r rune) rune {
+        if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') { return r }
+        return '_'
+    }, s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m { keys = append(keys, k) }
+    sort.Strings(keys)
+    return keys
+}
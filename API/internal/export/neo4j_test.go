@@ -0,0 +1,68 @@
+package export
+
+import (
+    "bytes"
+    "encoding/csv"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestNeo4jCSVRoundTrip(t *testing.T) {
+    nodes, edges := sample()
+    edges = append(edges, &dgraph.Edge{ID: "c1", EdgeType: "CITES", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH02:§3342",
+        Props: map[string]any{"order": float64(3), "pin_cite": "(a)(1)", "context": "as provided in \"Section 3342\",\nsubject to"}})
+    dir := t.TempDir()
+    files, err := WriteNeo4jCSV(dir, nodes, edges)
+    if err != nil { t.Fatal(err) }
+    want := []string{"nodes_CHAPTER.csv", "nodes_SECTION.csv", "rels_CITES.csv", "rels_PARENT_OF.csv"}
+    if !reflect.DeepEqual(files, want) { t.Fatalf("files = %v", files) }
+
+    f, err := os.Open(filepath.Join(dir, "rels_CITES.csv"))
+    if err != nil { t.Fatal(err) }
+    header, err := csv.NewReader(f).Read()
+    f.Close()
+    if err != nil { t.Fatal(err) }
+    if got := strings.Join(header, ","); got != ":START_ID,:END_ID,:TYPE,id,context:string,order:long,pin_cite:string,weight:double" {
+        t.Fatalf("header = %s", got)
+    }
+
+    gotNodes, gotEdges, err := ReadNeo4jCSV(dir)
+    if err != nil { t.Fatal(err) }
+    if len(gotNodes) != len(nodes) { t.Fatalf("nodes = %d", len(gotNodes)) }
+    byID := map[string]*dgraph.Node{}
+    for _, n := range gotNodes { byID[n.ID] = n }
+    for _, n := range nodes {
+        if !reflect.DeepEqual(byID[n.ID], n) { t.Errorf("node %s = %+v, want %+v", n.ID, byID[n.ID], n) }
+    }
+    edgeByID := map[string]*dgraph.Edge{}
+    for _, e := range gotEdges { edgeByID[e.ID] = e }
+    for _, e := range edges {
+        if e.ID == "" { continue }
+        if !reflect.DeepEqual(edgeByID[e.ID], e) { t.Errorf("edge %s = %+v, want %+v", e.ID, edgeByID[e.ID], e) }
+    }
+    c1 := edgeByID["c1"]
+    if c1.Props["order"] != float64(3) || c1.Props["pin_cite"] != "(a)(1)" || !strings.Contains(c1.Props["context"].(string), "\nsubject to") {
+        t.Errorf("c1 props = %v", c1.Props)
+    }
+}
+
+func TestCypherMergeScript(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := WriteCypher(&buf, nodes, edges); err != nil { t.Fatal(err) }
+    out := buf.String()
+    for _, want := range []string{
+        "CREATE CONSTRAINT IF NOT EXISTS FOR (n:`SECTION`) REQUIRE n.id IS UNIQUE;",
+        "MERGE (n:`CHAPTER` {id: \"CA:CIV:T02:CH02\"}) SET n += {`chapter_num`: 2, `jurisdiction`: \"CA\", `labels`: [\"CHAPTER\"], `title`: \"Chapter 2\"};",
+        "`title`: \"Section \\\"3342\\\" & more\"",
+        "MATCH (a:`CHAPTER` {id: \"CA:CIV:T02:CH02\"}), (b:`SECTION` {id: \"CA:CIV:T02:CH02:§3342\"}) MERGE (a)-[r:`PARENT_OF` {id: \"e4\"}]->(b) SET r += {`order`: 10};",
+        "SET r += {`pin_cite`: \"(b)\", `weight`: 0.5};",
+    } {
+        if !strings.Contains(out, want) { t.Errorf("missing %q in\n%s", want, out) }
+    }
+}
@@ -0,0 +1,278 @@
+package export
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// RDF vocabularies. Legislative structure maps to the European Legislation
+// Identifier (ELI) ontology with schema.org Legislation alongside; anything
+// without an ELI term goes to the LawMap vocabulary under the base URL.
+const (
+    nsRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+    nsXSD     = "http://www.w3.org/2001/XMLSchema#"
+    nsELI     = "http://data.europa.eu/eli/ontology#"
+    nsSchema  = "https://schema.org/"
+    nsDCTerms = "http://purl.org/dc/terms/"
+    nsOWL     = "http://www.w3.org/2002/07/owl#"
+    nsSKOS    = "http://www.w3.org/2004/02/skos/core#"
+)
+
+// term is an RDF IRI (iri set) or literal (value plus optional datatype IRI).
+type term struct {
+    iri      string
+    value    string
+    datatype string
+}
+
+type triple struct {
+    s, p string
+    o    term
+}
+
+func iri(s string) term { return term{iri: s} }
+
+// escapeIRI percent-encodes the characters an IRIREF may not hold (controls, space,
+// <>"{}|^`\), so a URL taken from source data cannot break out of <...>.
+func escapeIRI(s string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        if c <= 0x20 || c == 0x7f || strings.IndexByte("<>\"{}|^`\\", c) >= 0 {
+            fmt.Fprintf(&b, "%%%02X", c)
+            continue
+        }
+        b.WriteByte(c)
+    }
+    return b.String()
+}
+
+func lit(v string) term { return term{value: v} }
+
+func typed(v, dt string) term { return term{value: v, datatype: dt} }
+
+// NodeURI is the dereferenceable IRI for a canonical ID: {base}/nodes/{escaped id}.
+func NodeURI(base, id string) string {
+    return strings.TrimSuffix(base, "/") + "/nodes/" + url.PathEscape(id)
+}
+
+func vocab(base string) string {
+    return strings.TrimSuffix(base, "/") + "/vocab#"
+}
+
+// labelTypes maps node labels to RDF classes.
+var labelTypes = map[string][]string{
+    "CODE":         {nsELI + "LegalResource", nsSchema + "Legislation"},
+    "TITLE":        {nsELI + "LegalResourceSubdivision", nsSchema + "Legislation"},
+    "CHAPTER":      {nsELI + "LegalResourceSubdivision", nsSchema + "Legislation"},
+    "SECTION":      {nsELI + "LegalResourceSubdivision", nsSchema + "Legislation"},
+    "RULE":         {nsELI + "LegalResource", nsSchema + "Legislation"},
+    "REGULATION":   {nsELI + "LegalResource", nsSchema + "Legislation"},
+    "OPINION":      {nsSchema + "CreativeWork"},
+    "JURISDICTION": {nsSchema + "AdministrativeArea"},
+    "TOPIC":        {nsSKOS + "Concept"},
+}
+
+// edgePredicates maps edge types to predicates; PARENT_OF also gets eli:is_part_of on the child.
+var edgePredicates = map[string][]string{
+    "PARENT_OF": {nsELI + "has_part", nsSchema + "hasPart"},
+    "CITES":     {nsELI + "cites"},
+    "AMENDS":    {nsELI + "amends"},
+    "REPEALS":   {nsELI + "repeals"},
+    "SAME_AS":   {nsOWL + "sameAs"},
+    "HAS_TOPIC": {nsELI + "is_about"},
+}
+
+// triples converts nodes and edges to RDF statements. Edge props are not carried.
+func triples(base string, nodes []*dgraph.Node, edges []*dgraph.Edge) []triple {
+    voc := vocab(base)
+    var out []triple
+    for _, n := range nodes {
+        s := NodeURI(base, n.ID)
+        add := func(p string, o term) { out = append(out, triple{s, p, o}) }
+        for _, l := range n.Labels {
+            for _, c := range labelTypes[l] { add(nsRDF+"type", iri(c)) }
+            if l != "" { add(nsRDF+"type", iri(voc+l[:1]+strings.ToLower(l[1:]))) }
+        }
+        add(nsDCTerms+"identifier", lit(n.ID))
+        if n.Title != "" {
+            add(nsELI+"title", lit(n.Title))
+            add(nsSchema+"name", lit(n.Title))
+        }
+        if n.Citation != "" {
+            add(nsELI+"id_local", lit(n.Citation))
+            add(nsSchema+"legislationIdentifier", lit(n.Citation))
+        }
+        if n.Text != "" { add(nsSchema+"text", lit(n.Text)) }
+        if v := n.Version; v != nil {
+            if v.EffectiveDate != "" { add(nsELI+"date_applicability", typed(v.EffectiveDate, nsXSD+"date")) }
+            if v.FetchedAt != "" { add(voc+"fetched_at", typed(v.FetchedAt, nsXSD+"dateTime")) }
+            if v.Hash != "" { add(voc+"hash", lit(v.Hash)) }
+        }
+        for _, src := range n.Sources {
+            if src.URL != "" { add(nsDCTerms+"source", iri(escapeIRI(src.URL))) }
+        }
+        keys := make([]string, 0, len(n.Props))
+        for k := range n.Props { keys = append(keys, k) }
+        sort.Strings(keys)
+        for _, k := range keys {
+            v := n.Props[k]
+            if k == "jurisdiction" {
+                if j, ok := v.(string); ok && j != "" { add(nsELI+"jurisdiction", iri(NodeURI(base, j))) }
+                continue
+            }
+            add(voc+k, propLiteral(v))
+        }
+    }
+    for _, e := range edges {
+        from, to := NodeURI(base, e.FromID), NodeURI(base, e.ToID)
+        preds, ok := edgePredicates[e.EdgeType]
+        if !ok { preds = []string{voc + strings.ToLower(e.EdgeType)} }
+        for _, p := range preds { out = append(out, triple{from, p, iri(to)}) }
+        if e.EdgeType == "PARENT_OF" { out = append(out, triple{to, nsELI + "is_part_of", iri(from)}) }
+    }
+    return out
+}
+
+func propLiteral(v any) term {
+    switch x := v.(type) {
+    case string:
+        return lit(x)
+    case bool:
+        return typed(strconv.FormatBool(x), nsXSD+"boolean")
+    case float64:
+        if valueType(x) == "long" { return typed(strconv.FormatInt(int64(x), 10), nsXSD+"integer") }
+        return typed(strconv.FormatFloat(x, 'g', -1, 64), nsXSD+"double")
+    }
+    b, _ := json.Marshal(v)
+    return lit(string(b))
+}
+
+var ntEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func ntTerm(t term) string {
+    if t.iri != "" { return "<" + escapeIRI(t.iri) + ">" }
+    s := `"` + ntEscaper.Replace(t.value) + `"`
+    if t.datatype != "" { s += "^^<" + t.datatype + ">" }
+    return s
+}
+
+func writeNTriples(w io.Writer, base string, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    for _, t := range triples(base, nodes, edges) {
+        fmt.Fprintf(bw, "<%s> <%s> %s .\n", escapeIRI(t.s), t.p, ntTerm(t.o))
+    }
+    return bw.Flush()
+}
+
+// prefixes used by Turtle and JSON-LD output, in declaration order.
+func prefixes(base string) [][2]string {
+    return [][2]string{
+        {"rdf", nsRDF}, {"xsd", nsXSD}, {"eli", nsELI}, {"schema", nsSchema},
+        {"dcterms", nsDCTerms}, {"owl", nsOWL}, {"skos", nsSKOS}, {"lawmap", vocab(base)},
+    }
+}
+
+// compact shortens an IRI to prefix:local when the local part is a safe name.
+func compact(pfx [][2]string, s string) (string, bool) {
+    for _, p := range pfx {
+        if local := strings.TrimPrefix(s, p[1]); local != s && local != "" && safeLocal(local) { return p[0] + ":" + local, true }
+    }
+    return s, false
+}
+
+func safeLocal(s string) bool {
+    for _, r := range s {
+        if !(r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) { return false }
+    }
+    return true
+}
+
+// groupBySubject keeps subjects in first-seen order.
+func groupBySubject(ts []triple) ([]string, map[string][]triple) {
+    var order []string
+    bySubj := make(map[string][]triple)
+    for _, t := range ts {
+        if _, ok := bySubj[t.s]; !ok { order = append(order, t.s) }
+        bySubj[t.s] = append(bySubj[t.s], t)
+    }
+    return order, bySubj
+}
+
+func writeTurtle(w io.Writer, base string, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    bw := bufio.NewWriter(w)
+    pfx := prefixes(base)
+    for _, p := range pfx { fmt.Fprintf(bw, "@prefix %s: <%s> .\n", p[0], escapeIRI(p[1])) }
+    ttlTerm := func(t term) string {
+        if t.iri != "" {
+            if c, ok := compact(pfx, t.iri); ok { return c }
+            return "<" + escapeIRI(t.iri) + ">"
+        }
+        s := `"` + ntEscaper.Replace(t.value) + `"`
+        if t.datatype != "" {
+            if c, ok := compact(pfx, t.datatype); ok { return s + "^^" + c }
+            return s + "^^<" + t.datatype + ">"
+        }
+        return s
+    }
+    order, bySubj := groupBySubject(triples(base, nodes, edges))
+    for _, s := range order {
+        fmt.Fprintf(bw, "\n<%s>", escapeIRI(s))
+        for i, t := range bySubj[s] {
+            sep := " ;"
+            if i == 0 { sep = "" }
+            p := "a"
+            if t.p != nsRDF+"type" { p = ttlTerm(iri(t.p)) }
+            fmt.Fprintf(bw, "%s\n    %s %s", sep, p, ttlTerm(t.o))
+        }
+        bw.WriteString(" .\n")
+    }
+    return bw.Flush()
+}
+
+func writeJSONLD(w io.Writer, base string, nodes []*dgraph.Node, edges []*dgraph.Edge) error {
+    pfx := prefixes(base)
+    ctx := make(map[string]string, len(pfx))
+    for _, p := range pfx { ctx[p[0]] = p[1] }
+    name := func(s string) string { c, _ := compact(pfx, s); return c }
+    order, bySubj := groupBySubject(triples(base, nodes, edges))
+    graph := make([]map[string]any, 0, len(order))
+    for _, s := range order {
+        obj := map[string]any{"@id": s}
+        var types []string
+        for _, t := range bySubj[s] {
+            if t.p == nsRDF+"type" {
+                types = append(types, name(t.o.iri))
+                continue
+            }
+            var v any
+            switch {
+            case t.o.iri != "":
+                v = map[string]string{"@id": t.o.iri}
+            case t.o.datatype != "":
+                v = map[string]string{"@value": t.o.value, "@type": name(t.o.datatype)}
+            default:
+                v = t.o.value
+            }
+            key := name(t.p)
+            if prev, ok := obj[key]; ok {
+                if list, ok := prev.([]any); ok { obj[key] = append(list, v) } else { obj[key] = []any{prev, v} }
+            } else {
+                obj[key] = v
+            }
+        }
+        if len(types) > 0 { obj["@type"] = types }
+        graph = append(graph, obj)
+    }
+    enc := json.NewEncoder(w)
+    enc.SetEscapeHTML(false)
+    return enc.Encode(map[string]any{"@context": ctx, "@graph": graph})
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+    "bytes"
+    "encoding/json"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestNTriplesELIMapping(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := Write(&buf, NTriples, nodes, edges, Options{BaseURL: "https://lawmap.example/"}); err != nil { t.Fatal(err) }
+    out := buf.String()
+    sec := "<https://lawmap.example/nodes/CA:CIV:T02:CH02:%C2%A73342>"
+    ch := "<https://lawmap.example/nodes/CA:CIV:T02:CH02>"
+    for _, want := range []string{
+        sec + " <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://data.europa.eu/eli/ontology#LegalResourceSubdivision> .",
+        sec + " <http://data.europa.eu/eli/ontology#title> \"Section \\\"3342\\\" & more\" .",
+        ch + " <http://data.europa.eu/eli/ontology#has_part> " + sec + " .",
+        sec + " <http://data.europa.eu/eli/ontology#is_part_of> " + ch + " .",
+        sec + " <http://data.europa.eu/eli/ontology#cites> " + ch + " .",
+        ch + " <https://lawmap.example/vocab#chapter_num> \"2\"^^<http://www.w3.org/2001/XMLSchema#integer> .",
+        sec + " <http://data.europa.eu/eli/ontology#jurisdiction> <https://lawmap.example/nodes/CA> .",
+    } {
+        if !strings.Contains(out, want) { t.Fatalf("missing triple %s\n%s", want, out) }
+    }
+}
+
+func TestJSONLDAndTurtle(t *testing.T) {
+    nodes, edges := sample()
+    var buf bytes.Buffer
+    if err := Write(&buf, JSONLD, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+    var doc struct {
+        Context map[string]string `json:"@context"`
+        Graph []map[string]any `json:"@graph"`
+    }
+    if err := json.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatalf("invalid json-ld: %v", err) }
+    if doc.Context["eli"] != nsELI { t.Fatalf("expected eli prefix in context") }
+    first := doc.Graph[0]
+    if first["@id"] != "http://localhost:8080/nodes/CA:CIV:T02:CH02" { t.Fatalf("unexpected @id %v", first["@id"]) }
+    if part, ok := first["eli:has_part"].(map[string]any); !ok || part["@id"] != "http://localhost:8080/nodes/CA:CIV:T02:CH02:%C2%A73342" {
+        t.Fatalf("expected eli:has_part link, got %v", first["eli:has_part"])
+    }
+
+    buf.Reset()
+    if err := Write(&buf, Turtle, nodes, edges, Options{}); err != nil { t.Fatal(err) }
+    if !strings.Contains(buf.String(), "@prefix eli: <http://data.europa.eu/eli/ontology#> .") || !strings.Contains(buf.String(), "a eli:LegalResourceSubdivision") {
+        t.Fatalf("unexpected turtle:\n%s", buf.String())
+    }
+}
+
+func TestRDFEscapesSourceIRIs(t *testing.T) {
+    nodes, edges := sample()
+    nodes[1].Sources = []dgraph.SourceMeta{{URL: "https://leginfo.example/s?q=a b> <x> \"y\" .\n{z}"}}
+    want := "<https://leginfo.example/s?q=a%20b%3E%20%3Cx%3E%20%22y%22%20.%0A%7Bz%7D>"
+    for _, f := range []Format{NTriples, Turtle} {
+        var buf bytes.Buffer
+        if err := Write(&buf, f, nodes, edges, Options{BaseURL: "https://lawmap.example"}); err != nil { t.Fatal(err) }
+        if !strings.Contains(buf.String(), want) || strings.Contains(buf.String(), "<x>") { t.Fatalf("%v: source IRI not escaped:\n%s", f, buf.String()) }
+    }
+}
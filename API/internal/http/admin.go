@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+
+    "lawmap/internal/etl/scheduler"
+)
+
+// SetScheduler enables the /admin endpoints for the refresh scheduler.
+func (s *Server) SetScheduler(sch *scheduler.Scheduler) { s.scheduler = sch }
+
+// schedulerEnabled writes the 404 for a server without a scheduler.
+func (s *Server) schedulerEnabled(w http.ResponseWriter) bool {
+    if s.scheduler == nil {
+        writeError(w, http.StatusNotFound, "not_found", "Scheduler is not enabled", nil)
+        return false
+    }
+    return true
+}
+
+// handleAdminRuns lists the run log: GET /admin/runs?source=&limit=.
+func (s *Server) handleAdminRuns(w http.ResponseWriter, r *http.Request) {
+    if !s.schedulerEnabled(w) { return }
+    q := r.URL.Query()
+    p := newParams(r)
+    limit := p.intRange("limit", 50, 1, 1000)
+    if !p.ok(w) { return }
+    runs := s.scheduler.Runs(q.Get("source"), limit)
+    if runs == nil { runs = []scheduler.Run{} }
+    writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+// handleAdminTrigger runs a source now: POST /admin/runs?source=.
+func (s *Server) handleAdminTrigger(w http.ResponseWriter, r *http.Request) {
+    if !s.schedulerEnabled(w) { return }
+    run, err := s.scheduler.Trigger(r.URL.Query().Get("source"))
+    if err != nil {
+        s.writeSchedulerError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusAccepted, map[string]any{"run": run})
+}
+
+// handleAdminSources lists scheduled sources: GET /admin/sources.
+func (s *Server) handleAdminSources(w http.ResponseWriter, r *http.Request) {
+    if !s.schedulerEnabled(w) { return }
+    writeJSON(w, http.StatusOK, map[string]any{"sources": s.scheduler.Jobs()})
+}
+
+// handleAdminPause pauses or resumes a source: POST /admin/sources/pause?source=
+// and /admin/sources/resume?source=.
+func (s *Server) handleAdminPause(w http.ResponseWriter, r *http.Request) {
+    if !s.schedulerEnabled(w) { return }
+    job, err := s.scheduler.Pause(r.URL.Query().Get("source"), strings.HasSuffix(r.URL.Path, "/pause"))
+    if err != nil {
+        s.writeSchedulerError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) writeSchedulerError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, scheduler.ErrUnknownSource):
+        writeError(w, http.StatusNotFound, "not_found", "Source is not scheduled", nil)
+    case errors.Is(err, scheduler.ErrRunning):
+        writeError(w, http.StatusConflict, "conflict", "Source is already running", nil)
+    default:
+        writeError(w, http.StatusInternalServerError, "internal", err.Error(), nil)
+    }
+}
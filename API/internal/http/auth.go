@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/services/auth"
+)
+
+// SetAuth turns on authentication. Every route but the public ones then needs a
+// principal with the route's role (reader unless the route says otherwise), and a
+// principal scoped to jurisdictions or codes sees only nodes within them.
+func (s *Server) SetAuth(a *auth.Service) { s.auth = a }
+
+// requires sets the role a route needs when auth is on.
+func requires(role auth.Role) routeOption { return func(rt *route) { rt.role = role } }
+
+// public leaves a route open when auth is on.
+func public(rt *route) { rt.public = true }
+
+// authorize authenticates the request, checks its role and, for routes on a node,
+// that the node is in the principal's scope; the principal goes in the context.
+func (s *Server) authorize(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        p, err := s.auth.Authenticate(r)
+        if err != nil {
+            w.Header().Set("WWW-Authenticate", s.auth.Challenge())
+            code := "unauthorized"
+            if errors.Is(err, auth.ErrInvalid) { code = "invalid_credentials" }
+            writeError(w, http.StatusUnauthorized, code, err.Error(), nil)
+            return
+        }
+        if p.Role < role {
+            writeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("%s needs the %s role; %s has %s", r.URL.Path, role, p.Name, p.Role), map[string]string{"required": role.String(), "role": p.Role.String()})
+            return
+        }
+        if n, ok := s.store.GetNode(r.PathValue("id")); ok && !p.Allows(scopeOf(n)) {
+            writeError(w, http.StatusForbidden, "out_of_scope", fmt.Sprintf("%s is outside the jurisdictions and codes licensed to %s", n.ID, p.Name), nil)
+            return
+        }
+        h(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+    }
+}
+
+// scopeOf is a node's jurisdiction and code props.
+func scopeOf(n *dgraph.Node) (string, string) {
+    j, _ := n.Props["jurisdiction"].(string)
+    c, _ := n.Props["code"].(string)
+    return j, c
+}
+
+// visible reports whether the request's principal may see n; always true with auth off.
+func visible(r *http.Request, n *dgraph.Node) bool {
+    return auth.FromContext(r.Context()).Allows(scopeOf(n))
+}
+
+// edgeVisible reports whether the request's principal may see both ends of e.
+func (s *Server) edgeVisible(r *http.Request, e *dgraph.Edge) bool {
+    if !auth.FromContext(r.Context()).Scoped() { return true }
+    for _, id := range []string{e.FromID, e.ToID} {
+        n, ok := s.store.GetNode(id)
+        if !ok || !visible(r, n) { return false }
+    }
+    return true
+}
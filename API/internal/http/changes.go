@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/auth"
+)
+
+// sseHeartbeat is how often an idle change stream sends a comment line, so that
+// proxies keep the connection open and clients notice a dead one.
+var sseHeartbeat = 15 * time.Second
+
+// sseBatch bounds the changes read from the store per wake-up.
+const sseBatch = 500
+
+// parseSince reads a change seq from since (or, for streams, Last-Event-ID).
+func parseSince(v string) (int64, bool) {
+    n, err := strconv.ParseInt(v, 10, 64)
+    return n, err == nil && n >= 0
+}
+
+// handleChanges serves the change log: GET /changes?since=&jurisdiction=&code=&limit=.
+// Pass the returned next as since to continue; next equals latest once caught up.
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+    p := newParams(r)
+    var since int64
+    if v := p.str("since"); v != "" {
+        n, ok := parseSince(v)
+        if !ok { p.reject(invalidParam{Name: "since", Value: v, Reason: "since must be a non-negative change seq"}) }
+        since = n
+    }
+    limit := p.intRange("limit", 100, 1, 1000)
+    if !p.ok(w) { return }
+    changes, latest, ok := s.store.Changes(since, p.str("jurisdiction"), p.str("code"), limit)
+    if !ok {
+        writeError(w, http.StatusGone, "gone", "Changes after since are no longer kept; reload a full export and follow from latest", map[string]int64{"latest": latest})
+        return
+    }
+    next := latest
+    if len(changes) == limit { next = changes[len(changes)-1].Seq }
+    changes = allowedChanges(r, changes)
+    writeJSON(w, http.StatusOK, map[string]any{"changes": changes, "next": next, "latest": latest})
+}
+
+// allowedChanges drops the changes outside the principal's scope, by the changed
+// node's (or an edge's from node's) jurisdiction and code.
+func allowedChanges(r *http.Request, changes []graphrepo.Change) []graphrepo.Change {
+    p := auth.FromContext(r.Context())
+    out := make([]graphrepo.Change, 0, len(changes))
+    for _, c := range changes {
+        if p.Allows(c.Jurisdiction, c.Code) { out = append(out, c) }
+    }
+    return out
+}
+
+// handleChangesStream sends the change log as Server-Sent Events, one "change" event
+// per entry with the seq as event id, so EventSource reconnects resume via
+// Last-Event-ID. Without since or Last-Event-ID the stream starts at the latest seq.
+func (s *Server) handleChangesStream(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        writeError(w, http.StatusInternalServerError, "internal", "Streaming is not supported", nil)
+        return
+    }
+    q := r.URL.Query()
+    since := s.store.LastSeq()
+    for _, src := range [][2]string{{"Last-Event-ID", r.Header.Get("Last-Event-ID")}, {"since", q.Get("since")}} {
+        name, v := src[0], src[1]
+        if v == "" { continue }
+        n, ok := parseSince(v)
+        if !ok {
+            writeInvalidParams(w, invalidParam{Name: name, Value: v, Reason: name + " must be a non-negative change seq"})
+            return
+        }
+        since = n
+        break
+    }
+    jurisdiction, code := q.Get("jurisdiction"), q.Get("code")
+
+    h := w.Header()
+    h.Set("Content-Type", "text/event-stream")
+    h.Set("Cache-Control", "no-cache")
+    h.Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+    if r.Method == http.MethodHead { return }
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(sseHeartbeat)
+    defer heartbeat.Stop()
+    for {
+        signal := s.store.ChangeSignal()
+        changes, latest, ok := s.store.Changes(since, jurisdiction, code, sseBatch)
+        if !ok {
+            fmt.Fprintf(w, "event: gone\ndata: {\"latest\":%d}\n\n", latest)
+            flusher.Flush()
+            return
+        }
+        for i := range changes {
+            since = changes[i].Seq
+            if !auth.FromContext(r.Context()).Allows(changes[i].Jurisdiction, changes[i].Code) { continue }
+            b, _ := json.Marshal(changes[i])
+            if _, err := fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", changes[i].Seq, b); err != nil { return }
+        }
+        if len(changes) < sseBatch { since = latest }
+        flusher.Flush()
+        if len(changes) == sseBatch { continue }
+        select {
+        case <-signal:
+        case <-heartbeat.C:
+            if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil { return }
+            flusher.Flush()
+        case <-r.Context().Done():
+            return
+        }
+    }
+}
@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+    "compress/gzip"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/andybalholm/brotli"
+    "github.com/klauspost/compress/zstd"
+)
+
+// Responses are compressed with the best encoding the client accepts (Accept-Encoding,
+// q-values honored, ties going to the server's preference: br, zstd, gzip). The writer
+// holds the body until it reaches the route's threshold, so small bodies go out as they
+// are; a handler that flushes first commits to compressing, so streams stay streams.
+
+// compressor is a streaming encoder; Flush pushes out what was written so far.
+type compressor interface {
+    io.WriteCloser
+    Flush() error
+}
+
+type encoding struct {
+    name string
+    new  func(w io.Writer) compressor
+}
+
+// encodings are the codings served, in order of preference.
+var encodings = []encoding{
+    {"br", func(w io.Writer) compressor { return brotli.NewWriterLevel(w, brotliLevel) }},
+    {"zstd", newZstd},
+    {"gzip", func(w io.Writer) compressor { return gzip.NewWriter(w) }},
+}
+
+// brotliLevel trades ratio for speed, since bodies are compressed per request;
+// the top levels are meant for static assets.
+const brotliLevel = 5
+
+// newZstd encodes on the calling goroutine; the default spawns one per CPU, which
+// is wasted on a single response. Its options are fixed, so NewWriter cannot fail.
+func newZstd(w io.Writer) compressor {
+    zw, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+    return zw
+}
+
+// defaultCompressMin is the smallest body worth compressing; below it gzip's framing
+// costs more than it saves.
+const defaultCompressMin = 1024
+
+// compression is a route's policy; see the route options in router.go.
+type compression struct {
+    off bool
+    min int // bytes held before compressing
+}
+
+// negotiateEncoding picks the encoding for an Accept-Encoding header, or nil for
+// identity. Codings with q=0 are refused; * stands for any coding not named.
+func negotiateEncoding(header string) *encoding {
+    q := map[string]float64{}
+    for _, part := range strings.Split(header, ",") {
+        name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        name = strings.ToLower(strings.TrimSpace(name))
+        if name == "" { continue }
+        weight := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if f, err := strconv.ParseFloat(v, 64); err == nil { weight = f }
+        }
+        q[name] = weight
+    }
+    var best *encoding
+    bestQ := 0.0
+    for i, e := range encodings {
+        w, ok := q[e.name]
+        if !ok { w = q["*"] }
+        if w > bestQ { best, bestQ = &encodings[i], w }
+    }
+    return best
+}
+
+// compressible reports whether a Content-Type is text that compresses well.
+func compressible(ct string) bool {
+    ct, _, _ = strings.Cut(ct, ";")
+    ct = strings.TrimSpace(ct)
+    return strings.HasPrefix(ct, "text/") || strings.HasSuffix(ct, "json") || strings.HasSuffix(ct, "xml") ||
+        ct == "application/n-triples" || ct == "application/x-ndjson"
+}
+
+// compress applies c to h. Vary: Accept-Encoding is set whether or not this response
+// ends up compressed, since another client may get a different body.
+func compress(c compression, h http.HandlerFunc) http.HandlerFunc {
+    if c.off { return h }
+    if c.min == 0 { c.min = defaultCompressMin }
+    return func(w http.ResponseWriter, r *http.Request) {
+        addVary(w.Header(), "Accept-Encoding")
+        enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+        if enc == nil { h(w, r); return }
+        cw := &compressWriter{ResponseWriter: w, enc: enc, min: c.min, status: http.StatusOK}
+        defer cw.close()
+        h(cw, r)
+    }
+}
+
+// compressWriter buffers up to min bytes, then decides once: compress when the body
+// is large enough (or flushed) and its type compressible, else pass it through.
+type compressWriter struct {
+    http.ResponseWriter
+    enc     *encoding
+    min     int
+    status  int
+    buf     []byte
+    decided bool
+    zw      compressor
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+    if cw.decided || status < 200 { cw.ResponseWriter.WriteHeader(status); return }
+    cw.status = status
+    if status == http.StatusNoContent || status == http.StatusNotModified { cw.decide(false) }
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+    if !cw.decided {
+        cw.buf = append(cw.buf, p...)
+        if len(cw.buf) >= cw.min { return len(p), cw.decide(true) }
+        return len(p), nil
+    }
+    if cw.zw != nil { return cw.zw.Write(p) }
+    return cw.ResponseWriter.Write(p)
+}
+
+// decide sends the header and any held bytes, compressing them when want allows.
+func (cw *compressWriter) decide(want bool) error {
+    cw.decided = true
+    h := cw.Header()
+    if want && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+        h.Set("Content-Encoding", cw.enc.name)
+        h.Del("Content-Length")
+        // a strong ETag names one representation, so the encoded body gets its own
+        if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) { h.Set("ETag", encodedETag(etag, cw.enc.name)) }
+        cw.zw = cw.enc.new(cw.ResponseWriter)
+    }
+    cw.ResponseWriter.WriteHeader(cw.status)
+    buf := cw.buf
+    cw.buf = nil
+    if len(buf) == 0 { return nil }
+    if cw.zw != nil { _, err := cw.zw.Write(buf); return err }
+    _, err := cw.ResponseWriter.Write(buf)
+    return err
+}
+
+// Flush commits a response still under the threshold to compression: a handler
+// that flushes is streaming, and its total size is unknown.
+func (cw *compressWriter) Flush() {
+    if !cw.decided { _ = cw.decide(true) }
+    if cw.zw != nil { _ = cw.zw.Flush() }
+    if f, ok := cw.ResponseWriter.(http.Flusher); ok { f.Flush() }
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
+
+// close sends a body that stayed under the threshold as it is and ends the encoded stream.
+func (cw *compressWriter) close() {
+    if !cw.decided { _ = cw.decide(false) }
+    if cw.zw != nil { _ = cw.zw.Close() }
+}
+
+// encodedETag is etag for a body sent with Content-Encoding enc.
+func encodedETag(etag, enc string) string { return strings.TrimSuffix(etag, `"`) + "-" + enc + `"` }
+
+// addVary adds name to the Vary header unless it is listed already.
+func addVary(h http.Header, name string) {
+    for _, v := range h.Values("Vary") {
+        for _, f := range strings.Split(v, ",") {
+            if strings.EqualFold(strings.TrimSpace(f), name) { return }
+        }
+    }
+    h.Add("Vary", name)
+}
@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+    "bytes"
+    "compress/gzip"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/andybalholm/brotli"
+    "github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+    cases := []struct{ header, want string }{
+        {"", ""},
+        {"gzip", "gzip"},
+        {"deflate", ""},
+        {"gzip, zstd, br", "br"},
+        {"gzip, zstd", "zstd"},
+        {"gzip;q=1, br;q=0.5", "gzip"},
+        {"deflate, br", "br"},
+        {"gzip;q=0", ""},
+        {"*;q=0.5", "br"},
+        {"br;q=0, *", "zstd"},
+        {"br;q=0, zstd;q=0, *", "gzip"},
+    }
+    for _, c := range cases {
+        got := ""
+        if e := negotiateEncoding(c.header); e != nil { got = e.name }
+        if got != c.want { t.Errorf("%q: got %q, want %q", c.header, got, c.want) }
+    }
+}
+
+func TestCompressWriter(t *testing.T) {
+    big := strings.Repeat(`{"text":"Every person who owns a dog is liable"}`, 100)
+    mux := http.NewServeMux()
+    rt := newRouter(mux)
+    rt.handle("GET /big", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("ETag", `"abc"`)
+        io.WriteString(w, big)
+    })
+    rt.handle("GET /small", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        io.WriteString(w, `{"ok":true}`)
+    })
+    rt.handle("GET /small-routed", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        io.WriteString(w, `{"ok":true}`)
+    }, compressAbove(1))
+    rt.handle("GET /png", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "image/png")
+        io.WriteString(w, big)
+    })
+    rt.handle("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/event-stream")
+        io.WriteString(w, "data: 1\n\n")
+        w.(http.Flusher).Flush()
+        io.WriteString(w, "data: 2\n\n")
+    })
+    rt.handle("GET /off", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        io.WriteString(w, big)
+    }, uncompressed)
+    rt.finish()
+    get := func(path, accept string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("GET", path, nil)
+        req.Header.Set("Accept-Encoding", accept)
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    body := func(rr *httptest.ResponseRecorder) string {
+        var zr io.Reader = bytes.NewReader(rr.Body.Bytes())
+        switch rr.Header().Get("Content-Encoding") {
+        case "gzip":
+            gr, err := gzip.NewReader(zr)
+            if err != nil { t.Fatal(err) }
+            zr = gr
+        case "br":
+            zr = brotli.NewReader(zr)
+        case "zstd":
+            d, err := zstd.NewReader(zr)
+            if err != nil { t.Fatal(err) }
+            defer d.Close()
+            zr = d
+        default:
+            return rr.Body.String()
+        }
+        b, err := io.ReadAll(zr)
+        if err != nil { t.Fatal(err) }
+        return string(b)
+    }
+    cases := []struct {
+        path, accept, encoding, want string
+        vary                         bool
+    }{
+        {"/big", "gzip", "gzip", big, true},
+        {"/big", "", "", big, true},
+        {"/big", "br", "br", big, true},
+        {"/big", "zstd", "zstd", big, true},
+        {"/small", "gzip", "", `{"ok":true}`, true},
+        {"/small-routed", "gzip", "gzip", `{"ok":true}`, true},
+        {"/png", "gzip", "", big, true},
+        {"/stream", "gzip", "gzip", "data: 1\n\ndata: 2\n\n", true},
+        {"/stream", "br", "br", "data: 1\n\ndata: 2\n\n", true},
+        {"/stream", "zstd", "zstd", "data: 1\n\ndata: 2\n\n", true},
+        {"/off", "gzip", "", big, false},
+        {"/nope", "gzip", "", "", true},
+    }
+    for _, c := range cases {
+        rr := get(c.path, c.accept)
+        if got := rr.Header().Get("Content-Encoding"); got != c.encoding { t.Errorf("%s: Content-Encoding=%q, want %q", c.path, got, c.encoding) }
+        if got := body(rr); c.want != "" && got != c.want { t.Errorf("%s: body=%.40q", c.path, got) }
+        if got := strings.Contains(rr.Header().Get("Vary"), "Accept-Encoding"); got != c.vary { t.Errorf("%s: Vary=%q", c.path, rr.Header().Get("Vary")) }
+    }
+    for _, enc := range []string{"gzip", "br", "zstd"} {
+        if rr := get("/big", enc); rr.Header().Get("ETag") != `"abc-`+enc+`"` || rr.Body.Len() >= len(big)/4 { t.Errorf("%s etag=%q size=%d", enc, rr.Header().Get("ETag"), rr.Body.Len()) }
+    }
+    if rr := get("/stream", "gzip"); !rr.Flushed { t.Error("stream was not flushed") }
+}
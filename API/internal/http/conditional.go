@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "lawmap/internal/export"
+    "lawmap/internal/services/auth"
+)
+
+// Read endpoints answer conditional GETs. Each route names the state its response is
+// derived from: the {id} node's version hash for the node alone, the hashes of all
+// its versions for its history, the store generation for everything else. The ETag
+// hashes that state with the request URI and Accept, so every representation gets
+// its own strong tag; Last-Modified is the latest Version.FetchedAt or the time the
+// store last changed.
+type validator struct {
+    tag      string
+    modified time.Time // zero sends no Last-Modified
+}
+
+// storeState changes with every write to the store and with every load.
+func (s *Server) storeState(r *http.Request) (validator, bool) {
+    seq, at := s.store.Generation()
+    return validator{tag: fmt.Sprintf("g%d.%d", seq, at.UnixNano()), modified: at}, true
+}
+
+// nodeState is the {id} node's version hash. Nodes without one, expand=, which pulls
+// in other nodes, and exports (JSON-LD, Turtle, GraphML, ...), which carry the node's
+// edges, fall back to storeState; unknown IDs are not validated, so their 404 is
+// never cached.
+func (s *Server) nodeState(r *http.Request) (validator, bool) {
+    n, ok := s.store.GetNode(r.PathValue("id"))
+    if !ok { return validator{}, false }
+    if n.Version == nil || n.Version.Hash == "" || r.URL.Query().Has("expand") || wantsExport(r) { return s.storeState(r) }
+    v := validator{tag: "v" + n.Version.Hash}
+    v.modified, _ = time.Parse(time.RFC3339, n.Version.FetchedAt)
+    return v, true
+}
+
+// historyState is the hashes of every version of the {id} node, for /versions and
+// /diff: an older version filed into the history leaves the current hash alone.
+func (s *Server) historyState(r *http.Request) (validator, bool) {
+    hist := s.store.History(r.PathValue("id"))
+    if len(hist) == 0 { return validator{}, false }
+    var v validator
+    for _, n := range hist {
+        if n.Version == nil || n.Version.Hash == "" { return s.storeState(r) }
+        v.tag += "v" + n.Version.Hash
+        if t, err := time.Parse(time.RFC3339, n.Version.FetchedAt); err == nil && t.After(v.modified) { v.modified = t }
+    }
+    return v, true
+}
+
+// wantsExport reports whether format= or Accept selects an export rather than JSON;
+// an unknown format counts, its 400 is not validated anyway.
+func wantsExport(r *http.Request) bool {
+    if f := r.URL.Query().Get("format"); f != "" { return f != "json" }
+    _, want := export.FromAccept(r.Header.Get("Accept"))
+    return want
+}
+
+// etag also covers the principal's scope, since scoped principals see fewer rows.
+func (v validator) etag(r *http.Request) string {
+    scope := auth.FromContext(r.Context()).ScopeKey()
+    sum := sha256.Sum256([]byte(v.tag + "\x00" + r.URL.RequestURI() + "\x00" + r.Header.Get("Accept") + "\x00" + scope))
+    return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// conditional answers 304 when If-None-Match (or, without it, If-Modified-Since)
+// matches state, and otherwise sets ETag, Last-Modified and Cache-Control on the
+// handler's 200. no-cache lets clients and caches keep the body but revalidate it;
+// with auth on, responses are private.
+func conditional(state func(*http.Request) (validator, bool), h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        v, ok := state(r)
+        if !ok { h(w, r); return }
+        etag := v.etag(r)
+        hdr := w.Header()
+        hdr.Set("ETag", etag)
+        if !v.modified.IsZero() { hdr.Set("Last-Modified", v.modified.UTC().Format(http.TimeFormat)) }
+        hdr.Set("Cache-Control", "no-cache")
+        addVary(hdr, "Accept")
+        if auth.FromContext(r.Context()) != nil {
+            // bodies depend on who asks: shared caches must not keep them
+            hdr.Set("Cache-Control", "private, no-cache")
+            addVary(hdr, "Authorization")
+            addVary(hdr, "X-API-Key")
+        }
+        if tag, ok := notModified(r, etag, v.modified); ok {
+            hdr.Set("ETag", tag)
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+        h(validatedWriter{w}, r)
+    }
+}
+
+// notModified applies RFC 9110's precedence: If-Modified-Since counts only without
+// If-None-Match. Tags compare weakly, as GET allows, and the tag of an encoded body
+// (see compress) matches too; tag is the one the client holds.
+func notModified(r *http.Request, etag string, modified time.Time) (tag string, ok bool) {
+    if inm := r.Header.Values("If-None-Match"); len(inm) > 0 {
+        for _, t := range strings.Split(strings.Join(inm, ","), ",") {
+            t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+            if t == "*" || t == etag { return etag, true }
+            for _, e := range encodings {
+                if t == encodedETag(etag, e.name) { return t, true }
+            }
+        }
+        return "", false
+    }
+    ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+    return etag, err == nil && !modified.IsZero() && !modified.Truncate(time.Second).After(ims)
+}
+
+// validatedWriter drops the validators from responses other than 200, so that an
+// error is never revalidated into a 304.
+type validatedWriter struct{ http.ResponseWriter }
+
+func (vw validatedWriter) WriteHeader(status int) {
+    if status != http.StatusOK {
+        for _, k := range []string{"ETag", "Last-Modified", "Cache-Control"} { vw.Header().Del(k) }
+    }
+    vw.ResponseWriter.WriteHeader(status)
+}
+
+func (vw validatedWriter) Flush() { if f, ok := vw.ResponseWriter.(http.Flusher); ok { f.Flush() } }
+
+func (vw validatedWriter) Unwrap() http.ResponseWriter { return vw.ResponseWriter }
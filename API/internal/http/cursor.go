@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "sort"
+    "strings"
+)
+
+// List cursors are opaque to clients: base64 JSON of a cursorState, a dot and an
+// HMAC-SHA256 of that JSON. A cursor names the last row it returned, not an offset,
+// so rows added or removed before it do not shift the next page.
+var (
+    errCursorSignature = errors.New("cursor is not one issued by this server")
+    errCursorParams    = errors.New("cursor was issued for different parameters")
+)
+
+// cursorState is what a cursor carries between pages.
+type cursorState struct {
+    After  listKey `json:"a"` // the last row of the page
+    Offset int     `json:"o"` // where the next page starts, for lists without a sort key
+    Params string  `json:"p"` // listFingerprint of the request that issued it
+    Gen    int64   `json:"g"` // store change seq of the first page
+}
+
+// cursorCodec signs and verifies cursors with key.
+type cursorCodec struct{ key []byte }
+
+// newCursorCodec uses secret, or a random key when secret is empty; cursors from a
+// random key stop verifying when the process restarts.
+func newCursorCodec(secret string) cursorCodec {
+    if secret != "" { return cursorCodec{key: []byte(secret)} }
+    key := make([]byte, 32)
+    _, _ = rand.Read(key)
+    return cursorCodec{key: key}
+}
+
+func (c cursorCodec) sign(b []byte) []byte {
+    mac := hmac.New(sha256.New, c.key)
+    mac.Write(b)
+    return mac.Sum(nil)
+}
+
+func (c cursorCodec) encode(st cursorState) string {
+    b, _ := json.Marshal(st)
+    return base64.RawURLEncoding.EncodeToString(b) + "." + base64.RawURLEncoding.EncodeToString(c.sign(b))
+}
+
+// decode verifies cur and checks that it was issued for the parameters fingerprinted as params.
+func (c cursorCodec) decode(cur, params string) (cursorState, error) {
+    var st cursorState
+    body, sig, ok := strings.Cut(cur, ".")
+    if !ok { return st, errCursorSignature }
+    b, err := base64.RawURLEncoding.DecodeString(body)
+    if err != nil { return st, errCursorSignature }
+    mac, err := base64.RawURLEncoding.DecodeString(sig)
+    if err != nil || !hmac.Equal(mac, c.sign(b)) { return st, errCursorSignature }
+    if err := json.Unmarshal(b, &st); err != nil { return st, errCursorSignature }
+    if st.Params != params { return st, errCursorParams }
+    return st, nil
+}
+
+// cursorFree are the parameters a cursor may be reused with: they change the size or
+// shape of a page but not which rows the list holds or their order.
+var cursorFree = map[string]bool{"cursor": true, "offset": true, "limit": true, "fields": true, "count_only": true, "format": true}
+
+// listFingerprint hashes the path and every other query parameter, values sorted,
+// so that a cursor only continues the list it came from.
+func listFingerprint(r *http.Request) string {
+    q := r.URL.Query()
+    names := make([]string, 0, len(q))
+    for name := range q {
+        if !cursorFree[name] { names = append(names, name) }
+    }
+    sort.Strings(names)
+    h := sha256.New()
+    h.Write([]byte(r.URL.Path))
+    for _, name := range names {
+        vs := append([]string(nil), q[name]...)
+        sort.Strings(vs)
+        h.Write([]byte("\x00" + name + "=" + strings.Join(vs, "\x01")))
+    }
+    return hex.EncodeToString(h.Sum(nil)[:12])
+}
@@ -3,6 +3,7 @@ package httpapi
 import (
     "encoding/json"
     "net/http"
+    "strings"
 )
 
 type errorBody struct {
@@ -13,6 +14,51 @@ type errorBody struct {
     } `json:"error"`
 }
 
+// problemContentType is the RFC 7807 media type clients ask for in Accept.
+const problemContentType = "application/problem+json"
+
+// problem is the RFC 7807 form of an error body. code and the details (or, for
+// query validation, invalid_params) are extension members.
+type problem struct {
+    Type          string         `json:"type"`
+    Title         string         `json:"title"`
+    Status        int            `json:"status"`
+    Detail        string         `json:"detail"`
+    Code          string         `json:"code"`
+    InvalidParams []invalidParam `json:"invalid_params,omitempty"`
+    Details       interface{}    `json:"details,omitempty"`
+}
+
+// problemWriter marks a response whose client accepts problem+json, so that
+// writeError needs only the ResponseWriter.
+type problemWriter struct{ http.ResponseWriter }
+
+func (pw problemWriter) Flush() { if f, ok := pw.ResponseWriter.(http.Flusher); ok { f.Flush() } }
+
+func (pw problemWriter) Unwrap() http.ResponseWriter { return pw.ResponseWriter }
+
+// negotiateErrors wraps w in a problemWriter when Accept names application/problem+json.
+func negotiateErrors(h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if strings.Contains(r.Header.Get("Accept"), problemContentType) { w = problemWriter{w} }
+        h(w, r)
+    }
+}
+
+// acceptsProblem reports whether w, or a writer it wraps, is a problemWriter.
+func acceptsProblem(w http.ResponseWriter) bool {
+    for {
+        switch x := w.(type) {
+        case problemWriter:
+            return true
+        case interface{ Unwrap() http.ResponseWriter }:
+            w = x.Unwrap()
+        default:
+            return false
+        }
+    }
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
     w.Header().Set("Content-Type", "application/json; charset=utf-8")
     w.WriteHeader(status)
@@ -21,10 +67,17 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 }
 
 func writeError(w http.ResponseWriter, status int, code, msg string, details any) {
+    if acceptsProblem(w) {
+        p := problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: msg, Code: code}
+        if ips, ok := details.([]invalidParam); ok { p.InvalidParams = ips } else { p.Details = details }
+        w.Header().Set("Content-Type", problemContentType)
+        w.WriteHeader(status)
+        _ = json.NewEncoder(w).Encode(p)
+        return
+    }
     var eb errorBody
     eb.Error.Code = code
     eb.Error.Message = msg
     eb.Error.Details = details
     writeJSON(w, status, eb)
 }
-
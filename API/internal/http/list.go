@@ -0,0 +1,276 @@
+package httpapi
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/services/auth"
+)
+
+// List endpoints (children, citations, cites, amendments, topic members, graph slices
+// and search) share one pipeline: parseList reads the parameters, run filters, sorts
+// and pages the rows, and writeList sends the page as a count, an export, NDJSON or
+// the JSON envelope.
+const (
+    defaultListLimit = 100
+    maxListLimit     = 1000
+)
+
+// listItem is one row of a list: a node and the edge that ties it to the anchor
+// (nil for search hits and the root of a graph slice).
+type listItem struct {
+    n *dgraph.Node
+    e *dgraph.Edge
+}
+
+// pairItems zips the parallel node and edge slices the store returns.
+func pairItems(ns []*dgraph.Node, es []*dgraph.Edge) []listItem {
+    items := make([]listItem, 0, len(ns))
+    for i := range ns { items = append(items, listItem{ns[i], es[i]}) }
+    return items
+}
+
+// listOrder sorts rows by key, descending when desc, then by node and edge ID
+// ascending, so every row has one place in the order and a cursor can name the row
+// it stopped at.
+type listOrder struct {
+    name string
+    key  func(it listItem) listKey
+    desc bool
+}
+
+// listKey is a row's place in an order: Num, then Str, then the IDs.
+type listKey struct {
+    Num  float64 `json:"n,omitempty"`
+    Str  string  `json:"s,omitempty"`
+    ID   string  `json:"id"`
+    Edge string  `json:"e,omitempty"`
+}
+
+// keyOf keys it under o; the zero order (store order) keys by the IDs alone.
+func (o listOrder) keyOf(it listItem) listKey {
+    var k listKey
+    if o.key != nil { k = o.key(it) }
+    k.ID = it.n.ID
+    if it.e != nil { k.Edge = it.e.ID }
+    return k
+}
+
+// before reports whether a row keyed a sorts before one keyed b.
+func (o listOrder) before(a, b listKey) bool {
+    if a.Num != b.Num { return (a.Num < b.Num) != o.desc }
+    if a.Str != b.Str { return (a.Str < b.Str) != o.desc }
+    if a.ID != b.ID { return a.ID < b.ID }
+    return a.Edge < b.Edge
+}
+
+func byTitle(it listItem) listKey { return listKey{Str: strings.ToLower(it.n.Title)} }
+
+func byID(it listItem) listKey { return listKey{Str: it.n.ID} }
+
+// commonOrders are accepted by every list.
+var commonOrders = []listOrder{
+    {"title", byTitle, false}, {"-title", byTitle, true},
+    {"id", byID, false}, {"-id", byID, true},
+}
+
+// listSpec is what differs between lists: orders of their own and the default.
+type listSpec struct {
+    orders      []listOrder // accepted besides commonOrders
+    defaultSort string      // "" keeps the order the store returned
+}
+
+func (spec listSpec) order(name string) (listOrder, bool) {
+    for _, o := range append(spec.orders, commonOrders...) {
+        if o.name == name { return o, true }
+    }
+    return listOrder{}, false
+}
+
+// listQuery holds the parsed list parameters.
+type listQuery struct {
+    labels        map[string]struct{}
+    pin, context  string // lower-cased substrings of the edge's pin_cite and context
+    sort          string
+    limit, offset int
+    fields        map[string]struct{}
+    countOnly     bool
+    cursor        *cursorState // the page continues after cursor.After
+    params        string       // listFingerprint, carried by the next cursor
+    gen           int64        // rows added after this store change seq are hidden
+    added         func(nodeID, edgeID string) int64
+    cursors       *cursorCodec // nil issues no next cursor
+    principal     *auth.Principal // rows outside its scope are hidden
+}
+
+// parseList reads labels, pin_cite_contains, context_contains, sort, limit, cursor or
+// offset, fields and count_only. A cursor must verify and have been issued for the
+// same parameters; the list is then read as of the store generation of its first page.
+func (s *Server) parseList(spec listSpec, p *params, r *http.Request) listQuery {
+    var names []string
+    for _, o := range append(spec.orders, commonOrders...) { names = append(names, o.name) }
+    lq := listQuery{
+        labels:  p.set("labels"),
+        pin:     strings.ToLower(p.str("pin_cite_contains")),
+        context: strings.ToLower(p.str("context_contains")),
+        sort:    p.enum("sort", spec.defaultSort, names...),
+        limit:   p.intRange("limit", defaultListLimit, 1, maxListLimit),
+        params:  listFingerprint(r),
+        gen:     s.store.LastSeq(),
+        added:   s.store.AddedSeq,
+        cursors:   &s.cursors,
+        principal: auth.FromContext(r.Context()),
+    }
+    if cur := p.str("cursor"); cur != "" {
+        st, err := s.cursors.decode(cur, lq.params)
+        if err != nil {
+            p.reject(invalidParam{Name: "cursor", Value: cur, Reason: err.Error() + "; start again without cursor"})
+        } else if !s.store.TracksAddedSince(st.Gen) {
+            p.reject(invalidParam{Name: "cursor", Value: cur, Reason: "cursor expired: the store has changed too much since its first page; start again without cursor"})
+        } else {
+            lq.cursor, lq.gen = &st, st.Gen
+        }
+    } else {
+        lq.offset = p.intRange("offset", 0, 0, -1)
+    }
+    lq.fields = p.set("fields", nodeFields...)
+    lq.countOnly = p.boolean("count_only", false)
+    return lq
+}
+
+// listResult is a filtered, sorted list and the requested page of it.
+type listResult struct {
+    total int
+    page  []listItem
+    end   int    // offset just past page
+    next  string // cursor for the page after, "" on the last page
+}
+
+// run filters, sorts and pages items. With a cursor the page starts after the
+// cursor's row: by key when the list is sorted, else just past the row itself, or at
+// the cursor's offset when that row is gone.
+func (spec listSpec) run(lq listQuery, items []listItem) listResult {
+    kept := items[:0:0]
+    for _, it := range items {
+        if lq.keep(it) { kept = append(kept, it) }
+    }
+    o, sorted := spec.order(lq.sort)
+    if sorted {
+        sort.SliceStable(kept, func(i, j int) bool { return o.before(o.keyOf(kept[i]), o.keyOf(kept[j])) })
+    }
+    start := lq.offset
+    if c := lq.cursor; c != nil && sorted {
+        start = sort.Search(len(kept), func(i int) bool { return o.before(c.After, o.keyOf(kept[i])) })
+    } else if c != nil {
+        start = c.Offset
+        for i, it := range kept {
+            if o.keyOf(it) == c.After { start = i + 1; break }
+        }
+    }
+    if start > len(kept) { start = len(kept) }
+    end := start + lq.limit
+    if end > len(kept) { end = len(kept) }
+    res := listResult{total: len(kept), page: kept[start:end], end: end}
+    if end < len(kept) && lq.cursors != nil {
+        res.next = lq.cursors.encode(cursorState{After: o.keyOf(kept[end-1]), Offset: end, Params: lq.params, Gen: lq.gen})
+    }
+    return res
+}
+
+// keep applies the label and edge filters; rows without an edge fail the edge filters.
+// Rows added to the store after lq.gen are hidden, so that a cursor's pages do not
+// shift when rows are inserted ahead of it, as are rows the principal is not licensed for.
+func (lq listQuery) keep(it listItem) bool {
+    if !lq.principal.Allows(scopeOf(it.n)) { return false }
+    if lq.added != nil {
+        edgeID := ""
+        if it.e != nil { edgeID = it.e.ID }
+        if lq.added(it.n.ID, edgeID) > lq.gen { return false }
+    }
+    if lq.labels != nil {
+        found := false
+        for _, l := range it.n.Labels { _, found = lq.labels[l]; if found { break } }
+        if !found { return false }
+    }
+    for prop, want := range map[string]string{"pin_cite": lq.pin, "context": lq.context} {
+        if want == "" { continue }
+        if it.e == nil { return false }
+        v, _ := it.e.Props[prop].(string)
+        if !strings.Contains(strings.ToLower(v), want) { return false }
+    }
+    return true
+}
+
+// listOut varies how writeList sends a page.
+type listOut struct {
+    anchors []*dgraph.Node                      // exported ahead of the page so its edges are not dangling
+    extra   map[string]any                      // added to the JSON envelope
+    body    func(res listResult) any            // replaces the JSON envelope
+    edges   func(it listItem) []*dgraph.Edge    // the edges sent with a row, instead of its one edge
+}
+
+// rowEdges is what writeList sends with it.
+func (out listOut) rowEdges(it listItem) []*dgraph.Edge {
+    if out.edges != nil { return out.edges(it) }
+    if it.e == nil { return nil }
+    return []*dgraph.Edge{it.e}
+}
+
+// writeList sends {"total"} for count_only, an export for format= or an export Accept,
+// NDJSON (node then edge per row) for Accept application/x-ndjson, and otherwise
+// {"nodes", "edges", "total", "next_offset", "next_cursor"}. X-Total-Count is always set.
+func writeList(w http.ResponseWriter, r *http.Request, lq listQuery, res listResult, out listOut) {
+    if lq.countOnly {
+        w.Header().Set("X-Total-Count", strconv.Itoa(res.total))
+        writeJSON(w, http.StatusOK, map[string]any{"total": res.total})
+        return
+    }
+    if f, want, ok := exportFormat(w, r); !ok {
+        return
+    } else if want {
+        xn := append([]*dgraph.Node{}, out.anchors...)
+        var xe []*dgraph.Edge
+        for _, it := range res.page {
+            xn = append(xn, it.n)
+            xe = append(xe, out.rowEdges(it)...)
+        }
+        w.Header().Set("X-Total-Count", strconv.Itoa(res.total))
+        writeExport(w, r, f, xn, xe)
+        return
+    }
+    if r.URL.Query().Get("format") == "" && wantsNDJSON(r) {
+        ndjsonPageHeaders(w, res.total, res.next)
+        nw := newNDJSONWriter(w)
+        for _, it := range res.page {
+            if nw.node(it.n) != nil { return }
+            for _, e := range out.rowEdges(it) {
+                if nw.edge(e) != nil { return }
+            }
+        }
+        nw.flush()
+        return
+    }
+    w.Header().Set("X-Total-Count", strconv.Itoa(res.total))
+    if out.body != nil {
+        writeJSON(w, http.StatusOK, out.body(res))
+        return
+    }
+    nodes := make([]dgraph.NodeDTO, 0, len(res.page))
+    edges := make([]dgraph.EdgeDTO, 0, len(res.page))
+    for _, it := range res.page {
+        dto := nodeToDTO(it.n)
+        if lq.fields != nil { dto = filterNodeFields(dto, lq.fields) }
+        nodes = append(nodes, dto)
+        for _, e := range out.rowEdges(it) { edges = append(edges, edgeToDTO(e)) }
+    }
+    resp := map[string]any{"nodes": nodes, "edges": edges, "total": res.total, "next_offset": nil, "next_cursor": nil}
+    if res.next != "" {
+        resp["next_offset"] = res.end
+        resp["next_cursor"] = res.next
+    }
+    for k, v := range out.extra { resp[k] = v }
+    writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestListRun(t *testing.T) {
+    node := func(id, title, label string) *dgraph.Node { return &dgraph.Node{ID: id, Title: title, Labels: []string{label}} }
+    cites := func(order float64, pin string) *dgraph.Edge { return &dgraph.Edge{EdgeType: "CITES", Props: map[string]any{"order": order, "pin_cite": pin}} }
+    items := []listItem{
+        {node("c", "Alpha", "OPINION"), cites(2, "§3342(a)")},
+        {node("a", "charlie", "RULE"), cites(3, "§3342(b)")},
+        {node("b", "Bravo", "OPINION"), cites(1, "")},
+        {node("d", "Delta", "OPINION"), nil},
+    }
+    spec := listSpec{orders: []listOrder{{"order", byOrder, false}}}
+    cases := []struct {
+        name string
+        lq   listQuery
+        want string // page IDs
+        total, end int
+    }{
+        {"store order", listQuery{limit: 10}, "c,a,b,d", 4, 4},
+        {"title ignores case", listQuery{sort: "title", limit: 10}, "c,b,a,d", 4, 4},
+        {"-id", listQuery{sort: "-id", limit: 10}, "d,c,b,a", 4, 4},
+        {"labels", listQuery{labels: map[string]struct{}{"OPINION": {}}, sort: "id", limit: 10}, "b,c,d", 3, 3},
+        {"pin_cite drops rows without an edge", listQuery{pin: "(b)", limit: 10}, "a", 1, 1},
+        {"page", listQuery{sort: "id", limit: 2, offset: 1}, "b,c", 4, 3},
+        {"offset past the end", listQuery{limit: 2, offset: 9}, "", 4, 4},
+        {"endpoint order", listQuery{sort: "order", labels: map[string]struct{}{"OPINION": {}, "RULE": {}}, pin: "§", limit: 10}, "c,a", 2, 2},
+    }
+    codec := newCursorCodec("test")
+    for _, c := range cases {
+        c.lq.cursors = &codec
+        res := spec.run(c.lq, items)
+        var ids []string
+        for _, it := range res.page { ids = append(ids, it.n.ID) }
+        if strings.Join(ids, ",") != c.want || res.total != c.total || res.end != c.end { t.Errorf("%s: page=%v total=%d end=%d", c.name, ids, res.total, res.end) }
+        if (res.next != "") != (res.end < res.total) { t.Errorf("%s: next cursor %q", c.name, res.next) }
+    }
+    if items[0].n.ID != "c" { t.Error("run reordered its input") }
+}
+
+func TestListCursorSurvivesMutation(t *testing.T) {
+    node := func(id string) *dgraph.Node { return &dgraph.Node{ID: id, Title: id} }
+    items := []listItem{{node("a"), nil}, {node("b"), nil}, {node("c"), nil}, {node("d"), nil}}
+    // "b", the last row of the first page, is deleted and "a2" inserted ahead of it
+    mutated := []listItem{items[0], {node("a2"), nil}, items[2], items[3]}
+    codec := newCursorCodec("test")
+    for _, spec := range []listSpec{idList, {}} {
+        lq := listQuery{sort: spec.defaultSort, limit: 2, cursors: &codec, params: "p"}
+        st, err := codec.decode(spec.run(lq, items).next, "p")
+        if err != nil { t.Fatalf("sort %q: %v", lq.sort, err) }
+        lq.cursor = &st
+        var ids []string
+        for _, it := range spec.run(lq, mutated).page { ids = append(ids, it.n.ID) }
+        if strings.Join(ids, ",") != "c,d" { t.Errorf("sort %q: second page %v, want c,d", lq.sort, ids) }
+    }
+    next := idList.run(listQuery{sort: "id", limit: 1, cursors: &codec, params: "p"}, items).next
+    if _, err := codec.decode(next, "q"); err != errCursorParams { t.Errorf("other params: %v", err) }
+    if _, err := newCursorCodec("other").decode(next, "p"); err != errCursorSignature { t.Errorf("other key: %v", err) }
+    forged := "x" + next[1:]
+    if next[0] == 'x' { forged = "y" + next[1:] }
+    if _, err := codec.decode(forged, "p"); err != errCursorSignature { t.Errorf("forged: %v", err) }
+}
@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+// ndjsonFlushEvery bounds how many lines sit in the response buffer before a flush.
+const ndjsonFlushEvery = 64
+
+// wantsNDJSON reports whether the Accept header asks for application/x-ndjson.
+func wantsNDJSON(r *http.Request) bool {
+    for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+        if mt, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mt == "application/x-ndjson" { return true }
+    }
+    return false
+}
+
+// ndjsonWriter streams one node or edge per line in the item format LoadJSONL reads,
+// so a response body can be saved and re-imported as is.
+type ndjsonWriter struct {
+    jw      *graphrepo.JSONLWriter
+    flusher http.Flusher
+    pending int
+}
+
+// newNDJSONWriter sends the headers; set X-Total-Count and friends before calling it.
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+    w.Header().Set("Content-Type", "application/x-ndjson")
+    addVary(w.Header(), "Accept")
+    w.WriteHeader(http.StatusOK)
+    nw := &ndjsonWriter{jw: graphrepo.NewJSONLWriter(w)}
+    nw.flusher, _ = w.(http.Flusher)
+    return nw
+}
+
+func (nw *ndjsonWriter) node(n *dgraph.Node) error {
+    if err := nw.jw.WriteNode(n); err != nil { return err }
+    nw.tick()
+    return nil
+}
+
+func (nw *ndjsonWriter) edge(e *dgraph.Edge) error {
+    if err := nw.jw.WriteEdge(e); err != nil { return err }
+    nw.tick()
+    return nil
+}
+
+func (nw *ndjsonWriter) tick() {
+    if nw.pending++; nw.pending >= ndjsonFlushEvery { nw.flush() }
+}
+
+func (nw *ndjsonWriter) flush() {
+    if nw.flusher != nil { nw.flusher.Flush() }
+    nw.pending = 0
+}
+
+// ndjsonPageHeaders carries the paging fields of the JSON envelope for list endpoints:
+// X-Total-Count, and X-Next-Cursor unless this is the last page.
+func ndjsonPageHeaders(w http.ResponseWriter, total int, next string) {
+    w.Header().Set("X-Total-Count", strconv.Itoa(total))
+    if next != "" { w.Header().Set("X-Next-Cursor", next) }
+}
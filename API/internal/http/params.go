@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// nodeFields are the NodeDTO keys fields= may name.
+var nodeFields = []string{"id", "labels", "title", "citation", "text", "props", "version", "sources"}
+
+// invalidParam is one rejected query parameter in a 400's details.
+type invalidParam struct {
+    Name    string   `json:"name"`
+    Value   string   `json:"value"`
+    Reason  string   `json:"reason"`
+    Allowed []string `json:"allowed,omitempty"`
+    Min     *int     `json:"min,omitempty"`
+    Max     *int     `json:"max,omitempty"`
+}
+
+// params reads query parameters strictly. Each getter returns the default for an
+// absent parameter and records a present but invalid one, so a request with
+// several mistakes gets all of them back in one 400 from ok.
+type params struct {
+    q       url.Values
+    invalid []invalidParam
+}
+
+func newParams(r *http.Request) *params { return &params{q: r.URL.Query()} }
+
+// str returns a free-form parameter as given.
+func (p *params) str(name string) string { return p.q.Get(name) }
+
+func (p *params) reject(ip invalidParam) { p.invalid = append(p.invalid, ip) }
+
+// intRange reads an integer in [min, max]; max < min means no upper bound.
+func (p *params) intRange(name string, def, min, max int) int {
+    v := p.q.Get(name)
+    if v == "" { return def }
+    n, err := strconv.Atoi(v)
+    if err == nil && n >= min && (max < min || n <= max) { return n }
+    ip := invalidParam{Name: name, Value: v, Min: &min}
+    if max < min {
+        ip.Reason = fmt.Sprintf("%s must be an integer of at least %d", name, min)
+    } else {
+        ip.Reason = fmt.Sprintf("%s must be an integer between %d and %d", name, min, max)
+        ip.Max = &max
+    }
+    p.reject(ip)
+    return def
+}
+
+// enum reads one of allowed.
+func (p *params) enum(name, def string, allowed ...string) string {
+    v := p.q.Get(name)
+    if v == "" { return def }
+    for _, a := range allowed {
+        if v == a { return v }
+    }
+    p.reject(invalidParam{Name: name, Value: v, Reason: fmt.Sprintf("%s must be one of %s", name, strings.Join(allowed, ", ")), Allowed: allowed})
+    return def
+}
+
+// boolean reads true|false|1|0.
+func (p *params) boolean(name string, def bool) bool {
+    switch v := strings.ToLower(p.q.Get(name)); v {
+    case "": return def
+    case "true", "1": return true
+    case "false", "0": return false
+    }
+    p.reject(invalidParam{Name: name, Value: p.q.Get(name), Reason: name + " must be true or false", Allowed: []string{"true", "false", "1", "0"}})
+    return def
+}
+
+// set reads a comma-separated list; unless allowed is empty every entry must be
+// one of allowed. It is nil when the parameter is absent.
+func (p *params) set(name string, allowed ...string) map[string]struct{} {
+    v := p.q.Get(name)
+    if v == "" { return nil }
+    out := make(map[string]struct{})
+    var bad []string
+    for _, e := range strings.Split(v, ",") {
+        e = strings.TrimSpace(e)
+        if e == "" { continue }
+        known := len(allowed) == 0
+        for _, a := range allowed { known = known || e == a }
+        if !known { bad = append(bad, e) }
+        out[e] = struct{}{}
+    }
+    if len(bad) > 0 {
+        p.reject(invalidParam{Name: name, Value: v, Reason: fmt.Sprintf("%s has unknown values %s; allowed: %s", name, strings.Join(bad, ", "), strings.Join(allowed, ", ")), Allowed: allowed})
+    }
+    return out
+}
+
+// ok writes the 400 for any invalid parameters and reports whether there were none.
+func (p *params) ok(w http.ResponseWriter) bool {
+    if len(p.invalid) == 0 { return true }
+    writeInvalidParams(w, p.invalid...)
+    return false
+}
+
+// writeInvalidParams writes a 400 whose message joins the reasons and whose
+// details list each parameter.
+func writeInvalidParams(w http.ResponseWriter, invalid ...invalidParam) {
+    reasons := make([]string, 0, len(invalid))
+    for _, ip := range invalid { reasons = append(reasons, ip.Reason) }
+    writeError(w, http.StatusBadRequest, "bad_request", strings.Join(reasons, "; "), invalid)
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+
+    "lawmap/internal/services/auth"
+)
+
+// router registers method patterns ("GET /nodes/{id}/children") on a Go 1.22
+// ServeMux and fills in what ServeMux answers in plain text: a JSON 405 with Allow
+// for other methods on a known path, OPTIONS with the same Allow list, and a JSON
+// 404 for unknown paths. GET patterns also serve HEAD (net/http drops the body).
+// Errors are RFC 7807 problems for clients that accept them (negotiateErrors).
+//
+// Path wildcards match one segment and are unescaped, so IDs containing "/" are
+// sent as %2F ("/nodes/A%2FB/children"); "§" and spaces may be sent raw or escaped,
+// except next to a %2F: net/url ignores the escaping of a path that also holds raw
+// non-ASCII bytes, so escape the whole ID then.
+type router struct {
+    mux     *http.ServeMux
+    methods []string // every method some route uses
+    guard   func(role auth.Role, h http.HandlerFunc) http.HandlerFunc // checks credentials; nil leaves routes open
+}
+
+func newRouter(mux *http.ServeMux) *router { return &router{mux: mux} }
+
+// route collects what routeOptions set for one route.
+type route struct {
+    compress compression
+    role     auth.Role // needed with a guard; None means reader
+    public   bool      // skips the guard
+}
+
+// routeOption adjusts one route at registration.
+type routeOption func(*route)
+
+// uncompressed turns response compression off for a route.
+func uncompressed(rt *route) { rt.compress.off = true }
+
+// compressAbove compresses a route's responses from n bytes on instead of defaultCompressMin.
+func compressAbove(n int) routeOption { return func(rt *route) { rt.compress.min = n } }
+
+// handle registers h for a "METHOD /path" pattern. Responses are compressed (see
+// compress) and, with a guard, callers need at least the reader role, unless an
+// option says otherwise.
+func (rt *router) handle(pattern string, h http.HandlerFunc, opts ...routeOption) {
+    method, _, ok := strings.Cut(pattern, " ")
+    if !ok { panic("httpapi: route without method: " + pattern) }
+    seen := false
+    for _, m := range rt.methods { seen = seen || m == method }
+    if !seen { rt.methods = append(rt.methods, method) }
+    var ro route
+    for _, o := range opts { o(&ro) }
+    if rt.guard != nil && !ro.public {
+        if ro.role == auth.None { ro.role = auth.Reader }
+        h = rt.guard(ro.role, h)
+    }
+    rt.mux.HandleFunc(pattern, compress(ro.compress, negotiateErrors(h)))
+}
+
+// finish registers the catch-all that answers requests no route matched; call
+// it after the last handle.
+func (rt *router) finish() { rt.mux.HandleFunc("/", compress(compression{}, negotiateErrors(rt.fallback))) }
+
+// fallback asks the mux which methods would have matched the path: none is a 404,
+// otherwise OPTIONS gets the Allow list and anything else a 405 with it.
+func (rt *router) fallback(w http.ResponseWriter, r *http.Request) {
+    var allowed []string
+    for _, m := range rt.methods {
+        probe := *r
+        probe.Method = m
+        if _, pattern := rt.mux.Handler(&probe); pattern != "" && pattern != "/" { allowed = append(allowed, m) }
+    }
+    if len(allowed) == 0 {
+        writeError(w, http.StatusNotFound, "not_found", "No route for "+r.URL.Path, nil)
+        return
+    }
+    allow := allowList(allowed)
+    w.Header().Set("Allow", allow)
+    if r.Method == http.MethodOptions {
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+    writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("%s is not allowed here; use %s", r.Method, allow), map[string]string{"allow": allow})
+}
+
+// allowList is the Allow header for a path's methods, with HEAD after GET and OPTIONS last.
+func allowList(methods []string) string {
+    var out []string
+    for _, m := range methods {
+        out = append(out, m)
+        if m == http.MethodGet { out = append(out, http.MethodHead) }
+    }
+    return strings.Join(append(out, http.MethodOptions), ", ")
+}
+
+// withID adapts a node handler to the {id} path wildcard.
+func withID(h func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id := r.PathValue("id")
+        if id == "" {
+            writeError(w, http.StatusBadRequest, "bad_request", "missing id", nil)
+            return
+        }
+        h(w, r, id)
+    }
+}
@@ -1,22 +1,37 @@
 package httpapi
 
 import (
+    "bytes"
     "fmt"
+    "net"
     "net/http"
+    "net/netip"
     "os"
     "strconv"
     "strings"
-    "sort"
-    "encoding/base64"
+    "sync"
 
     dgraph "lawmap/internal/domain/graph"
+    "lawmap/internal/export"
+    "lawmap/internal/etl/scheduler"
     graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/repo/index"
+    "lawmap/internal/services/documents"
+    "lawmap/internal/services/auth"
+    "lawmap/internal/services/webhooks"
     conf "lawmap/internal/config"
 )
 
 type Server struct {
     store   *graphrepo.MemoryStore
     sources []sourceDesc
+    similarMu  sync.Mutex
+    similar    *index.Similarity
+    similarGen string // store generation the index was built at
+    scheduler *scheduler.Scheduler
+    webhooks  *webhooks.Service
+    cursors   cursorCodec
+    auth      *auth.Service
 }
 
 func NewServer(store *graphrepo.MemoryStore, sourcesCfg []conf.SourceDescriptor) *Server {
@@ -27,19 +42,62 @@ func NewServer(store *graphrepo.MemoryStore, sourcesCfg []conf.SourceDescriptor)
             Name: s.Name, Jurisdictions: s.Jurisdictions, Codes: s.Codes, Kind: s.Kind, URLs: s.URLs,
         })
     }
-    return &Server{store: store, sources: sdescs}
+    return &Server{store: store, sources: sdescs, cursors: newCursorCodec("")}
 }
 
+// similarity is the TF-IDF index over the store's nodes, rebuilt on first use after
+// each write or load so that it never offers deleted nodes or misses new ones.
+func (s *Server) similarity() *index.Similarity {
+    seq, at := s.store.Generation()
+    gen := fmt.Sprintf("%d.%d", seq, at.UnixNano())
+    s.similarMu.Lock()
+    defer s.similarMu.Unlock()
+    if s.similar == nil || s.similarGen != gen { s.similar, s.similarGen = index.NewSimilarity(s.store.Nodes()), gen }
+    return s.similar
+}
+
+// SetCursorSecret sets the key list cursors are signed with. Without one a random key
+// is used, and cursors do not survive a restart or work across replicas.
+func (s *Server) SetCursorSecret(secret string) { s.cursors = newCursorCodec(secret) }
+
+// Routes registers every endpoint on mux by method and path pattern (see router).
 func (s *Server) Routes(mux *http.ServeMux) {
-    mux.HandleFunc("/health", s.handleHealth)
-    mux.HandleFunc("/sources", s.handleSources)
-    mux.HandleFunc("/topics", s.handleTopics)
-    mux.HandleFunc("/topics/", s.handleTopics)
-    mux.HandleFunc("/nodes/", s.handleNodes)
-    mux.HandleFunc("/graph", s.handleGraph)
-    mux.HandleFunc("/search", s.handleSearch)
-    mux.HandleFunc("/diff/", s.handleDiff)
-    mux.HandleFunc("/versions/", s.handleVersions)
+    rt := newRouter(mux)
+    if s.auth != nil { rt.guard = s.authorize }
+    rt.handle("GET /health", s.handleHealth, uncompressed, public)
+    rt.handle("GET /sources", conditional(s.storeState, s.handleSources))
+    rt.handle("GET /topics", conditional(s.storeState, s.handleTopics))
+    rt.handle("GET /topics/{id}", conditional(s.storeState, withID(s.handleTopic)))
+    rt.handle("GET /nodes/{id}", conditional(s.nodeState, withID(s.handleNode)))
+    rt.handle("GET /nodes/{id}/children", conditional(s.storeState, withID(s.handleNodeChildren)))
+    rt.handle("GET /nodes/{id}/parents", conditional(s.storeState, withID(s.handleNodeParents)))
+    rt.handle("GET /nodes/{id}/citations", conditional(s.storeState, withID(s.handleNodeCitations)))
+    rt.handle("GET /nodes/{id}/citers", conditional(s.storeState, withID(s.handleNodeCitations)))
+    rt.handle("GET /nodes/{id}/cites", conditional(s.storeState, withID(s.handleNodeCites)))
+    rt.handle("GET /nodes/{id}/amendments", conditional(s.storeState, withID(s.handleNodeAmendments)))
+    rt.handle("GET /nodes/{id}/siblings", conditional(s.storeState, withID(s.handleNodeSiblings)))
+    rt.handle("GET /nodes/{id}/neighbors", conditional(s.storeState, withID(s.handleNodeNeighbors)))
+    rt.handle("GET /nodes/{id}/document", conditional(s.storeState, withID(s.handleNodeDocument)))
+    rt.handle("GET /nodes/{id}/similar", conditional(s.storeState, withID(s.handleNodeSimilar)))
+    rt.handle("GET /parents", conditional(s.storeState, s.handleParentsBatch))
+    rt.handle("GET /graph", conditional(s.storeState, s.handleGraph))
+    rt.handle("GET /search", conditional(s.storeState, s.handleSearch))
+    rt.handle("GET /diff/{id}", conditional(s.historyState, withID(s.handleDiff)))
+    rt.handle("GET /versions/{id}", conditional(s.historyState, withID(s.handleVersions)))
+    rt.handle("GET /changes", conditional(s.storeState, s.handleChanges))
+    rt.handle("GET /changes/stream", s.handleChangesStream, uncompressed)
+    rt.handle("GET /subscriptions", s.handleSubscriptionList, requires(auth.Editor))
+    rt.handle("POST /subscriptions", s.handleSubscribe, requires(auth.Editor))
+    rt.handle("GET /subscriptions/{id}", withID(s.handleSubscription), requires(auth.Editor))
+    rt.handle("DELETE /subscriptions/{id}", withID(s.handleUnsubscribe), requires(auth.Editor))
+    rt.handle("GET /subscriptions/dead-letters", s.handleDeadLetters, requires(auth.Editor))
+    rt.handle("POST /subscriptions/dead-letters/{id}/retry", withID(s.handleRedeliver), requires(auth.Editor))
+    rt.handle("GET /admin/runs", s.handleAdminRuns, requires(auth.Admin))
+    rt.handle("POST /admin/runs", s.handleAdminTrigger, requires(auth.Admin))
+    rt.handle("GET /admin/sources", s.handleAdminSources, requires(auth.Admin))
+    rt.handle("POST /admin/sources/pause", s.handleAdminPause, requires(auth.Admin))
+    rt.handle("POST /admin/sources/resume", s.handleAdminPause, requires(auth.Admin))
+    rt.finish()
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -75,53 +133,38 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
     writeJSON(w, http.StatusOK, map[string]any{"sources": sources})
 }
 
-func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
-    path := strings.TrimPrefix(r.URL.Path, "/nodes/")
-    if path == "" || path == r.URL.Path {
-        writeError(w, http.StatusBadRequest, "bad_request", "missing id", nil)
-        return
-    }
-    if strings.HasSuffix(path, "/children") {
-        id := strings.TrimSuffix(path, "/children")
-        s.handleNodeChildren(w, r, id)
-        return
-    }
-    if strings.HasSuffix(path, "/parents") {
-        id := strings.TrimSuffix(path, "/parents")
-        s.handleNodeParents(w, r, id)
-        return
-    }
-    if strings.HasSuffix(path, "/citations") || strings.HasSuffix(path, "/citers") {
-        id := strings.TrimSuffix(path, "/citations")
-        id = strings.TrimSuffix(id, "/citers")
-        s.handleNodeCitations(w, r, id)
-        return
-    }
-    if strings.HasSuffix(path, "/cites") {
-        id := strings.TrimSuffix(path, "/cites")
-        s.handleNodeCites(w, r, id)
-        return
-    }
-    id := path
+// handleNode serves one node, with optional expand= and fields=.
+func (s *Server) handleNode(w http.ResponseWriter, r *http.Request, id string) {
     n, ok := s.store.GetNode(id)
     if !ok {
         writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
         return
     }
+    p := newParams(r)
+    expand := p.set("expand", "parents", "children")
+    fs := p.set("fields", nodeFields...)
+    if !p.ok(w) { return }
+    // Linked-data clients dereference node IRIs with Accept: application/ld+json or text/turtle.
+    if f, want, ok := exportFormat(w, r); !ok {
+        return
+    } else if want {
+        var es []*dgraph.Edge
+        for _, e := range s.store.EdgesOf(id) {
+            if s.edgeVisible(r, e) { es = append(es, e) }
+        }
+        writeExport(w, r, f, []*dgraph.Node{n}, es)
+        return
+    }
     dto := nodeToDTO(n)
-    // Optional expansions and field selection
-    q := r.URL.Query()
-    expand := q.Get("expand")
-    fieldsParam := q.Get("fields")
     // If no expand and no fields selection, return DTO directly
-    if expand == "" && fieldsParam == "" {
+    if expand == nil && fs == nil {
         writeJSON(w, http.StatusOK, dto)
         return
     }
     // Build response map (enables field selection)
     resp := map[string]any{}
     // If fields specified, include only those keys; else include full NodeDTO fields
-    include := func(k string) bool { if fieldsParam == "" { return true }; for _, f := range strings.Split(fieldsParam, ",") { if strings.TrimSpace(f) == k { return true } }; return false }
+    include := func(k string) bool { if fs == nil { return true }; _, ok := fs[k]; return ok }
     if include("id") { resp["id"] = dto.ID }
     if include("labels") { resp["labels"] = dto.Labels }
     if include("title") { resp["title"] = dto.Title }
@@ -130,409 +173,452 @@ func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
     if include("props") { resp["props"] = dto.Props }
     if include("version") { resp["version"] = dto.Version }
     if include("sources") { resp["sources"] = dto.Sources }
-    switch expand {
-    case "parents":
-        nodes, edges := s.store.GetParentsPath(id)
-        resp["parents"] = dgraph.PathDTO{Nodes: nodes, Edges: edges}
-    case "children":
+    if _, ok := expand["parents"]; ok {
+        resp["parents"] = s.parentsPath(r, id, nil, false)
+    }
+    if _, ok := expand["children"]; ok {
         ns, es := s.store.GetChildren(id)
         cn := make([]dgraph.NodeDTO, 0, len(ns))
-        for _, n2 := range ns { cn = append(cn, nodeToDTO(n2)) }
         ce := make([]dgraph.EdgeDTO, 0, len(es))
-        for _, e := range es { ce = append(ce, edgeToDTO(e)) }
+        for i, n2 := range ns {
+            if !visible(r, n2) { continue }
+            cn = append(cn, nodeToDTO(n2))
+            ce = append(ce, edgeToDTO(es[i]))
+        }
         resp["children"] = dgraph.GraphSliceDTO{Nodes: cn, Edges: ce}
-    default:
-        // ignore unknown expand
     }
     writeJSON(w, http.StatusOK, resp)
 }
 
+// handleNodeChildren lists the PARENT_OF children of id, in order by default.
 func (s *Server) handleNodeChildren(w http.ResponseWriter, r *http.Request, id string) {
+    p := newParams(r)
+    lq := s.parseList(childrenList, p, r)
+    if !p.ok(w) { return }
     ns, es := s.store.GetChildren(id)
-    // Optional label filter and pagination
-    q := r.URL.Query()
-    labelsParam := q.Get("labels")
-    sortParam := q.Get("sort") // order|title|-title
-    haveFilter := labelsParam != ""
-    labelSet := make(map[string]struct{})
-    if haveFilter { for _, l := range strings.Split(labelsParam, ",") { labelSet[strings.TrimSpace(l)] = struct{}{} } }
-    type pair struct{ n *dgraph.Node; e *dgraph.Edge }
-    pairs := make([]pair, 0, len(ns))
-    for i := range ns {
-        keep := true
-        if haveFilter {
-            keep = false
-            for _, l := range ns[i].Labels { if _, ok := labelSet[l]; ok { keep = true; break } }
+    anchor, _ := s.store.GetNode(id)
+    writeList(w, r, lq, childrenList.run(lq, pairItems(ns, es)), listOut{anchors: nonNil(anchor)})
+}
+
+// childrenList sorts by the PARENT_OF edges' order prop by default.
+var childrenList = listSpec{orders: []listOrder{{"order", byOrder, false}}, defaultSort: "order"}
+
+func byOrder(it listItem) listKey {
+    o, _ := it.e.Props["order"].(float64)
+    return listKey{Num: o}
+}
+
+// nonNil lists n unless it is nil.
+func nonNil(n *dgraph.Node) []*dgraph.Node {
+    if n == nil { return nil }
+    return []*dgraph.Node{n}
+}
+
+func (s *Server) handleNodeParents(w http.ResponseWriter, r *http.Request, id string) {
+    p := newParams(r)
+    fs, expand := breadcrumbFields(p)
+    if !p.ok(w) { return }
+    writeJSON(w, http.StatusOK, s.parentsPath(r, id, fs, expand))
+}
+
+// handleParentsBatch returns ancestor paths for many nodes at once (e.g. a page of search results).
+// IDs come from repeated or comma-separated ids params.
+func (s *Server) handleParentsBatch(w http.ResponseWriter, r *http.Request) {
+    var ids []string
+    for _, v := range r.URL.Query()["ids"] {
+        for _, id := range strings.Split(v, ",") {
+            if id = strings.TrimSpace(id); id != "" { ids = append(ids, id) }
         }
-        if keep { pairs = append(pairs, pair{ns[i], es[i]}) }
-    }
-    // Sorting
-    switch sortParam {
-    case "title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) < strings.ToLower(pairs[j].n.Title) })
-    case "-title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) > strings.ToLower(pairs[j].n.Title) })
-    default: // "order" (edge props)
-        sort.SliceStable(pairs, func(i, j int) bool {
-            oi, oj := 0, 0
-            if v, ok := pairs[i].e.Props["order"].(float64); ok { oi = int(v) }
-            if v, ok := pairs[j].e.Props["order"].(float64); ok { oj = int(v) }
-            return oi < oj
-        })
     }
-    limit := 1000 // children usually small; cap to 1000
-    if lv := q.Get("limit"); lv != "" { if n, err := strconv.Atoi(lv); err == nil && n > 0 && n <= 1000 { limit = n } }
-    offset := 0
-    if cur := q.Get("cursor"); cur != "" {
-        if b, err := base64.URLEncoding.DecodeString(cur); err == nil {
-            s := string(b)
-            if strings.HasPrefix(s, "o:") {
-                if n, err := strconv.Atoi(strings.TrimPrefix(s, "o:")); err == nil && n >= 0 { offset = n }
-            }
+    if len(ids) == 0 {
+        writeError(w, http.StatusBadRequest, "bad_request", "ids is required", nil)
+        return
+    }
+    if len(ids) > maxBatchIDs {
+        writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("at most %d ids per request", maxBatchIDs), map[string]int{"max": maxBatchIDs, "got": len(ids)})
+        return
+    }
+    p := newParams(r)
+    fs, expand := breadcrumbFields(p)
+    if !p.ok(w) { return }
+    resp := dgraph.PathsDTO{Paths: make(map[string]dgraph.PathDTO, len(ids))}
+    for _, id := range ids {
+        // nodes outside the principal's scope are reported missing, as if unknown
+        if n, ok := s.store.GetNode(id); !ok || !visible(r, n) {
+            resp.Missing = append(resp.Missing, id)
+            continue
         }
-    } else if ov := q.Get("offset"); ov != "" { if n, err := strconv.Atoi(ov); err == nil && n >= 0 { offset = n } }
-    start := offset
-    if start > len(pairs) { start = len(pairs) }
-    end := start + limit
-    if end > len(pairs) { end = len(pairs) }
-    slice := pairs[start:end]
-    nodes := make([]dgraph.NodeDTO, 0, len(slice))
-    edges := make([]dgraph.EdgeDTO, 0, len(slice))
-    fieldsParam := q.Get("fields")
-    var fs map[string]struct{}
-    if fieldsParam != "" { fs = make(map[string]struct{}); for _, f := range strings.Split(fieldsParam, ",") { fs[strings.TrimSpace(f)] = struct{}{} } }
-    for _, pr := range slice {
-        ndto := nodeToDTO(pr.n)
-        if fs != nil { ndto = filterNodeFields(ndto, fs) }
-        nodes = append(nodes, ndto)
-        edges = append(edges, edgeToDTO(pr.e))
-    }
-    resp := map[string]any{
-        "nodes": nodes,
-        "edges": edges,
-        "total": len(pairs),
-        "next_offset": end,
-    }
-    if end >= len(pairs) {
-        resp["next_offset"] = nil
-        resp["next_cursor"] = nil
-    } else {
-        resp["next_cursor"] = base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("o:%d", end)))
+        resp.Paths[id] = s.parentsPath(r, id, fs, expand)
     }
-    w.Header().Set("X-Total-Count", strconv.Itoa(len(pairs)))
     writeJSON(w, http.StatusOK, resp)
 }
 
-func (s *Server) handleNodeParents(w http.ResponseWriter, r *http.Request, id string) {
-    nodes, edges := s.store.GetParentsPath(id)
-    writeJSON(w, http.StatusOK, dgraph.PathDTO{Nodes: nodes, Edges: edges})
+const maxBatchIDs = 100
+
+// breadcrumbFields reads expand=nodes and fields=. Asking for fields implies expand=nodes;
+// without fields the ancestors carry just what a breadcrumb needs.
+func breadcrumbFields(p *params) (map[string]struct{}, bool) {
+    expand := p.enum("expand", "", "nodes") == "nodes"
+    fs := p.set("fields", nodeFields...)
+    if fs != nil { return fs, true }
+    if !expand { return nil, false }
+    return map[string]struct{}{"id": {}, "labels": {}, "title": {}, "citation": {}}, true
 }
 
-func (s *Server) handleNodeCitations(w http.ResponseWriter, r *http.Request, id string) {
-    ns, es := s.store.GetCitations(id)
-    // Optional label filter
-    q := r.URL.Query()
-    labelsParam := q.Get("labels")
-    pinFilter := strings.ToLower(q.Get("pin_cite_contains"))
-    ctxFilter := strings.ToLower(q.Get("context_contains"))
-    sortParam := q.Get("sort") // title|-title|id|-id
-    haveFilter := labelsParam != ""
-    labelSet := make(map[string]struct{})
-    if haveFilter {
-        for _, l := range strings.Split(labelsParam, ",") { labelSet[strings.TrimSpace(l)] = struct{}{} }
-    }
-    type pair struct{ n *dgraph.Node; e *dgraph.Edge }
-    pairs := make([]pair, 0, len(ns))
-    for i := range ns {
-        keep := true
-        if haveFilter {
-            keep = false
-            for _, l := range ns[i].Labels { if _, ok := labelSet[l]; ok { keep = true; break } }
-        }
-        if keep && pinFilter != "" {
-            sVal := ""
-            if v, ok := es[i].Props["pin_cite"].(string); ok { sVal = strings.ToLower(v) }
-            keep = strings.Contains(sVal, pinFilter)
-        }
-        if keep && ctxFilter != "" {
-            sVal := ""
-            if v, ok := es[i].Props["context"].(string); ok { sVal = strings.ToLower(v) }
-            keep = strings.Contains(sVal, ctxFilter)
-        }
-        if keep { pairs = append(pairs, pair{ns[i], es[i]}) }
-    }
-    // Sorting
-    switch sortParam {
-    case "title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) < strings.ToLower(pairs[j].n.Title) })
-    case "-title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) > strings.ToLower(pairs[j].n.Title) })
-    case "-id":
-        sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].n.ID > pairs[j].n.ID })
-    case "id":
-        fallthrough
-    default:
-        sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].n.ID < pairs[j].n.ID })
-    }
-    // Pagination
-    limit := 20
-    if lv := q.Get("limit"); lv != "" { if n, err := strconv.Atoi(lv); err == nil && n > 0 && n <= 100 { limit = n } }
-    offset := 0
-    if cur := q.Get("cursor"); cur != "" {
-        if b, err := base64.URLEncoding.DecodeString(cur); err == nil {
-            s := string(b)
-            if strings.HasPrefix(s, "o:") {
-                if n, err := strconv.Atoi(strings.TrimPrefix(s, "o:")); err == nil && n >= 0 { offset = n }
-            }
+// parentsPath is id's ancestor path from the root. Above an ancestor outside the
+// principal's scope it is cut, so the path starts below the last one hidden.
+func (s *Server) parentsPath(r *http.Request, id string, fs map[string]struct{}, expand bool) dgraph.PathDTO {
+    nodes, edges := s.store.GetParentsPath(id)
+    for i := len(nodes) - 1; i >= 0; i-- {
+        if n, ok := s.store.GetNode(nodes[i]); ok && !visible(r, n) {
+            if i == len(nodes)-1 { nodes, edges = nil, nil; break }
+            nodes, edges = nodes[i+1:], edges[i+1:]
+            break
         }
-    } else if ov := q.Get("offset"); ov != "" { if n, err := strconv.Atoi(ov); err == nil && n >= 0 { offset = n } }
-    start := offset
-    if start > len(pairs) { start = len(pairs) }
-    end := start + limit
-    if end > len(pairs) { end = len(pairs) }
-    slice := pairs[start:end]
-    nodes := make([]dgraph.NodeDTO, 0, len(slice))
-    edges := make([]dgraph.EdgeDTO, 0, len(slice))
-    fieldsParam := q.Get("fields")
-    var fs map[string]struct{}
-    if fieldsParam != "" { fs = make(map[string]struct{}); for _, f := range strings.Split(fieldsParam, ",") { fs[strings.TrimSpace(f)] = struct{}{} } }
-    for _, pr := range slice {
-        ndto := nodeToDTO(pr.n)
-        if fs != nil { ndto = filterNodeFields(ndto, fs) }
-        nodes = append(nodes, ndto)
-        edges = append(edges, edgeToDTO(pr.e))
-    }
-    // Count-only fast path
-    if cv := strings.ToLower(q.Get("count_only")); cv == "true" || cv == "1" {
-        writeJSON(w, http.StatusOK, map[string]any{"total": len(pairs)})
+    }
+    p := dgraph.PathDTO{Nodes: nodes, Edges: edges}
+    if !expand { return p }
+    p.Items = make([]dgraph.NodeDTO, 0, len(nodes))
+    for _, nid := range nodes {
+        if n, ok := s.store.GetNode(nid); ok { p.Items = append(p.Items, filterNodeFields(nodeToDTO(n), fs)) }
+    }
+    return p
+}
+
+// handleNodeSiblings lists the other children of id's parent, in order by default.
+func (s *Server) handleNodeSiblings(w http.ResponseWriter, r *http.Request, id string) {
+    if _, ok := s.store.GetNode(id); !ok {
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
         return
     }
-    resp := map[string]any{
-        "nodes": nodes,
-        "edges": edges,
-        "total": len(pairs),
-        "next_offset": end,
+    p := newParams(r)
+    lq := s.parseList(childrenList, p, r)
+    if !p.ok(w) { return }
+    ns, es := s.store.GetSiblings(id)
+    writeList(w, r, lq, childrenList.run(lq, pairItems(ns, es)), listOut{})
+}
+
+// handleNodeNeighbors pages sequentially: scope=siblings stays within the parent,
+// scope=document follows document order across chapter boundaries.
+func (s *Server) handleNodeNeighbors(w http.ResponseWriter, r *http.Request, id string) {
+    if _, ok := s.store.GetNode(id); !ok {
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
+        return
     }
-    if end >= len(pairs) {
-        resp["next_offset"] = nil
-        resp["next_cursor"] = nil
+    p := newParams(r)
+    dir := p.enum("dir", "next", "prev", "next")
+    scope := p.enum("scope", "siblings", "siblings", "document")
+    n := p.intRange("n", 1, 1, 100)
+    if !p.ok(w) { return }
+    var ns []*dgraph.Node
+    if scope == "document" {
+        ns = s.store.DocumentNeighbors(id, dir == "prev", n)
     } else {
-        resp["next_cursor"] = base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("o:%d", end)))
+        ns = s.store.SiblingNeighbors(id, dir == "prev", n)
     }
-    w.Header().Set("X-Total-Count", strconv.Itoa(len(pairs)))
-    writeJSON(w, http.StatusOK, resp)
+    nodes := make([]dgraph.NodeDTO, 0, len(ns))
+    for _, nd := range ns { nodes = append(nodes, nodeToDTO(nd)) }
+    writeJSON(w, http.StatusOK, dgraph.NeighborsDTO{ID: id, Dir: dir, Scope: scope, Nodes: nodes})
 }
 
+// handleNodeCitations lists the nodes citing id with their CITES edges.
+func (s *Server) handleNodeCitations(w http.ResponseWriter, r *http.Request, id string) {
+    p := newParams(r)
+    lq := s.parseList(idList, p, r)
+    if !p.ok(w) { return }
+    ns, es := s.store.GetCitations(id)
+    anchor, _ := s.store.GetNode(id)
+    writeList(w, r, lq, idList.run(lq, pairItems(ns, es)), listOut{anchors: nonNil(anchor)})
+}
+
+// handleNodeCites lists the nodes id cites with their CITES edges.
 func (s *Server) handleNodeCites(w http.ResponseWriter, r *http.Request, id string) {
+    p := newParams(r)
+    lq := s.parseList(idList, p, r)
+    if !p.ok(w) { return }
     ns, es := s.store.GetOutgoingCitations(id)
+    anchor, _ := s.store.GetNode(id)
+    writeList(w, r, lq, idList.run(lq, pairItems(ns, es)), listOut{anchors: nonNil(anchor)})
+}
+
+// idList sorts by ID by default (citations, cites, search, topics).
+var idList = listSpec{defaultSort: "id"}
+
+// handleNodeAmendments lists the rulemaking history of a node: documents with AMENDS
+// edges to it and, unless ancestors=false, to its enclosing units, by publication date.
+func (s *Server) handleNodeAmendments(w http.ResponseWriter, r *http.Request, id string) {
+    anchor, ok := s.store.GetNode(id)
+    if !ok {
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
+        return
+    }
+    p := newParams(r)
+    ancestors := p.boolean("ancestors", true)
+    typeFilter := p.str("document_type")
+    lq := s.parseList(amendmentList, p, r)
+    if !p.ok(w) { return }
+    ns, es := s.store.GetAmendments(id, ancestors)
+    items := make([]listItem, 0, len(ns))
+    for _, it := range pairItems(ns, es) {
+        if typeFilter == "" || it.e.Props["document_type"] == typeFilter { items = append(items, it) }
+    }
+    writeList(w, r, lq, amendmentList.run(lq, items), listOut{anchors: []*dgraph.Node{anchor}})
+}
+
+// amendmentList sorts by publication date, oldest first, by default; equal dates go by ID.
+var amendmentList = listSpec{orders: []listOrder{{"date", byPublished, false}, {"-date", byPublished, true}}, defaultSort: "date"}
+
+func byPublished(it listItem) listKey { return listKey{Str: published(it)} }
+
+// published is the AMENDS edge's publication_date, else the document's effective date.
+func published(it listItem) string {
+    if v, ok := it.e.Props["publication_date"].(string); ok { return v }
+    if it.n.Version != nil { return it.n.Version.EffectiveDate }
+    return ""
+}
+
+// handleNodeDocument renders the PARENT_OF subtree under id as one ordered document.
+// The body is written entry by entry and flushed so large codes stream.
+func (s *Server) handleNodeDocument(w http.ResponseWriter, r *http.Request, id string) {
+    if _, ok := s.store.GetNode(id); !ok {
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
+        return
+    }
     q := r.URL.Query()
-    labelsParam := q.Get("labels")
-    pinFilter := strings.ToLower(q.Get("pin_cite_contains"))
-    ctxFilter := strings.ToLower(q.Get("context_contains"))
-    sortParam := q.Get("sort") // title|-title|id|-id
-    haveFilter := labelsParam != ""
-    labelSet := make(map[string]struct{})
-    if haveFilter { for _, l := range strings.Split(labelsParam, ",") { labelSet[strings.TrimSpace(l)] = struct{}{} } }
-    type pair struct{ n *dgraph.Node; e *dgraph.Edge }
-    pairs := make([]pair, 0, len(ns))
-    for i := range ns {
-        keep := true
-        if haveFilter {
-            keep = false
-            for _, l := range ns[i].Labels { if _, ok := labelSet[l]; ok { keep = true; break } }
+    if fv := q.Get("format"); fv == "akn" || (fv == "" && strings.Contains(r.Header.Get("Accept"), "application/akn+xml")) {
+        all, allEdges, err := s.store.Subtree(id)
+        if err != nil {
+            writeError(w, http.StatusNotFound, "not_found", err.Error(), nil)
+            return
         }
-        if keep && pinFilter != "" {
-            sVal := ""
-            if v, ok := es[i].Props["pin_cite"].(string); ok { sVal = strings.ToLower(v) }
-            keep = strings.Contains(sVal, pinFilter)
+        // the subtree carries the nodes it cites, which may lie outside the principal's scope
+        var ns []*dgraph.Node
+        var es []*dgraph.Edge
+        for _, n := range all {
+            if visible(r, n) { ns = append(ns, n) }
         }
-        if keep && ctxFilter != "" {
-            sVal := ""
-            if v, ok := es[i].Props["context"].(string); ok { sVal = strings.ToLower(v) }
-            keep = strings.Contains(sVal, ctxFilter)
+        for _, e := range allEdges {
+            if s.edgeVisible(r, e) { es = append(es, e) }
         }
-        if keep { pairs = append(pairs, pair{ns[i], es[i]}) }
-    }
-    switch sortParam {
-    case "title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) < strings.ToLower(pairs[j].n.Title) })
-    case "-title":
-        sort.SliceStable(pairs, func(i, j int) bool { return strings.ToLower(pairs[i].n.Title) > strings.ToLower(pairs[j].n.Title) })
-    case "-id":
-        sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].n.ID > pairs[j].n.ID })
-    case "id":
-        fallthrough
-    default:
-        sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].n.ID < pairs[j].n.ID })
-    }
-    limit := 20
-    if lv := q.Get("limit"); lv != "" { if n, err := strconv.Atoi(lv); err == nil && n > 0 && n <= 100 { limit = n } }
-    offset := 0
-    if cur := q.Get("cursor"); cur != "" {
-        if b, err := base64.URLEncoding.DecodeString(cur); err == nil {
-            s := string(b)
-            if strings.HasPrefix(s, "o:") {
-                if n, err := strconv.Atoi(strings.TrimPrefix(s, "o:")); err == nil && n >= 0 { offset = n }
-            }
+        var buf bytes.Buffer
+        if err := export.WriteAkomaNtoso(&buf, id, ns, es, export.Options{BaseURL: baseURL(r)}); err != nil {
+            writeError(w, http.StatusBadRequest, "bad_request", err.Error(), nil)
+            return
         }
-    } else if ov := q.Get("offset"); ov != "" { if n, err := strconv.Atoi(ov); err == nil && n >= 0 { offset = n } }
-    start := offset
-    if start > len(pairs) { start = len(pairs) }
-    end := start + limit
-    if end > len(pairs) { end = len(pairs) }
-    slice := pairs[start:end]
-    nodes := make([]dgraph.NodeDTO, 0, len(slice))
-    edges := make([]dgraph.EdgeDTO, 0, len(slice))
-    fieldsParam := q.Get("fields")
-    var fs map[string]struct{}
-    if fieldsParam != "" { fs = make(map[string]struct{}); for _, f := range strings.Split(fieldsParam, ",") { fs[strings.TrimSpace(f)] = struct{}{} } }
-    for _, pr := range slice {
-        ndto := nodeToDTO(pr.n)
-        if fs != nil { ndto = filterNodeFields(ndto, fs) }
-        nodes = append(nodes, ndto)
-        edges = append(edges, edgeToDTO(pr.e))
-    }
-    if cv := strings.ToLower(q.Get("count_only")); cv == "true" || cv == "1" {
-        writeJSON(w, http.StatusOK, map[string]any{"total": len(pairs)})
+        w.Header().Set("Content-Type", export.AkomaNtosoContentType)
+        addVary(w.Header(), "Accept")
+        w.WriteHeader(http.StatusOK)
+        _, _ = w.Write(buf.Bytes())
         return
     }
-    resp := map[string]any{
-        "nodes": nodes,
-        "edges": edges,
-        "total": len(pairs),
-        "next_offset": end,
+    format, ok := documents.ParseFormat(q.Get("format"))
+    if !ok {
+        allowed := []string{"json", "markdown", "html", "text", "akn"}
+        writeInvalidParams(w, invalidParam{Name: "format", Value: q.Get("format"), Reason: "format must be one of " + strings.Join(allowed, ", "), Allowed: allowed})
+        return
     }
-    if end >= len(pairs) {
-        resp["next_offset"] = nil
-        resp["next_cursor"] = nil
-    } else {
-        resp["next_cursor"] = base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("o:%d", end)))
+    p := newParams(r)
+    opts := documents.Options{TOC: p.boolean("toc", false)}
+    if !p.ok(w) { return }
+    if f, ok := w.(http.Flusher); ok { opts.Flush = f.Flush }
+    walk := func(visit func(n *dgraph.Node, depth int) error) error {
+        return s.store.WalkSubtree(id, func(n *dgraph.Node, depth int) error {
+            if !visible(r, n) { return nil }
+            return visit(n, depth)
+        })
     }
-    w.Header().Set("X-Total-Count", strconv.Itoa(len(pairs)))
-    writeJSON(w, http.StatusOK, resp)
+    w.Header().Set("Content-Type", format.ContentType())
+    w.WriteHeader(http.StatusOK)
+    // headers are already sent; a failed write means the client went away
+    _ = documents.Render(w, format, walk, opts)
 }
 
+func (s *Server) handleNodeSimilar(w http.ResponseWriter, r *http.Request, id string) {
+    if _, ok := s.store.GetNode(id); !ok {
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
+        return
+    }
+    p := newParams(r)
+    limit := p.intRange("limit", 10, 1, 100)
+    if !p.ok(w) { return }
+    scoped := auth.FromContext(r.Context()).Scoped()
+    want := limit
+    if scoped { want = 0 } // rank everything, then drop what the principal may not see
+    matches := s.similarity().Similar(id, p.str("jurisdiction"), want)
+    items := make([]dgraph.SimilarItem, 0, len(matches))
+    for _, m := range matches {
+        // a node deleted since the index was read is dropped
+        n, ok := s.store.GetNode(m.ID)
+        if !ok || !visible(r, n) { continue }
+        it := dgraph.SimilarItem{ID: m.ID, Score: m.Score, Title: n.Title, Citation: n.Citation}
+        if items = append(items, it); len(items) == limit { break }
+    }
+    writeJSON(w, http.StatusOK, dgraph.SimilarResultDTO{ID: id, Items: items})
+}
+
+// handleGraph serves the nodes within depth PARENT_OF steps below root, breadth-first,
+// with every PARENT_OF edge the walk follows. labels filters the nodes but keeps the
+// root. The whole slice is sent unless the request asks for a page (limit, offset,
+// cursor, sort or count_only): NDJSON streams from the walk, exports and JSON carry
+// every node and edge.
 func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
-    q := r.URL.Query()
-    root := q.Get("root")
+    p := newParams(r)
+    root := p.str("root")
     if root == "" {
         writeError(w, http.StatusBadRequest, "bad_request", "root is required", nil)
         return
     }
-    depth := 1
-    if d := q.Get("depth"); d != "" {
-        if n, err := strconv.Atoi(d); err == nil && n >= 0 { depth = n }
+    depth := p.intRange("depth", 1, 0, -1)
+    lq := s.parseList(graphList, p, r)
+    if !p.ok(w) { return }
+    if _, ok := s.store.GetNode(root); !ok {
+        writeError(w, http.StatusNotFound, "not_found", "root not found", nil)
+        return
     }
-    labelsParam := q.Get("labels")
-    lf := make(map[string]struct{})
-    if labelsParam != "" {
-        for _, l := range strings.Split(labelsParam, ",") { lf[strings.TrimSpace(l)] = struct{}{} }
+    q := r.URL.Query()
+    paged := q.Has("limit") || q.Has("offset") || q.Has("cursor") || q.Has("sort") || q.Has("count_only")
+    if !paged && q.Get("format") == "" && wantsNDJSON(r) {
+        // nodes and edges go out as the breadth-first walk reaches them
+        nw := newNDJSONWriter(w)
+        err := s.store.WalkSlice(root, depth, lq.labels,
+            func(n *dgraph.Node) error {
+                if !visible(r, n) { return nil }
+                return nw.node(n)
+            },
+            func(e *dgraph.Edge) error {
+                if !s.edgeVisible(r, e) { return nil }
+                return nw.edge(e)
+            })
+        if err == nil { nw.flush() }
+        return
     }
-    ns, es, err := s.store.SliceFromRoot(root, depth, lf)
+    ns, es, err := s.store.SliceFromRoot(root, depth, lq.labels)
     if err != nil {
         writeError(w, http.StatusNotFound, "not_found", err.Error(), nil)
         return
     }
-    nodes := make([]dgraph.NodeDTO, 0, len(ns))
-    for _, n := range ns { nodes = append(nodes, nodeToDTO(n)) }
-    edges := make([]dgraph.EdgeDTO, 0, len(es))
-    for _, e := range es { edges = append(edges, edgeToDTO(e)) }
-    writeJSON(w, http.StatusOK, dgraph.GraphSliceDTO{Nodes: nodes, Edges: edges})
+    var nodes []*dgraph.Node
+    for _, n := range ns {
+        if visible(r, n) { nodes = append(nodes, n) }
+    }
+    var edges []*dgraph.Edge
+    byTo := make(map[string][]*dgraph.Edge, len(es))
+    for _, e := range es {
+        if !s.edgeVisible(r, e) { continue }
+        edges = append(edges, e)
+        byTo[e.ToID] = append(byTo[e.ToID], e)
+    }
+    if !paged {
+        if f, want, ok := exportFormat(w, r); !ok {
+            return
+        } else if want {
+            writeExport(w, r, f, nodes, edges)
+            return
+        }
+        dtos := make([]dgraph.NodeDTO, 0, len(nodes))
+        for _, n := range nodes {
+            dto := nodeToDTO(n)
+            if lq.fields != nil { dto = filterNodeFields(dto, lq.fields) }
+            dtos = append(dtos, dto)
+        }
+        edtos := make([]dgraph.EdgeDTO, 0, len(edges))
+        for _, e := range edges { edtos = append(edtos, edgeToDTO(e)) }
+        w.Header().Set("X-Total-Count", strconv.Itoa(len(nodes)))
+        writeJSON(w, http.StatusOK, map[string]any{"nodes": dtos, "edges": edtos, "total": len(nodes), "next_offset": nil, "next_cursor": nil})
+        return
+    }
+    // a page: one row per node, sent with every slice edge that reaches it
+    items := make([]listItem, 0, len(nodes))
+    for _, n := range nodes {
+        it := listItem{n: n}
+        if in := byTo[n.ID]; len(in) > 0 { it.e = in[0] }
+        items = append(items, it)
+    }
+    lq.labels = nil // the walk applied labels already, keeping the root
+    writeList(w, r, lq, graphList.run(lq, items), listOut{edges: func(it listItem) []*dgraph.Edge { return byTo[it.n.ID] }})
 }
 
+// graphList and topicList keep the store's order (walk, association) by default.
+var (
+    graphList = listSpec{}
+    topicList = listSpec{}
+)
+
+// handleSearch matches q against titles, text and citations; results are in ID order by default.
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-    q := r.URL.Query()
-    query := strings.TrimSpace(q.Get("q"))
-    jur := q.Get("jurisdiction")
-    code := q.Get("code")
-    sortParam := q.Get("sort") // title|-title|id|-id
-    limit := 20
-    if lv := q.Get("limit"); lv != "" { if n, err := strconv.Atoi(lv); err == nil && n > 0 && n <= 100 { limit = n } }
-    offset := 0
-    if cur := q.Get("cursor"); cur != "" {
-        if b, err := base64.URLEncoding.DecodeString(cur); err == nil {
-            s := string(b)
-            if strings.HasPrefix(s, "o:") {
-                if n, err := strconv.Atoi(strings.TrimPrefix(s, "o:")); err == nil && n >= 0 { offset = n }
-            }
-        }
-    } else if ov := q.Get("offset"); ov != "" { if n, err := strconv.Atoi(ov); err == nil && n >= 0 { offset = n } }
-    // get more than we need to compute next_cursor
-    cap := offset + limit
-    if cap < limit { cap = limit }
-    results := s.store.Search(query, jur, code, cap)
-    // sort
-    switch sortParam {
-    case "title":
-        sort.SliceStable(results, func(i, j int) bool { return strings.ToLower(results[i].Title) < strings.ToLower(results[j].Title) })
-    case "-title":
-        sort.SliceStable(results, func(i, j int) bool { return strings.ToLower(results[i].Title) > strings.ToLower(results[j].Title) })
-    case "-id":
-        sort.SliceStable(results, func(i, j int) bool { return results[i].ID > results[j].ID })
-    case "id":
-        fallthrough
-    default:
-        sort.SliceStable(results, func(i, j int) bool { return results[i].ID < results[j].ID })
-    }
-    if offset > len(results) { offset = len(results) }
-    end := offset + limit
-    if end > len(results) { end = len(results) }
-    page := results[offset:end]
-    items := make([]dgraph.SearchItem, 0, len(page))
-    for _, n := range page { items = append(items, dgraph.SearchItem{Type: "node", ID: n.ID, Title: n.Title}) }
-    resp := dgraph.SearchResultDTO{Query: query, Items: items}
-    if end < len(results) {
-        resp.NextCursor = base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("o:%d", end)))
-    }
-    writeJSON(w, http.StatusOK, resp)
+    p := newParams(r)
+    query := strings.TrimSpace(p.str("q"))
+    jur := p.str("jurisdiction")
+    code := p.str("code")
+    lq := s.parseList(idList, p, r)
+    if !p.ok(w) { return }
+    results := s.store.Search(query, jur, code, 0)
+    items := make([]listItem, 0, len(results))
+    for i := range results { items = append(items, listItem{n: &results[i]}) }
+    writeList(w, r, lq, idList.run(lq, items), listOut{body: func(res listResult) any {
+        out := dgraph.SearchResultDTO{Query: query, Items: make([]dgraph.SearchItem, 0, len(res.page)), Total: res.total, NextCursor: res.next}
+        for _, it := range res.page { out.Items = append(out.Items, dgraph.SearchItem{Type: "node", ID: it.n.ID, Title: it.n.Title}) }
+        return out
+    }})
 }
 
-func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
-    id := strings.TrimPrefix(r.URL.Path, "/diff/")
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request, id string) {
     n, ok := s.store.GetNode(id)
     if !ok { writeError(w, http.StatusNotFound, "not_found", "Node not found", nil); return }
-    var versions []dgraph.Version
-    if n.Version != nil { versions = append(versions, *n.Version) }
+    versions := s.versions(n.ID)
     writeJSON(w, http.StatusOK, map[string]any{"id": n.ID, "versions": versions, "diff": ""})
 }
 
-func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
-    id := strings.TrimPrefix(r.URL.Path, "/versions/")
+// versions lists the stored versions of id, oldest first.
+func (s *Server) versions(id string) []dgraph.Version {
+    var out []dgraph.Version
+    for _, h := range s.store.History(id) {
+        if h.Version != nil { out = append(out, *h.Version) }
+    }
+    return out
+}
+
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request, id string) {
     n, ok := s.store.GetNode(id)
     if !ok { writeError(w, http.StatusNotFound, "not_found", "Node not found", nil); return }
-    var versions []dgraph.Version
-    if n.Version != nil { versions = append(versions, *n.Version) }
+    versions := s.versions(n.ID)
     writeJSON(w, http.StatusOK, versions)
 }
 
-// Topics
+// handleTopics lists the TOPIC nodes, by ID by default.
 func (s *Server) handleTopics(w http.ResponseWriter, r *http.Request) {
-    if r.URL.Path == "/topics" {
-        ts := s.store.GetTopics()
-        out := make([]dgraph.NodeDTO, 0, len(ts))
-        for _, n := range ts { out = append(out, nodeToDTO(n)) }
-        writeJSON(w, http.StatusOK, map[string]any{"topics": out})
-        return
-    }
-    // /topics/{id}
-    id := strings.TrimPrefix(r.URL.Path, "/topics/")
-    if id == r.URL.Path || id == "" {
-        writeError(w, http.StatusBadRequest, "bad_request", "missing id", nil)
-        return
-    }
+    p := newParams(r)
+    lq := s.parseList(idList, p, r)
+    if !p.ok(w) { return }
+    ts := s.store.GetTopics()
+    items := make([]listItem, 0, len(ts))
+    for _, n := range ts { items = append(items, listItem{n: n}) }
+    writeList(w, r, lq, idList.run(lq, items), listOut{body: func(res listResult) any {
+        out := make([]dgraph.NodeDTO, 0, len(res.page))
+        for _, it := range res.page {
+            dto := nodeToDTO(it.n)
+            if lq.fields != nil { dto = filterNodeFields(dto, lq.fields) }
+            out = append(out, dto)
+        }
+        resp := map[string]any{"topics": out, "total": res.total, "next_cursor": nil}
+        if res.next != "" { resp["next_cursor"] = res.next }
+        return resp
+    }})
+}
+
+// handleTopic lists the nodes classified under a topic with their HAS_TOPIC edges;
+// the topic itself is under "topic".
+func (s *Server) handleTopic(w http.ResponseWriter, r *http.Request, id string) {
     topic, ok := s.store.GetNode(id)
     if !ok {
         writeError(w, http.StatusNotFound, "not_found", "Topic not found", nil)
         return
     }
+    p := newParams(r)
+    lq := s.parseList(topicList, p, r)
+    if !p.ok(w) { return }
     ns, es := s.store.GetTopicAssociations(id)
-    // include topic node in results
-    nodes := make([]dgraph.NodeDTO, 0, len(ns)+1)
-    nodes = append(nodes, nodeToDTO(topic))
-    for _, n := range ns { nodes = append(nodes, nodeToDTO(n)) }
-    edges := make([]dgraph.EdgeDTO, 0, len(es))
-    for _, e := range es { edges = append(edges, edgeToDTO(e)) }
-    writeJSON(w, http.StatusOK, dgraph.GraphSliceDTO{Nodes: nodes, Edges: edges})
+    writeList(w, r, lq, topicList.run(lq, pairItems(ns, es)), listOut{anchors: []*dgraph.Node{topic}, extra: map[string]any{"topic": nodeToDTO(topic)}})
 }
 
 func nodeToDTO(n *dgraph.Node) dgraph.NodeDTO {
@@ -546,6 +632,61 @@ func edgeToDTO(e *dgraph.Edge) dgraph.EdgeDTO {
     return dgraph.EdgeDTO{ID: e.ID, Type: e.EdgeType, FromID: e.FromID, ToID: e.ToID, Props: e.Props}
 }
 
+// exportFormat reports whether the client asked for a graph export, either with
+// format=graphml|gexf|dot|jsonld|turtle|ntriples or an Accept header naming one of their media types.
+// format=json forces the default JSON body. ok is false when format is unknown;
+// the 400 has then already been written.
+func exportFormat(w http.ResponseWriter, r *http.Request) (f export.Format, want bool, ok bool) {
+    if fv := r.URL.Query().Get("format"); fv != "" {
+        if fv == "json" { return "", false, true }
+        f, known := export.ParseFormat(fv)
+        if !known {
+            allowed := []string{"json", "graphml", "gexf", "dot", "jsonld", "turtle", "ntriples"}
+            writeInvalidParams(w, invalidParam{Name: "format", Value: fv, Reason: "format must be one of " + strings.Join(allowed, ", "), Allowed: allowed})
+            return "", false, false
+        }
+        return f, true, true
+    }
+    f, want = export.FromAccept(r.Header.Get("Accept"))
+    return f, want, true
+}
+
+func writeExport(w http.ResponseWriter, r *http.Request, f export.Format, nodes []*dgraph.Node, edges []*dgraph.Edge) {
+    w.Header().Set("Content-Type", f.ContentType())
+    addVary(w.Header(), "Accept")
+    w.WriteHeader(http.StatusOK)
+    _ = export.Write(w, f, nodes, edges, export.Options{BaseURL: baseURL(r)})
+}
+
+// baseURL is the public origin used to mint node IRIs. PUBLIC_BASE_URL overrides
+// what is inferred from the request (Host, TLS). X-Forwarded-Proto counts only from
+// a peer listed in TRUSTED_PROXIES, and only as http or https.
+func baseURL(r *http.Request) string {
+    if b := os.Getenv("PUBLIC_BASE_URL"); b != "" { return strings.TrimSuffix(b, "/") }
+    scheme := "http"
+    if r.TLS != nil { scheme = "https" }
+    if p := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); (p == "http" || p == "https") && trustedProxy(r.RemoteAddr) { scheme = p }
+    return scheme + "://" + r.Host
+}
+
+// trustedProxy reports whether addr (host:port) is in TRUSTED_PROXIES, a comma-separated
+// list of IPs and CIDR prefixes.
+func trustedProxy(addr string) bool {
+    list := os.Getenv("TRUSTED_PROXIES")
+    if list == "" { return false }
+    host, _, err := net.SplitHostPort(addr)
+    if err != nil { host = addr }
+    ip, err := netip.ParseAddr(host)
+    if err != nil { return false }
+    ip = ip.Unmap()
+    for _, e := range strings.Split(list, ",") {
+        e = strings.TrimSpace(e)
+        if pfx, err := netip.ParsePrefix(e); err == nil && pfx.Contains(ip) { return true }
+        if a, err := netip.ParseAddr(e); err == nil && a.Unmap() == ip { return true }
+    }
+    return false
+}
+
 // filterNodeFields returns a copy of n where only keys in fields are preserved.
 func filterNodeFields(n dgraph.NodeDTO, fields map[string]struct{}) dgraph.NodeDTO {
     var out dgraph.NodeDTO
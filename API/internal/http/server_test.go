@@ -1,13 +1,28 @@
 package httpapi
 
 import (
+    "bufio"
+    "bytes"
+    "compress/gzip"
+    "context"
     "encoding/json"
+    "fmt"
+    "io"
     "net/http"
     "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
     "testing"
+    "time"
 
+    dgraph "lawmap/internal/domain/graph"
     graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/auth"
     conf "lawmap/internal/config"
+    "lawmap/internal/etl/scheduler"
+    "lawmap/internal/etl/sources"
+    "lawmap/internal/services/webhooks"
 )
 
 func newTestMux(t *testing.T) *http.ServeMux {
@@ -55,6 +70,48 @@ func TestGraphSliceEndpoint(t *testing.T) {
     if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
 }
 
+func TestGraphSliceIsWhole(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    nodes := []*dgraph.Node{{ID: "R", Labels: []string{"CODE"}}}
+    var edges []*dgraph.Edge
+    for i := 0; i < 150; i++ {
+        id := fmt.Sprintf("R:%03d", i)
+        nodes = append(nodes, &dgraph.Node{ID: id, Labels: []string{"SECTION"}})
+        edges = append(edges, &dgraph.Edge{ID: "p" + id, EdgeType: "PARENT_OF", FromID: "R", ToID: id, Props: map[string]any{"order": float64(i)}})
+    }
+    store.Apply(nodes, edges)
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    get := func(path, accept string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("GET", path, nil)
+        if accept != "" { req.Header.Set("Accept", accept) }
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        if rr.Code != 200 { t.Fatalf("%s: status=%d %s", path, rr.Code, rr.Body.String()) }
+        return rr
+    }
+    var body struct {
+        Nodes      []dgraph.NodeDTO `json:"nodes"`
+        Edges      []dgraph.EdgeDTO `json:"edges"`
+        Total      int              `json:"total"`
+        NextCursor *string          `json:"next_cursor"`
+    }
+    // no paging unless asked for: the root, every node and every edge, past the list limit
+    _ = json.Unmarshal(get("/graph?root=R", "").Body.Bytes(), &body)
+    if len(body.Nodes) != 151 || len(body.Edges) != 150 || body.Total != 151 || body.NextCursor != nil || body.Nodes[0].ID != "R" { t.Fatalf("whole slice: %d nodes, %d edges, total %d", len(body.Nodes), len(body.Edges), body.Total) }
+    _ = json.Unmarshal(get("/graph?root=R&labels=SECTION", "").Body.Bytes(), &body)
+    if len(body.Nodes) != 151 || body.Nodes[0].ID != "R" || len(body.Edges) != 150 { t.Fatalf("labels dropped the root or edges: %d nodes, %d edges", len(body.Nodes), len(body.Edges)) }
+    if rr := get("/graph?root=R", "application/x-ndjson"); strings.Count(rr.Body.String(), "\n") != 301 { t.Fatalf("ndjson: %d lines", strings.Count(rr.Body.String(), "\n")) }
+    if rr := get("/graph?root=R&format=graphml", ""); strings.Count(rr.Body.String(), "<node ") != 151 || strings.Count(rr.Body.String(), "<edge ") != 150 { t.Fatalf("graphml truncated:\n%s", rr.Body.String()) }
+
+    // paging is opt-in, each row comes with the edges reaching it
+    _ = json.Unmarshal(get("/graph?root=R&limit=10", "").Body.Bytes(), &body)
+    if len(body.Nodes) != 10 || len(body.Edges) != 9 || body.Total != 151 || body.NextCursor == nil { t.Fatalf("page: %d nodes, %d edges, total %d", len(body.Nodes), len(body.Edges), body.Total) }
+    rr := get("/graph?root=R&limit=10&cursor="+*body.NextCursor, "")
+    _ = json.Unmarshal(rr.Body.Bytes(), &body)
+    if len(body.Nodes) != 10 || len(body.Edges) != 10 || body.Nodes[0].ID != "R:009" { t.Fatalf("second page: %s", rr.Body.String()) }
+}
+
 func TestSearchEndpoint(t *testing.T) {
     mux := newTestMux(t)
     req := httptest.NewRequest("GET", "/search?q=dog+bite&jurisdiction=CA&code=CIV&sort=title&limit=1", nil)
@@ -221,3 +278,962 @@ func TestCitationsFilterAndPaginate(t *testing.T) {
     mux.ServeHTTP(rr2, req2)
     if rr2.Code != 200 { t.Fatalf("status=%d", rr2.Code) }
 }
+
+func TestSimilarEndpoint(t *testing.T) {
+    mux := newTestMux(t)
+    // US Const. amend. IV and Cal. Const. art. I, § 13 share their opening text in fixtures
+    req := httptest.NewRequest("GET", "/nodes/US:CONST:AmdIV/similar?limit=3", nil)
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    var body struct{ Items []struct{ ID string `json:"id"`; Score float64 `json:"score"` } `json:"items"` }
+    _ = json.Unmarshal(rr.Body.Bytes(), &body)
+    if len(body.Items) == 0 || body.Items[0].ID != "CA:CONS:ArtI:§13" { t.Fatalf("expected CA:CONS:ArtI:§13 first, got %+v", body.Items) }
+
+    req2 := httptest.NewRequest("GET", "/nodes/US:CONST:AmdIV/similar?jurisdiction=US", nil)
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, req2)
+    var body2 struct{ Items []struct{ ID string `json:"id"` } `json:"items"` }
+    _ = json.Unmarshal(rr2.Body.Bytes(), &body2)
+    for _, it := range body2.Items {
+        if it.ID == "CA:CONS:ArtI:§13" { t.Fatalf("jurisdiction filter not applied") }
+    }
+
+    req3 := httptest.NewRequest("GET", "/nodes/NOPE/similar", nil)
+    rr3 := httptest.NewRecorder()
+    mux.ServeHTTP(rr3, req3)
+    if rr3.Code != 404 { t.Fatalf("expected 404, got %d", rr3.Code) }
+}
+
+func TestSimilarFollowsStoreChanges(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    similar := func() string {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", "/nodes/US:CONST:AmdIV/similar?limit=100", nil))
+        return rr.Body.String()
+    }
+    if !strings.Contains(similar(), "CA:CONS:ArtI:§13") { t.Fatal("fixture match missing") }
+    amd, _ := store.GetNode("US:CONST:AmdIV")
+    store.Apply([]*dgraph.Node{{ID: "US:CONST:AmdIV:copy", Title: "Copy", Text: amd.Text}}, nil)
+    store.Delete([]string{"CA:CONS:ArtI:§13"}, nil)
+    if got := similar(); !strings.Contains(got, "US:CONST:AmdIV:copy") || strings.Contains(got, "CA:CONS:ArtI:§13") { t.Errorf("after add and delete: %s", got) }
+}
+
+func TestSiblingsAndNeighborsEndpoints(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342/siblings", nil)
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("siblings status=%d", rr.Code) }
+    var slice struct{ Nodes []struct{ ID string `json:"id"` } `json:"nodes"` }
+    _ = json.Unmarshal(rr.Body.Bytes(), &slice)
+    if len(slice.Nodes) != 1 || slice.Nodes[0].ID != "CA:CIV:T02:CH02:§3343" { t.Fatalf("unexpected siblings: %+v", slice.Nodes) }
+
+    cases := []struct{ path string; wantStatus int; wantFirst string }{
+        {"/nodes/CA:CIV:T02:CH02:%C2%A73342/neighbors?dir=prev", 200, "CA:CIV:T02:CH02:§3343"},
+        {"/nodes/CA:CIV:T02:CH02:%C2%A73343/neighbors?dir=next&n=5&scope=document", 200, "CA:CIV:T02:CH02:§3342"},
+        {"/nodes/CA:CIV:T02:CH02:%C2%A73342/neighbors?dir=sideways", 400, ""},
+        {"/nodes/NOPE/neighbors", 404, ""},
+    }
+    for _, c := range cases {
+        req := httptest.NewRequest("GET", c.path, nil)
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        if rr.Code != c.wantStatus { t.Fatalf("%s status=%d", c.path, rr.Code) }
+        if c.wantFirst == "" { continue }
+        var body struct{ Nodes []struct{ ID string `json:"id"` } `json:"nodes"` }
+        _ = json.Unmarshal(rr.Body.Bytes(), &body)
+        if len(body.Nodes) == 0 || body.Nodes[0].ID != c.wantFirst { t.Fatalf("%s unexpected nodes: %+v", c.path, body.Nodes) }
+    }
+}
+
+func TestParentsExpandNodes(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342/parents?expand=nodes", nil)
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    var path struct{
+        Nodes []string `json:"nodes"`
+        Items []map[string]any `json:"items"`
+    }
+    _ = json.Unmarshal(rr.Body.Bytes(), &path)
+    if len(path.Items) != len(path.Nodes) || len(path.Items) < 2 { t.Fatalf("expected one item per path node; got %d/%d", len(path.Items), len(path.Nodes)) }
+    if path.Items[1]["title"] != "California Civil Code" { t.Fatalf("expected code title in breadcrumb, got %v", path.Items[1]["title"]) }
+    if _, ok := path.Items[len(path.Items)-1]["text"]; ok { t.Fatalf("breadcrumbs should omit text by default") }
+
+    // fields implies expansion and trims the DTOs
+    req2 := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342/parents?fields=id,citation", nil)
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, req2)
+    var path2 struct{ Items []map[string]any `json:"items"` }
+    _ = json.Unmarshal(rr2.Body.Bytes(), &path2)
+    last := path2.Items[len(path2.Items)-1]
+    if last["citation"] != "CIV § 3342" { t.Fatalf("expected citation, got %v", last) }
+    if _, ok := last["title"]; ok { t.Fatalf("title should be trimmed by fields") }
+}
+
+func TestParentsBatch(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/parents?ids=CA:CIV:T02:CH02:%C2%A73342,US:USC:T18:%C2%A71028A&ids=NOPE&expand=nodes", nil)
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    var body struct{
+        Paths map[string]struct{ Nodes []string `json:"nodes"`; Items []map[string]any `json:"items"` } `json:"paths"`
+        Missing []string `json:"missing"`
+    }
+    _ = json.Unmarshal(rr.Body.Bytes(), &body)
+    if len(body.Paths) != 2 { t.Fatalf("expected two paths, got %d", len(body.Paths)) }
+    if p := body.Paths["US:USC:T18:§1028A"]; len(p.Nodes) != 4 || len(p.Items) != 4 { t.Fatalf("unexpected USC path: %+v", p) }
+    if len(body.Missing) != 1 || body.Missing[0] != "NOPE" { t.Fatalf("expected NOPE to be reported missing") }
+
+    req2 := httptest.NewRequest("GET", "/parents", nil)
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, req2)
+    if rr2.Code != 400 { t.Fatalf("expected 400 without ids, got %d", rr2.Code) }
+}
+
+func TestDocumentEndpoint(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02/document?format=markdown&toc=true", nil)
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") { t.Fatalf("content-type=%s", ct) }
+    body := rr.Body.String()
+    // §3343 (order 5) renders before §3342 (order 10)
+    i3343, i3342 := strings.Index(body, "## Section 3343"), strings.Index(body, "## Section 3342")
+    if i3343 < 0 || i3342 < 0 || i3343 > i3342 { t.Fatalf("expected sections in order:\n%s", body) }
+    if !strings.Contains(body, "## Contents") { t.Fatalf("expected table of contents") }
+
+    req2 := httptest.NewRequest("GET", "/nodes/CA:CIV/document", nil)
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, req2)
+    var doc struct{ Entries []struct{ ID string `json:"id"`; Depth int `json:"depth"` } `json:"entries"` }
+    if err := json.Unmarshal(rr2.Body.Bytes(), &doc); err != nil { t.Fatalf("json: %v", err) }
+    if len(doc.Entries) != 5 || doc.Entries[0].ID != "CA:CIV" || doc.Entries[4].Depth != 3 { t.Fatalf("unexpected entries: %+v", doc.Entries) }
+
+    for path, want := range map[string]int{"/nodes/CA:CIV/document?format=pdf": 400, "/nodes/NOPE/document": 404} {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        if rr.Code != want { t.Fatalf("%s status=%d want %d", path, rr.Code, want) }
+    }
+}
+
+func TestDocumentAkomaNtoso(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02/document", nil)
+    req.Header.Set("Accept", "application/akn+xml")
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String()) }
+    if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/akn+xml") { t.Fatalf("content-type=%s", ct) }
+    body := rr.Body.String()
+    for _, want := range []string{`<chapter eId="chp_2">`, `<section eId="chp_2__sec_3342">`, `<FRBRthis value="/akn/us-ca/act/code/civ/main~chp_2"/>`} {
+        if !strings.Contains(body, want) { t.Fatalf("missing %s in\n%s", want, body) }
+    }
+
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342/document?format=akn", nil))
+    if rr2.Code != 400 { t.Fatalf("section root status=%d", rr2.Code) }
+}
+
+func TestGraphExportFormats(t *testing.T) {
+    mux := newTestMux(t)
+    cases := []struct{ path, accept string; wantStatus int; wantType, wantBody string }{
+        {"/graph?root=CA:CIV:T02:CH02&depth=1&format=graphml", "", 200, "application/graphml+xml", "<graphml"},
+        {"/topics/TOPIC:Dogs", "text/vnd.graphviz", 200, "text/vnd.graphviz", "-> \"TOPIC:Dogs\""},
+        {"/nodes/CA:CIV:T02:CH02:%C2%A73342/citations?format=gexf", "", 200, "application/gexf+xml", "label=\"CITES\""},
+        {"/nodes/CA:OPN:People_v_Smith_2020_1/cites?format=dot", "", 200, "text/vnd.graphviz", "CA:OPN:People_v_Smith_2020_1"},
+        {"/graph?root=CA&format=json", "text/vnd.graphviz", 200, "application/json", "\"nodes\""},
+        {"/graph?root=CA&format=svg", "", 400, "application/json", "bad_request"},
+    }
+    for _, c := range cases {
+        req := httptest.NewRequest("GET", c.path, nil)
+        if c.accept != "" { req.Header.Set("Accept", c.accept) }
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        if rr.Code != c.wantStatus { t.Fatalf("%s status=%d", c.path, rr.Code) }
+        if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, c.wantType) { t.Fatalf("%s content-type=%s", c.path, ct) }
+        if !strings.Contains(rr.Body.String(), c.wantBody) { t.Fatalf("%s body missing %q:\n%s", c.path, c.wantBody, rr.Body.String()) }
+    }
+}
+
+func TestNodeLinkedDataNegotiation(t *testing.T) {
+    mux := newTestMux(t)
+    req := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342", nil)
+    req.Header.Set("Accept", "application/ld+json")
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/ld+json") { t.Fatalf("content-type=%s", ct) }
+    var doc struct{ Graph []map[string]any `json:"@graph"` }
+    if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil { t.Fatalf("json-ld: %v", err) }
+    // the node itself plus the chapter (is_part_of) and citing opinions appear as subjects
+    if len(doc.Graph) == 0 || doc.Graph[0]["@id"] != "http://example.com/nodes/CA:CIV:T02:CH02:%C2%A73342" { t.Fatalf("unexpected graph: %v", doc.Graph) }
+
+    req2 := httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342?format=ttl", nil)
+    rr2 := httptest.NewRecorder()
+    mux.ServeHTTP(rr2, req2)
+    if ct := rr2.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/turtle") { t.Fatalf("content-type=%s", ct) }
+    if !strings.Contains(rr2.Body.String(), "eli:is_part_of <http://example.com/nodes/CA:CIV:T02:CH02>") { t.Fatalf("expected parent link:\n%s", rr2.Body.String()) }
+
+    // plain JSON is unchanged
+    rr3 := httptest.NewRecorder()
+    mux.ServeHTTP(rr3, httptest.NewRequest("GET", "/nodes/CA:CIV:T02:CH02:%C2%A73342", nil))
+    if ct := rr3.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") { t.Fatalf("content-type=%s", ct) }
+}
+
+func TestBaseURLForwardedProto(t *testing.T) {
+    t.Setenv("PUBLIC_BASE_URL", "")
+    cases := []struct {
+        trusted, proto, want string
+    }{
+        {"", "https", "http://example.com"},               // no trusted proxies: header ignored
+        {"10.0.0.0/8", "https", "http://example.com"},     // peer 192.0.2.1 is not in the list
+        {"192.0.2.0/24", "https", "https://example.com"},
+        {"10.0.0.1, 192.0.2.1", "HTTPS", "https://example.com"},
+        {"192.0.2.1", "javascript", "http://example.com"}, // only http and https
+        {"192.0.2.1", "https://evil.example/x#", "http://example.com"},
+    }
+    for _, c := range cases {
+        t.Setenv("TRUSTED_PROXIES", c.trusted)
+        r := httptest.NewRequest("GET", "/nodes/CA", nil) // RemoteAddr 192.0.2.1:1234
+        r.Header.Set("X-Forwarded-Proto", c.proto)
+        if got := baseURL(r); got != c.want { t.Errorf("trusted=%q proto=%q: %s, want %s", c.trusted, c.proto, got, c.want) }
+    }
+    t.Setenv("PUBLIC_BASE_URL", "https://lawmap.example/")
+    if got := baseURL(httptest.NewRequest("GET", "/", nil)); got != "https://lawmap.example" { t.Errorf("PUBLIC_BASE_URL: %s", got) }
+}
+
+func TestNDJSONStreaming(t *testing.T) {
+    mux := newTestMux(t)
+    get := func(path string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("GET", path, nil)
+        req.Header.Set("Accept", "application/x-ndjson")
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    rr := get("/graph?root=CA:CIV&depth=10")
+    if rr.Code != 200 { t.Fatalf("status=%d", rr.Code) }
+    if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" { t.Fatalf("content-type=%s", ct) }
+    lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+    if len(lines) != 9 { t.Fatalf("expected 5 nodes + 4 edges, got %d lines:\n%s", len(lines), rr.Body.String()) }
+    for _, l := range lines {
+        var item map[string]any
+        if err := json.Unmarshal([]byte(l), &item); err != nil { t.Fatalf("line %q: %v", l, err) }
+        if item["type"] != "node" && item["type"] != "edge" { t.Fatalf("unexpected item %v", item) }
+    }
+    // the stream re-imports as is
+    path := filepath.Join(t.TempDir(), "civ.jsonl")
+    if err := os.WriteFile(path, rr.Body.Bytes(), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL(path); err != nil { t.Fatalf("reload: %v", err) }
+    if kids, _ := store.GetChildren("CA:CIV:T02:CH02"); len(kids) != 2 || kids[0].ID != "CA:CIV:T02:CH02:§3343" { t.Fatalf("reloaded children: %+v", kids) }
+
+    rr = get("/nodes/CA:CIV:T02:CH02/children?limit=1")
+    if rr.Header().Get("X-Total-Count") != "2" || rr.Header().Get("X-Next-Cursor") == "" { t.Fatalf("paging headers: %v", rr.Header()) }
+    if got := strings.Count(rr.Body.String(), "\n"); got != 2 { t.Fatalf("expected node + edge, got %d lines", got) }
+
+    rr = get("/nodes/CA:CIV:T02:CH02:%C2%A73342/citations")
+    if !strings.Contains(rr.Body.String(), `"edge_type":"CITES"`) { t.Fatalf("citations: %s", rr.Body.String()) }
+
+    rr = get("/search?q=dog")
+    if rr.Code != 200 || !strings.HasPrefix(rr.Body.String(), `{"type":"node"`) { t.Fatalf("search: %d %s", rr.Code, rr.Body.String()) }
+
+    if rr := get("/graph?root=NOPE"); rr.Code != 404 { t.Fatalf("missing root status=%d", rr.Code) }
+}
+
+func TestAmendmentsEndpoint(t *testing.T) {
+    fr := `{"type":"node","id":"US:FR:2024-03412","labels":["FR_DOCUMENT"],"title":"General Powers of Special Counsel","props":{"document_type":"rule"}}
+{"type":"node","id":"US:FR:2023-00100","labels":["FR_DOCUMENT"],"title":"Judicial Administration","props":{"document_type":"proposed_rule"}}
+{"type":"edge","id":"a1","edge_type":"AMENDS","from_id":"US:FR:2024-03412","to_id":"US:CFR:T28:§600.4","props":{"action":"revise","document_type":"rule","publication_date":"2024-02-21"}}
+{"type":"edge","id":"a2","edge_type":"AMENDS","from_id":"US:FR:2023-00100","to_id":"US:CFR:T28","props":{"action":"amend","document_type":"proposed_rule","publication_date":"2023-01-05","status":"proposed"}}
+`
+    path := filepath.Join(t.TempDir(), "fr.jsonl")
+    if err := os.WriteFile(path, []byte(fr), 0o644); err != nil { t.Fatal(err) }
+    store := graphrepo.NewMemoryStore()
+    for _, p := range []string{"../../docs/EXAMPLES.graph.jsonl", path} {
+        if err := store.LoadJSONL(p); err != nil { t.Fatalf("load: %v", err) }
+    }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    cases := []struct{ query string; want []string }{
+        {"", []string{"US:FR:2023-00100", "US:FR:2024-03412"}},
+        {"?sort=-date", []string{"US:FR:2024-03412", "US:FR:2023-00100"}},
+        {"?ancestors=false", []string{"US:FR:2024-03412"}},
+        {"?document_type=proposed_rule", []string{"US:FR:2023-00100"}},
+    }
+    for _, c := range cases {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", "/nodes/US:CFR:T28:§600.4/amendments"+c.query, nil))
+        if rr.Code != 200 { t.Fatalf("%s status=%d", c.query, rr.Code) }
+        var resp struct{ Nodes []struct{ ID string `json:"id"` } `json:"nodes"` }
+        _ = json.Unmarshal(rr.Body.Bytes(), &resp)
+        var got []string
+        for _, n := range resp.Nodes { got = append(got, n.ID) }
+        if strings.Join(got, ",") != strings.Join(c.want, ",") { t.Errorf("%s: got %v, want %v", c.query, got, c.want) }
+    }
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, httptest.NewRequest("GET", "/nodes/US:CFR:T28:§999/amendments", nil))
+    if rr.Code != 404 { t.Errorf("missing node status=%d", rr.Code) }
+}
+
+func TestAdminRunsEndpoint(t *testing.T) {
+    get := func(mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+        return rr
+    }
+    if rr := get(newTestMux(t), "GET", "/admin/runs"); rr.Code != 404 { t.Fatalf("disabled status=%d", rr.Code) }
+
+    feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.ServeFile(w, r, "../test/fixtures/cfr/title28_annual_2023.xml")
+    }))
+    defer feed.Close()
+    dir := t.TempDir()
+    cfg := conf.SchedulerConfig{
+        StateDir: filepath.Join(dir, "state"), CacheDir: filepath.Join(dir, "cache"),
+        Sources: []conf.ScheduledSource{{Name: "GovInfo CFR", Schedule: "@monthly", URLs: []string{feed.URL + "/title28.xml"}}},
+    }
+    descs := []conf.SourceDescriptor{{Name: "GovInfo CFR", Jurisdictions: []string{"US"}, Codes: []string{"CFR"}}}
+    store := graphrepo.NewMemoryStore()
+    sch, err := scheduler.New(cfg, descs, sources.Registry(), store)
+    if err != nil { t.Fatal(err) }
+    srv := NewServer(store, descs)
+    srv.SetScheduler(sch)
+    mux := http.NewServeMux()
+    srv.Routes(mux)
+
+    if rr := get(mux, "POST", "/admin/runs?source=Nope"); rr.Code != 404 { t.Errorf("unknown source status=%d", rr.Code) }
+    if rr := get(mux, "DELETE", "/admin/runs"); rr.Code != 405 || rr.Header().Get("Allow") != "GET, HEAD, POST, OPTIONS" { t.Errorf("delete status=%d allow=%q", rr.Code, rr.Header().Get("Allow")) }
+    if rr := get(mux, "POST", "/admin/runs?source=GovInfo+CFR"); rr.Code != 202 { t.Fatalf("trigger status=%d body=%s", rr.Code, rr.Body.String()) }
+    sch.Wait()
+    rr := get(mux, "GET", "/admin/runs?source=GovInfo+CFR")
+    var resp struct{ Runs []scheduler.Run `json:"runs"` }
+    if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil { t.Fatal(err) }
+    if len(resp.Runs) != 1 || resp.Runs[0].Status != "ok" || resp.Runs[0].Added == 0 { t.Fatalf("runs = %+v", resp.Runs) }
+    if _, ok := store.GetNode("US:CFR:T28:§600.4"); !ok { t.Error("run not applied to the store") }
+
+    if rr := get(mux, "POST", "/admin/sources/pause?source=GovInfo+CFR"); rr.Code != 200 || !strings.Contains(rr.Body.String(), `"paused":true`) { t.Errorf("pause status=%d body=%s", rr.Code, rr.Body.String()) }
+    if rr := get(mux, "GET", "/admin/sources"); !strings.Contains(rr.Body.String(), `"paused":true`) { t.Errorf("sources body=%s", rr.Body.String()) }
+    if rr := get(mux, "POST", "/admin/sources/resume?source=GovInfo+CFR"); rr.Code != 200 || !strings.Contains(rr.Body.String(), `"paused":false`) { t.Errorf("resume status=%d body=%s", rr.Code, rr.Body.String()) }
+    if rr := get(mux, "GET", "/admin/sources/pause?source=GovInfo+CFR"); rr.Code != 405 { t.Errorf("GET pause status=%d", rr.Code) }
+}
+
+func TestChangesFeed(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    get := func(path string) *httptest.ResponseRecorder {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        return rr
+    }
+    sec, _ := store.GetNode("CA:CIV:T02:CH02:§3342")
+    next := *sec
+    next.Version = &dgraph.Version{EffectiveDate: "2025-01-01", Hash: "sha256:next"}
+    store.Apply([]*dgraph.Node{&next, {ID: "US:USC:T18:§1", Labels: []string{"SECTION"}, Props: map[string]any{"jurisdiction": "US", "code": "USC"}}}, nil)
+
+    type feed struct {
+        Changes []graphrepo.Change `json:"changes"`
+        Next    int64              `json:"next"`
+        Latest  int64              `json:"latest"`
+    }
+    cases := []struct{ query string; ids []string; next int64 }{
+        {"", []string{"CA:CIV:T02:CH02:§3342", "US:USC:T18:§1"}, 2},
+        {"?jurisdiction=CA&code=CIV", []string{"CA:CIV:T02:CH02:§3342"}, 2},
+        {"?since=1", []string{"US:USC:T18:§1"}, 2},
+        {"?limit=1", []string{"CA:CIV:T02:CH02:§3342"}, 1},
+        {"?since=2", nil, 2},
+    }
+    for _, c := range cases {
+        rr := get("/changes" + c.query)
+        if rr.Code != 200 { t.Fatalf("%s status=%d", c.query, rr.Code) }
+        var f feed
+        if err := json.Unmarshal(rr.Body.Bytes(), &f); err != nil { t.Fatal(err) }
+        var ids []string
+        for _, ch := range f.Changes { ids = append(ids, ch.ID) }
+        if strings.Join(ids, ",") != strings.Join(c.ids, ",") || f.Next != c.next || f.Latest != 2 { t.Errorf("%s: ids=%v next=%d latest=%d", c.query, ids, f.Next, f.Latest) }
+    }
+    if rr := get("/changes"); !strings.Contains(rr.Body.String(), `"op":"version"`) { t.Errorf("body=%s", rr.Body.String()) }
+    if rr := get("/changes?since=x"); rr.Code != 400 { t.Errorf("bad since status=%d", rr.Code) }
+    store.SetMaxChanges(1)
+    store.Apply([]*dgraph.Node{&next}, nil)
+    if rr := get("/changes?since=0"); rr.Code != 410 { t.Errorf("trimmed since status=%d", rr.Code) }
+}
+
+func TestChangesStream(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    srv := httptest.NewServer(mux)
+    defer srv.Close()
+    store.Apply([]*dgraph.Node{{ID: "CA:PEN", Labels: []string{"CODE"}, Props: map[string]any{"jurisdiction": "CA", "code": "PEN"}}}, nil)
+
+    req, _ := http.NewRequest("GET", srv.URL+"/changes/stream?jurisdiction=US", nil)
+    req.Header.Set("Last-Event-ID", "0")
+    resp, err := srv.Client().Do(req)
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" { t.Fatalf("content-type=%q", ct) }
+    events := make(chan string)
+    go func() {
+        sc := bufio.NewScanner(resp.Body)
+        var ev []string
+        for sc.Scan() {
+            if sc.Text() != "" { ev = append(ev, sc.Text()); continue }
+            events <- strings.Join(ev, "\n")
+            ev = nil
+        }
+        close(events)
+    }()
+    // the CA change before the stream opened is filtered out; later US ones arrive live
+    for i, id := range []string{"US:USC", "US:CFR"} {
+        store.Apply([]*dgraph.Node{{ID: id, Labels: []string{"CODE"}, Props: map[string]any{"jurisdiction": "US"}}}, nil)
+        select {
+        case ev := <-events:
+            want := fmt.Sprintf("id: %d\nevent: change\ndata: {\"seq\":%d,\"op\":\"upsert\",\"kind\":\"node\",\"id\":%q", i+2, i+2, id)
+            if !strings.HasPrefix(ev, want) { t.Errorf("event = %q, want prefix %q", ev, want) }
+        case <-time.After(5 * time.Second):
+            t.Fatalf("no event for %s", id)
+        }
+    }
+}
+
+func TestSubscriptionsEndpoint(t *testing.T) {
+    disabled := httptest.NewRecorder()
+    newTestMux(t).ServeHTTP(disabled, httptest.NewRequest("GET", "/subscriptions", nil))
+    if disabled.Code != 404 { t.Fatalf("disabled status=%d", disabled.Code) }
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    wh, err := webhooks.New(conf.WebhooksConfig{AllowHosts: []string{"127.0.0.1"}}, store)
+    if err != nil { t.Fatal(err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() { cancel(); wh.Wait() }()
+    wh.Start(ctx)
+    srv := NewServer(store, []conf.SourceDescriptor{})
+    srv.SetWebhooks(wh)
+    mux := http.NewServeMux()
+    srv.Routes(mux)
+    do := func(method, path, body string) *httptest.ResponseRecorder {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+        return rr
+    }
+
+    got := make(chan webhooks.Event, 1)
+    var secret string
+    hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, _ := io.ReadAll(r.Body)
+        if err := webhooks.Verify(secret, r.Header.Get(webhooks.SignatureHeader), b, time.Now(), time.Minute); err != nil { t.Errorf("verify: %v", err) }
+        var ev webhooks.Event
+        _ = json.Unmarshal(b, &ev)
+        got <- ev
+    }))
+    defer hook.Close()
+
+    cases := []struct{ body string; code int }{
+        {`{"node_id":"CA:CIV:T02:CH02:§3342","url":"mailto:x"}`, 400},
+        {`{"node_id":"CA:CIV:T02:CH02:§3342","url":"http://169.254.169.254/"}`, 400},
+        {`{"node_id":"CA:CIV:T02:CH02:§3342","url":"` + hook.URL + `","events":["edited"]}`, 400},
+        {`{"node_id":"CA:CIV:T02:CH02:§3342","callback":"` + hook.URL + `"}`, 400},
+        {`{"node_id":"CA:NOPE","url":"` + hook.URL + `"}`, 404},
+    }
+    for _, c := range cases {
+        if rr := do("POST", "/subscriptions", c.body); rr.Code != c.code { t.Errorf("%s: status=%d want %d", c.body, rr.Code, c.code) }
+    }
+    rr := do("POST", "/subscriptions", `{"node_id":"CA:CIV:T02","subtree":true,"events":["version"],"url":"`+hook.URL+`"}`)
+    if rr.Code != 201 { t.Fatalf("create status=%d body=%s", rr.Code, rr.Body.String()) }
+    var sub webhooks.Subscription
+    _ = json.Unmarshal(rr.Body.Bytes(), &sub)
+    if sub.ID == "" || sub.Secret == "" || rr.Header().Get("Location") != "/subscriptions/"+sub.ID { t.Fatalf("created = %+v", sub) }
+    secret = sub.Secret
+    if rr := do("GET", "/subscriptions", ""); rr.Code != 200 || strings.Contains(rr.Body.String(), secret) || !strings.Contains(rr.Body.String(), sub.ID) { t.Errorf("list body=%s", rr.Body.String()) }
+
+    sec, _ := store.GetNode("CA:CIV:T02:CH02:§3342")
+    next := *sec
+    next.Version = &dgraph.Version{EffectiveDate: "2025-01-01", Hash: "sha256:next"}
+    store.Apply([]*dgraph.Node{&next}, nil)
+    select {
+    case ev := <-got:
+        if ev.Type != "version" || ev.Node.ID != sec.ID || ev.SubscriptionID != sub.ID { t.Errorf("event = %+v", ev) }
+    case <-time.After(5 * time.Second):
+        t.Fatal("no webhook delivered")
+    }
+
+    if rr := do("GET", "/subscriptions/dead-letters", ""); rr.Code != 200 || !strings.Contains(rr.Body.String(), `"dead_letters":[]`) { t.Errorf("dead letters body=%s", rr.Body.String()) }
+    if rr := do("POST", "/subscriptions/dead-letters/evt_nope/retry", ""); rr.Code != 404 { t.Errorf("retry unknown status=%d", rr.Code) }
+    if rr := do("DELETE", "/subscriptions/"+sub.ID, ""); rr.Code != 204 { t.Errorf("delete status=%d", rr.Code) }
+    if rr := do("GET", "/subscriptions/"+sub.ID, ""); rr.Code != 404 { t.Errorf("deleted get status=%d", rr.Code) }
+}
+
+func TestSubscriptionOwners(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    a, err := auth.New(conf.AuthConfig{Enabled: true, APIKeys: []conf.APIKey{
+        {Name: "e1", Key: "e1", Role: "editor"},
+        {Name: "e2", Key: "e2", Role: "editor"},
+        {Name: "admin", Key: "a", Role: "admin"},
+        {Name: "ca-civ", Key: "ca", Role: "editor", Jurisdictions: []string{"CA"}, Codes: []string{"CIV"}},
+    }})
+    if err != nil { t.Fatal(err) }
+    wh, err := webhooks.New(conf.WebhooksConfig{MaxAttempts: 1, Backoff: "5ms", AllowHosts: []string{"127.0.0.1"}}, store)
+    if err != nil { t.Fatal(err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() { cancel(); wh.Wait() }()
+    wh.Start(ctx)
+    srv := NewServer(store, []conf.SourceDescriptor{})
+    srv.SetAuth(a)
+    srv.SetWebhooks(wh)
+    mux := http.NewServeMux()
+    srv.Routes(mux)
+    do := func(method, path, key, body string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, path, strings.NewReader(body))
+        req.Header.Set("X-API-Key", key)
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }))
+    defer hook.Close()
+
+    rr := do("POST", "/subscriptions", "e1", `{"node_id":"CA:CIV:T02:CH02:§3342","url":"`+hook.URL+`"}`)
+    var sub webhooks.Subscription
+    _ = json.Unmarshal(rr.Body.Bytes(), &sub)
+    if rr.Code != 201 || sub.Owner != "api_key:e1" || sub.Jurisdictions != nil { t.Fatalf("create status=%d body=%s", rr.Code, rr.Body.String()) }
+    rr = do("POST", "/subscriptions", "ca", `{"node_id":"CA:CIV:T02","subtree":true,"url":"`+hook.URL+`","owner":"api_key:e2"}`)
+    if rr.Code != 400 { t.Errorf("owner in body: status=%d", rr.Code) }
+    rr = do("POST", "/subscriptions", "ca", `{"node_id":"CA:CIV:T02","subtree":true,"url":"`+hook.URL+`"}`)
+    if rr.Code != 201 || !strings.Contains(rr.Body.String(), `"jurisdictions":["CA"],"codes":["CIV"]`) { t.Errorf("scoped create: status=%d body=%s", rr.Code, rr.Body.String()) }
+
+    sec, _ := store.GetNode("CA:CIV:T02:CH02:§3342")
+    next := *sec
+    next.Version = &dgraph.Version{EffectiveDate: "2025-01-01", Hash: "sha256:next"}
+    store.Apply([]*dgraph.Node{&next}, nil)
+    var dead []webhooks.Delivery
+    for deadline := time.Now().Add(5 * time.Second); len(dead) == 0 && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        dead = wh.DeadLetters(sub.ID)
+    }
+    if len(dead) != 1 { t.Fatalf("dead letters = %+v", dead) }
+    evt := dead[0].Event.ID
+
+    // another editor sees none of it and cannot touch it; the owner and an admin can
+    for _, key := range []string{"e1", "e2", "a"} {
+        owns := key != "e2"
+        list, one, letters := do("GET", "/subscriptions", key, ""), do("GET", "/subscriptions/"+sub.ID, key, ""), do("GET", "/subscriptions/dead-letters", key, "")
+        if strings.Contains(list.Body.String(), sub.ID) != owns || (one.Code == 200) != owns || strings.Contains(letters.Body.String(), evt) != owns { t.Errorf("%s: list=%s get=%d dead letters=%s", key, list.Body.String(), one.Code, letters.Body.String()) }
+    }
+    if rr := do("POST", "/subscriptions/dead-letters/"+evt+"/retry", "e2", ""); rr.Code != 404 { t.Errorf("retry by another editor: status=%d", rr.Code) }
+    if rr := do("DELETE", "/subscriptions/"+sub.ID, "e2", ""); rr.Code != 404 { t.Errorf("delete by another editor: status=%d", rr.Code) }
+    if rr := do("POST", "/subscriptions/dead-letters/"+evt+"/retry", "e1", ""); rr.Code != 202 { t.Errorf("retry by owner: status=%d", rr.Code) }
+    if rr := do("DELETE", "/subscriptions/"+sub.ID, "e1", ""); rr.Code != 204 { t.Errorf("delete by owner: status=%d", rr.Code) }
+}
+
+func TestRoutingMethodsAndEscapedIDs(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    // IDs that used to be cut at a "/children" or "/citers" suffix
+    store.Apply([]*dgraph.Node{
+        {ID: "TEST:a/children", Labels: []string{"CHAPTER"}, Title: "Slash"},
+        {ID: "TEST:a/children:§ 1", Labels: []string{"SECTION"}, Title: "Child"},
+        {ID: "TEST:b c/citers", Labels: []string{"SECTION"}, Title: "Space"},
+    }, []*dgraph.Edge{{ID: "t1", EdgeType: "PARENT_OF", FromID: "TEST:a/children", ToID: "TEST:a/children:§ 1"}})
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    cases := []struct {
+        method, path string
+        code         int
+        allow, body  string
+    }{
+        {"GET", "/nodes/TEST:a%2Fchildren", 200, "", `"title":"Slash"`},
+        {"GET", "/nodes/TEST:a%2Fchildren/children", 200, "", `"id":"TEST:a/children:§ 1"`},
+        {"GET", "/nodes/TEST:a%2Fchildren:%C2%A7%201", 200, "", `"title":"Child"`},
+        {"GET", "/nodes/TEST:a%2Fchildren:%C2%A7%201/parents", 200, "", `"TEST:a/children"`},
+        {"GET", "/nodes/CA:CIV:T02:CH02:§3342/parents", 200, "", `"CA:CIV:T02:CH02"`},
+        {"GET", "/nodes/TEST:b%20c%2Fciters", 200, "", `"title":"Space"`},
+        {"GET", "/nodes/TEST:b%20c%2Fciters/citers", 200, "", `"nodes":[]`},
+        {"GET", "/nodes/TEST:a/children/parents", 404, "", `"code":"not_found"`}, // unescaped "/" is a path separator
+        {"GET", "/nodes/CA:CIV:T02:CH02/nope", 404, "", `"code":"not_found"`},
+        {"GET", "/nope", 404, "", `"code":"not_found"`},
+        {"POST", "/nodes/CA:CIV:T02:CH02/children", 405, "GET, HEAD, OPTIONS", `"code":"method_not_allowed"`},
+        {"DELETE", "/search", 405, "GET, HEAD, OPTIONS", `"allow":"GET, HEAD, OPTIONS"`},
+        {"PUT", "/subscriptions", 405, "GET, HEAD, POST, OPTIONS", ""},
+        {"GET", "/subscriptions/dead-letters/x/retry", 405, "POST, OPTIONS", ""},
+        {"OPTIONS", "/nodes/CA:CIV:T02:CH02", 204, "GET, HEAD, OPTIONS", ""},
+        {"OPTIONS", "/subscriptions/sub_1", 204, "GET, HEAD, DELETE, OPTIONS", ""},
+        {"HEAD", "/nodes/CA:CIV:T02:CH02", 200, "", ""},
+        {"HEAD", "/changes/stream", 200, "", ""},
+    }
+    for _, c := range cases {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest(c.method, c.path, nil))
+        if rr.Code != c.code { t.Errorf("%s %s: status=%d want %d body=%s", c.method, c.path, rr.Code, c.code, rr.Body.String()); continue }
+        if got := rr.Header().Get("Allow"); got != c.allow { t.Errorf("%s %s: Allow=%q want %q", c.method, c.path, got, c.allow) }
+        if !strings.Contains(rr.Body.String(), c.body) { t.Errorf("%s %s: body=%s, want %s", c.method, c.path, rr.Body.String(), c.body) }
+    }
+
+    // over a real connection HEAD sends the GET headers without a body
+    srv := httptest.NewServer(mux)
+    defer srv.Close()
+    resp, err := srv.Client().Head(srv.URL + "/nodes/CA:CIV:T02:CH02")
+    if err != nil { t.Fatal(err) }
+    b, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != 200 || len(b) != 0 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") { t.Errorf("HEAD status=%d len=%d type=%q", resp.StatusCode, len(b), resp.Header.Get("Content-Type")) }
+}
+
+func TestInvalidQueryParams(t *testing.T) {
+    mux := newTestMux(t)
+    sec := "/nodes/CA:CIV:T02:CH02:%C2%A73342"
+    cases := []struct {
+        path    string
+        invalid []string // parameter names in details, in order
+    }{
+        {sec + "/citations?limit=5000", []string{"limit"}},
+        {sec + "/citations?limit=0&sort=bogus", []string{"sort", "limit"}},
+        {sec + "/citations?cursor=not-a-cursor", []string{"cursor"}},
+        {sec + "/cites?count_only=maybe&fields=id,colour", []string{"fields", "count_only"}},
+        {"/nodes/CA:CIV:T02:CH02/children?sort=date&offset=-1", []string{"sort", "offset"}},
+        {sec + "/amendments?ancestors=no&sort=title,date", []string{"ancestors", "sort"}},
+        {"/graph?root=CA:CIV&depth=-1", []string{"depth"}},
+        {"/search?q=dog&cursor=bz94", []string{"cursor"}},
+        {sec + "?expand=siblings", []string{"expand"}},
+        {sec + "/parents?expand=items", []string{"expand"}},
+        {sec + "/neighbors?n=101", []string{"n"}},
+        {sec + "/similar?limit=x", []string{"limit"}},
+        {sec + "/document?toc=yes", []string{"toc"}},
+        {"/graph?root=CA&format=svg", []string{"format"}},
+        {"/changes?since=x&limit=1001", []string{"since", "limit"}},
+    }
+    for _, c := range cases {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", c.path, nil))
+        if rr.Code != 400 { t.Errorf("%s: status=%d body=%s", c.path, rr.Code, rr.Body.String()); continue }
+        var body struct{ Error struct{ Code, Message string; Details []invalidParam } }
+        if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatal(err) }
+        var names []string
+        for _, ip := range body.Error.Details { names = append(names, ip.Name) }
+        if body.Error.Code != "bad_request" || strings.Join(names, ",") != strings.Join(c.invalid, ",") { t.Errorf("%s: error=%+v", c.path, body.Error) }
+    }
+
+    // details carry the allowed values and ranges
+    rr := httptest.NewRecorder()
+    mux.ServeHTTP(rr, httptest.NewRequest("GET", sec+"/citations?limit=5000&sort=bogus", nil))
+    want := `"details":[{"name":"sort","value":"bogus","reason":"sort must be one of title, -title, id, -id","allowed":["title","-title","id","-id"]},{"name":"limit","value":"5000","reason":"limit must be an integer between 1 and 1000","min":1,"max":1000}]`
+    if !strings.Contains(rr.Body.String(), want) { t.Errorf("body=%s", rr.Body.String()) }
+
+    // RFC 7807 for clients that ask for it, including route errors
+    for _, path := range []string{sec + "/citations?limit=5000", "/nope"} {
+        req := httptest.NewRequest("GET", path, nil)
+        req.Header.Set("Accept", "application/problem+json, application/json")
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        var p problem
+        if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil { t.Fatal(err) }
+        if rr.Header().Get("Content-Type") != "application/problem+json" || p.Status != rr.Code || p.Title != http.StatusText(rr.Code) || p.Type != "about:blank" || p.Detail == "" { t.Errorf("%s: %s %+v", path, rr.Header().Get("Content-Type"), p) }
+    }
+    req := httptest.NewRequest("GET", sec+"/citations?limit=5000", nil)
+    req.Header.Set("Accept", "application/problem+json")
+    rr = httptest.NewRecorder()
+    mux.ServeHTTP(rr, req)
+    if !strings.Contains(rr.Body.String(), `"invalid_params":[{"name":"limit"`) { t.Errorf("problem body=%s", rr.Body.String()) }
+
+    // valid values and cursors returned by the API still pass
+    for _, path := range []string{sec + "/citations?limit=100&sort=-title&count_only=1", sec + "?expand=parents,children&fields=id", "/graph?root=CA:CIV&depth=0"} {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        if rr.Code != 200 { t.Errorf("%s: status=%d body=%s", path, rr.Code, rr.Body.String()) }
+    }
+}
+
+func TestListEndpointsShareBehavior(t *testing.T) {
+    mux := newTestMux(t)
+    get := func(path string) (*httptest.ResponseRecorder, []map[string]any, map[string]any) {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        var body map[string]any
+        _ = json.Unmarshal(rr.Body.Bytes(), &body)
+        var rows []map[string]any
+        for _, key := range []string{"nodes", "items", "topics"} {
+            list, _ := body[key].([]any)
+            for _, v := range list { rows = append(rows, v.(map[string]any)) }
+        }
+        return rr, rows, body
+    }
+    cases := []struct {
+        name, path string // path ends in ? or &
+        total      int
+        unlabeled  int // rows labels=NOPE keeps
+    }{
+        {"children", "/nodes/CA:CIV:T02:CH02/children?", 2, 0},
+        {"siblings", "/nodes/CA:CIV:T02:CH02:%C2%A73342/siblings?", 1, 0},
+        {"citations", "/nodes/CA:CIV:T02:CH02:%C2%A73342/citations?", 2, 0},
+        {"cites", "/nodes/CA:OPN:People_v_Smith_2020_1/cites?", 1, 0},
+        {"topic", "/topics/TOPIC:Dogs?", 2, 0},
+        {"topics", "/topics?", 1, 0},
+        {"graph", "/graph?root=CA:CIV&depth=10&", 5, 1}, // the root stays
+        {"search", "/search?q=&jurisdiction=CA&code=CIV&", 0, 0},
+    }
+    for _, c := range cases {
+        rr, rows, body := get(c.path + "limit=1000")
+        if rr.Code != 200 { t.Errorf("%s: status=%d body=%s", c.name, rr.Code, rr.Body.String()); continue }
+        total := len(rows)
+        if c.total > 0 && total != c.total { t.Errorf("%s: %d rows, want %d", c.name, total, c.total) }
+        if rr.Header().Get("X-Total-Count") != fmt.Sprint(total) || body["total"] != float64(total) { t.Errorf("%s: total=%v header=%q rows=%d", c.name, body["total"], rr.Header().Get("X-Total-Count"), total) }
+        if _, _, b := get(c.path + "count_only=true"); b["total"] != float64(total) || len(b) != 1 { t.Errorf("%s: count_only=%v", c.name, b) }
+        if _, rows, _ := get(c.path + "labels=NOPE"); len(rows) != c.unlabeled { t.Errorf("%s: labels filter kept %d rows", c.name, len(rows)) }
+
+        // sort=-id and sort=id are mirror images; paging with cursors walks the same order
+        _, asc, _ := get(c.path + "sort=id")
+        _, desc, _ := get(c.path + "sort=-id")
+        for i := range asc {
+            if asc[i]["id"] != desc[len(desc)-1-i]["id"] { t.Errorf("%s: sort=-id is not sort=id reversed", c.name); break }
+        }
+        var walked []any
+        for cursor, pages := "", 0; pages <= total; pages++ {
+            _, page, b := get(c.path + "sort=id&limit=1&cursor=" + cursor)
+            for _, row := range page { walked = append(walked, row["id"]) }
+            next, _ := b["next_cursor"].(string)
+            if next == "" { break }
+            cursor = next
+        }
+        if len(walked) != len(asc) { t.Errorf("%s: cursor walk got %d rows, want %d", c.name, len(walked), len(asc)) }
+        for i := range walked {
+            if i < len(asc) && walked[i] != asc[i]["id"] { t.Errorf("%s: page %d = %v, want %v", c.name, i, walked[i], asc[i]["id"]) }
+        }
+
+        if c.name != "search" {
+            if _, rows, _ := get(c.path + "fields=id"); len(rows) > 0 && (rows[0]["id"] == nil || rows[0]["title"] != nil) { t.Errorf("%s: fields=id row=%v", c.name, rows[0]) }
+        }
+        if rr, _, _ := get(c.path + "sort=bogus"); rr.Code != 400 { t.Errorf("%s: sort=bogus status=%d", c.name, rr.Code) }
+    }
+}
+
+func TestListCursors(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    get := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+        var body map[string]any
+        _ = json.Unmarshal(rr.Body.Bytes(), &body)
+        return rr, body
+    }
+    ids := func(body map[string]any) string {
+        var out []string
+        for _, n := range body["nodes"].([]any) { out = append(out, n.(map[string]any)["id"].(string)) }
+        return strings.Join(out, ",")
+    }
+    cits := "/nodes/CA:CIV:T02:CH02:%C2%A73342/citations?sort=id"
+    _, first := get(cits + "&limit=1")
+    cursor, _ := first["next_cursor"].(string)
+    if ids(first) != "CA:OPN:AG:2010_01" || cursor == "" { t.Fatalf("first page=%v", first) }
+
+    // citing opinions added after the first page, on both sides of the cursor, stay out of its pages
+    cite := func(from string) *dgraph.Edge { return &dgraph.Edge{ID: "new-" + from, EdgeType: "CITES", FromID: from, ToID: "CA:CIV:T02:CH02:§3342"} }
+    store.Apply([]*dgraph.Node{{ID: "CA:OPN:A"}, {ID: "CA:OPN:Z"}}, []*dgraph.Edge{cite("CA:OPN:A"), cite("CA:OPN:Z")})
+    for i := 0; i < 2; i++ {
+        rr, next := get(cits + "&limit=5&fields=id&cursor=" + cursor)
+        if rr.Code != 200 || ids(next) != "CA:OPN:People_v_Smith_2020_1" || next["total"] != float64(2) || next["next_cursor"] != nil { t.Errorf("second page status=%d body=%s", rr.Code, rr.Body.String()) }
+    }
+    if _, fresh := get(cits); fresh["total"] != float64(4) { t.Errorf("fresh total=%v", fresh["total"]) }
+
+    // a cursor only continues the list it came from
+    for _, path := range []string{
+        "/nodes/CA:CIV:T02:CH02:%C2%A73342/citations?sort=-id&cursor=" + cursor,
+        cits + "&labels=OPINION&cursor=" + cursor,
+        "/nodes/CA:OPN:People_v_Smith_2020_1/cites?sort=id&cursor=" + cursor,
+        cits + "&cursor=x" + cursor[1:],
+        cits + "&cursor=not-a-cursor",
+    } {
+        rr, body := get(path)
+        if rr.Code != 400 || !strings.Contains(rr.Body.String(), `"name":"cursor"`) { t.Errorf("%s: status=%d body=%v", path, rr.Code, body) }
+    }
+
+    // once the change log has moved past the cursor's generation it can no longer tell new rows apart
+    store.SetMaxChanges(2)
+    for i := 0; i < 5; i++ { store.Apply([]*dgraph.Node{{ID: fmt.Sprintf("CA:OPN:N%d", i)}}, nil) }
+    if rr, _ := get(cits + "&limit=5&fields=id&cursor=" + cursor); rr.Code != 400 || !strings.Contains(rr.Body.String(), "expired") { t.Errorf("expired cursor: status=%d body=%s", rr.Code, rr.Body.String()) }
+}
+
+func TestConditionalGET(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    mux := http.NewServeMux()
+    NewServer(store, []conf.SourceDescriptor{}).Routes(mux)
+    get := func(path string, hdr ...string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("GET", path, nil)
+        for i := 0; i+1 < len(hdr); i += 2 { req.Header.Set(hdr[i], hdr[i+1]) }
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    sec := "/nodes/CA:CIV:T02:CH02:%C2%A73342"
+    byVersion := map[string]bool{sec: true, sec + "?fields=id": true, "/diff/CA:CIV:T02:CH02:%C2%A73342": true, "/versions/CA:CIV:T02:CH02:%C2%A73342": true}
+    paths := []string{
+        "/sources", "/topics", "/topics/TOPIC:Dogs", sec, sec + "?fields=id", sec + "?expand=children",
+        sec + "/children", sec + "/parents", sec + "/citations", sec + "/citers", "/nodes/CA:OPN:People_v_Smith_2020_1/cites",
+        sec + "/amendments", sec + "/siblings", sec + "/neighbors", "/nodes/CA:CIV:T02:CH02/document", "/nodes/US:CONST:AmdIV/similar",
+        "/parents?ids=CA:CIV:T02:CH02:%C2%A73342", "/graph?root=CA:CIV", "/search?q=dog", "/diff/CA:CIV:T02:CH02:%C2%A73342",
+        "/versions/CA:CIV:T02:CH02:%C2%A73342", "/changes",
+    }
+    etags := map[string]string{}
+    for _, path := range paths {
+        rr := get(path)
+        etag, lm := rr.Header().Get("ETag"), rr.Header().Get("Last-Modified")
+        if rr.Code != 200 || !strings.HasPrefix(etag, `"`) || lm == "" || rr.Header().Get("Cache-Control") != "no-cache" {
+            t.Errorf("%s: status=%d etag=%q last-modified=%q cache-control=%q", path, rr.Code, etag, lm, rr.Header().Get("Cache-Control"))
+            continue
+        }
+        etags[path] = etag
+        if byVersion[path] && lm != "Wed, 01 Jan 2025 00:00:00 GMT" { t.Errorf("%s: last-modified=%q, want the version's fetched_at", path, lm) }
+        if rr := get(path, "If-None-Match", `"other", W/`+etag); rr.Code != 304 || rr.Body.Len() != 0 || rr.Header().Get("ETag") != etag { t.Errorf("%s: If-None-Match status=%d body=%q", path, rr.Code, rr.Body.String()) }
+        if rr := get(path, "If-Modified-Since", lm); rr.Code != 304 { t.Errorf("%s: If-Modified-Since status=%d", path, rr.Code) }
+        if rr := get(path, "If-None-Match", `"other"`, "If-Modified-Since", lm); rr.Code != 200 { t.Errorf("%s: If-None-Match takes precedence, status=%d", path, rr.Code) }
+        if rr := get(path, "Accept", "application/x-ndjson"); rr.Header().Get("ETag") == etag { t.Errorf("%s: NDJSON has the JSON etag", path) }
+    }
+    if etags[sec] == etags[sec+"?fields=id"] { t.Error("fields= shares the full node's etag") }
+    if rr := get("/nodes/NOPE"); rr.Code != 404 || rr.Header().Get("ETag") != "" { t.Errorf("404 status=%d etag=%q", rr.Code, rr.Header().Get("ETag")) }
+    if rr := get("/graph?root=NOPE"); rr.Code != 404 || rr.Header().Get("ETag") != "" { t.Errorf("graph 404 etag=%q", rr.Header().Get("ETag")) }
+
+    // a write outdates the store-derived tags; the node's own tags follow its version hash
+    store.Apply([]*dgraph.Node{{ID: "CA:OPN:New"}}, nil)
+    for _, path := range paths {
+        rr := get(path, "If-None-Match", etags[path])
+        if want := map[bool]int{true: 304, false: 200}[byVersion[path]]; rr.Code != want { t.Errorf("%s after an unrelated write: status=%d, want %d", path, rr.Code, want) }
+    }
+    n, _ := store.GetNode("CA:CIV:T02:CH02:§3342")
+    old := *n
+    old.Version = &dgraph.Version{Hash: "sha256:old", EffectiveDate: "1990-01-01", FetchedAt: "2025-01-01T00:00:00Z"}
+    store.Apply([]*dgraph.Node{&old}, nil)
+    for path := range byVersion {
+        if want := map[bool]int{true: 304, false: 200}[strings.HasPrefix(path, "/nodes/")]; get(path, "If-None-Match", etags[path]).Code != want { t.Errorf("%s after an older version: want %d", path, want) }
+    }
+    next := *n
+    next.Version = &dgraph.Version{Hash: "sha256:next", EffectiveDate: "2026-01-01", FetchedAt: "2026-01-01T00:00:00Z"}
+    store.Apply([]*dgraph.Node{&next}, nil)
+    if rr := get(sec, "If-None-Match", etags[sec]); rr.Code != 200 || rr.Header().Get("Last-Modified") != "Thu, 01 Jan 2026 00:00:00 GMT" { t.Errorf("new version: status=%d last-modified=%q", rr.Code, rr.Header().Get("Last-Modified")) }
+
+    // exports of a node carry its edges: a new edge outdates them, not the JSON node
+    exports := [][]string{{sec + "?format=turtle"}, {sec + "?format=nt"}, {sec, "Accept", "application/ld+json"}, {sec, "Accept", "application/n-triples"}}
+    tags := map[int]string{}
+    for i, x := range exports { tags[i] = get(x[0], x[1:]...).Header().Get("ETag") }
+    json := get(sec).Header().Get("ETag")
+    store.Apply(nil, []*dgraph.Edge{{ID: "c9", EdgeType: "CITES", FromID: "CA:OPN:New", ToID: "CA:CIV:T02:CH02:§3342"}})
+    for i, x := range exports {
+        rr := get(x[0], append(x[1:], "If-None-Match", tags[i])...)
+        if rr.Code != 200 || !strings.Contains(rr.Body.String(), "CA:OPN:New") { t.Errorf("%v after a new edge: status=%d", x, rr.Code) }
+    }
+    if rr := get(sec, "If-None-Match", json); rr.Code != 304 { t.Errorf("JSON node after a new edge: status=%d, want 304", rr.Code) }
+}
+
+func TestCompressedResponses(t *testing.T) {
+    mux := newTestMux(t)
+    get := func(path string, hdr ...string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("GET", path, nil)
+        for i := 0; i+1 < len(hdr); i += 2 { req.Header.Set(hdr[i], hdr[i+1]) }
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    for _, path := range []string{"/graph?root=CA:CIV&depth=10", "/nodes/CA:CIV/document", "/graph?root=CA:CIV&depth=10&format=graphml"} {
+        plain := get(path)
+        rr := get(path, "Accept-Encoding", "br;q=0.9, gzip")
+        if rr.Code != 200 || rr.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(rr.Header().Get("Vary"), "Accept-Encoding") { t.Errorf("%s: status=%d headers=%v", path, rr.Code, rr.Header()); continue }
+        zr, err := gzip.NewReader(rr.Body)
+        if err != nil { t.Fatal(err) }
+        b, _ := io.ReadAll(zr)
+        if !bytes.Equal(b, plain.Body.Bytes()) { t.Errorf("%s: gzip body differs from the plain one", path) }
+        etag := rr.Header().Get("ETag")
+        if etag != strings.TrimSuffix(plain.Header().Get("ETag"), `"`)+`-gzip"` { t.Errorf("%s: etag=%q plain=%q", path, etag, plain.Header().Get("ETag")) }
+        if nm := get(path, "Accept-Encoding", "gzip", "If-None-Match", etag); nm.Code != 304 || nm.Header().Get("ETag") != etag || nm.Header().Get("Content-Encoding") != "" { t.Errorf("%s: revalidation status=%d etag=%q", path, nm.Code, nm.Header().Get("ETag")) }
+    }
+    if rr := get("/nodes/NOPE", "Accept-Encoding", "gzip"); rr.Header().Get("Content-Encoding") != "" || rr.Code != 404 { t.Errorf("small error compressed: %v", rr.Header()) }
+    if rr := get("/health", "Accept-Encoding", "gzip"); rr.Header().Get("Vary") != "" { t.Errorf("/health Vary=%q", rr.Header().Get("Vary")) }
+}
+
+func TestAuth(t *testing.T) {
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    a, err := auth.New(conf.AuthConfig{Enabled: true, APIKeys: []conf.APIKey{
+        {Name: "reader", Key: "r", Role: "reader"},
+        {Name: "editor", Key: "e", Role: "editor"},
+        {Name: "admin", Key: "a", Role: "admin"},
+        {Name: "ca-civ", Key: "ca", Role: "editor", Jurisdictions: []string{"CA"}, Codes: []string{"CIV"}},
+    }})
+    if err != nil { t.Fatal(err) }
+    srv := NewServer(store, []conf.SourceDescriptor{})
+    srv.SetAuth(a)
+    mux := http.NewServeMux()
+    srv.Routes(mux)
+    do := func(method, path, key string, hdr ...string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, path, strings.NewReader(`{"node_id":"US:USC:T18:§1028A","url":"https://example.com/hook"}`))
+        if key != "" { req.Header.Set("X-API-Key", key) }
+        for i := 0; i+1 < len(hdr); i += 2 { req.Header.Set(hdr[i], hdr[i+1]) }
+        rr := httptest.NewRecorder()
+        mux.ServeHTTP(rr, req)
+        return rr
+    }
+    sec := "/nodes/CA:CIV:T02:CH02:%C2%A73342"
+    cases := []struct {
+        method, path, key string
+        status            int
+        code              string
+    }{
+        {"GET", "/health", "", 200, ""},
+        {"GET", sec, "", 401, "unauthorized"},
+        {"GET", sec, "wrong", 401, "invalid_credentials"},
+        {"GET", sec, "r", 200, ""},
+        {"GET", "/subscriptions", "r", 403, "forbidden"},
+        {"GET", "/subscriptions", "e", 404, "not_found"}, // webhooks are off in this server
+        {"GET", "/admin/runs", "e", 403, "forbidden"},
+        {"GET", "/admin/runs", "a", 404, "not_found"},
+        {"GET", sec, "ca", 200, ""},
+        {"GET", "/nodes/US:USC:T18:%C2%A71028A", "ca", 403, "out_of_scope"},
+        {"GET", "/nodes/US:USC:T18:%C2%A71028A/children", "ca", 403, "out_of_scope"},
+        {"GET", "/nodes/US:USC:T18:%C2%A71028A", "r", 200, ""},
+        {"GET", "/topics/TOPIC:Dogs", "ca", 200, ""},
+        {"OPTIONS", sec, "", 204, ""},
+    }
+    for _, c := range cases {
+        rr := do(c.method, c.path, c.key)
+        var body errorBody
+        _ = json.Unmarshal(rr.Body.Bytes(), &body)
+        if rr.Code != c.status || body.Error.Code != c.code { t.Errorf("%s %s as %q: status=%d body=%s", c.method, c.path, c.key, rr.Code, rr.Body.String()) }
+        if rr.Code == 401 && !strings.Contains(rr.Header().Get("WWW-Authenticate"), "ApiKey") { t.Errorf("%s: WWW-Authenticate=%q", c.path, rr.Header().Get("WWW-Authenticate")) }
+    }
+    if rr := do("GET", sec, "", "Accept", "application/problem+json"); rr.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(rr.Body.String(), `"status":401`) { t.Errorf("problem 401: %s", rr.Body.String()) }
+
+    // a scoped key sees only its jurisdictions and codes, in lists, searches and graphs
+    for _, path := range []string{"/search?q=&limit=1000", sec + "/citations", "/graph?root=CA&depth=10", "/topics/TOPIC:Dogs"} {
+        all, scoped := do("GET", path, "r"), do("GET", path, "ca")
+        if scoped.Code != 200 || scoped.Body.Len() >= all.Body.Len() { t.Errorf("%s: scoped status=%d, %d bytes vs %d unscoped", path, scoped.Code, scoped.Body.Len(), all.Body.Len()) }
+        if strings.Contains(scoped.Body.String(), `"jurisdiction":"US"`) || strings.Contains(scoped.Body.String(), "People_v_Smith") { t.Errorf("%s: scoped body leaks %s", path, scoped.Body.String()) }
+        if scoped.Header().Get("ETag") == all.Header().Get("ETag") || scoped.Header().Get("Cache-Control") != "private, no-cache" || !strings.Contains(strings.Join(scoped.Header().Values("Vary"), ","), "X-API-Key") { t.Errorf("%s: scoped cache headers %v", path, scoped.Header()) }
+    }
+    store.Apply([]*dgraph.Node{{ID: "US:USC:T1", Props: map[string]any{"jurisdiction": "US", "code": "USC"}}, {ID: "CA:CIV:T9", Props: map[string]any{"jurisdiction": "CA", "code": "CIV"}}}, nil)
+    if rr := do("GET", "/changes", "ca"); !strings.Contains(rr.Body.String(), "CA:CIV:T9") || strings.Contains(rr.Body.String(), "US:USC:T1") { t.Errorf("scoped changes: %s", rr.Body.String()) }
+
+    // ancestors and the edges of an exported node are cut to the scope as well
+    store.Apply([]*dgraph.Node{{ID: "CA:CIV:T9:CH1", Props: map[string]any{"jurisdiction": "CA", "code": "CIV"}}},
+        []*dgraph.Edge{{ID: "p1", EdgeType: "PARENT_OF", FromID: "US:USC:T1", ToID: "CA:CIV:T9"}, {ID: "p2", EdgeType: "PARENT_OF", FromID: "CA:CIV:T9", ToID: "CA:CIV:T9:CH1"}})
+    for _, path := range []string{"/nodes/CA:CIV:T9:CH1/parents", "/nodes/CA:CIV:T9:CH1/parents?expand=nodes", "/nodes/CA:CIV:T9:CH1?expand=parents", "/parents?ids=CA:CIV:T9:CH1"} {
+        if all := do("GET", path, "r").Body.String(); !strings.Contains(all, "US:USC:T1") { t.Errorf("%s unscoped: %s", path, all) }
+        if rr := do("GET", path, "ca"); rr.Code != 200 || strings.Contains(rr.Body.String(), "US:USC:T1") || !strings.Contains(rr.Body.String(), `"CA:CIV:T9"`) { t.Errorf("%s scoped: status=%d %s", path, rr.Code, rr.Body.String()) }
+    }
+    if rr := do("GET", sec+"?format=ntriples", "ca"); rr.Code != 200 || strings.Contains(rr.Body.String(), "People_v_Smith") { t.Errorf("scoped export: status=%d %s", rr.Code, rr.Body.String()) }
+    store.Apply(nil, []*dgraph.Edge{{ID: "x1", EdgeType: "CITES", FromID: "CA:CIV:T02:CH02:§3342", ToID: "US:USC:T18:§1028A"}})
+    doc := "/nodes/CA:CIV:T02:CH02/document?format=akn"
+    if rr := do("GET", doc, "r"); !strings.Contains(rr.Body.String(), "1028A") { t.Errorf("unscoped akn lacks the cited section: %s", rr.Body.String()) }
+    if rr := do("GET", doc, "ca"); rr.Code != 200 || strings.Contains(rr.Body.String(), "1028A") || !strings.Contains(rr.Body.String(), "3342") { t.Errorf("scoped akn: status=%d %s", rr.Code, rr.Body.String()) }
+}
@@ -0,0 +1,163 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "lawmap/internal/services/auth"
+    "lawmap/internal/services/webhooks"
+)
+
+// SetWebhooks enables the /subscriptions endpoints.
+func (s *Server) SetWebhooks(wh *webhooks.Service) { s.webhooks = wh }
+
+// webhooksEnabled writes the 404 for a server without webhooks.
+func (s *Server) webhooksEnabled(w http.ResponseWriter) bool {
+    if s.webhooks == nil {
+        writeError(w, http.StatusNotFound, "not_found", "Webhooks are not enabled", nil)
+        return false
+    }
+    return true
+}
+
+// ownerOf names the request's principal as a subscription owner; "" with auth off.
+func ownerOf(r *http.Request) string {
+    p := auth.FromContext(r.Context())
+    if p == nil { return "" }
+    return p.Via + ":" + p.Name
+}
+
+// managesAll reports whether the request's principal may manage every subscription:
+// an admin may, and with auth off everyone may.
+func managesAll(r *http.Request) bool {
+    p := auth.FromContext(r.Context())
+    return p == nil || p.Role >= auth.Admin
+}
+
+// manages reports whether the request's principal may see and change sub.
+func manages(r *http.Request, sub webhooks.Subscription) bool { return managesAll(r) || sub.Owner == ownerOf(r) }
+
+// managed is the request principal's subscriptions, by ID.
+func (s *Server) managed(r *http.Request) map[string]webhooks.Subscription {
+    out := map[string]webhooks.Subscription{}
+    for _, sub := range s.webhooks.Subscriptions() {
+        if manages(r, sub) { out[sub.ID] = sub }
+    }
+    return out
+}
+
+// handleSubscriptionList: GET /subscriptions, the caller's own (all of them for an admin).
+func (s *Server) handleSubscriptionList(w http.ResponseWriter, r *http.Request) {
+    if !s.webhooksEnabled(w) { return }
+    subs := []webhooks.Subscription{}
+    for _, sub := range s.webhooks.Subscriptions() {
+        if manages(r, sub) { subs = append(subs, sub) }
+    }
+    writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subs})
+}
+
+// handleSubscribe: POST /subscriptions with {node_id, subtree, events, url, secret}.
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+    if !s.webhooksEnabled(w) { return }
+    // only these fields are the caller's to set; owner and scope come from its credentials
+    var sub struct {
+        NodeID  string   `json:"node_id"`
+        Subtree bool     `json:"subtree"`
+        Events  []string `json:"events"`
+        URL     string   `json:"url"`
+        Secret  string   `json:"secret"`
+    }
+    dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&sub); err != nil {
+        writeError(w, http.StatusBadRequest, "bad_request", "Invalid subscription JSON", err.Error())
+        return
+    }
+    if n, ok := s.store.GetNode(sub.NodeID); ok && !visible(r, n) {
+        writeError(w, http.StatusForbidden, "out_of_scope", sub.NodeID+" is outside the jurisdictions and codes licensed to this key", nil)
+        return
+    }
+    // the creator's scope filters the subscription's events
+    want := webhooks.Subscription{NodeID: sub.NodeID, Subtree: sub.Subtree, Events: sub.Events, URL: sub.URL, Secret: sub.Secret, Owner: ownerOf(r)}
+    if p := auth.FromContext(r.Context()); p.Scoped() {
+        want.Jurisdictions = append([]string(nil), p.Jurisdictions...)
+        want.Codes = append([]string(nil), p.Codes...)
+    }
+    created, err := s.webhooks.Subscribe(want)
+    if err != nil {
+        writeWebhooksError(w, err)
+        return
+    }
+    w.Header().Set("Location", "/subscriptions/"+created.ID)
+    writeJSON(w, http.StatusCreated, created)
+}
+
+// handleSubscription: GET /subscriptions/{id}. Another caller's subscription is a 404.
+func (s *Server) handleSubscription(w http.ResponseWriter, r *http.Request, id string) {
+    if !s.webhooksEnabled(w) { return }
+    sub, ok := s.webhooks.Subscription(id)
+    if !ok || !manages(r, sub) {
+        writeError(w, http.StatusNotFound, "not_found", "Subscription not found", nil)
+        return
+    }
+    writeJSON(w, http.StatusOK, sub)
+}
+
+// handleUnsubscribe: DELETE /subscriptions/{id}.
+func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request, id string) {
+    if !s.webhooksEnabled(w) { return }
+    if sub, ok := s.webhooks.Subscription(id); ok && !manages(r, sub) {
+        writeWebhooksError(w, webhooks.ErrNotFound)
+        return
+    }
+    if err := s.webhooks.Unsubscribe(id); err != nil {
+        writeWebhooksError(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeadLetters: GET /subscriptions/dead-letters[?subscription=], for the caller's
+// subscriptions. Those of deleted subscriptions are left to admins.
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+    if !s.webhooksEnabled(w) { return }
+    mine, all := s.managed(r), managesAll(r)
+    dead := []webhooks.Delivery{}
+    for _, d := range s.webhooks.DeadLetters(r.URL.Query().Get("subscription")) {
+        if _, ok := mine[d.Event.SubscriptionID]; ok || all { dead = append(dead, d) }
+    }
+    writeJSON(w, http.StatusOK, map[string]any{"dead_letters": dead})
+}
+
+// handleRedeliver: POST /subscriptions/dead-letters/{event id}/retry, for a dead letter
+// of one of the caller's subscriptions.
+func (s *Server) handleRedeliver(w http.ResponseWriter, r *http.Request, id string) {
+    if !s.webhooksEnabled(w) { return }
+    mine := s.managed(r)
+    for _, d := range s.webhooks.DeadLetters("") {
+        if _, ok := mine[d.Event.SubscriptionID]; d.Event.ID == id && !ok {
+            writeWebhooksError(w, webhooks.ErrNotFound)
+            return
+        }
+    }
+    d, err := s.webhooks.Redeliver(id)
+    if err != nil {
+        writeWebhooksError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusAccepted, d)
+}
+
+func writeWebhooksError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, webhooks.ErrInvalid):
+        writeError(w, http.StatusBadRequest, "bad_request", err.Error(), map[string]any{"events": webhooks.EventTypes})
+    case errors.Is(err, webhooks.ErrUnknownNode):
+        writeError(w, http.StatusNotFound, "not_found", "Node not found", nil)
+    case errors.Is(err, webhooks.ErrNotFound):
+        writeError(w, http.StatusNotFound, "not_found", "Subscription or delivery not found", nil)
+    default:
+        writeError(w, http.StatusInternalServerError, "internal", err.Error(), nil)
+    }
+}
@@ -0,0 +1,61 @@
+package parse
+
+import (
+    "bufio"
+    "errors"
+    "io"
+)
+
+// ReadCSV reads comma-separated rows with double-quoted fields that may span lines.
+// Inside quotes both "" and the backslash escapes written by PostgreSQL's
+// COPY ... CSV ESCAPE '\' (\" and \\) are accepted, as in the CourtListener bulk files.
+func ReadCSV(r io.Reader) ([][]string, error) {
+    br := bufio.NewReader(r)
+    var rows [][]string
+    var row []string
+    var field []rune
+    inQuote, started := false, false
+    for {
+        c, _, err := br.ReadRune()
+        if err == io.EOF { break }
+        if err != nil { return nil, err }
+        started = true
+        if inQuote {
+            switch c {
+            case '\\':
+                n, _, err := br.ReadRune()
+                if err == io.EOF { return nil, errors.New("csv: unterminated escape") }
+                if n == '"' || n == '\\' { field = append(field, n) } else { field = append(field, c, n) }
+            case '"':
+                if n, _, err := br.ReadRune(); err == nil {
+                    if n == '"' { field = append(field, '"'); continue }
+                    br.UnreadRune()
+                }
+                inQuote = false
+            default:
+                field = append(field, c)
+            }
+            continue
+        }
+        switch c {
+        case '"':
+            inQuote = true
+        case ',':
+            row = append(row, string(field))
+            field = field[:0]
+        case '\n':
+            row = append(row, string(field))
+            rows = append(rows, row)
+            row, field, started = nil, field[:0], false
+        case '\r':
+        default:
+            field = append(field, c)
+        }
+    }
+    if inQuote { return nil, errors.New("csv: unterminated quoted field") }
+    if started {
+        row = append(row, string(field))
+        rows = append(rows, row)
+    }
+    return rows, nil
+}
@@ -0,0 +1,50 @@
+package parse
+
+import (
+    "bufio"
+    "io"
+    "strings"
+)
+
+// ReadDat reads a MySQL-style tab-separated dump as shipped in the LegInfo pubinfo
+// archives: one row per line, values optionally enclosed in backticks (which may span
+// lines and contain tabs), and NULL written unquoted. NULL becomes "".
+func ReadDat(r io.Reader) ([][]string, error) {
+    br := bufio.NewReader(r)
+    var rows [][]string
+    var row []string
+    var field strings.Builder
+    quoted, inQuote := false, false
+    endField := func() {
+        v := field.String()
+        if !quoted { v = strings.TrimSpace(v); if v == "NULL" { v = "" } }
+        row = append(row, v)
+        field.Reset()
+        quoted = false
+    }
+    for {
+        c, _, err := br.ReadRune()
+        if err == io.EOF { break }
+        if err != nil { return nil, err }
+        switch {
+        case inQuote:
+            if c == '`' { inQuote = false } else { field.WriteRune(c) }
+        case c == '`':
+            inQuote, quoted = true, true
+        case c == '\t':
+            endField()
+        case c == '\n':
+            endField()
+            rows = append(rows, row)
+            row = nil
+        case c == '\r':
+        default:
+            field.WriteRune(c)
+        }
+    }
+    if field.Len() > 0 || quoted || len(row) > 0 {
+        endField()
+        rows = append(rows, row)
+    }
+    return rows, nil
+}
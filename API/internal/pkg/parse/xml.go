@@ -0,0 +1,116 @@
+package parse
+
+import (
+    "encoding/xml"
+    "errors"
+    "io"
+    "strings"
+)
+
+// Element is a minimal XML DOM node that keeps mixed content in document order,
+// which legislative markup (USLM, eCFR, Federal Register) relies on.
+type Element struct {
+    Name  string
+    Attrs map[string]string
+    Kids  []any // string or *Element
+}
+
+// ParseXML reads a whole document. Namespaces are dropped: names and attribute keys
+// are local names. HTML entities such as &nbsp; are accepted.
+func ParseXML(r io.Reader) (*Element, error) {
+    dec := xml.NewDecoder(r)
+    dec.Strict = false
+    dec.Entity = xml.HTMLEntity
+    var root *Element
+    var stack []*Element
+    for {
+        tok, err := dec.Token()
+        if err == io.EOF { break }
+        if err != nil { return nil, err }
+        switch t := tok.(type) {
+        case xml.StartElement:
+            e := &Element{Name: t.Name.Local, Attrs: make(map[string]string, len(t.Attr))}
+            for _, a := range t.Attr { e.Attrs[a.Name.Local] = a.Value }
+            if len(stack) > 0 {
+                p := stack[len(stack)-1]
+                p.Kids = append(p.Kids, e)
+            } else if root == nil {
+                root = e
+            }
+            stack = append(stack, e)
+        case xml.EndElement:
+            if len(stack) > 0 { stack = stack[:len(stack)-1] }
+        case xml.CharData:
+            if len(stack) > 0 {
+                p := stack[len(stack)-1]
+                p.Kids = append(p.Kids, string(t))
+            }
+        }
+    }
+    if root == nil { return nil, errors.New("empty xml document") }
+    return root, nil
+}
+
+// Attr returns the named attribute or "".
+func (e *Element) Attr(name string) string {
+    if e == nil { return "" }
+    return e.Attrs[name]
+}
+
+// Child returns the first direct child element with the given name, or nil.
+func (e *Element) Child(name string) *Element {
+    if e == nil { return nil }
+    for _, k := range e.Kids {
+        if c, ok := k.(*Element); ok && c.Name == name { return c }
+    }
+    return nil
+}
+
+// Elements returns the direct child elements, optionally only those with the given names.
+func (e *Element) Elements(names ...string) []*Element {
+    if e == nil { return nil }
+    var out []*Element
+    for _, k := range e.Kids {
+        c, ok := k.(*Element)
+        if !ok { continue }
+        if len(names) == 0 { out = append(out, c); continue }
+        for _, n := range names {
+            if c.Name == n { out = append(out, c); break }
+        }
+    }
+    return out
+}
+
+// Find returns the first descendant (depth-first, including e) with the given name, or nil.
+func (e *Element) Find(name string) *Element {
+    if e == nil { return nil }
+    if e.Name == name { return e }
+    for _, c := range e.Elements() {
+        if f := c.Find(name); f != nil { return f }
+    }
+    return nil
+}
+
+// Text is the element's descendant text with whitespace collapsed.
+func (e *Element) Text() string {
+    if e == nil { return "" }
+    var b strings.Builder
+    e.appendText(&b)
+    return Collapse(b.String())
+}
+
+func (e *Element) appendText(b *strings.Builder) {
+    for _, k := range e.Kids {
+        switch x := k.(type) {
+        case string:
+            b.WriteString(x)
+        case *Element:
+            x.appendText(b)
+        }
+    }
+}
+
+// Collapse trims s and folds runs of whitespace into single spaces.
+func Collapse(s string) string {
+    return strings.Join(strings.Fields(s), " ")
+}
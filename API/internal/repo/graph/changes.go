@@ -0,0 +1,179 @@
+package graphrepo
+
+import (
+    "strings"
+    "time"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// DefaultMaxChanges bounds the change log; older entries are dropped and readers
+// asking for them are told to resync from a full dump.
+const DefaultMaxChanges = 100000
+
+// Change ops.
+const (
+    OpUpsert  = "upsert"  // node or edge added or replaced with the same version
+    OpVersion = "version" // node stored under a new version hash
+    OpDelete  = "delete"
+)
+
+// Change is one entry of the store's change log. Seq increases by one per entry.
+// Jurisdiction and code come from the node's props; for an edge they are the
+// from node's, and the edge also matches a filter on its to node.
+type Change struct {
+    Seq           int64  `json:"seq"`
+    Op            string `json:"op"`
+    Kind          string `json:"kind"` // node|edge
+    ID            string `json:"id,omitempty"`
+    Jurisdiction  string `json:"jurisdiction,omitempty"`
+    Code          string `json:"code,omitempty"`
+    Hash          string `json:"hash,omitempty"`
+    EffectiveDate string `json:"effective_date,omitempty"`
+    EdgeType      string `json:"edge_type,omitempty"`
+    FromID        string `json:"from_id,omitempty"`
+    ToID          string `json:"to_id,omitempty"`
+    At            string `json:"at"`
+    toJur, toCode string
+    added         bool
+}
+
+// Matches reports whether the change concerns the given jurisdiction and code
+// (empty matches anything; case-insensitive, as in Search).
+func (c *Change) Matches(jurisdiction, code string) bool {
+    in := func(j, k string) bool {
+        return (jurisdiction == "" || strings.EqualFold(j, jurisdiction)) && (code == "" || strings.EqualFold(k, code))
+    }
+    return in(c.Jurisdiction, c.Code) || (c.Kind == "edge" && in(c.toJur, c.toCode))
+}
+
+// Added reports whether the change added the node or edge, as opposed to storing
+// one already there again. An edge moved to other endpoints counts as added.
+func (c *Change) Added() bool { return c.added }
+
+// changeLog is guarded by MemoryStore.mu. Bulk loads are not logged: the loaded
+// graph is the baseline and the log starts at seq 0.
+type changeLog struct {
+    entries []Change
+    seq     int64
+    max     int
+    signal  chan struct{} // closed and replaced on every write
+    added   map[string]int64 // "node:ID" or "edge:ID" -> seq that added it; loaded items are absent
+    swept   int64            // added entries older than this seq were dropped with the log
+    at      time.Time        // time of the latest change or load
+}
+
+func scopeOf(n *dgraph.Node) (string, string) {
+    if n == nil { return "", "" }
+    j, _ := n.Props["jurisdiction"].(string)
+    c, _ := n.Props["code"].(string)
+    return j, c
+}
+
+func (m *MemoryStore) logNode(op string, n *dgraph.Node, added bool) {
+    c := Change{Op: op, Kind: "node", ID: n.ID, added: added}
+    c.Jurisdiction, c.Code = scopeOf(n)
+    if n.Version != nil { c.Hash, c.EffectiveDate = n.Version.Hash, n.Version.EffectiveDate }
+    m.logChange(c)
+}
+
+func (m *MemoryStore) logEdge(op string, e *dgraph.Edge, added bool) {
+    c := Change{Op: op, Kind: "edge", ID: e.ID, EdgeType: e.EdgeType, FromID: e.FromID, ToID: e.ToID, added: added}
+    c.Jurisdiction, c.Code = scopeOf(m.nodes[e.FromID])
+    c.toJur, c.toCode = scopeOf(m.nodes[e.ToID])
+    m.logChange(c)
+}
+
+func (m *MemoryStore) logChange(c Change) {
+    l := &m.changes
+    l.seq++
+    c.Seq = l.seq
+    l.at = time.Now().UTC()
+    c.At = l.at.Format(time.RFC3339)
+    l.entries = append(l.entries, c)
+    if c.Op == OpDelete { delete(l.added, c.Kind+":"+c.ID) }
+    if max := l.max; max > 0 && len(l.entries) > max {
+        l.entries = append(l.entries[:0:0], l.entries[len(l.entries)-max:]...)
+        // added follows the log's window, swept every tenth of it so the cost spreads out
+        if first := l.entries[0].Seq; first-l.swept > int64(max/10) {
+            for k, seq := range l.added {
+                if seq < first { delete(l.added, k) }
+            }
+            l.swept = first
+        }
+    }
+}
+
+// notify wakes ChangeSignal waiters; call once per batch, with the write lock held.
+func (m *MemoryStore) notify() {
+    close(m.changes.signal)
+    m.changes.signal = make(chan struct{})
+}
+
+// SetMaxChanges sets how many change log entries are kept (0 keeps all).
+func (m *MemoryStore) SetMaxChanges(n int) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.changes.max = n
+}
+
+// Changes returns up to limit changes after seq since that match the filter, and
+// the latest seq. ok is false when entries after since were already dropped.
+func (m *MemoryStore) Changes(since int64, jurisdiction, code string, limit int) (out []Change, latest int64, ok bool) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    l := &m.changes
+    if len(l.entries) > 0 && since < l.entries[0].Seq-1 { return nil, l.seq, false }
+    if since >= l.seq { return nil, l.seq, true }
+    // seqs are contiguous, so the first entry after since is found by offset
+    i := 0
+    if len(l.entries) > 0 && since >= l.entries[0].Seq { i = int(since - l.entries[0].Seq + 1) }
+    for ; i < len(l.entries) && (limit <= 0 || len(out) < limit); i++ {
+        if l.entries[i].Matches(jurisdiction, code) { out = append(out, l.entries[i]) }
+    }
+    return out, l.seq, true
+}
+
+// LastSeq returns the seq of the latest change.
+func (m *MemoryStore) LastSeq() int64 {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.changes.seq
+}
+
+// Generation returns the latest change seq and when the store last changed, by a
+// change or a load. Loads do not move the seq, so the time tells them apart.
+func (m *MemoryStore) Generation() (int64, time.Time) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.changes.seq, m.changes.at
+}
+
+// AddedSeq returns the seq of the change that added the node or edge (the later of
+// the two when both IDs are given), or 0 for loaded and unknown items, and for items
+// added before the change log's window (see TracksAddedSince). List cursors use it to
+// hide items added after the first page.
+func (m *MemoryStore) AddedSeq(nodeID, edgeID string) int64 {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    seq := m.changes.added["node:"+nodeID]
+    if edgeID != "" { if s := m.changes.added["edge:"+edgeID]; s > seq { seq = s } }
+    return seq
+}
+
+// TracksAddedSince reports whether AddedSeq still knows every item added after seq
+// gen. Additions drop out of it with the change log, so an older generation cannot
+// tell them from loaded items.
+func (m *MemoryStore) TracksAddedSince(gen int64) bool {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return gen+1 >= m.changes.swept
+}
+
+// ChangeSignal returns a channel closed by the next write. Take it before reading
+// Changes so that a write in between is not missed.
+func (m *MemoryStore) ChangeSignal() <-chan struct{} {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.changes.signal
+}
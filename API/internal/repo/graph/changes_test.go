@@ -0,0 +1,80 @@
+package graphrepo
+
+import (
+    "fmt"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestChangeAdded(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    const id = "CA:CIV:T02:CH02:§3342"
+    usc := &dgraph.Node{ID: "US:USC:T18:§1", Labels: []string{"SECTION"}}
+    m.Apply([]*dgraph.Node{usc}, []*dgraph.Edge{{ID: "x1", EdgeType: "CITES", FromID: usc.ID, ToID: id}})
+    m.Apply([]*dgraph.Node{usc}, []*dgraph.Edge{{ID: "x1", EdgeType: "CITES", FromID: usc.ID, ToID: id, Props: map[string]any{"pin": "(a)"}}})
+    m.Apply(nil, []*dgraph.Edge{{ID: "x1", EdgeType: "CITES", FromID: usc.ID, ToID: "CA:CIV:T02:CH02:§3343"}})
+    all, _, _ := m.Changes(0, "", "", 0)
+    var got []bool
+    for i := range all { got = append(got, all[i].Added()) }
+    if fmt.Sprint(got) != "[true true false false true]" { t.Errorf("added = %v; want new node and edge, then re-imports, then a moved edge", got) }
+}
+
+func TestChangeLog(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    if m.LastSeq() != 0 { t.Fatalf("load was logged: seq=%d", m.LastSeq()) }
+    sig := m.ChangeSignal()
+
+    const id = "CA:CIV:T02:CH02:§3342"
+    cur, _ := m.GetNode(id)
+    next := *cur
+    next.Version = &dgraph.Version{EffectiveDate: "2025-01-01", Hash: "sha256:next"}
+    usc := &dgraph.Node{ID: "US:USC:T18:§1", Labels: []string{"SECTION"}, Props: map[string]any{"jurisdiction": "US", "code": "USC"}}
+    cites := &dgraph.Edge{ID: "x1", EdgeType: "CITES", FromID: usc.ID, ToID: id}
+    m.Apply([]*dgraph.Node{&next, usc}, []*dgraph.Edge{cites})
+    select {
+    case <-sig:
+    default: t.Fatal("signal not closed by Apply")
+    }
+
+    all, latest, ok := m.Changes(0, "", "", 0)
+    if !ok || latest != 3 || len(all) != 3 { t.Fatalf("changes = %+v latest=%d", all, latest) }
+    if c := all[0]; c.Op != OpVersion || c.ID != id || c.Hash != "sha256:next" || c.Jurisdiction != "CA" || c.Code != "CIV" { t.Errorf("version change = %+v", c) }
+    if c := all[1]; c.Op != OpUpsert || c.Kind != "node" || c.Seq != 2 { t.Errorf("new node change = %+v", c) }
+
+    // the US edge touches a CA section, so it shows up for both
+    ca, _, _ := m.Changes(0, "ca", "civ", 0)
+    if len(ca) != 2 || ca[1].Kind != "edge" { t.Errorf("CA changes = %+v", ca) }
+    if us, _, _ := m.Changes(1, "US", "", 0); len(us) != 2 || us[0].Seq != 2 { t.Errorf("US changes since 1 = %+v", us) }
+    if page, _, _ := m.Changes(0, "", "", 2); len(page) != 2 || page[1].Seq != 2 { t.Errorf("limited = %+v", page) }
+
+    m.Delete([]string{id}, nil)
+    if _, ok := m.GetNode(id); ok { t.Fatal("node not deleted") }
+    if kids, _ := m.GetChildren("CA:CIV:T02:CH02"); len(kids) != 1 || kids[0].ID != "CA:CIV:T02:CH02:§3343" { t.Errorf("children after delete = %v", kids) }
+    if cs, _ := m.GetCitations(id); len(cs) != 0 { t.Errorf("citations after delete = %d", len(cs)) }
+    if _, ok := m.Edge("e4"); ok { t.Error("PARENT_OF edge kept") }
+    del, latest, _ := m.Changes(3, "", "", 0)
+    if n := len(del); n < 5 || latest != int64(3+n) { t.Fatalf("delete changes = %+v", del) }
+    if c := del[len(del)-1]; c.Op != OpDelete || c.Kind != "node" || c.ID != id { t.Errorf("last change = %+v", c) }
+    for _, c := range del[:len(del)-1] {
+        if c.Op != OpDelete || c.Kind != "edge" || (c.FromID != id && c.ToID != id) { t.Errorf("edge change = %+v", c) }
+    }
+
+    m.SetMaxChanges(2)
+    m.Apply([]*dgraph.Node{usc}, nil)
+    if _, _, ok := m.Changes(0, "", "", 0); ok { t.Error("expected dropped entries to be reported") }
+    if got, _, ok := m.Changes(latest, "", "", 0); !ok || len(got) != 1 { t.Errorf("after trim = %+v %v", got, ok) }
+}
+
+func TestAddedSeqFollowsChangeLogWindow(t *testing.T) {
+    m := NewMemoryStore()
+    m.SetMaxChanges(10)
+    for i := 0; i < 100; i++ {
+        m.Apply([]*dgraph.Node{{ID: fmt.Sprintf("N%d", i)}}, nil)
+    }
+    if n := len(m.changes.added); n > 20 { t.Errorf("added holds %d entries for a log of 10", n) }
+    if m.AddedSeq("N99", "") != 100 || m.AddedSeq("N0", "") != 0 { t.Errorf("added seqs: N99=%d N0=%d", m.AddedSeq("N99", ""), m.AddedSeq("N0", "")) }
+    if !m.TracksAddedSince(95) || m.TracksAddedSince(10) { t.Error("TracksAddedSince does not follow the window") }
+}
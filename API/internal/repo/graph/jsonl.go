@@ -0,0 +1,63 @@
+package graphrepo
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// JSONLWriter writes nodes and edges in the item format read by LoadJSONL.
+type JSONLWriter struct {
+    enc *json.Encoder
+}
+
+type nodeItem struct {
+    Type string `json:"type"`
+    *dgraph.Node
+}
+
+type edgeItem struct {
+    Type string `json:"type"`
+    *dgraph.Edge
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+    enc := json.NewEncoder(w)
+    enc.SetEscapeHTML(false)
+    return &JSONLWriter{enc: enc}
+}
+
+func (jw *JSONLWriter) WriteNode(n *dgraph.Node) error {
+    return jw.enc.Encode(nodeItem{Type: "node", Node: n})
+}
+
+func (jw *JSONLWriter) WriteEdge(e *dgraph.Edge) error {
+    return jw.enc.Encode(edgeItem{Type: "edge", Edge: e})
+}
+
+// ReadJSONL decodes loader items from r, passing nodes and edges to the callbacks in
+// file order. Blank lines and items of other types are skipped.
+func ReadJSONL(r io.Reader, onNode func(*dgraph.Node) error, onEdge func(*dgraph.Edge) error) error {
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+    for sc.Scan() {
+        line := strings.TrimSpace(sc.Text())
+        if line == "" { continue }
+        var item struct{ Type string `json:"type"` }
+        if err := json.Unmarshal([]byte(line), &item); err != nil { return err }
+        switch item.Type {
+        case "node":
+            var n dgraph.Node
+            if err := json.Unmarshal([]byte(line), &n); err != nil { return err }
+            if err := onNode(&n); err != nil { return err }
+        case "edge":
+            var e dgraph.Edge
+            if err := json.Unmarshal([]byte(line), &e); err != nil { return err }
+            if err := onEdge(&e); err != nil { return err }
+        }
+    }
+    return sc.Err()
+}
@@ -0,0 +1,33 @@
+package graphrepo
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestJSONLWriterRoundTrip(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    path := filepath.Join(t.TempDir(), "out.jsonl")
+    f, err := os.Create(path)
+    if err != nil { t.Fatal(err) }
+    jw := NewJSONLWriter(f)
+    for _, n := range m.Nodes() {
+        if err := jw.WriteNode(n); err != nil { t.Fatal(err) }
+    }
+    for _, e := range m.edges {
+        if err := jw.WriteEdge(e); err != nil { t.Fatal(err) }
+    }
+    f.Close()
+
+    m2 := NewMemoryStore()
+    if err := m2.LoadJSONL(path); err != nil { t.Fatalf("reload: %v", err) }
+    if len(m2.Nodes()) != len(m.Nodes()) || len(m2.edges) != len(m.edges) {
+        t.Fatalf("round trip mismatch: nodes %d/%d edges %d/%d", len(m2.Nodes()), len(m.Nodes()), len(m2.edges), len(m.edges))
+    }
+    n, _ := m2.GetNode("CA:CIV:T02:CH02:§3342")
+    if n == nil || n.Version == nil || n.Version.Hash != "sha256:example" { t.Fatalf("expected version to survive round trip") }
+    kids, _ := m2.GetChildren("CA:CIV:T02:CH02")
+    if len(kids) < 2 || kids[0].ID != "CA:CIV:T02:CH02:§3343" { t.Fatalf("expected child order to survive round trip") }
+}
@@ -1,12 +1,13 @@
 package graphrepo
 
 import (
-    "bufio"
-    "encoding/json"
     "errors"
+    "io"
     "os"
     "sort"
     "strings"
+    "sync"
+    "time"
 
     dgraph "lawmap/internal/domain/graph"
 )
@@ -19,6 +20,10 @@ type MemoryStore struct {
     edgesByTo   map[string][]*dgraph.Edge
     parentOf    map[string][]string // parent -> children IDs (PARENT_OF)
     parentID    map[string]string   // child -> parent ID
+    edgeByID    map[string]*dgraph.Edge
+    history     map[string][]*dgraph.Node // superseded versions, oldest first
+    changes     changeLog
+    mu          sync.RWMutex
 }
 
 func NewMemoryStore() *MemoryStore {
@@ -29,6 +34,9 @@ func NewMemoryStore() *MemoryStore {
         edgesByTo:   make(map[string][]*dgraph.Edge),
         parentOf:    make(map[string][]string),
         parentID:    make(map[string]string),
+        edgeByID:    make(map[string]*dgraph.Edge),
+        history:     make(map[string][]*dgraph.Node),
+        changes:     changeLog{max: DefaultMaxChanges, signal: make(chan struct{}), added: make(map[string]int64)},
     }
 }
 
@@ -37,35 +45,179 @@ func (m *MemoryStore) LoadJSONL(path string) error {
     f, err := os.Open(path)
     if err != nil { return err }
     defer f.Close()
-    sc := bufio.NewScanner(f)
-    for sc.Scan() {
-        line := strings.TrimSpace(sc.Text())
-        if line == "" { continue }
-        var raw map[string]any
-        if err := json.Unmarshal([]byte(line), &raw); err != nil { return err }
-        t, _ := raw["type"].(string)
-        switch t {
-        case "node":
-            var n dgraph.Node
-            if err := json.Unmarshal([]byte(line), &n); err != nil { return err }
-            m.nodes[n.ID] = &n
-        case "edge":
-            var e dgraph.Edge
-            if err := json.Unmarshal([]byte(line), &e); err != nil { return err }
-            m.edges = append(m.edges, &e)
-            m.edgesByFrom[e.FromID] = append(m.edgesByFrom[e.FromID], &e)
-            m.edgesByTo[e.ToID] = append(m.edgesByTo[e.ToID], &e)
+    return m.Load(f)
+}
+
+// Load reads loader items from r into the store.
+func (m *MemoryStore) Load(r io.Reader) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    err := ReadJSONL(r,
+        func(n *dgraph.Node) error { m.putNode(n); return nil },
+        func(e *dgraph.Edge) error { m.putEdge(e); return nil })
+    m.sortChildren()
+    m.changes.at = time.Now().UTC()
+    return err
+}
+
+// Apply adds nodes and edges as Load does: nodes with a new hash become versions,
+// edges with a known ID are updated. Readers see the store before or after the
+// whole batch, never part of it. Each item is recorded in the change log.
+func (m *MemoryStore) Apply(nodes []*dgraph.Node, edges []*dgraph.Edge) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    for _, n := range nodes {
+        op := OpUpsert
+        if _, ok := m.nodes[n.ID]; ok && n.Version != nil && n.Version.Hash != "" && !m.hasVersion(n.ID, n.Version.Hash) { op = OpVersion }
+        _, known := m.nodes[n.ID]
+        m.putNode(n)
+        m.logNode(op, n, !known)
+        if !known { m.changes.added["node:"+n.ID] = m.changes.seq }
+    }
+    for _, e := range edges {
+        old, known := m.edgeByID[e.ID]
+        moved := known && (old.FromID != e.FromID || old.ToID != e.ToID || old.EdgeType != e.EdgeType)
+        m.putEdge(e)
+        m.logEdge(OpUpsert, e, e.ID == "" || !known || moved)
+        if !known && e.ID != "" { m.changes.added["edge:"+e.ID] = m.changes.seq }
+    }
+    m.sortChildren()
+    if len(nodes)+len(edges) > 0 { m.notify() }
+}
+
+// Delete removes nodes (with their history and every edge touching them) and edges
+// by ID, recording each removal in the change log. Unknown IDs are ignored.
+func (m *MemoryStore) Delete(nodeIDs, edgeIDs []string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    gone := make(map[*dgraph.Edge]bool)
+    for _, id := range edgeIDs {
+        if e, ok := m.edgeByID[id]; ok { gone[e] = true }
+    }
+    for _, id := range nodeIDs {
+        for _, e := range m.edgesByFrom[id] { gone[e] = true }
+        for _, e := range m.edgesByTo[id] { gone[e] = true }
+    }
+    logged := false
+    if len(gone) > 0 {
+        kept := m.edges[:0]
+        for _, e := range m.edges {
+            if !gone[e] { kept = append(kept, e); continue }
+            // logged before the nodes go, so the entry still carries their scope
+            m.logEdge(OpDelete, e, false)
+            logged = true
+        }
+        for i := len(kept); i < len(m.edges); i++ { m.edges[i] = nil }
+        m.edges = kept
+        for e := range gone {
+            m.edgesByFrom[e.FromID] = withoutEdges(m.edgesByFrom[e.FromID], gone)
+            m.edgesByTo[e.ToID] = withoutEdges(m.edgesByTo[e.ToID], gone)
+            if m.edgeByID[e.ID] == e { delete(m.edgeByID, e.ID) }
             if e.EdgeType == "PARENT_OF" {
-                m.parentOf[e.FromID] = append(m.parentOf[e.FromID], e.ToID)
-                m.parentID[e.ToID] = e.FromID
+                kids := m.parentOf[e.FromID]
+                for i, k := range kids {
+                    if k == e.ToID { m.parentOf[e.FromID] = append(kids[:i:i], kids[i+1:]...); break }
+                }
+                if m.parentID[e.ToID] == e.FromID { delete(m.parentID, e.ToID) }
             }
-        default:
-            // ignore unknown lines
         }
     }
-    if err := sc.Err(); err != nil { return err }
+    for _, id := range nodeIDs {
+        n, ok := m.nodes[id]
+        if !ok { continue }
+        m.logNode(OpDelete, n, false)
+        logged = true
+        delete(m.nodes, id)
+        delete(m.history, id)
+        delete(m.parentOf, id)
+        delete(m.edgesByFrom, id)
+        delete(m.edgesByTo, id)
+    }
+    if logged { m.notify() }
+}
+
+func withoutEdges(list []*dgraph.Edge, gone map[*dgraph.Edge]bool) []*dgraph.Edge {
+    out := list[:0:0]
+    for _, e := range list {
+        if !gone[e] { out = append(out, e) }
+    }
+    if len(out) == 0 { return nil }
+    return out
+}
+
+// HasVersion reports whether a version of id with the given hash is stored, current or not.
+func (m *MemoryStore) HasVersion(id, hash string) bool {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.hasVersion(id, hash)
+}
+
+func (m *MemoryStore) hasVersion(id, hash string) bool {
+    n, ok := m.nodes[id]
+    if !ok { return false }
+    if n.Version == nil { return hash == "" }
+    if n.Version.Hash == hash { return true }
+    for _, h := range m.history[id] {
+        if h.Version != nil && h.Version.Hash == hash { return true }
+    }
+    return false
+}
+
+// Edge returns the edge with the given ID.
+func (m *MemoryStore) Edge(id string) (*dgraph.Edge, bool) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    e, ok := m.edgeByID[id]
+    return e, ok
+}
+
+// putEdge indexes e. An edge whose ID is known replaces the old one: with the same
+// endpoints and type it takes the old one's slots, else the old one is unlinked first.
+// Stored edges are never mutated, since readers keep them after the lock is released.
+func (m *MemoryStore) putEdge(e *dgraph.Edge) {
+    if old, ok := m.edgeByID[e.ID]; ok && e.ID != "" {
+        if old.FromID == e.FromID && old.ToID == e.ToID && old.EdgeType == e.EdgeType {
+            m.edgeByID[e.ID] = e
+            replaceEdge(m.edges, old, e)
+            replaceEdge(m.edgesByFrom[e.FromID], old, e)
+            replaceEdge(m.edgesByTo[e.ToID], old, e)
+            return
+        }
+        m.unlinkEdge(old)
+    }
+    if e.ID != "" { m.edgeByID[e.ID] = e }
+    m.edges = append(m.edges, e)
+    m.edgesByFrom[e.FromID] = append(m.edgesByFrom[e.FromID], e)
+    m.edgesByTo[e.ToID] = append(m.edgesByTo[e.ToID], e)
+    if e.EdgeType == "PARENT_OF" {
+        m.parentOf[e.FromID] = append(m.parentOf[e.FromID], e.ToID)
+        m.parentID[e.ToID] = e.FromID
+    }
+}
 
-    // stable child order if "order" property exists
+func replaceEdge(list []*dgraph.Edge, old, e *dgraph.Edge) {
+    for i, x := range list {
+        if x == old { list[i] = e }
+    }
+}
+
+// unlinkEdge removes e from every index.
+func (m *MemoryStore) unlinkEdge(e *dgraph.Edge) {
+    gone := map[*dgraph.Edge]bool{e: true}
+    m.edges = withoutEdges(m.edges, gone)
+    m.edgesByFrom[e.FromID] = withoutEdges(m.edgesByFrom[e.FromID], gone)
+    m.edgesByTo[e.ToID] = withoutEdges(m.edgesByTo[e.ToID], gone)
+    if m.edgeByID[e.ID] == e { delete(m.edgeByID, e.ID) }
+    if e.EdgeType != "PARENT_OF" { return }
+    kids := m.parentOf[e.FromID]
+    for i, k := range kids {
+        if k == e.ToID { m.parentOf[e.FromID] = append(kids[:i:i], kids[i+1:]...); break }
+    }
+    if m.parentID[e.ToID] == e.FromID { delete(m.parentID, e.ToID) }
+}
+
+// sortChildren keeps child lists in props.order.
+func (m *MemoryStore) sortChildren() {
     for p, kids := range m.parentOf {
         sort.SliceStable(kids, func(i, j int) bool {
             a, b := kids[i], kids[j]
@@ -79,15 +231,93 @@ func (m *MemoryStore) LoadJSONL(path string) error {
         })
         m.parentOf[p] = kids
     }
-    return nil
+}
+
+// putNode stores n under its ID. A node whose version hash differs from the stored one
+// is another version of the same provision: the version with the latest effective date
+// (then fetched_at) stays current and the others are kept as history. Re-loading a
+// version that is already known just replaces it.
+func (m *MemoryStore) putNode(n *dgraph.Node) {
+    cur, ok := m.nodes[n.ID]
+    if !ok || cur.Version == nil || n.Version == nil || cur.Version.Hash == n.Version.Hash || n.Version.Hash == "" {
+        m.nodes[n.ID] = n
+        return
+    }
+    hist := m.history[n.ID]
+    for i, h := range hist {
+        if h.Version != nil && h.Version.Hash == n.Version.Hash { hist[i] = n; return }
+    }
+    if versionBefore(cur.Version, n.Version) {
+        hist = append(hist, cur)
+        m.nodes[n.ID] = n
+    } else {
+        hist = append(hist, n)
+    }
+    sort.SliceStable(hist, func(i, j int) bool { return versionBefore(hist[i].Version, hist[j].Version) })
+    m.history[n.ID] = hist
+}
+
+func versionBefore(a, b *dgraph.Version) bool {
+    if a.EffectiveDate != b.EffectiveDate { return a.EffectiveDate < b.EffectiveDate }
+    return a.FetchedAt < b.FetchedAt
+}
+
+// History returns every known version of id, oldest first; the last one is the current node.
+func (m *MemoryStore) History(id string) []*dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    n, ok := m.nodes[id]
+    if !ok { return nil }
+    out := make([]*dgraph.Node, 0, len(m.history[id])+1)
+    out = append(out, m.history[id]...)
+    return append(out, n)
 }
 
 func (m *MemoryStore) GetNode(id string) (*dgraph.Node, bool) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     n, ok := m.nodes[id]
     return n, ok
 }
 
+// Nodes returns every stored node ordered by ID.
+func (m *MemoryStore) Nodes() []*dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    out := make([]*dgraph.Node, 0, len(m.nodes))
+    for _, n := range m.nodes { out = append(out, n) }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+// Edges returns every stored edge in load order.
+func (m *MemoryStore) Edges() []*dgraph.Edge {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    out := make([]*dgraph.Edge, len(m.edges))
+    copy(out, m.edges)
+    return out
+}
+
+// EdgesOf returns every edge that starts or ends at id, outgoing first.
+func (m *MemoryStore) EdgesOf(id string) []*dgraph.Edge {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    out := make([]*dgraph.Edge, 0, len(m.edgesByFrom[id])+len(m.edgesByTo[id]))
+    out = append(out, m.edgesByFrom[id]...)
+    for _, e := range m.edgesByTo[id] {
+        if e.FromID != id { out = append(out, e) }
+    }
+    return out
+}
+
 func (m *MemoryStore) GetChildren(id string) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.children(id)
+}
+
+func (m *MemoryStore) children(id string) ([]*dgraph.Node, []*dgraph.Edge) {
     children := m.parentOf[id]
     nodes := make([]*dgraph.Node, 0, len(children))
     edges := make([]*dgraph.Edge, 0, len(children))
@@ -100,7 +330,163 @@ func (m *MemoryStore) GetChildren(id string) ([]*dgraph.Node, []*dgraph.Edge) {
     return nodes, edges
 }
 
+// GetSiblings returns the other children of id's parent in PARENT_OF order, with the parent's edges to them.
+func (m *MemoryStore) GetSiblings(id string) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    p := m.parentID[id]
+    if p == "" { return nil, nil }
+    ns, es := m.children(p)
+    nodes := make([]*dgraph.Node, 0, len(ns))
+    edges := make([]*dgraph.Edge, 0, len(es))
+    for _, n := range ns { if n.ID != id { nodes = append(nodes, n) } }
+    for _, e := range es { if e.ToID != id { edges = append(edges, e) } }
+    return nodes, edges
+}
+
+// SiblingNeighbors returns up to n siblings immediately before (prev) or after id, in PARENT_OF order.
+func (m *MemoryStore) SiblingNeighbors(id string, prev bool, n int) []*dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    p := m.parentID[id]
+    if p == "" { return nil }
+    return m.window(m.parentOf[p], id, prev, n)
+}
+
+// DocumentNeighbors returns up to n nodes before (prev) or after id in document order, crossing
+// chapter and title boundaries. Document order is a pre-order walk of the enclosing CODE (or the
+// topmost ancestor when there is none); only nodes sharing id's first label are considered, so a
+// SECTION pages to the next SECTION.
+func (m *MemoryStore) DocumentNeighbors(id string, prev bool, n int) []*dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    cur, ok := m.nodes[id]
+    if !ok { return nil }
+    root := id
+    for a := id; a != ""; a = m.parentID[a] {
+        root = a
+        if an, ok := m.nodes[a]; ok && hasLabel(an, "CODE") { break }
+    }
+    label := ""
+    if len(cur.Labels) > 0 { label = cur.Labels[0] }
+    var seq []string
+    for _, nid := range m.preorder(root) {
+        if nd, ok := m.nodes[nid]; ok && (label == "" || hasLabel(nd, label)) { seq = append(seq, nid) }
+    }
+    return m.window(seq, id, prev, n)
+}
+
+// preorder lists root and its PARENT_OF descendants in document order.
+func (m *MemoryStore) preorder(root string) []string {
+    var out []string
+    _ = m.walkSubtree(root, func(n *dgraph.Node, depth int) error {
+        out = append(out, n.ID)
+        return nil
+    })
+    return out
+}
+
+// WalkSubtree visits root and its PARENT_OF descendants depth-first in document order
+// (children by props.order), passing each node's depth below root. Walking stops at the
+// first error returned by fn.
+//
+// The subtree is snapshotted under the read lock and fn runs without it, so a slow
+// consumer (a client reading a streamed document) never holds up writers.
+func (m *MemoryStore) WalkSubtree(root string, fn func(n *dgraph.Node, depth int) error) error {
+    type visit struct{ n *dgraph.Node; d int }
+    var seq []visit
+    m.mu.RLock()
+    err := m.walkSubtree(root, func(n *dgraph.Node, depth int) error {
+        seq = append(seq, visit{n, depth})
+        return nil
+    })
+    m.mu.RUnlock()
+    if err != nil { return err }
+    for _, v := range seq {
+        if err := fn(v.n, v.d); err != nil { return err }
+    }
+    return nil
+}
+
+func (m *MemoryStore) walkSubtree(root string, fn func(n *dgraph.Node, depth int) error) error {
+    if _, ok := m.nodes[root]; !ok { return errors.New("root not found") }
+    type item struct{ id string; d int }
+    seen := make(map[string]struct{})
+    stack := []item{{root, 0}}
+    for len(stack) > 0 {
+        cur := stack[len(stack)-1]
+        stack = stack[:len(stack)-1]
+        if _, ok := seen[cur.id]; ok { continue }
+        seen[cur.id] = struct{}{}
+        if n, ok := m.nodes[cur.id]; ok {
+            if err := fn(n, cur.d); err != nil { return err }
+        }
+        kids := m.parentOf[cur.id]
+        for i := len(kids) - 1; i >= 0; i-- { stack = append(stack, item{kids[i], cur.d + 1}) }
+    }
+    return nil
+}
+
+// Subtree returns root and its descendants in document order with the PARENT_OF edges
+// between them, followed by the CITES edges leaving the subtree. Cited nodes outside the
+// subtree are appended after the subtree nodes so references can be labeled.
+func (m *MemoryStore) Subtree(root string) ([]*dgraph.Node, []*dgraph.Edge, error) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    var nodes []*dgraph.Node
+    in := make(map[string]struct{})
+    err := m.walkSubtree(root, func(n *dgraph.Node, depth int) error {
+        nodes = append(nodes, n)
+        in[n.ID] = struct{}{}
+        return nil
+    })
+    if err != nil { return nil, nil, err }
+    var edges, cites []*dgraph.Edge
+    var cited []*dgraph.Node
+    seen := make(map[string]struct{})
+    for _, n := range nodes {
+        for _, e := range m.edgesByFrom[n.ID] {
+            switch e.EdgeType {
+            case "PARENT_OF":
+                if _, ok := in[e.ToID]; ok { edges = append(edges, e) }
+            case "CITES":
+                cites = append(cites, e)
+                _, inside := in[e.ToID]
+                _, dup := seen[e.ToID]
+                if t, ok := m.nodes[e.ToID]; ok && !inside && !dup {
+                    seen[e.ToID] = struct{}{}
+                    cited = append(cited, t)
+                }
+            }
+        }
+    }
+    return append(nodes, cited...), append(edges, cites...), nil
+}
+
+// window returns up to n existing nodes adjacent to id within seq, kept in seq order.
+func (m *MemoryStore) window(seq []string, id string, prev bool, n int) []*dgraph.Node {
+    idx := -1
+    for i, s := range seq { if s == id { idx = i; break } }
+    if idx < 0 || n <= 0 { return nil }
+    start, end := idx+1, idx+1+n
+    if prev { start, end = idx-n, idx }
+    if start < 0 { start = 0 }
+    if end > len(seq) { end = len(seq) }
+    out := make([]*dgraph.Node, 0, end-start)
+    for _, nid := range seq[start:end] {
+        if nd, ok := m.nodes[nid]; ok { out = append(out, nd) }
+    }
+    return out
+}
+
+func hasLabel(n *dgraph.Node, label string) bool {
+    for _, l := range n.Labels { if l == label { return true } }
+    return false
+}
+
 func (m *MemoryStore) GetParentsPath(id string) ([]string, []string) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     var nodes []string
     var edges []string
     cur := id
@@ -115,47 +501,75 @@ func (m *MemoryStore) GetParentsPath(id string) ([]string, []string) {
 }
 
 func (m *MemoryStore) SliceFromRoot(root string, depth int, labelFilter map[string]struct{}) ([]*dgraph.Node, []*dgraph.Edge, error) {
-    if _, ok := m.nodes[root]; !ok { return nil, nil, errors.New("root not found") }
-    visited := make(map[string]struct{})
-    q := []struct{ id string; d int }{{root, 0}}
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     var nodes []*dgraph.Node
     var edges []*dgraph.Edge
+    err := m.walkSlice(root, depth, labelFilter,
+        func(n *dgraph.Node) error { nodes = append(nodes, n); return nil },
+        func(e *dgraph.Edge) error { edges = append(edges, e); return nil })
+    if err != nil { return nil, nil, err }
+    return nodes, edges, nil
+}
+
+// WalkSlice produces the SliceFromRoot result item by item, in walk order: nodes
+// breadth-first as they are dequeued and each distinct PARENT_OF edge as it is followed.
+// It stops at the first error returned by a callback. As with WalkSubtree, the walk is
+// snapshotted under the read lock and the callbacks run without it.
+func (m *MemoryStore) WalkSlice(root string, depth int, labelFilter map[string]struct{}, onNode func(*dgraph.Node) error, onEdge func(*dgraph.Edge) error) error {
+    type item struct{ n *dgraph.Node; e *dgraph.Edge }
+    var seq []item
+    m.mu.RLock()
+    err := m.walkSlice(root, depth, labelFilter,
+        func(n *dgraph.Node) error { seq = append(seq, item{n: n}); return nil },
+        func(e *dgraph.Edge) error { seq = append(seq, item{e: e}); return nil })
+    m.mu.RUnlock()
+    if err != nil { return err }
+    for _, it := range seq {
+        if it.n != nil { err = onNode(it.n) } else { err = onEdge(it.e) }
+        if err != nil { return err }
+    }
+    return nil
+}
+
+func (m *MemoryStore) walkSlice(root string, depth int, labelFilter map[string]struct{}, onNode func(*dgraph.Node) error, onEdge func(*dgraph.Edge) error) error {
+    if _, ok := m.nodes[root]; !ok { return errors.New("root not found") }
+    visited := make(map[string]struct{})
+    dedup := make(map[string]struct{})
+    q := []struct{ id string; d int }{{root, 0}}
     for len(q) > 0 {
         cur := q[0]; q = q[1:]
         if _, ok := visited[cur.id]; ok { continue }
         visited[cur.id] = struct{}{}
         n := m.nodes[cur.id]
         if n != nil {
-            if len(labelFilter) > 0 {
-                keep := false
-                for _, l := range n.Labels { if _, ok := labelFilter[l]; ok { keep = true; break } }
-                if keep || cur.d == 0 { nodes = append(nodes, n) }
-            } else {
-                nodes = append(nodes, n)
+            keep := len(labelFilter) == 0 || cur.d == 0
+            for _, l := range n.Labels { if _, ok := labelFilter[l]; ok { keep = true; break } }
+            if keep {
+                if err := onNode(n); err != nil { return err }
             }
         }
         if cur.d >= depth { continue }
         for _, e := range m.edgesByFrom[cur.id] {
             if e.EdgeType != "PARENT_OF" { continue }
-            edges = append(edges, e)
+            key := e.FromID + "->" + e.ToID + ":" + e.EdgeType
+            if _, ok := dedup[key]; !ok {
+                dedup[key] = struct{}{}
+                if err := onEdge(e); err != nil { return err }
+            }
             q = append(q, struct{ id string; d int }{e.ToID, cur.d + 1})
         }
     }
-    // Deduplicate edges
-    dedup := make(map[string]struct{})
-    outEdges := make([]*dgraph.Edge, 0, len(edges))
-    for _, e := range edges {
-        key := e.FromID + "->" + e.ToID + ":" + e.EdgeType
-        if _, ok := dedup[key]; ok { continue }
-        dedup[key] = struct{}{}
-        outEdges = append(outEdges, e)
-    }
-    return nodes, outEdges, nil
+    return nil
 }
 
+// Search returns nodes whose title, text or citation contains q, at most limit of them
+// when limit > 0, in no particular order.
 func (m *MemoryStore) Search(q string, jurisdiction, code string, limit int) []dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     ql := strings.ToLower(q)
-    out := make([]dgraph.Node, 0, limit)
+    var out []dgraph.Node
     for _, n := range m.nodes {
         if jurisdiction != "" {
             if j, _ := n.Props["jurisdiction"].(string); strings.ToUpper(j) != strings.ToUpper(jurisdiction) { continue }
@@ -165,7 +579,7 @@ func (m *MemoryStore) Search(q string, jurisdiction, code string, limit int) []d
         }
         if q == "" || strings.Contains(strings.ToLower(n.Title), ql) || strings.Contains(strings.ToLower(n.Text), ql) || strings.Contains(strings.ToLower(n.Citation), ql) {
             out = append(out, *n)
-            if len(out) >= limit { break }
+            if limit > 0 && len(out) >= limit { break }
         }
     }
     return out
@@ -173,6 +587,8 @@ func (m *MemoryStore) Search(q string, jurisdiction, code string, limit int) []d
 
 // GetCitations returns nodes that cite the given target via CITES edges and those edges.
 func (m *MemoryStore) GetCitations(targetID string) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     var nodes []*dgraph.Node
     var edges []*dgraph.Edge
     for _, e := range m.edgesByTo[targetID] {
@@ -187,6 +603,8 @@ func (m *MemoryStore) GetCitations(targetID string) ([]*dgraph.Node, []*dgraph.E
 
 // GetOutgoingCitations returns nodes that the given source cites via CITES edges and those edges.
 func (m *MemoryStore) GetOutgoingCitations(sourceID string) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     var nodes []*dgraph.Node
     var edges []*dgraph.Edge
     for _, e := range m.edgesByFrom[sourceID] {
@@ -199,8 +617,31 @@ func (m *MemoryStore) GetOutgoingCitations(sourceID string) ([]*dgraph.Node, []*
     return nodes, edges
 }
 
+// GetAmendments returns the documents amending id via AMENDS edges and those edges. With
+// ancestors, amendments of the enclosing units (a rule revising a whole CFR part) are
+// included, nearest first.
+func (m *MemoryStore) GetAmendments(id string, ancestors bool) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    var nodes []*dgraph.Node
+    var edges []*dgraph.Edge
+    for cur := id; cur != ""; cur = m.parentID[cur] {
+        for _, e := range m.edgesByTo[cur] {
+            if e.EdgeType != "AMENDS" { continue }
+            if n, ok := m.nodes[e.FromID]; ok {
+                nodes = append(nodes, n)
+                edges = append(edges, e)
+            }
+        }
+        if !ancestors { break }
+    }
+    return nodes, edges
+}
+
 // GetTopics returns all nodes labeled TOPIC.
 func (m *MemoryStore) GetTopics() []*dgraph.Node {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     out := make([]*dgraph.Node, 0)
     for _, n := range m.nodes {
         for _, l := range n.Labels {
@@ -215,6 +656,8 @@ func (m *MemoryStore) GetTopics() []*dgraph.Node {
 
 // GetTopicAssociations returns nodes linked to the given topic via HAS_TOPIC edges and the edges themselves.
 func (m *MemoryStore) GetTopicAssociations(topicID string) ([]*dgraph.Node, []*dgraph.Edge) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
     var nodes []*dgraph.Node
     var edges []*dgraph.Edge
     for _, e := range m.edgesByTo[topicID] {
@@ -1,8 +1,11 @@
 package graphrepo
 
 import (
+    "os"
     "path/filepath"
     "testing"
+
+    dgraph "lawmap/internal/domain/graph"
 )
 
 func exFile() string {
@@ -76,3 +79,80 @@ func TestChildrenOrder(t *testing.T) {
         t.Fatalf("expected first child to be §3343, got %s", nodes[0].ID)
     }
 }
+
+func TestSiblingsAndNeighbors(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    sibs, edges := m.GetSiblings("CA:CIV:T02:CH02:§3342")
+    if len(sibs) != 1 || sibs[0].ID != "CA:CIV:T02:CH02:§3343" || len(edges) != 1 {
+        t.Fatalf("expected §3343 as only sibling; got %d nodes", len(sibs))
+    }
+    // §3343 (order 5) precedes §3342 (order 10)
+    next := m.SiblingNeighbors("CA:CIV:T02:CH02:§3343", false, 1)
+    if len(next) != 1 || next[0].ID != "CA:CIV:T02:CH02:§3342" { t.Fatalf("unexpected next sibling: %v", next) }
+    if prev := m.SiblingNeighbors("CA:CIV:T02:CH02:§3343", true, 1); len(prev) != 0 { t.Fatalf("expected no previous sibling") }
+    // Under US:USC:T18, §924(e) (order 924) precedes §1028A (order 1028)
+    prev := m.SiblingNeighbors("US:USC:T18:§1028A", true, 5)
+    if len(prev) != 1 || prev[0].ID != "US:USC:T18:§924(e)" { t.Fatalf("unexpected previous sibling: %v", prev) }
+}
+
+func TestDocumentNeighborsCrossBoundaries(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    // Add a second chapter so paging has to cross from CH02 into CH03.
+    extra := filepath.Join(t.TempDir(), "extra.jsonl")
+    lines := `{"type":"node","id":"CA:CIV:T02:CH03","labels":["CHAPTER"],"title":"Chapter 3"}
+{"type":"node","id":"CA:CIV:T02:CH03:§3350","labels":["SECTION"],"title":"Section 3350"}
+{"type":"edge","edge_type":"PARENT_OF","from_id":"CA:CIV:T02","to_id":"CA:CIV:T02:CH03","props":{"order":3}}
+{"type":"edge","edge_type":"PARENT_OF","from_id":"CA:CIV:T02:CH03","to_id":"CA:CIV:T02:CH03:§3350","props":{"order":1}}
+`
+    if err := os.WriteFile(extra, []byte(lines), 0o644); err != nil { t.Fatal(err) }
+    if err := m.LoadJSONL(extra); err != nil { t.Fatal(err) }
+    next := m.DocumentNeighbors("CA:CIV:T02:CH02:§3342", false, 2)
+    if len(next) != 1 || next[0].ID != "CA:CIV:T02:CH03:§3350" { t.Fatalf("expected to cross into CH03; got %v", next) }
+    prev := m.DocumentNeighbors("CA:CIV:T02:CH03:§3350", true, 2)
+    if len(prev) != 2 || prev[0].ID != "CA:CIV:T02:CH02:§3343" || prev[1].ID != "CA:CIV:T02:CH02:§3342" {
+        t.Fatalf("unexpected previous sections: %v", prev)
+    }
+}
+
+func TestWalkSubtreeDoesNotBlockWriters(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    var ids []string
+    err := m.WalkSubtree("CA:CIV:T02:CH02", func(n *dgraph.Node, depth int) error {
+        // a writer inside the walk would deadlock if the walk held the read lock
+        if depth == 0 { m.Apply([]*dgraph.Node{{ID: "CA:CIV:T02:CH02:§3399"}}, []*dgraph.Edge{{EdgeType: "PARENT_OF", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH02:§3399"}}) }
+        ids = append(ids, n.ID)
+        return nil
+    })
+    if err != nil { t.Fatal(err) }
+    if len(ids) != 3 || ids[0] != "CA:CIV:T02:CH02" { t.Fatalf("expected the subtree as of the walk's start; got %v", ids) }
+    if kids, _ := m.GetChildren("CA:CIV:T02:CH02"); len(kids) != 3 { t.Fatalf("expected the write to land; got %d children", len(kids)) }
+}
+
+func TestApplyReplacesEdgeByID(t *testing.T) {
+    m := NewMemoryStore()
+    if err := m.LoadJSONL(exFile()); err != nil { t.Fatal(err) }
+    m.Apply([]*dgraph.Node{{ID: "CA:CIV:T02:CH03"}}, []*dgraph.Edge{{ID: "x1", EdgeType: "PARENT_OF", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH03", Props: map[string]any{"order": float64(1)}}})
+    held, _ := m.Edge("x1")
+
+    // same endpoints, new props: a new edge takes the old one's place, the held one is untouched
+    m.Apply(nil, []*dgraph.Edge{{ID: "x1", EdgeType: "PARENT_OF", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH03", Props: map[string]any{"order": float64(99)}}})
+    if held.Props["order"] != float64(1) { t.Fatalf("stored edge mutated: %v", held.Props) }
+    kids, edges := m.GetChildren("CA:CIV:T02:CH02")
+    if len(kids) != 3 || kids[2].ID != "CA:CIV:T02:CH03" || edges[2].Props["order"] != float64(99) { t.Fatalf("unexpected children after refresh: %v", kids) }
+
+    // new endpoints: the old edge leaves every index
+    m.Apply(nil, []*dgraph.Edge{{ID: "x1", EdgeType: "PARENT_OF", FromID: "CA:CIV:T02", ToID: "CA:CIV:T02:CH03"}})
+    if kids, _ := m.GetChildren("CA:CIV:T02:CH02"); len(kids) != 2 { t.Fatalf("old parent keeps the child: %v", kids) }
+    for _, e := range m.EdgesOf("CA:CIV:T02:CH03") {
+        if e.FromID == "CA:CIV:T02:CH02" { t.Fatalf("old edge still indexed: %+v", e) }
+    }
+    if n, _ := m.GetParentsPath("CA:CIV:T02:CH03"); len(n) < 2 || n[len(n)-2] != "CA:CIV:T02" { t.Fatalf("unexpected parents: %v", n) }
+    count := 0
+    for _, e := range m.Edges() {
+        if e.ID == "x1" { count++ }
+    }
+    if count != 1 { t.Fatalf("expected one x1 edge, got %d", count) }
+}
@@ -0,0 +1,155 @@
+package index
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "unicode"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Match is one scored result of a similarity lookup.
+type Match struct {
+    ID    string
+    Score float64
+}
+
+// Similarity is a TF-IDF index over node text. Vectors are L2-normalized so the
+// dot product of two documents is their cosine similarity.
+type Similarity struct {
+    vectors  map[string]map[string]float64 // node ID -> term -> weight
+    postings map[string][]posting          // term -> documents containing it
+    nodes    map[string]*dgraph.Node
+}
+
+type posting struct {
+    id     string
+    weight float64
+}
+
+// stopWords are dropped before weighting; they carry no signal across statutes.
+var stopWords = map[string]struct{}{
+    "a": {}, "an": {}, "and": {}, "any": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {},
+    "for": {}, "from": {}, "in": {}, "is": {}, "it": {}, "of": {}, "on": {}, "or": {}, "shall": {},
+    "such": {}, "that": {}, "the": {}, "this": {}, "to": {}, "which": {}, "with": {},
+}
+
+// NewSimilarity builds the index from node text. Nodes without text are skipped.
+func NewSimilarity(nodes []*dgraph.Node) *Similarity {
+    s := &Similarity{
+        vectors:  make(map[string]map[string]float64),
+        postings: make(map[string][]posting),
+        nodes:    make(map[string]*dgraph.Node),
+    }
+    tfs := make(map[string]map[string]int)
+    df := make(map[string]int)
+    for _, n := range nodes {
+        toks := tokenize(n.Text)
+        if len(toks) == 0 { continue }
+        tf := make(map[string]int)
+        for _, t := range toks { tf[t]++ }
+        for t := range tf { df[t]++ }
+        tfs[n.ID] = tf
+        s.nodes[n.ID] = n
+    }
+    total := float64(len(tfs))
+    // iterate IDs in order so postings lists are deterministic
+    ids := make([]string, 0, len(tfs))
+    for id := range tfs { ids = append(ids, id) }
+    sort.Strings(ids)
+    for _, id := range ids {
+        vec := make(map[string]float64, len(tfs[id]))
+        norm := 0.0
+        for t, c := range tfs[id] {
+            // smoothed idf keeps terms shared by every document from zeroing out
+            w := (1 + math.Log(float64(c))) * (1 + math.Log((1+total)/(1+float64(df[t]))))
+            vec[t] = w
+            norm += w * w
+        }
+        norm = math.Sqrt(norm)
+        for t := range vec {
+            vec[t] /= norm
+            s.postings[t] = append(s.postings[t], posting{id: id, weight: vec[t]})
+        }
+        s.vectors[id] = vec
+    }
+    return s
+}
+
+// Similar returns up to limit nodes most similar to id, highest score first.
+// When jurisdiction is set only nodes with a matching props.jurisdiction are returned.
+func (s *Similarity) Similar(id, jurisdiction string, limit int) []Match {
+    vec, ok := s.vectors[id]
+    if !ok { return nil }
+    scores := make(map[string]float64)
+    for t, w := range vec {
+        for _, p := range s.postings[t] {
+            if p.id == id { continue }
+            scores[p.id] += w * p.weight
+        }
+    }
+    out := make([]Match, 0, len(scores))
+    for cid, sc := range scores {
+        if jurisdiction != "" {
+            j, _ := s.nodes[cid].Props["jurisdiction"].(string)
+            if !strings.EqualFold(j, jurisdiction) { continue }
+        }
+        out = append(out, Match{ID: cid, Score: round(sc)})
+    }
+    sortMatches(out)
+    if limit > 0 && len(out) > limit { out = out[:limit] }
+    return out
+}
+
+// ProposeEdges compares every indexed pair and returns candidate edges for human review.
+// Pairs scoring at least sameAs become SAME_AS edges; others at or above threshold become
+// SIMILAR_TO edges. Each edge carries its score and status "proposed" in props.
+func (s *Similarity) ProposeEdges(threshold, sameAs float64) []*dgraph.Edge {
+    ids := make([]string, 0, len(s.vectors))
+    for id := range s.vectors { ids = append(ids, id) }
+    sort.Strings(ids)
+    var out []*dgraph.Edge
+    for _, a := range ids {
+        for _, m := range s.Similar(a, "", 0) {
+            // each unordered pair once; direction is from the lower ID
+            if m.ID <= a || m.Score < threshold { continue }
+            et := "SIMILAR_TO"
+            if m.Score >= sameAs { et = "SAME_AS" }
+            out = append(out, &dgraph.Edge{
+                ID:       fmt.Sprintf("sim:%s->%s", a, m.ID),
+                EdgeType: et,
+                FromID:   a,
+                ToID:     m.ID,
+                Props:    map[string]any{"score": m.Score, "method": "tfidf", "status": "proposed"},
+            })
+        }
+    }
+    return out
+}
+
+func sortMatches(ms []Match) {
+    sort.Slice(ms, func(i, j int) bool {
+        if ms[i].Score != ms[j].Score { return ms[i].Score > ms[j].Score }
+        return ms[i].ID < ms[j].ID
+    })
+}
+
+// round trims float noise so identical texts score exactly 1.
+func round(f float64) float64 {
+    return math.Round(f*10000) / 10000
+}
+
+func tokenize(text string) []string {
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    out := words[:0]
+    for _, w := range words {
+        if len(w) < 2 { continue }
+        if _, ok := stopWords[w]; ok { continue }
+        out = append(out, w)
+    }
+    return out
+}
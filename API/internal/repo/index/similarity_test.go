@@ -0,0 +1,53 @@
+package index
+
+import (
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func testNodes() []*dgraph.Node {
+    return []*dgraph.Node{
+        {ID: "US:CONST:AmdIV", Text: "The right of the people to be secure in their persons, houses, papers", Props: map[string]any{"jurisdiction": "US"}},
+        {ID: "CA:CONS:ArtI:§13", Text: "The right of the people to be secure in their persons, houses, papers", Props: map[string]any{"jurisdiction": "CA"}},
+        {ID: "CA:CIV:§3342", Text: "Any owner of any dog is liable for damages suffered by any person bitten by the dog", Props: map[string]any{"jurisdiction": "CA"}},
+        {ID: "CA:CIV:§3341", Text: "Any owner of a dog is liable when the dog bites a person", Props: map[string]any{"jurisdiction": "CA"}},
+        {ID: "CA:CIV:empty", Props: map[string]any{"jurisdiction": "CA"}},
+    }
+}
+
+func TestSimilarRanksIdenticalTextFirst(t *testing.T) {
+    s := NewSimilarity(testNodes())
+    got := s.Similar("US:CONST:AmdIV", "", 10)
+    if len(got) == 0 { t.Fatalf("expected matches") }
+    if got[0].ID != "CA:CONS:ArtI:§13" || got[0].Score != 1 {
+        t.Fatalf("expected identical text to score 1, got %+v", got[0])
+    }
+    if s.Similar("CA:CIV:empty", "", 10) != nil { t.Fatalf("nodes without text should have no matches") }
+}
+
+func TestSimilarJurisdictionFilter(t *testing.T) {
+    s := NewSimilarity(testNodes())
+    got := s.Similar("CA:CIV:§3342", "US", 10)
+    for _, m := range got {
+        if m.ID != "US:CONST:AmdIV" { t.Fatalf("unexpected match outside US: %s", m.ID) }
+    }
+    got = s.Similar("CA:CIV:§3342", "CA", 1)
+    if len(got) != 1 || got[0].ID != "CA:CIV:§3341" { t.Fatalf("expected §3341, got %+v", got) }
+}
+
+func TestProposeEdges(t *testing.T) {
+    s := NewSimilarity(testNodes())
+    edges := s.ProposeEdges(0.3, 0.95)
+    var sameAs, similar int
+    for _, e := range edges {
+        if e.FromID >= e.ToID { t.Fatalf("expected each pair once from lower ID: %s -> %s", e.FromID, e.ToID) }
+        if e.Props["status"] != "proposed" { t.Fatalf("expected proposed status") }
+        switch e.EdgeType {
+        case "SAME_AS": sameAs++
+        case "SIMILAR_TO": similar++
+        }
+    }
+    if sameAs != 1 { t.Fatalf("expected one SAME_AS proposal, got %d", sameAs) }
+    if similar == 0 { t.Fatalf("expected SIMILAR_TO proposals for the dog sections") }
+}
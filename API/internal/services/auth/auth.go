@@ -0,0 +1,189 @@
+// Package auth identifies API callers and says what they may do. Authenticators
+// turn a request's credentials into a Principal: static API keys from the config,
+// and JWTs checked against a local JWKS file. A Principal has a role, which routes
+// require, and optionally jurisdictions and codes it is licensed for.
+package auth
+
+import (
+    "context"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+
+    conf "lawmap/internal/config"
+)
+
+// Role is what a principal may do; each role includes the ones before it.
+type Role int
+
+const (
+    None Role = iota
+    Reader    // read the graph
+    Editor    // also manage subscriptions
+    Admin     // also run and pause the refresh scheduler
+)
+
+var roleNames = []string{"none", "reader", "editor", "admin"}
+
+func (r Role) String() string {
+    if r < 0 || int(r) >= len(roleNames) { return fmt.Sprintf("role(%d)", int(r)) }
+    return roleNames[r]
+}
+
+// ParseRole reads reader, editor or admin.
+func ParseRole(s string) (Role, error) {
+    for i, n := range roleNames {
+        if i > 0 && strings.EqualFold(s, n) { return Role(i), nil }
+    }
+    return None, fmt.Errorf("unknown role %q (want reader, editor or admin)", s)
+}
+
+var (
+    ErrNoCredentials = errors.New("authentication required")
+    ErrInvalid       = errors.New("invalid credentials")
+)
+
+// Principal is an authenticated caller. Empty Jurisdictions or Codes mean all.
+type Principal struct {
+    Name          string   `json:"name"`
+    Role          Role     `json:"-"`
+    Via           string   `json:"via"` // api_key|jwt
+    Jurisdictions []string `json:"jurisdictions,omitempty"`
+    Codes         []string `json:"codes,omitempty"`
+}
+
+// Scoped reports whether p is limited to some jurisdictions or codes.
+func (p *Principal) Scoped() bool { return p != nil && (len(p.Jurisdictions) > 0 || len(p.Codes) > 0) }
+
+// Allows reports whether content of the jurisdiction and code is licensed to p.
+// Content without a jurisdiction (topics, for one) is not licensed and always allowed;
+// a nil Principal is unrestricted. Comparisons ignore case, as in search.
+func (p *Principal) Allows(jurisdiction, code string) bool {
+    if !p.Scoped() || jurisdiction == "" { return true }
+    in := func(v string, list []string) bool {
+        if len(list) == 0 { return true }
+        for _, x := range list {
+            if strings.EqualFold(x, v) { return true }
+        }
+        return false
+    }
+    return in(jurisdiction, p.Jurisdictions) && in(code, p.Codes)
+}
+
+// ScopeKey identifies what p may see, for keys of cached responses.
+func (p *Principal) ScopeKey() string {
+    if !p.Scoped() { return "" }
+    return strings.ToUpper(strings.Join(p.Jurisdictions, ",") + "/" + strings.Join(p.Codes, ","))
+}
+
+// Authenticator reads credentials from a request. It returns ErrNoCredentials when
+// the request carries none it understands, so the next one can try.
+type Authenticator interface {
+    Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Service tries its authenticators in order.
+type Service struct {
+    authenticators []Authenticator
+    challenges     []string
+}
+
+// New builds the authenticators the config enables: API keys, then JWTs.
+func New(cfg conf.AuthConfig) (*Service, error) {
+    s := &Service{}
+    if len(cfg.APIKeys) > 0 {
+        keys, err := NewAPIKeys(cfg.APIKeys)
+        if err != nil { return nil, err }
+        s.Add(keys, `ApiKey realm="lawmap"`)
+    }
+    if cfg.JWT.JWKSFile != "" {
+        jwt, err := NewJWT(cfg.JWT)
+        if err != nil { return nil, err }
+        s.Add(jwt, `Bearer realm="lawmap"`)
+    }
+    if len(s.authenticators) == 0 { return nil, errors.New("auth: enabled without api_keys or jwt.jwks_file") }
+    return s, nil
+}
+
+// Add appends a, with the WWW-Authenticate challenge sent when credentials are missing.
+func (s *Service) Add(a Authenticator, challenge string) {
+    s.authenticators = append(s.authenticators, a)
+    if challenge != "" { s.challenges = append(s.challenges, challenge) }
+}
+
+// Challenge is the WWW-Authenticate value for a 401.
+func (s *Service) Challenge() string { return strings.Join(s.challenges, ", ") }
+
+// Authenticate returns the first principal an authenticator finds; ErrNoCredentials
+// when none found credentials, else the first failure (wrapping ErrInvalid).
+func (s *Service) Authenticate(r *http.Request) (*Principal, error) {
+    for _, a := range s.authenticators {
+        p, err := a.Authenticate(r)
+        if errors.Is(err, ErrNoCredentials) { continue }
+        return p, err
+    }
+    return nil, ErrNoCredentials
+}
+
+// APIKeys authenticates X-API-Key (or Authorization: ApiKey <key>) against static keys,
+// held as SHA-256 digests.
+type APIKeys struct {
+    keys map[[sha256.Size]byte]*Principal
+}
+
+// NewAPIKeys checks each key has a secret and a known role.
+func NewAPIKeys(keys []conf.APIKey) (*APIKeys, error) {
+    a := &APIKeys{keys: make(map[[sha256.Size]byte]*Principal, len(keys))}
+    for i, k := range keys {
+        name := k.Name
+        if name == "" { name = fmt.Sprintf("api_keys[%d]", i) }
+        role, err := ParseRole(k.Role)
+        if err != nil { return nil, fmt.Errorf("auth: %s: %w", name, err) }
+        var sum [sha256.Size]byte
+        switch {
+        case k.Key != "" && k.KeySHA256 != "":
+            return nil, fmt.Errorf("auth: %s: set key or key_sha256, not both", name)
+        case k.Key != "":
+            sum = sha256.Sum256([]byte(k.Key))
+        default:
+            b, err := hex.DecodeString(k.KeySHA256)
+            if err != nil || len(b) != sha256.Size { return nil, fmt.Errorf("auth: %s: key_sha256 must be 64 hex digits", name) }
+            copy(sum[:], b)
+        }
+        if _, dup := a.keys[sum]; dup { return nil, fmt.Errorf("auth: %s: duplicate key", name) }
+        a.keys[sum] = &Principal{Name: name, Role: role, Via: "api_key", Jurisdictions: k.Jurisdictions, Codes: k.Codes}
+    }
+    return a, nil
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+    key := r.Header.Get("X-API-Key")
+    if key == "" {
+        scheme, rest, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+        if !strings.EqualFold(scheme, "ApiKey") { return nil, ErrNoCredentials }
+        key = strings.TrimSpace(rest)
+    }
+    sum := sha256.Sum256([]byte(key))
+    // compare digests in constant time instead of indexing the map with the key
+    for k, p := range a.keys {
+        if subtle.ConstantTimeCompare(k[:], sum[:]) == 1 { return p, nil }
+    }
+    return nil, fmt.Errorf("%w: unknown API key", ErrInvalid)
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+    return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal of a request, or nil when auth is off.
+func FromContext(ctx context.Context) *Principal {
+    p, _ := ctx.Value(ctxKey{}).(*Principal)
+    return p
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "math/big"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    conf "lawmap/internal/config"
+)
+
+func TestAPIKeys(t *testing.T) {
+    keys, err := NewAPIKeys([]conf.APIKey{
+        {Name: "ui", Key: "k1", Role: "reader"},
+        {Name: "ops", KeySHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Role: "Admin"}, // "test"
+        {Name: "ca", Key: "k3", Role: "editor", Jurisdictions: []string{"CA"}, Codes: []string{"CIV"}},
+    })
+    if err != nil { t.Fatal(err) }
+    cases := []struct {
+        header, value string
+        name          string
+        role          Role
+        err           error
+    }{
+        {"X-API-Key", "k1", "ui", Reader, nil},
+        {"Authorization", "ApiKey test", "ops", Admin, nil},
+        {"X-API-Key", "k3", "ca", Editor, nil},
+        {"X-API-Key", "nope", "", None, ErrInvalid},
+        {"Authorization", "Bearer k1", "", None, ErrNoCredentials},
+        {"", "", "", None, ErrNoCredentials},
+    }
+    for _, c := range cases {
+        r := httptest.NewRequest("GET", "/", nil)
+        if c.header != "" { r.Header.Set(c.header, c.value) }
+        p, err := keys.Authenticate(r)
+        if c.err != nil {
+            if !errors.Is(err, c.err) { t.Errorf("%s %q: err=%v, want %v", c.header, c.value, err, c.err) }
+            continue
+        }
+        if err != nil || p.Name != c.name || p.Role != c.role { t.Errorf("%s %q: %+v, %v", c.header, c.value, p, err) }
+    }
+    for _, bad := range [][]conf.APIKey{
+        {{Key: "k", Role: "owner"}},
+        {{Role: "reader"}},
+        {{Key: "k", KeySHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Role: "reader"}},
+        {{Key: "k", Role: "reader"}, {Key: "k", Role: "admin"}},
+    } {
+        if _, err := NewAPIKeys(bad); err == nil { t.Errorf("accepted %+v", bad) }
+    }
+}
+
+func TestPrincipalAllows(t *testing.T) {
+    ca := &Principal{Jurisdictions: []string{"CA"}, Codes: []string{"CIV", "PEN"}}
+    us := &Principal{Jurisdictions: []string{"US"}}
+    cases := []struct {
+        p         *Principal
+        jur, code string
+        want      bool
+    }{
+        {nil, "US", "USC", true},
+        {&Principal{}, "US", "USC", true},
+        {ca, "CA", "CIV", true},
+        {ca, "ca", "pen", true},
+        {ca, "CA", "OPN", false},
+        {ca, "US", "CIV", false},
+        {ca, "", "", true},
+        {us, "US", "CFR", true},
+        {us, "CA", "CIV", false},
+    }
+    for _, c := range cases {
+        if got := c.p.Allows(c.jur, c.code); got != c.want { t.Errorf("%+v.Allows(%q, %q) = %v", c.p, c.jur, c.code, got) }
+    }
+    if ca.ScopeKey() == us.ScopeKey() || (&Principal{}).ScopeKey() != "" { t.Error("scope keys collide") }
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// sign makes a compact JWS; sign is the raw signature over the signing input.
+func sign(t *testing.T, hdr, claims map[string]any, signer func(digest []byte) []byte) string {
+    t.Helper()
+    h, _ := json.Marshal(hdr)
+    c, _ := json.Marshal(claims)
+    input := b64(h) + "." + b64(c)
+    d := sha256.Sum256([]byte(input))
+    return input + "." + b64(signer(d[:]))
+}
+
+func TestJWT(t *testing.T) {
+    rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil { t.Fatal(err) }
+    ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil { t.Fatal(err) }
+    jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+        {"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+        {"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+        {"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+    }})
+    path := filepath.Join(t.TempDir(), "jwks.json")
+    if err := os.WriteFile(path, jwks, 0o600); err != nil { t.Fatal(err) }
+    j, err := NewJWT(conf.JWTConfig{JWKSFile: path, Issuer: "https://auth.example.com/", Audience: "lawmap"})
+    if err != nil { t.Fatal(err) }
+    now := time.Unix(1_800_000_000, 0)
+    j.now = func() time.Time { return now }
+
+    byRSA := func(d []byte) []byte { s, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, d); return s }
+    byEC := func(d []byte) []byte {
+        r, s, _ := ecdsa.Sign(rand.Reader, ecKey, d)
+        return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+    }
+    claims := func(extra map[string]any) map[string]any {
+        c := map[string]any{"sub": "ana", "iss": "https://auth.example.com/", "aud": []string{"other", "lawmap"}, "exp": now.Add(time.Hour).Unix(), "role": "editor"}
+        for k, v := range extra {
+            if v == nil { delete(c, k) } else { c[k] = v }
+        }
+        return c
+    }
+    rs := map[string]any{"alg": "RS256", "kid": "rsa1"}
+    cases := []struct {
+        name  string
+        token string
+        role  Role
+        scope string // ScopeKey, or the error text when role is None
+    }{
+        {"RS256", sign(t, rs, claims(nil), byRSA), Editor, ""},
+        {"ES256 with scope", sign(t, map[string]any{"alg": "ES256", "kid": "ec1"}, claims(map[string]any{"jurisdictions": []string{"CA"}, "codes": "CIV", "aud": "lawmap"}), byEC), Editor, "CA/CIV"},
+        {"highest role of a list", sign(t, rs, claims(map[string]any{"role": []string{"reader", "admin", "owner"}}), byRSA), Admin, ""},
+        {"expired", sign(t, rs, claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()}), byRSA), None, "expired"},
+        {"within leeway", sign(t, rs, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}), byRSA), Editor, ""},
+        {"no exp", sign(t, rs, claims(map[string]any{"exp": nil}), byRSA), None, "no exp"},
+        {"not yet", sign(t, rs, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()}), byRSA), None, "not valid yet"},
+        {"issuer", sign(t, rs, claims(map[string]any{"iss": "https://evil.example.com/"}), byRSA), None, "issuer"},
+        {"audience", sign(t, rs, claims(map[string]any{"aud": "other"}), byRSA), None, "audience"},
+        {"no role", sign(t, rs, claims(map[string]any{"role": "owner"}), byRSA), None, "role claim"},
+        {"unknown kid", sign(t, map[string]any{"alg": "RS256", "kid": "enc"}, claims(nil), byRSA), None, "unknown kid"},
+        {"EC key, RS alg", sign(t, map[string]any{"alg": "RS256", "kid": "ec1"}, claims(nil), byRSA), None, "does not match"},
+        {"alg none", sign(t, map[string]any{"alg": "none", "kid": "rsa1"}, claims(nil), func([]byte) []byte { return nil }), None, "not accepted"},
+        {"HS256", sign(t, map[string]any{"alg": "HS256", "kid": "rsa1"}, claims(nil), byRSA), None, "not accepted"},
+        {"other key", sign(t, rs, claims(nil), byEC), None, "bad signature"},
+        {"garbage", "a.b", None, "not a JWS"},
+    }
+    for _, c := range cases {
+        p, err := j.Verify(c.token)
+        if c.role == None {
+            if err == nil || !strings.Contains(err.Error(), c.scope) { t.Errorf("%s: err=%v, want %q", c.name, err, c.scope) }
+            continue
+        }
+        if err != nil || p.Role != c.role || p.Name != "ana" || p.ScopeKey() != c.scope { t.Errorf("%s: %+v, %v", c.name, p, err) }
+    }
+
+    // a payload edited after signing fails, through the request path too
+    good := sign(t, rs, claims(nil), byRSA)
+    parts := strings.Split(good, ".")
+    forged, _ := json.Marshal(claims(map[string]any{"role": "admin"}))
+    r := httptest.NewRequest("GET", "/", nil)
+    r.Header.Set("Authorization", "Bearer "+parts[0]+"."+b64(forged)+"."+parts[2])
+    if _, err := j.Authenticate(r); !errors.Is(err, ErrInvalid) { t.Errorf("forged: %v", err) }
+    r.Header.Set("Authorization", "Bearer "+good)
+    if p, err := j.Authenticate(r); err != nil || p.Via != "jwt" { t.Errorf("good: %+v, %v", p, err) }
+}
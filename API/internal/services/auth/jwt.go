@@ -0,0 +1,227 @@
+package auth
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/sha512"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+
+    conf "lawmap/internal/config"
+)
+
+// JWT authenticates Authorization: Bearer tokens signed with RS256/384/512 or
+// ES256/384 by a key of a local JWKS file. Tokens must carry exp; nbf, iss and aud
+// are checked when present or configured. The role comes from RoleClaim (a string,
+// or a list of which the highest known role counts); jurisdictions and codes
+// claims scope the token as they do an API key.
+type JWT struct {
+    keys      map[string]any // kid -> *rsa.PublicKey or *ecdsa.PublicKey
+    issuer    string
+    audience  string
+    roleClaim string
+    now       func() time.Time
+}
+
+// jwtLeeway absorbs clock skew between the issuer and this server.
+const jwtLeeway = time.Minute
+
+// NewJWT loads the JWKS file named by cfg.
+func NewJWT(cfg conf.JWTConfig) (*JWT, error) {
+    b, err := os.ReadFile(cfg.JWKSFile)
+    if err != nil { return nil, fmt.Errorf("auth: read jwks: %w", err) }
+    keys, err := ParseJWKS(b)
+    if err != nil { return nil, fmt.Errorf("auth: %s: %w", cfg.JWKSFile, err) }
+    j := &JWT{keys: keys, issuer: cfg.Issuer, audience: cfg.Audience, roleClaim: cfg.RoleClaim, now: time.Now}
+    if j.roleClaim == "" { j.roleClaim = "role" }
+    return j, nil
+}
+
+type jwk struct {
+    Kty string `json:"kty"`
+    Kid string `json:"kid"`
+    Use string `json:"use"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+}
+
+// ParseJWKS reads the public RSA and EC (P-256, P-384) keys of a JWKS document,
+// by kid. Keys for use other than "sig" are skipped.
+func ParseJWKS(b []byte) (map[string]any, error) {
+    var set struct{ Keys []jwk `json:"keys"` }
+    if err := json.Unmarshal(b, &set); err != nil { return nil, fmt.Errorf("parse jwks: %w", err) }
+    keys := make(map[string]any, len(set.Keys))
+    for i, k := range set.Keys {
+        if k.Use != "" && k.Use != "sig" { continue }
+        num := func(s string) (*big.Int, error) {
+            b, err := base64.RawURLEncoding.DecodeString(s)
+            if err != nil || len(b) == 0 { return nil, fmt.Errorf("jwks key %d: bad base64url number", i) }
+            return new(big.Int).SetBytes(b), nil
+        }
+        switch k.Kty {
+        case "RSA":
+            n, err := num(k.N)
+            if err != nil { return nil, err }
+            e, err := num(k.E)
+            if err != nil { return nil, err }
+            keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+        case "EC":
+            var curve elliptic.Curve
+            switch k.Crv {
+            case "P-256":
+                curve = elliptic.P256()
+            case "P-384":
+                curve = elliptic.P384()
+            default:
+                return nil, fmt.Errorf("jwks key %d: unsupported curve %q", i, k.Crv)
+            }
+            x, err := num(k.X)
+            if err != nil { return nil, err }
+            y, err := num(k.Y)
+            if err != nil { return nil, err }
+            keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+        default:
+            return nil, fmt.Errorf("jwks key %d: unsupported kty %q", i, k.Kty)
+        }
+    }
+    if len(keys) == 0 { return nil, errors.New("jwks has no signing keys") }
+    return keys, nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+    scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+    if !strings.EqualFold(scheme, "Bearer") { return nil, ErrNoCredentials }
+    p, err := j.Verify(strings.TrimSpace(token))
+    if err != nil { return nil, fmt.Errorf("%w: %v", ErrInvalid, err) }
+    return p, nil
+}
+
+// claims are the registered claims checked here; the rest stay raw for the role and scope.
+type claims struct {
+    Sub string          `json:"sub"`
+    Iss string          `json:"iss"`
+    Aud json.RawMessage `json:"aud"`
+    Exp *float64        `json:"exp"`
+    Nbf *float64        `json:"nbf"`
+}
+
+// Verify checks a compact JWS and its claims and returns its principal.
+func (j *JWT) Verify(token string) (*Principal, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 { return nil, errors.New("token is not a JWS") }
+    var hdr struct{ Alg, Kid string }
+    if err := decodeSegment(parts[0], &hdr); err != nil { return nil, fmt.Errorf("header: %w", err) }
+    key, ok := j.keys[hdr.Kid]
+    if !ok && hdr.Kid == "" && len(j.keys) == 1 {
+        for _, k := range j.keys { key, ok = k, true }
+    }
+    if !ok { return nil, fmt.Errorf("unknown kid %q", hdr.Kid) }
+    sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil { return nil, errors.New("signature is not base64url") }
+    if err := verifySignature(hdr.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil { return nil, err }
+
+    var c claims
+    if err := decodeSegment(parts[1], &c); err != nil { return nil, fmt.Errorf("claims: %w", err) }
+    var raw map[string]any
+    if err := decodeSegment(parts[1], &raw); err != nil { return nil, fmt.Errorf("claims: %w", err) }
+    now := j.now()
+    if c.Exp == nil { return nil, errors.New("token has no exp") }
+    if now.Add(-jwtLeeway).After(time.Unix(int64(*c.Exp), 0)) { return nil, errors.New("token expired") }
+    if c.Nbf != nil && now.Add(jwtLeeway).Before(time.Unix(int64(*c.Nbf), 0)) { return nil, errors.New("token not valid yet") }
+    if j.issuer != "" && c.Iss != j.issuer { return nil, fmt.Errorf("issuer %q not accepted", c.Iss) }
+    if j.audience != "" && !hasAudience(c.Aud, j.audience) { return nil, errors.New("token is for another audience") }
+
+    p := &Principal{Name: c.Sub, Via: "jwt", Jurisdictions: stringList(raw["jurisdictions"]), Codes: stringList(raw["codes"])}
+    for _, name := range stringList(raw[j.roleClaim]) {
+        if role, err := ParseRole(name); err == nil && role > p.Role { p.Role = role }
+    }
+    if p.Role == None { return nil, fmt.Errorf("token has no known %s claim", j.roleClaim) }
+    return p, nil
+}
+
+func decodeSegment(s string, v any) error {
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil { return errors.New("not base64url") }
+    return json.Unmarshal(b, v)
+}
+
+// verifySignature checks sig over signed with key under alg; the key type must fit
+// the algorithm, so an RSA key never validates an HMAC or EC token.
+func verifySignature(alg string, key any, signed, sig []byte) error {
+    var h crypto.Hash
+    switch alg {
+    case "RS256", "ES256":
+        h = crypto.SHA256
+    case "RS384", "ES384":
+        h = crypto.SHA384
+    case "RS512":
+        h = crypto.SHA512
+    default:
+        return fmt.Errorf("alg %q not accepted", alg)
+    }
+    var digest []byte
+    switch h {
+    case crypto.SHA256:
+        d := sha256.Sum256(signed)
+        digest = d[:]
+    case crypto.SHA384:
+        d := sha512.Sum384(signed)
+        digest = d[:]
+    default:
+        d := sha512.Sum512(signed)
+        digest = d[:]
+    }
+    switch k := key.(type) {
+    case *rsa.PublicKey:
+        if alg[0] != 'R' { break }
+        if rsa.VerifyPKCS1v15(k, h, digest, sig) != nil { return errors.New("bad signature") }
+        return nil
+    case *ecdsa.PublicKey:
+        size := (k.Curve.Params().BitSize + 7) / 8
+        if alg[0] != 'E' || len(sig) != 2*size || (h == crypto.SHA256) != (size == 32) { break }
+        r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+        if !ecdsa.Verify(k, digest, r, s) { return errors.New("bad signature") }
+        return nil
+    }
+    return fmt.Errorf("alg %q does not match the key", alg)
+}
+
+// hasAudience reads aud as a string or a list of strings.
+func hasAudience(raw json.RawMessage, want string) bool {
+    var one string
+    if json.Unmarshal(raw, &one) == nil { return one == want }
+    var many []string
+    if json.Unmarshal(raw, &many) != nil { return false }
+    for _, a := range many {
+        if a == want { return true }
+    }
+    return false
+}
+
+// stringList reads a claim that is a string or a list of strings.
+func stringList(v any) []string {
+    switch x := v.(type) {
+    case string:
+        return []string{x}
+    case []any:
+        var out []string
+        for _, e := range x {
+            if s, ok := e.(string); ok { out = append(out, s) }
+        }
+        return out
+    }
+    return nil
+}
@@ -0,0 +1,182 @@
+// Package citations finds statutory citations in free text and resolves them to
+// canonical node IDs.
+//
+// Recognized forms include "18 U.S.C. § 924(e)", "28 C.F.R. § 600.4", California
+// code citations in Bluebook and California Style Manual form ("Civ. Code, § 3342",
+// "Penal Code section 187", "Bus. & Prof. Code § 17200"), the graph's own short form
+// ("CIV § 3342") and "Cal. Const., art. I, § 13".
+package citations
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Citation is one statutory citation found in text.
+type Citation struct {
+    Jurisdiction string // US or CA
+    Code         string // USC, CFR, CONS or a LegInfo law code
+    Title        string // USC/CFR title number or California Constitution article
+    Section      string // "924", "600.4", "3342"
+    Pin          string // subdivisions following the section, e.g. "(e)" or "(a)(1)"
+    Text         string // the matched text
+    Start, End   int    // byte offsets of Text
+}
+
+// String renders c in the graph's citation style ("18 USC § 924(e)", "CIV § 3342").
+func (c Citation) String() string {
+    switch c.Code {
+    case "USC", "CFR":
+        return fmt.Sprintf("%s %s § %s%s", c.Title, c.Code, c.Section, c.Pin)
+    case "CONS":
+        return fmt.Sprintf("Cal. Const. art. %s, § %s%s", c.Title, c.Section, c.Pin)
+    }
+    return fmt.Sprintf("%s § %s%s", c.Code, c.Section, c.Pin)
+}
+
+const (
+    secMark = `\s*,?\s*(?:§§?|(?i:sections?|secs?\.))\s*`
+    secNum  = `(\d+(?:\.\d+)*[a-zA-Z]?(?:-\d+)?)`
+    pin     = `((?:\([a-zA-Z0-9]{1,4}\))*)`
+)
+
+// caCodes are the California code names as written in opinions, by LegInfo code.
+var caCodes = []struct{ code, names string }{
+    {"CIV", `Civ(?:il|\.)? Code`},
+    {"PEN", `Pen(?:al|\.)? Code`},
+    {"BPC", `Bus(?:iness|\.)? (?:&|and) Prof(?:essions|\.)? Code`},
+    {"CCP", `Code Civ\. Proc\.|Code of Civil Procedure`},
+    {"EVID", `Evid(?:ence|\.)? Code`},
+    {"GOV", `Gov(?:ernment|\.|t\.)? Code`},
+    {"VEH", `Veh(?:icle|\.)? Code`},
+    {"FAM", `Fam(?:ily|\.)? Code`},
+    {"PROB", `Prob(?:ate|\.)? Code`},
+    {"LAB", `Lab(?:or|\.)? Code`},
+    {"CORP", `Corp(?:orations|\.)? Code`},
+    {"INS", `Ins(?:urance|\.)? Code`},
+    {"HSC", `Health (?:&|and) Saf(?:ety|\.)? Code`},
+    {"WIC", `Welf(?:are|\.)? (?:&|and) Inst(?:itutions|\.)? Code`},
+    {"RTC", `Rev(?:enue|\.)? (?:&|and) Tax(?:ation|\.)? Code`},
+    {"EDC", `Ed(?:ucation|uc\.)? Code`},
+    {"COM", `Com(?:mercial|\.)? Code`},
+}
+
+type pattern struct {
+    re   *regexp.Regexp
+    make func(m []string) Citation
+}
+
+var patterns = buildPatterns()
+
+func buildPatterns() []pattern {
+    ps := []pattern{
+        {regexp.MustCompile(`\b(\d+)\s+U\.?\s?S\.?\s?C\.?(?:\s?A\.?)?\s*(?:§§?|(?i:sections?|secs?\.))?\s*` + secNum + pin),
+            func(m []string) Citation { return Citation{Jurisdiction: "US", Code: "USC", Title: m[1], Section: m[2], Pin: m[3]} }},
+        {regexp.MustCompile(`\b(\d+)\s+C\.?\s?F\.?\s?R\.?\s*(?:§§?\s*)?(\d+\.\d+[a-z]?)` + pin),
+            func(m []string) Citation { return Citation{Jurisdiction: "US", Code: "CFR", Title: m[1], Section: m[2], Pin: m[3]} }},
+        {regexp.MustCompile(`Cal(?:ifornia|\.)?\s+Const(?:itution|\.)?\s*,?\s*(?i:art(?:icle|\.)?)\s*([IVXLC]+)` + secMark + secNum + pin),
+            func(m []string) Citation { return Citation{Jurisdiction: "CA", Code: "CONS", Title: m[1], Section: m[2], Pin: m[3]} }},
+    }
+    var short []string
+    for _, c := range caCodes {
+        code := c.code
+        ps = append(ps, pattern{regexp.MustCompile(`(?:Cal(?:ifornia|\.)?\s+)?\b(?:` + c.names + `)` + secMark + secNum + pin),
+            func(m []string) Citation { return Citation{Jurisdiction: "CA", Code: code, Section: m[1], Pin: m[2]} }})
+        short = append(short, c.code)
+    }
+    // the graph's own form, "CIV § 3342"
+    ps = append(ps, pattern{regexp.MustCompile(`\b(` + strings.Join(short, "|") + `)\s*§\s*` + secNum + pin),
+        func(m []string) Citation { return Citation{Jurisdiction: "CA", Code: m[1], Section: m[2], Pin: m[3]} }})
+    return ps
+}
+
+// Extract returns the statutory citations in text in order of appearance. Where
+// patterns overlap the earliest, then longest, match wins.
+func Extract(text string) []Citation {
+    var out []Citation
+    for _, p := range patterns {
+        for _, loc := range p.re.FindAllStringSubmatchIndex(text, -1) {
+            m := make([]string, len(loc)/2)
+            for i := range m {
+                if loc[2*i] >= 0 { m[i] = text[loc[2*i]:loc[2*i+1]] }
+            }
+            c := p.make(m)
+            c.Start, c.End, c.Text = loc[0], loc[1], m[0]
+            out = append(out, c)
+        }
+    }
+    sort.SliceStable(out, func(i, j int) bool {
+        if out[i].Start != out[j].Start { return out[i].Start < out[j].Start }
+        return out[i].End > out[j].End
+    })
+    kept := out[:0]
+    end := -1
+    for _, c := range out {
+        if c.Start < end { continue }
+        kept = append(kept, c)
+        end = c.End
+    }
+    return kept
+}
+
+// consArticle reads the article from IDs like CA:CONS:ArtI:§13 when props lack article_num.
+var consArticle = regexp.MustCompile(`:Art([IVXLC]+)(?::|$)`)
+
+// Index resolves citations to node IDs by the jurisdiction, code, title and
+// section_num props of the graph's sections and regulations.
+type Index struct {
+    ids map[string]string
+}
+
+// NewIndex indexes nodes that carry a section_num prop.
+func NewIndex(nodes []*dgraph.Node) *Index {
+    ix := &Index{ids: make(map[string]string)}
+    for _, n := range nodes {
+        sec, ok := n.Props["section_num"]
+        if !ok { continue }
+        j, _ := n.Props["jurisdiction"].(string)
+        code, _ := n.Props["code"].(string)
+        title := ""
+        switch code {
+        case "USC", "CFR":
+            title = propString(n.Props["title_num"])
+        case "CONS":
+            title = propString(n.Props["article_num"])
+            if m := consArticle.FindStringSubmatch(n.ID); title == "" && m != nil { title = m[1] }
+        }
+        k := key(j, code, title, propString(sec))
+        // the first node wins so that a re-listed version does not move the key
+        if _, dup := ix.ids[k]; !dup { ix.ids[k] = n.ID }
+    }
+    return ix
+}
+
+// Resolve returns the ID of the most specific node for c: the section with its pin
+// cite (as in US:USC:T18:§924(e)) if the graph has one, else the section.
+func (ix *Index) Resolve(c Citation) (string, bool) {
+    pins := c.Pin
+    for {
+        if id, ok := ix.ids[key(c.Jurisdiction, c.Code, c.Title, c.Section+pins)]; ok { return id, true }
+        i := strings.LastIndex(pins, "(")
+        if i < 0 { return "", false }
+        pins = pins[:i]
+    }
+}
+
+func key(j, code, title, sec string) string {
+    return strings.ToUpper(j + "|" + code + "|" + title + "|" + sec)
+}
+
+func propString(v any) string {
+    switch x := v.(type) {
+    case string:
+        return x
+    case float64:
+        return fmt.Sprintf("%g", x)
+    }
+    return ""
+}
@@ -0,0 +1,41 @@
+package citations
+
+import (
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func TestExtract(t *testing.T) {
+    text := "See 18 U.S.C. § 924(e)(2)(B) and 42 USC 1983; 28 C.F.R. § 600.4(a). Under Civ. Code, § 3342, Cal. Civ. Code § 1714, " +
+        "Penal Code section 187(a), Bus. & Prof. Code § 17200, CIV § 3343, Cal. Const., art. I, § 13 and Code Civ. Proc., § 425.16."
+    var got []string
+    for _, c := range Extract(text) {
+        got = append(got, c.String())
+        if text[c.Start:c.End] != c.Text { t.Errorf("offsets of %q", c.Text) }
+    }
+    want := "18 USC § 924(e)(2)(B)|42 USC § 1983|28 CFR § 600.4(a)|CIV § 3342|CIV § 1714|PEN § 187(a)|BPC § 17200|CIV § 3343|Cal. Const. art. I, § 13|CCP § 425.16"
+    if strings.Join(got, "|") != want { t.Errorf("got  %s\nwant %s", strings.Join(got, "|"), want) }
+}
+
+func TestResolve(t *testing.T) {
+    ix := NewIndex([]*dgraph.Node{
+        {ID: "US:USC:T18:§924", Props: map[string]any{"jurisdiction": "US", "code": "USC", "title_num": float64(18), "section_num": "924"}},
+        {ID: "US:USC:T18:§924(e)", Props: map[string]any{"jurisdiction": "US", "code": "USC", "title_num": float64(18), "section_num": "924(e)"}},
+        {ID: "CA:CONS:ArtI:§13", Props: map[string]any{"jurisdiction": "CA", "code": "CONS", "section_num": "13"}},
+        {ID: "CA:CIV:T02:CH02:§3342", Props: map[string]any{"jurisdiction": "CA", "code": "CIV", "section_num": "3342"}},
+    })
+    cases := map[string]string{
+        "18 U.S.C. § 924(e)(2)(B)": "US:USC:T18:§924(e)",
+        "18 U.S.C. § 924(c)":       "US:USC:T18:§924",
+        "Cal. Const., art. I, § 13": "CA:CONS:ArtI:§13",
+        "Civil Code section 3342":   "CA:CIV:T02:CH02:§3342",
+        "19 U.S.C. § 924":           "",
+    }
+    for in, want := range cases {
+        cs := Extract(in)
+        if len(cs) != 1 { t.Fatalf("%q: %d citations", in, len(cs)) }
+        if got, _ := ix.Resolve(cs[0]); got != want { t.Errorf("%q resolved to %q, want %q", in, got, want) }
+    }
+}
@@ -0,0 +1,305 @@
+package documents
+
+import (
+    "encoding/json"
+    "fmt"
+    "html"
+    "io"
+    "strconv"
+    "strings"
+    "unicode"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+// Format names a rendering of a subtree.
+type Format string
+
+const (
+    FormatJSON     Format = "json"
+    FormatMarkdown Format = "markdown"
+    FormatHTML     Format = "html"
+    FormatText     Format = "text"
+)
+
+// ParseFormat maps a query value to a Format; the empty string means JSON.
+func ParseFormat(s string) (Format, bool) {
+    switch strings.ToLower(s) {
+    case "", "json":
+        return FormatJSON, true
+    case "markdown", "md":
+        return FormatMarkdown, true
+    case "html":
+        return FormatHTML, true
+    case "text", "txt":
+        return FormatText, true
+    }
+    return "", false
+}
+
+// ContentType is the HTTP media type for f.
+func (f Format) ContentType() string {
+    switch f {
+    case FormatMarkdown:
+        return "text/markdown; charset=utf-8"
+    case FormatHTML:
+        return "text/html; charset=utf-8"
+    case FormatText:
+        return "text/plain; charset=utf-8"
+    }
+    return "application/json; charset=utf-8"
+}
+
+// WalkFunc visits the subtree in document order. Render calls it exactly once, so
+// the table of contents and the body come from the same snapshot.
+type WalkFunc func(visit func(n *dgraph.Node, depth int) error) error
+
+// Options controls rendering.
+type Options struct {
+    TOC   bool
+    Flush func() // called after each rendered entry so large subtrees stream
+}
+
+// Entry is the JSON shape of one rendered node.
+type Entry struct {
+    ID            string   `json:"id"`
+    Anchor        string   `json:"anchor"`
+    Depth         int      `json:"depth"`
+    Labels        []string `json:"labels"`
+    Heading       string   `json:"heading"`
+    Citation      string   `json:"citation,omitempty"`
+    Text          string   `json:"text,omitempty"`
+    EffectiveDate string   `json:"effective_date,omitempty"`
+    FetchedAt     string   `json:"fetched_at,omitempty"`
+}
+
+// TOCEntry is one line of the table of contents.
+type TOCEntry struct {
+    ID      string `json:"id"`
+    Anchor  string `json:"anchor"`
+    Depth   int    `json:"depth"`
+    Heading string `json:"heading"`
+}
+
+// Render writes the subtree produced by walk to w in the given format. Without a
+// table of contents entries stream as they are visited; with one the walk is
+// collected first so both are built from the same nodes.
+func Render(w io.Writer, f Format, walk WalkFunc, opts Options) error {
+    r := newRenderer(f)
+    if r == nil { return fmt.Errorf("unsupported format %q", f) }
+    anchors := anchorSet{}
+    emit := func(e Entry) error {
+        if err := r.entry(w, e); err != nil { return err }
+        if opts.Flush != nil { opts.Flush() }
+        return nil
+    }
+    if !opts.TOC {
+        if err := r.begin(w, nil); err != nil { return err }
+        err := walk(func(n *dgraph.Node, depth int) error { return emit(toEntry(n, depth, anchors.next(n.ID))) })
+        if err != nil { return err }
+        return r.end(w)
+    }
+    var entries []Entry
+    err := walk(func(n *dgraph.Node, depth int) error {
+        entries = append(entries, toEntry(n, depth, anchors.next(n.ID)))
+        return nil
+    })
+    if err != nil { return err }
+    toc := make([]TOCEntry, len(entries))
+    for i, e := range entries { toc[i] = TOCEntry{ID: e.ID, Anchor: e.Anchor, Depth: e.Depth, Heading: e.Heading} }
+    if err := r.begin(w, toc); err != nil { return err }
+    for _, e := range entries {
+        if err := emit(e); err != nil { return err }
+    }
+    return r.end(w)
+}
+
+type renderer interface {
+    begin(w io.Writer, toc []TOCEntry) error
+    entry(w io.Writer, e Entry) error
+    end(w io.Writer) error
+}
+
+// newRenderer returns a fresh renderer per call; the JSON renderer is stateful.
+func newRenderer(f Format) renderer {
+    switch f {
+    case FormatJSON:
+        return &jsonRenderer{}
+    case FormatMarkdown:
+        return markdownRenderer{}
+    case FormatHTML:
+        return htmlRenderer{}
+    case FormatText:
+        return textRenderer{}
+    }
+    return nil
+}
+
+func toEntry(n *dgraph.Node, depth int, anchor string) Entry {
+    e := Entry{ID: n.ID, Anchor: anchor, Depth: depth, Labels: n.Labels, Heading: Heading(n), Citation: n.Citation, Text: n.Text}
+    if n.Version != nil {
+        e.EffectiveDate = n.Version.EffectiveDate
+        e.FetchedAt = n.Version.FetchedAt
+    }
+    return e
+}
+
+// Heading is the node title, or its label and ID when untitled.
+func Heading(n *dgraph.Node) string {
+    if n.Title != "" { return n.Title }
+    label := "NODE"
+    if len(n.Labels) > 0 { label = n.Labels[0] }
+    return label + " " + n.ID
+}
+
+// Anchor derives a stable fragment identifier from a canonical ID.
+func Anchor(id string) string {
+    var b strings.Builder
+    dash := false
+    for _, r := range strings.ReplaceAll(id, "§", "s") {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' {
+            b.WriteRune(unicode.ToLower(r))
+            dash = false
+        } else if !dash && b.Len() > 0 {
+            b.WriteByte('-')
+            dash = true
+        }
+    }
+    return strings.TrimSuffix(b.String(), "-")
+}
+
+// anchorSet hands out anchors unique within one document: an Anchor already taken
+// by an earlier node gets a -2, -3, ... suffix.
+type anchorSet map[string]bool
+
+func (a anchorSet) next(id string) string {
+    base := Anchor(id)
+    out := base
+    for i := 2; a[out]; i++ { out = base + "-" + strconv.Itoa(i) }
+    a[out] = true
+    return out
+}
+
+// mdEscape backslash-escapes the characters that would otherwise start links,
+// headings or emphasis in Markdown inline text.
+func mdEscape(s string) string {
+    const special = "\\[]#*_`"
+    if !strings.ContainsAny(s, special) { return s }
+    var b strings.Builder
+    for _, r := range s {
+        if strings.ContainsRune(special, r) { b.WriteByte('\\') }
+        b.WriteRune(r)
+    }
+    return b.String()
+}
+
+func versionLine(e Entry) string {
+    var parts []string
+    if e.EffectiveDate != "" { parts = append(parts, "Effective "+e.EffectiveDate) }
+    if e.FetchedAt != "" { parts = append(parts, "Retrieved "+e.FetchedAt) }
+    return strings.Join(parts, " · ")
+}
+
+// jsonRenderer streams {"toc":[...],"entries":[...]} one entry at a time.
+type jsonRenderer struct{ n int }
+
+func (r *jsonRenderer) begin(w io.Writer, toc []TOCEntry) error {
+    if _, err := io.WriteString(w, "{"); err != nil { return err }
+    if toc != nil {
+        b, err := json.Marshal(toc)
+        if err != nil { return err }
+        if _, err := fmt.Fprintf(w, "\"toc\":%s,", b); err != nil { return err }
+    }
+    _, err := io.WriteString(w, "\"entries\":[")
+    return err
+}
+
+func (r *jsonRenderer) entry(w io.Writer, e Entry) error {
+    b, err := json.Marshal(e)
+    if err != nil { return err }
+    if r.n > 0 { if _, err := io.WriteString(w, ","); err != nil { return err } }
+    r.n++
+    _, err = w.Write(b)
+    return err
+}
+
+func (r *jsonRenderer) end(w io.Writer) error {
+    _, err := io.WriteString(w, "]}\n")
+    return err
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) begin(w io.Writer, toc []TOCEntry) error {
+    if toc == nil { return nil }
+    if _, err := io.WriteString(w, "## Contents\n\n"); err != nil { return err }
+    for _, t := range toc {
+        if _, err := fmt.Fprintf(w, "%s- [%s](#%s)\n", strings.Repeat("  ", t.Depth), mdEscape(t.Heading), t.Anchor); err != nil { return err }
+    }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
+
+func (markdownRenderer) entry(w io.Writer, e Entry) error {
+    level := e.Depth + 1
+    if level > 6 { level = 6 }
+    if _, err := fmt.Fprintf(w, "<a id=\"%s\"></a>\n%s %s\n\n", e.Anchor, strings.Repeat("#", level), mdEscape(e.Heading)); err != nil { return err }
+    if e.Citation != "" { if _, err := fmt.Fprintf(w, "*%s*\n\n", mdEscape(e.Citation)); err != nil { return err } }
+    if v := versionLine(e); v != "" { if _, err := fmt.Fprintf(w, "_%s_\n\n", v); err != nil { return err } }
+    if e.Text != "" { if _, err := fmt.Fprintf(w, "%s\n\n", e.Text); err != nil { return err } }
+    return nil
+}
+
+func (markdownRenderer) end(w io.Writer) error { return nil }
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) begin(w io.Writer, toc []TOCEntry) error {
+    if _, err := io.WriteString(w, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"></head>\n<body>\n"); err != nil { return err }
+    if toc == nil { return nil }
+    if _, err := io.WriteString(w, "<nav>\n<ul>\n"); err != nil { return err }
+    for _, t := range toc {
+        if _, err := fmt.Fprintf(w, "<li style=\"margin-left:%dem\"><a href=\"#%s\">%s</a></li>\n", t.Depth, t.Anchor, html.EscapeString(t.Heading)); err != nil { return err }
+    }
+    _, err := io.WriteString(w, "</ul>\n</nav>\n")
+    return err
+}
+
+func (htmlRenderer) entry(w io.Writer, e Entry) error {
+    level := e.Depth + 1
+    if level > 6 { level = 6 }
+    if _, err := fmt.Fprintf(w, "<section id=\"%s\">\n<h%d>%s</h%d>\n", e.Anchor, level, html.EscapeString(e.Heading), level); err != nil { return err }
+    if e.Citation != "" { if _, err := fmt.Fprintf(w, "<p><cite>%s</cite></p>\n", html.EscapeString(e.Citation)); err != nil { return err } }
+    if v := versionLine(e); v != "" { if _, err := fmt.Fprintf(w, "<p><small>%s</small></p>\n", html.EscapeString(v)); err != nil { return err } }
+    if e.Text != "" { if _, err := fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(e.Text)); err != nil { return err } }
+    _, err := io.WriteString(w, "</section>\n")
+    return err
+}
+
+func (htmlRenderer) end(w io.Writer) error {
+    _, err := io.WriteString(w, "</body>\n</html>\n")
+    return err
+}
+
+type textRenderer struct{}
+
+func (textRenderer) begin(w io.Writer, toc []TOCEntry) error {
+    if toc == nil { return nil }
+    if _, err := io.WriteString(w, "CONTENTS\n\n"); err != nil { return err }
+    for _, t := range toc {
+        if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", t.Depth), t.Heading); err != nil { return err }
+    }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
+
+func (textRenderer) entry(w io.Writer, e Entry) error {
+    if _, err := fmt.Fprintf(w, "%s\n", e.Heading); err != nil { return err }
+    if e.Citation != "" { if _, err := fmt.Fprintf(w, "%s\n", e.Citation); err != nil { return err } }
+    if v := versionLine(e); v != "" { if _, err := fmt.Fprintf(w, "%s\n", v); err != nil { return err } }
+    if e.Text != "" { if _, err := fmt.Fprintf(w, "\n%s\n", e.Text); err != nil { return err } }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
+
+func (textRenderer) end(w io.Writer) error { return nil }
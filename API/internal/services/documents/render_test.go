@@ -0,0 +1,85 @@
+package documents
+
+import (
+    "bytes"
+    "encoding/json"
+    "strings"
+    "testing"
+
+    dgraph "lawmap/internal/domain/graph"
+)
+
+func testWalk() WalkFunc {
+    nodes := []struct{ n *dgraph.Node; d int }{
+        {&dgraph.Node{ID: "CA:CIV:T02:CH02", Labels: []string{"CHAPTER"}, Title: "Chapter 2"}, 0},
+        {&dgraph.Node{ID: "CA:CIV:T02:CH02:§3343", Labels: []string{"SECTION"}, Title: "Section 3343", Citation: "CIV § 3343", Text: "other <text>"}, 1},
+        {&dgraph.Node{ID: "CA:CIV:T02:CH02:§3342", Labels: []string{"SECTION"}, Title: "Section 3342", Citation: "CIV § 3342", Text: "dog text",
+            Version: &dgraph.Version{EffectiveDate: "2024-01-01"}}, 1},
+    }
+    return func(visit func(n *dgraph.Node, depth int) error) error {
+        for _, x := range nodes {
+            if err := visit(x.n, x.d); err != nil { return err }
+        }
+        return nil
+    }
+}
+
+func TestRenderJSON(t *testing.T) {
+    var buf bytes.Buffer
+    if err := Render(&buf, FormatJSON, testWalk(), Options{TOC: true}); err != nil { t.Fatal(err) }
+    var doc struct{ TOC []TOCEntry `json:"toc"`; Entries []Entry `json:"entries"` }
+    if err := json.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatalf("invalid json: %v\n%s", err, buf.String()) }
+    if len(doc.TOC) != 3 || len(doc.Entries) != 3 { t.Fatalf("expected 3 toc entries and 3 entries; got %d/%d", len(doc.TOC), len(doc.Entries)) }
+    if doc.Entries[1].ID != "CA:CIV:T02:CH02:§3343" || doc.Entries[2].EffectiveDate != "2024-01-01" { t.Fatalf("unexpected entries: %+v", doc.Entries) }
+}
+
+func TestRenderMarkdownAndHTML(t *testing.T) {
+    var md bytes.Buffer
+    if err := Render(&md, FormatMarkdown, testWalk(), Options{TOC: true}); err != nil { t.Fatal(err) }
+    for _, want := range []string{"- [Chapter 2](#ca-civ-t02-ch02)", "# Chapter 2", "## Section 3342", "*CIV § 3342*", "_Effective 2024-01-01_"} {
+        if !strings.Contains(md.String(), want) { t.Fatalf("markdown missing %q:\n%s", want, md.String()) }
+    }
+    if strings.Index(md.String(), "Section 3343\n") > strings.Index(md.String(), "Section 3342\n") { t.Fatalf("expected walk order preserved") }
+
+    var h bytes.Buffer
+    if err := Render(&h, FormatHTML, testWalk(), Options{}); err != nil { t.Fatal(err) }
+    if !strings.Contains(h.String(), "other &lt;text&gt;") { t.Fatalf("expected escaped text in html") }
+    if strings.Contains(h.String(), "<nav>") { t.Fatalf("toc should be omitted unless requested") }
+}
+
+func TestRenderFlushesPerEntry(t *testing.T) {
+    var buf bytes.Buffer
+    flushes := 0
+    if err := Render(&buf, FormatText, testWalk(), Options{Flush: func() { flushes++ }}); err != nil { t.Fatal(err) }
+    if flushes != 3 { t.Fatalf("expected a flush per entry, got %d", flushes) }
+}
+
+func TestAnchor(t *testing.T) {
+    if got := Anchor("US:USC:T18:§924(e)"); got != "us-usc-t18-s924-e" { t.Fatalf("anchor=%q", got) }
+}
+
+func TestRenderUniqueAnchorsAndEscaping(t *testing.T) {
+    walk := func(visit func(n *dgraph.Node, depth int) error) error {
+        for _, n := range []*dgraph.Node{
+            {ID: "US:USC:T18:§924(e)", Title: "Armed [career] criminals #1"},
+            {ID: "US:USC:T18:§924-e", Title: "*Other* section", Citation: "18 U.S.C. § 924_e"},
+        } {
+            if err := visit(n, 0); err != nil { return err }
+        }
+        return nil
+    }
+    var md bytes.Buffer
+    if err := Render(&md, FormatMarkdown, walk, Options{TOC: true}); err != nil { t.Fatal(err) }
+    for _, want := range []string{
+        `- [Armed \[career\] criminals \#1](#us-usc-t18-s924-e)`, `- [\*Other\* section](#us-usc-t18-s924-e-2)`,
+        `<a id="us-usc-t18-s924-e-2"></a>`, `# \*Other\* section`, `*18 U.S.C. § 924\_e*`,
+    } {
+        if !strings.Contains(md.String(), want) { t.Fatalf("markdown missing %q:\n%s", want, md.String()) }
+    }
+
+    calls := 0
+    once := func(visit func(n *dgraph.Node, depth int) error) error { calls++; return walk(visit) }
+    var buf bytes.Buffer
+    if err := Render(&buf, FormatJSON, once, Options{TOC: true}); err != nil { t.Fatal(err) }
+    if calls != 1 { t.Fatalf("walked %d times, want once", calls) }
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/http"
+    "strings"
+    "syscall"
+    "time"
+)
+
+// blockedIP reports whether a callback may not reach ip: loopback, private,
+// link-local (which covers cloud metadata at 169.254.169.254), multicast or
+// unspecified addresses.
+func blockedIP(ip net.IP) bool {
+    return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+        ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// allowlist holds the hosts and networks (webhooks.allow_hosts) that callbacks may
+// reach even though their addresses are blocked.
+type allowlist struct {
+    hosts map[string]bool
+    nets  []*net.IPNet
+}
+
+// newAllowlist parses host names, IP addresses and CIDRs.
+func newAllowlist(entries []string) (allowlist, error) {
+    a := allowlist{hosts: map[string]bool{}}
+    for _, e := range entries {
+        e = strings.ToLower(strings.TrimSpace(e))
+        if e == "" { continue }
+        if _, n, err := net.ParseCIDR(e); err == nil { a.nets = append(a.nets, n); continue }
+        if ip := net.ParseIP(e); ip != nil {
+            bits := 8 * net.IPv6len
+            if ip.To4() != nil { ip, bits = ip.To4(), 8*net.IPv4len }
+            a.nets = append(a.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+            continue
+        }
+        if strings.ContainsAny(e, "/: ") { return allowlist{}, fmt.Errorf("webhooks: bad allow_hosts entry %q", e) }
+        a.hosts[e] = true
+    }
+    return a, nil
+}
+
+func (a allowlist) host(host string) bool { return a.hosts[strings.ToLower(strings.TrimSuffix(host, "."))] }
+
+// ip reports whether ip may be dialed.
+func (a allowlist) ip(ip net.IP) bool {
+    if !blockedIP(ip) { return true }
+    for _, n := range a.nets {
+        if n.Contains(ip) { return true }
+    }
+    return false
+}
+
+// checkHost resolves a callback host and rejects it when any of its addresses is blocked.
+func (s *Service) checkHost(host string) error {
+    if s.allow.host(host) { return nil }
+    ips := []net.IP{net.ParseIP(host)}
+    if ips[0] == nil {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+        if err != nil || len(addrs) == 0 { return fmt.Errorf("%w: url host %q does not resolve", ErrInvalid, host) }
+        ips = ips[:0]
+        for _, a := range addrs { ips = append(ips, a.IP) }
+    }
+    for _, ip := range ips {
+        if !s.allow.ip(ip) { return fmt.Errorf("%w: url host %q resolves to a non-public address (%s)", ErrInvalid, host, ip) }
+    }
+    return nil
+}
+
+// transport dials callbacks directly (no proxy) and checks the address each
+// connection actually goes to, so a name that later resolves inside the network
+// (DNS rebinding, redirects) is refused at delivery too.
+func (s *Service) transport(timeout time.Duration) *http.Transport {
+    open := &net.Dialer{Timeout: timeout}
+    guarded := &net.Dialer{Timeout: timeout, Control: func(network, address string, _ syscall.RawConn) error {
+        host, _, err := net.SplitHostPort(address)
+        if err != nil { return err }
+        if ip := net.ParseIP(host); ip == nil || !s.allow.ip(ip) { return fmt.Errorf("webhooks: address %s is not allowed", host) }
+        return nil
+    }}
+    t := http.DefaultTransport.(*http.Transport).Clone()
+    t.Proxy = nil
+    t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+        if host, _, err := net.SplitHostPort(addr); err == nil && s.allow.host(host) { return open.DialContext(ctx, network, addr) }
+        return guarded.DialContext(ctx, network, addr)
+    }
+    return t
+}
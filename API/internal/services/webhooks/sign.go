@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>". The MAC covers
+// "<t>.<body>" with the subscription secret as key, so a captured request cannot
+// be replayed with a new timestamp.
+const SignatureHeader = "X-Lawmap-Signature"
+
+// ErrBadSignature is returned by Verify for a missing, malformed, wrong or stale signature.
+var ErrBadSignature = errors.New("bad webhook signature")
+
+// Sign returns the SignatureHeader value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+    ts := strconv.FormatInt(t.Unix(), 10)
+    return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+// Verify checks a SignatureHeader value against body. A tolerance above zero also
+// rejects signatures made more than that long before (or after) now.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+    var ts string
+    var sigs [][]byte
+    for _, part := range strings.Split(header, ",") {
+        k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+        switch k {
+        case "t":
+            ts = v
+        case "v1":
+            if b, err := hex.DecodeString(v); err == nil { sigs = append(sigs, b) }
+        }
+    }
+    sec, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil || len(sigs) == 0 { return ErrBadSignature }
+    if tolerance > 0 {
+        if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance { return ErrBadSignature }
+    }
+    want := mac(secret, ts, body)
+    for _, sig := range sigs {
+        if hmac.Equal(sig, want) { return nil }
+    }
+    return ErrBadSignature
+}
+
+func mac(secret, ts string, body []byte) []byte {
+    h := hmac.New(sha256.New, []byte(secret))
+    h.Write([]byte(ts))
+    h.Write([]byte("."))
+    h.Write(body)
+    return h.Sum(nil)
+}
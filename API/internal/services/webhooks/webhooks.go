@@ -0,0 +1,560 @@
+// Package webhooks delivers graph changes to subscribed callback URLs. It follows
+// the store's change log, matches each change against the subscriptions and POSTs
+// an HMAC-signed event, retrying with exponential backoff; deliveries that keep
+// failing, or that find the queue full, are kept on a dead-letter list until
+// redelivered.
+package webhooks
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+
+    conf "lawmap/internal/config"
+    graphrepo "lawmap/internal/repo/graph"
+    "lawmap/internal/services/auth"
+)
+
+// Event types a subscription can ask for.
+const (
+    EventVersion = "version" // the node was stored under a new version hash (amended text)
+    EventAmended = "amended" // a new AMENDS edge points at the node (e.g. a Federal Register rule); re-imports of it are not events
+    EventCited   = "cited"   // a new CITES edge points at the node (e.g. an opinion); re-imports of it are not events
+    EventUpdated = "updated" // the node was added, or re-imported without a new version
+    EventDeleted = "deleted"
+)
+
+// EventTypes lists the accepted event types.
+var EventTypes = []string{EventVersion, EventAmended, EventCited, EventUpdated, EventDeleted}
+
+// defaultEvents is used when a subscription names none.
+var defaultEvents = []string{EventVersion, EventAmended, EventCited}
+
+// maxInFlight bounds concurrent deliveries.
+const maxInFlight = 8
+
+// defaultMaxQueue bounds the delivery queue unless webhooks.max_queue says otherwise.
+const defaultMaxQueue = 10000
+
+// maxDeadLetters bounds the dead-letter list; the oldest entries go first.
+const maxDeadLetters = 1000
+
+var (
+    ErrInvalid     = errors.New("invalid subscription")
+    ErrUnknownNode = errors.New("node not found")
+    ErrNotFound    = errors.New("not found")
+)
+
+// Subscription watches one node, or with Subtree the node and everything under it.
+// Owner is the caller that created it; Jurisdictions and Codes are that caller's scope,
+// and events about nodes outside it are not sent.
+type Subscription struct {
+    ID            string   `json:"id"`
+    NodeID        string   `json:"node_id"`
+    Subtree       bool     `json:"subtree"`
+    Events        []string `json:"events"`
+    URL           string   `json:"url"`
+    Secret        string   `json:"secret,omitempty"` // shown once, when the subscription is created
+    CreatedAt     string   `json:"created_at"`
+    Owner         string   `json:"owner,omitempty"`
+    Jurisdictions []string `json:"jurisdictions,omitempty"`
+    Codes         []string `json:"codes,omitempty"`
+}
+
+// allows reports whether node id is within sub's scope. Nodes no longer stored
+// (deleted ones) are allowed: only a subscription on the node itself matches them.
+func (s *Service) allows(sub *Subscription, id string) bool {
+    scope := &auth.Principal{Jurisdictions: sub.Jurisdictions, Codes: sub.Codes}
+    if !scope.Scoped() { return true }
+    n, ok := s.store.GetNode(id)
+    if !ok { return true }
+    j, _ := n.Props["jurisdiction"].(string)
+    c, _ := n.Props["code"].(string)
+    return scope.Allows(j, c)
+}
+
+// NodeRef names a node in an event.
+type NodeRef struct {
+    ID       string `json:"id"`
+    Title    string `json:"title,omitempty"`
+    Citation string `json:"citation,omitempty"`
+}
+
+// Event is the body of a webhook POST. ID stays the same across retries, so
+// receivers can drop duplicates.
+type Event struct {
+    ID             string           `json:"id"`
+    Type           string           `json:"type"`
+    SubscriptionID string           `json:"subscription_id"`
+    Node           NodeRef          `json:"node"`           // the changed (or cited/amended) node
+    From           *NodeRef         `json:"from,omitempty"` // citing or amending node, for edge events
+    Change         graphrepo.Change `json:"change"`
+}
+
+// Delivery is an event on its way to a subscription's URL, or on the dead-letter list.
+type Delivery struct {
+    Event       Event  `json:"event"`
+    URL         string `json:"url"`
+    Attempts    int    `json:"attempts"`
+    LastStatus  int    `json:"last_status,omitempty"`
+    LastError   string `json:"last_error,omitempty"`
+    NextAttempt string `json:"next_attempt,omitempty"`
+    FailedAt    string `json:"failed_at,omitempty"`
+}
+
+type pending struct {
+    Delivery
+    due      time.Time
+    inflight bool
+}
+
+// Service owns the subscriptions and delivery queue.
+type Service struct {
+    Client      *http.Client
+    Now         func() time.Time // defaults to time.Now
+    MaxAttempts int
+    MaxQueue    int // queued deliveries; events beyond it go straight to the dead-letter list
+    Backoff     time.Duration // before the first retry; doubled per failure
+    MaxBackoff  time.Duration
+
+    store    *graphrepo.MemoryStore
+    file     string
+    allow    allowlist
+    mu       sync.Mutex
+    subs     map[string]*Subscription
+    order    []string
+    queue    []*pending
+    dead     []Delivery
+    since    int64
+    inflight int
+    wake     chan struct{}
+    wg       sync.WaitGroup
+}
+
+// New builds the service from config and loads saved subscriptions. Changes are
+// followed from the store's current seq.
+func New(cfg conf.WebhooksConfig, store *graphrepo.MemoryStore) (*Service, error) {
+    s := &Service{
+        MaxAttempts: cfg.MaxAttempts, MaxQueue: cfg.MaxQueue, Backoff: 30 * time.Second, MaxBackoff: time.Hour,
+        store: store, file: cfg.File, subs: map[string]*Subscription{}, since: store.LastSeq(), wake: make(chan struct{}, 1),
+    }
+    if s.MaxAttempts <= 0 { s.MaxAttempts = 6 }
+    if s.MaxQueue <= 0 { s.MaxQueue = defaultMaxQueue }
+    timeout := 10 * time.Second
+    for _, d := range []struct{ v string; dst *time.Duration }{{cfg.Backoff, &s.Backoff}, {cfg.MaxBackoff, &s.MaxBackoff}, {cfg.Timeout, &timeout}} {
+        if d.v == "" { continue }
+        v, err := time.ParseDuration(d.v)
+        if err != nil || v <= 0 { return nil, fmt.Errorf("webhooks: bad duration %q", d.v) }
+        *d.dst = v
+    }
+    allow, err := newAllowlist(cfg.AllowHosts)
+    if err != nil { return nil, err }
+    s.allow = allow
+    s.Client = &http.Client{Timeout: timeout, Transport: s.transport(timeout)}
+    if err := s.load(); err != nil { return nil, err }
+    return s, nil
+}
+
+func (s *Service) now() time.Time {
+    if s.Now != nil { return s.Now().UTC() }
+    return time.Now().UTC()
+}
+
+func newID(prefix string) string {
+    b := make([]byte, 8)
+    _, _ = rand.Read(b)
+    return prefix + hex.EncodeToString(b)
+}
+
+// Subscribe validates and stores a subscription. A secret is generated unless given;
+// the returned copy is the only one that carries it.
+func (s *Service) Subscribe(sub Subscription) (Subscription, error) {
+    if sub.NodeID == "" { return Subscription{}, fmt.Errorf("%w: node_id is required", ErrInvalid) }
+    if _, ok := s.store.GetNode(sub.NodeID); !ok { return Subscription{}, ErrUnknownNode }
+    u, err := url.Parse(sub.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return Subscription{}, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalid)
+    }
+    if err := s.checkHost(u.Hostname()); err != nil { return Subscription{}, err }
+    if len(sub.Events) == 0 { sub.Events = defaultEvents }
+    for _, e := range sub.Events {
+        if !contains(EventTypes, e) { return Subscription{}, fmt.Errorf("%w: unknown event %q", ErrInvalid, e) }
+    }
+    if sub.Secret == "" {
+        b := make([]byte, 32)
+        if _, err := rand.Read(b); err != nil { return Subscription{}, err }
+        sub.Secret = hex.EncodeToString(b)
+    }
+    sub.ID = newID("sub_")
+    sub.CreatedAt = s.now().Format(time.RFC3339)
+    sub.Events = append([]string(nil), sub.Events...)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    stored := sub
+    s.subs[sub.ID] = &stored
+    s.order = append(s.order, sub.ID)
+    return sub, s.saveLocked()
+}
+
+// Unsubscribe removes a subscription and drops its queued deliveries.
+func (s *Service) Unsubscribe(id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.subs[id]; !ok { return ErrNotFound }
+    delete(s.subs, id)
+    for i, o := range s.order {
+        if o == id { s.order = append(s.order[:i:i], s.order[i+1:]...); break }
+    }
+    kept := s.queue[:0]
+    for _, p := range s.queue {
+        if p.inflight || p.Event.SubscriptionID != id { kept = append(kept, p) }
+    }
+    s.queue = kept
+    return s.saveLocked()
+}
+
+// Subscription returns one subscription, without its secret.
+func (s *Service) Subscription(id string) (Subscription, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sub, ok := s.subs[id]
+    if !ok { return Subscription{}, false }
+    out := *sub
+    out.Secret = ""
+    return out, true
+}
+
+// Subscriptions lists subscriptions in creation order, without secrets.
+func (s *Service) Subscriptions() []Subscription {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Subscription, 0, len(s.order))
+    for _, id := range s.order {
+        sub := *s.subs[id]
+        sub.Secret = ""
+        out = append(out, sub)
+    }
+    return out
+}
+
+// DeadLetters lists deliveries that used up their attempts, newest first,
+// optionally for one subscription.
+func (s *Service) DeadLetters(subID string) []Delivery {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := []Delivery{}
+    for i := len(s.dead) - 1; i >= 0; i-- {
+        if subID == "" || s.dead[i].Event.SubscriptionID == subID { out = append(out, s.dead[i]) }
+    }
+    return out
+}
+
+// Redeliver moves a dead letter back to the queue with a fresh set of attempts.
+func (s *Service) Redeliver(eventID string) (Delivery, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for i, d := range s.dead {
+        if d.Event.ID != eventID { continue }
+        if _, ok := s.subs[d.Event.SubscriptionID]; !ok { return Delivery{}, ErrNotFound }
+        s.dead = append(s.dead[:i:i], s.dead[i+1:]...)
+        d.Attempts, d.FailedAt, d.NextAttempt = 0, "", ""
+        s.queue = append(s.queue, &pending{Delivery: d, due: s.now()})
+        s.poke()
+        return d, s.saveLocked()
+    }
+    return Delivery{}, ErrNotFound
+}
+
+// Start follows the change log and delivers events until ctx is done.
+func (s *Service) Start(ctx context.Context) {
+    go func() {
+        timer := time.NewTimer(time.Hour)
+        defer timer.Stop()
+        for {
+            signal := s.store.ChangeSignal()
+            s.Poll()
+            s.dispatch(ctx)
+            if !timer.Stop() {
+                select {
+                case <-timer.C:
+                default:
+                }
+            }
+            timer.Reset(s.untilDue())
+            select {
+            case <-ctx.Done():
+                s.mu.Lock()
+                if err := s.saveLocked(); err != nil { fmt.Fprintf(os.Stderr, "webhooks: save: %v\n", err) }
+                s.mu.Unlock()
+                return
+            case <-signal:
+            case <-s.wake:
+            case <-timer.C:
+            }
+        }
+    }()
+}
+
+// Wait blocks until the deliveries in flight have finished.
+func (s *Service) Wait() { s.wg.Wait() }
+
+func (s *Service) poke() {
+    select {
+    case s.wake <- struct{}{}:
+    default:
+    }
+}
+
+// Poll queues events for the changes logged since the last call. The queue is
+// saved after each batch that added to it.
+func (s *Service) Poll() {
+    for {
+        s.mu.Lock()
+        since := s.since
+        s.mu.Unlock()
+        changes, latest, ok := s.store.Changes(since, "", "", 1000)
+        if !ok {
+            fmt.Fprintf(os.Stderr, "webhooks: change log no longer reaches seq %d; skipping to %d\n", since, latest)
+            s.mu.Lock()
+            s.since = latest
+            s.mu.Unlock()
+            return
+        }
+        queued, overflow := 0, 0
+        for i := range changes {
+            q, o := s.match(&changes[i])
+            queued, overflow = queued+q, overflow+o
+        }
+        s.mu.Lock()
+        if len(changes) > 0 { s.since = changes[len(changes)-1].Seq }
+        if len(changes) < 1000 { s.since = latest }
+        if overflow > 0 { fmt.Fprintf(os.Stderr, "webhooks: queue full (%d); %d deliveries dead-lettered\n", s.MaxQueue, overflow) }
+        if queued+overflow > 0 {
+            if err := s.saveLocked(); err != nil { fmt.Fprintf(os.Stderr, "webhooks: save: %v\n", err) }
+        }
+        s.mu.Unlock()
+        if len(changes) < 1000 { return }
+    }
+}
+
+// eventOf maps a change to an event type and the node it concerns. Edges stored
+// again unchanged are not events: only a new citation or amendment is.
+func eventOf(c *graphrepo.Change) (string, string) {
+    if c.Kind == "node" {
+        switch c.Op {
+        case graphrepo.OpVersion: return EventVersion, c.ID
+        case graphrepo.OpDelete: return EventDeleted, c.ID
+        default: return EventUpdated, c.ID
+        }
+    }
+    if c.Op != graphrepo.OpUpsert || !c.Added() { return "", "" }
+    switch c.EdgeType {
+    case "CITES": return EventCited, c.ToID
+    case "AMENDS": return EventAmended, c.ToID
+    }
+    return "", ""
+}
+
+// match queues an event for each subscription c concerns and reports how many were
+// queued and how many went to the dead-letter list because the queue was full.
+func (s *Service) match(c *graphrepo.Change) (queued, overflow int) {
+    typ, target := eventOf(c)
+    if typ == "" { return 0, 0 }
+    var ancestors []string
+    resolved := false
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, id := range s.order {
+        sub := s.subs[id]
+        if !contains(sub.Events, typ) { continue }
+        if sub.NodeID != target {
+            if !sub.Subtree { continue }
+            if !resolved {
+                // deleted nodes have no parents left, so they only match exact subscriptions
+                ancestors, _ = s.store.GetParentsPath(target)
+                resolved = true
+            }
+            if !contains(ancestors, sub.NodeID) { continue }
+        }
+        if !s.allows(sub, target) || (c.Kind == "edge" && !s.allows(sub, c.FromID)) { continue }
+        ev := Event{ID: newID("evt_"), Type: typ, SubscriptionID: sub.ID, Node: s.ref(target), Change: *c}
+        if c.Kind == "edge" { from := s.ref(c.FromID); ev.From = &from }
+        d := Delivery{Event: ev, URL: sub.URL}
+        if len(s.queue) >= s.MaxQueue {
+            d.LastError = "queue full"
+            s.deadLetterLocked(d)
+            overflow++
+            continue
+        }
+        s.queue = append(s.queue, &pending{Delivery: d, due: s.now()})
+        queued++
+    }
+    return queued, overflow
+}
+
+func (s *Service) ref(id string) NodeRef {
+    n, ok := s.store.GetNode(id)
+    if !ok { return NodeRef{ID: id} }
+    return NodeRef{ID: id, Title: n.Title, Citation: n.Citation}
+}
+
+// untilDue is the time until the earliest queued delivery that is not in flight.
+func (s *Service) untilDue() time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    wait := time.Hour
+    now := s.now()
+    for _, p := range s.queue {
+        if p.inflight { continue }
+        if d := p.due.Sub(now); d < wait { wait = d }
+    }
+    if wait < time.Millisecond { wait = time.Millisecond }
+    return wait
+}
+
+// dispatch starts the due deliveries, up to maxInFlight at a time.
+func (s *Service) dispatch(ctx context.Context) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := s.now()
+    for _, p := range s.queue {
+        if s.inflight >= maxInFlight { return }
+        if p.inflight || p.due.After(now) { continue }
+        sub, ok := s.subs[p.Event.SubscriptionID]
+        if !ok { continue }
+        p.inflight = true
+        s.inflight++
+        s.wg.Add(1)
+        go s.send(ctx, p, sub.Secret)
+    }
+}
+
+// send makes one delivery attempt and requeues, drops or dead-letters it.
+func (s *Service) send(ctx context.Context, p *pending, secret string) {
+    defer s.wg.Done()
+    status, err := s.post(ctx, p.URL, secret, p.Event)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    defer s.poke()
+    s.inflight--
+    p.inflight = false
+    p.Attempts++
+    p.LastStatus = status
+    if _, ok := s.subs[p.Event.SubscriptionID]; err == nil || !ok {
+        s.remove(p)
+        return
+    }
+    p.LastError = err.Error()
+    now := s.now()
+    if p.Attempts >= s.MaxAttempts {
+        s.remove(p)
+        s.deadLetterLocked(p.Delivery)
+        if err := s.saveLocked(); err != nil { fmt.Fprintf(os.Stderr, "webhooks: save: %v\n", err) }
+        return
+    }
+    p.due = now.Add(s.backoff(p.Attempts))
+    p.NextAttempt = p.due.Format(time.RFC3339)
+}
+
+// deadLetterLocked moves d to the dead-letter list, dropping the oldest beyond maxDeadLetters.
+func (s *Service) deadLetterLocked(d Delivery) {
+    d.NextAttempt, d.FailedAt = "", s.now().Format(time.RFC3339)
+    s.dead = append(s.dead, d)
+    if len(s.dead) > maxDeadLetters { s.dead = s.dead[len(s.dead)-maxDeadLetters:] }
+}
+
+// backoff is the wait after the n-th failed attempt: Backoff, 2×, 4×, ... up to MaxBackoff.
+func (s *Service) backoff(n int) time.Duration {
+    d := s.Backoff
+    for i := 1; i < n && d < s.MaxBackoff; i++ { d *= 2 }
+    if d > s.MaxBackoff { d = s.MaxBackoff }
+    return d
+}
+
+func (s *Service) remove(p *pending) {
+    for i, q := range s.queue {
+        if q == p { s.queue = append(s.queue[:i:i], s.queue[i+1:]...); return }
+    }
+}
+
+// post sends one signed request; any status outside 2xx is an error.
+func (s *Service) post(ctx context.Context, u, secret string, ev Event) (int, error) {
+    body, err := json.Marshal(ev)
+    if err != nil { return 0, err }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+    if err != nil { return 0, err }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "lawmap-webhooks/1")
+    req.Header.Set("X-Lawmap-Event", ev.Type)
+    req.Header.Set("X-Lawmap-Delivery", ev.ID)
+    req.Header.Set(SignatureHeader, Sign(secret, s.now(), body))
+    resp, err := s.Client.Do(req)
+    if err != nil { return 0, err }
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+    resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 { return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode) }
+    return resp.StatusCode, nil
+}
+
+func contains(list []string, v string) bool {
+    for _, x := range list {
+        if x == v { return true }
+    }
+    return false
+}
+
+// saved is the subscriptions file. It holds the secrets, so it is written 0600.
+// Queue is written when events are queued and on shutdown, so deliveries made in
+// between may be sent again after a crash; receivers drop them by event ID.
+type saved struct {
+    Subscriptions []Subscription `json:"subscriptions"`
+    DeadLetters   []Delivery     `json:"dead_letters"`
+    Queue         []Delivery     `json:"queue,omitempty"`
+}
+
+func (s *Service) saveLocked() error {
+    if s.file == "" { return nil }
+    out := saved{Subscriptions: []Subscription{}, DeadLetters: s.dead}
+    for _, id := range s.order { out.Subscriptions = append(out.Subscriptions, *s.subs[id]) }
+    for _, p := range s.queue { out.Queue = append(out.Queue, p.Delivery) }
+    b, err := json.MarshalIndent(out, "", "  ")
+    if err != nil { return err }
+    if err := os.MkdirAll(filepath.Dir(s.file), 0o755); err != nil { return err }
+    tmp := s.file + ".tmp"
+    if err := os.WriteFile(tmp, b, 0o600); err != nil { return err }
+    return os.Rename(tmp, s.file)
+}
+
+func (s *Service) load() error {
+    if s.file == "" { return nil }
+    b, err := os.ReadFile(s.file)
+    if errors.Is(err, os.ErrNotExist) { return nil }
+    if err != nil { return err }
+    var in saved
+    if err := json.Unmarshal(b, &in); err != nil { return fmt.Errorf("webhooks: %s: %w", s.file, err) }
+    for i := range in.Subscriptions {
+        sub := in.Subscriptions[i]
+        s.subs[sub.ID] = &sub
+        s.order = append(s.order, sub.ID)
+    }
+    s.dead = in.DeadLetters
+    now := s.now()
+    for _, d := range in.Queue {
+        if _, ok := s.subs[d.Event.SubscriptionID]; !ok { continue }
+        due := now
+        if t, err := time.Parse(time.RFC3339, d.NextAttempt); err == nil && t.After(now) { due = t }
+        s.queue = append(s.queue, &pending{Delivery: d, due: due})
+    }
+    return nil
+}
@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    conf "lawmap/internal/config"
+    dgraph "lawmap/internal/domain/graph"
+    graphrepo "lawmap/internal/repo/graph"
+)
+
+const section = "CA:CIV:T02:CH02:§3342"
+
+// receiver is a local webhook endpoint that fails the first failures requests.
+type receiver struct {
+    *httptest.Server
+    t        *testing.T
+    secret   string
+    mu       sync.Mutex
+    failures int
+    attempts map[string]int // by event ID
+    events   chan Event
+}
+
+func newReceiver(t *testing.T, failures int) *receiver {
+    rc := &receiver{t: t, failures: failures, attempts: map[string]int{}, events: make(chan Event, 16)}
+    rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        rc.mu.Lock()
+        secret := rc.secret
+        rc.mu.Unlock()
+        if err := Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute); err != nil {
+            t.Errorf("signature: %v", err)
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        var ev Event
+        if err := json.Unmarshal(body, &ev); err != nil { t.Errorf("body: %v", err) }
+        if r.Header.Get("X-Lawmap-Event") != ev.Type || r.Header.Get("X-Lawmap-Delivery") != ev.ID { t.Errorf("headers = %v", r.Header) }
+        rc.mu.Lock()
+        rc.attempts[ev.ID]++
+        fail := rc.failures > 0
+        if fail { rc.failures-- }
+        rc.mu.Unlock()
+        if fail {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        rc.events <- ev
+    }))
+    t.Cleanup(rc.Close)
+    return rc
+}
+
+func (rc *receiver) subscribe(t *testing.T, s *Service, sub Subscription) Subscription {
+    t.Helper()
+    sub.URL = rc.URL + "/hook"
+    out, err := s.Subscribe(sub)
+    if err != nil { t.Fatal(err) }
+    if out.Secret == "" { t.Fatal("no secret returned") }
+    rc.mu.Lock()
+    rc.secret = out.Secret
+    rc.mu.Unlock()
+    return out
+}
+
+func (rc *receiver) next(t *testing.T) Event {
+    t.Helper()
+    select {
+    case ev := <-rc.events:
+        return ev
+    case <-time.After(5 * time.Second):
+        t.Fatal("no delivery")
+    }
+    return Event{}
+}
+
+func newService(t *testing.T, cfg conf.WebhooksConfig) (*Service, *graphrepo.MemoryStore) {
+    t.Helper()
+    store := graphrepo.NewMemoryStore()
+    if err := store.LoadJSONL("../../../docs/EXAMPLES.graph.jsonl"); err != nil { t.Fatal(err) }
+    if cfg.Backoff == "" { cfg.Backoff = "5ms" }
+    if cfg.AllowHosts == nil { cfg.AllowHosts = []string{"127.0.0.1"} } // the receivers listen on loopback
+    s, err := New(cfg, store)
+    if err != nil { t.Fatal(err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    t.Cleanup(func() { cancel(); s.Wait() })
+    s.Start(ctx)
+    return s, store
+}
+
+func newVersion(t *testing.T, store *graphrepo.MemoryStore, id, hash string) *dgraph.Node {
+    t.Helper()
+    n, ok := store.GetNode(id)
+    if !ok { t.Fatalf("no node %s", id) }
+    next := *n
+    next.Version = &dgraph.Version{EffectiveDate: "2025-01-01", Hash: hash}
+    return &next
+}
+
+func TestDeliversMatchingEvents(t *testing.T) {
+    s, store := newService(t, conf.WebhooksConfig{})
+    rc := newReceiver(t, 0)
+    sub := rc.subscribe(t, s, Subscription{NodeID: section})
+
+    // a change elsewhere is not delivered; the ones below arrive in order
+    store.Apply([]*dgraph.Node{newVersion(t, store, "CA:CIV:T02:CH02:§3343", "sha256:other")}, nil)
+    store.Apply([]*dgraph.Node{newVersion(t, store, section, "sha256:v2")}, nil)
+    ev := rc.next(t)
+    if ev.Type != EventVersion || ev.SubscriptionID != sub.ID || ev.Node.ID != section || ev.Node.Citation != "CIV § 3342" || ev.Change.Hash != "sha256:v2" { t.Fatalf("event = %+v", ev) }
+
+    opinion := &dgraph.Node{ID: "CA:OPN:Doe_v_Roe_2025", Labels: []string{"OPINION"}, Title: "Doe v. Roe", Props: map[string]any{"jurisdiction": "CA"}}
+    store.Apply([]*dgraph.Node{opinion}, []*dgraph.Edge{{ID: "c9", EdgeType: "CITES", FromID: opinion.ID, ToID: section}})
+    ev = rc.next(t)
+    if ev.Type != EventCited || ev.Node.ID != section || ev.From == nil || ev.From.Title != "Doe v. Roe" { t.Fatalf("cited event = %+v", ev) }
+
+    // importing the opinion again stores the same citation: no new event
+    store.Apply([]*dgraph.Node{opinion}, []*dgraph.Edge{{ID: "c9", EdgeType: "CITES", FromID: opinion.ID, ToID: section}})
+    select {
+    case ev := <-rc.events:
+        t.Errorf("re-imported citation delivered: %+v", ev)
+    case <-time.After(100 * time.Millisecond):
+    }
+}
+
+func TestSubtreeAndEventFilter(t *testing.T) {
+    s, store := newService(t, conf.WebhooksConfig{})
+    rc := newReceiver(t, 0)
+    rc.subscribe(t, s, Subscription{NodeID: "CA:CIV:T02", Subtree: true, Events: []string{EventAmended}})
+    rule := &dgraph.Node{ID: "US:FR:2025-00001", Labels: []string{"FR_DOCUMENT"}, Title: "Dog Owner Liability"}
+    store.Apply([]*dgraph.Node{newVersion(t, store, section, "sha256:v2"), rule}, nil) // not subscribed to versions
+    store.Apply(nil, []*dgraph.Edge{{ID: "amends:1", EdgeType: "AMENDS", FromID: rule.ID, ToID: section}})
+    ev := rc.next(t)
+    if ev.Type != EventAmended || ev.Node.ID != section || ev.From.ID != rule.ID { t.Fatalf("event = %+v", ev) }
+}
+
+func TestScopedSubscriptionSkipsOtherCodes(t *testing.T) {
+    s, store := newService(t, conf.WebhooksConfig{})
+    rc := newReceiver(t, 0)
+    rc.subscribe(t, s, Subscription{NodeID: "CA", Subtree: true, Events: []string{EventUpdated, EventCited}, Jurisdictions: []string{"CA"}, Codes: []string{"CIV"}})
+    scoped := func(id, code string) *dgraph.Node { return &dgraph.Node{ID: id, Props: map[string]any{"jurisdiction": "CA", "code": code}} }
+    store.Apply([]*dgraph.Node{scoped("CA:CONS:art_99", "CONS")}, []*dgraph.Edge{{ID: "p1", EdgeType: "PARENT_OF", FromID: "CA:CONS", ToID: "CA:CONS:art_99"}})
+    store.Apply(nil, []*dgraph.Edge{{ID: "c9", EdgeType: "CITES", FromID: "CA:CRC:rule_1.1", ToID: section}}) // cited from outside the scope
+    store.Apply([]*dgraph.Node{scoped("CA:CIV:T02:CH02:§3399", "CIV")}, []*dgraph.Edge{{ID: "p2", EdgeType: "PARENT_OF", FromID: "CA:CIV:T02:CH02", ToID: "CA:CIV:T02:CH02:§3399"}})
+    // deliveries run concurrently, so wait for the in-scope one and then for stragglers
+    if ev := rc.next(t); ev.Type != EventUpdated || ev.Node.ID != "CA:CIV:T02:CH02:§3399" { t.Fatalf("event = %+v, want the CIV section", ev) }
+    select {
+    case ev := <-rc.events:
+        t.Errorf("out-of-scope event delivered: %+v", ev)
+    case <-time.After(100 * time.Millisecond):
+    }
+}
+
+func TestRetriesWithBackoffThenDeadLetters(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "webhooks.json")
+    s, store := newService(t, conf.WebhooksConfig{MaxAttempts: 3, File: file})
+    flaky := newReceiver(t, 2)
+    flaky.subscribe(t, s, Subscription{NodeID: section})
+    store.Apply([]*dgraph.Node{newVersion(t, store, section, "sha256:v2")}, nil)
+    ev := flaky.next(t)
+    flaky.mu.Lock()
+    if n := flaky.attempts[ev.ID]; n != 3 { t.Errorf("attempts = %d, want 3 with one event ID", n) }
+    flaky.mu.Unlock()
+
+    down := newReceiver(t, 1000)
+    sub := down.subscribe(t, s, Subscription{NodeID: section})
+    store.Apply([]*dgraph.Node{newVersion(t, store, section, "sha256:v3")}, nil)
+    flaky.next(t)
+    var dead []Delivery
+    for deadline := time.Now().Add(5 * time.Second); len(dead) == 0 && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        dead = s.DeadLetters(sub.ID)
+    }
+    if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastStatus != 503 || dead[0].FailedAt == "" { t.Fatalf("dead letters = %+v", dead) }
+
+    // the dead letter and both subscriptions survive a restart
+    s2, err := New(conf.WebhooksConfig{File: file}, store)
+    if err != nil { t.Fatal(err) }
+    if len(s2.Subscriptions()) != 2 || len(s2.DeadLetters("")) != 1 { t.Fatalf("reloaded %d subscriptions, %d dead letters", len(s2.Subscriptions()), len(s2.DeadLetters(""))) }
+
+    down.mu.Lock()
+    down.failures = 0
+    down.mu.Unlock()
+    if _, err := s.Redeliver(dead[0].Event.ID); err != nil { t.Fatal(err) }
+    if ev := down.next(t); ev.ID != dead[0].Event.ID { t.Errorf("redelivered %s, want %s", ev.ID, dead[0].Event.ID) }
+    if len(s.DeadLetters("")) != 0 { t.Error("dead letter kept after redelivery") }
+    if _, err := s.Redeliver("evt_nope"); !errors.Is(err, ErrNotFound) { t.Errorf("redeliver unknown: %v", err) }
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+    s := &Service{Backoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+    want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+    for i, w := range want {
+        if got := s.backoff(i + 1); got != w { t.Errorf("backoff(%d) = %s, want %s", i+1, got, w) }
+    }
+}
+
+func TestQueueOverflowAndRestart(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "webhooks.json")
+    s, store := newService(t, conf.WebhooksConfig{MaxQueue: 2, Backoff: "1h", File: file})
+    down := newReceiver(t, 1000)
+    sub := down.subscribe(t, s, Subscription{NodeID: section})
+    for _, h := range []string{"sha256:v2", "sha256:v3", "sha256:v4"} {
+        store.Apply([]*dgraph.Node{newVersion(t, store, section, h)}, nil)
+    }
+    var dead []Delivery
+    for deadline := time.Now().Add(5 * time.Second); len(dead) == 0 && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        dead = s.DeadLetters(sub.ID)
+    }
+    if len(dead) != 1 || dead[0].LastError != "queue full" || dead[0].Event.Change.Hash != "sha256:v4" { t.Fatalf("dead letters = %+v", dead) }
+
+    // the two queued deliveries are saved and picked up after a restart
+    s2, err := New(conf.WebhooksConfig{File: file}, store)
+    if err != nil { t.Fatal(err) }
+    s2.mu.Lock()
+    n := len(s2.queue)
+    s2.mu.Unlock()
+    if n != 2 { t.Fatalf("reloaded queue = %d, want 2", n) }
+}
+
+func TestSubscribeValidation(t *testing.T) {
+    s, _ := newService(t, conf.WebhooksConfig{})
+    cases := []struct {
+        sub  Subscription
+        want error
+    }{
+        {Subscription{URL: "https://203.0.113.7/hook"}, ErrInvalid},
+        {Subscription{NodeID: "CA:NOPE", URL: "https://203.0.113.7/hook"}, ErrUnknownNode},
+        {Subscription{NodeID: section, URL: "ftp://203.0.113.7/hook"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "/hook"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "https://203.0.113.7/hook", Events: []string{"edited"}}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "http://169.254.169.254/latest/meta-data/"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "http://10.1.2.3/hook"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "http://[::1]/hook"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "http://0.0.0.0/hook"}, ErrInvalid},
+        {Subscription{NodeID: section, URL: "http://lawmap-no-such-host.invalid/hook"}, ErrInvalid},
+    }
+    for _, c := range cases {
+        if _, err := s.Subscribe(c.sub); !errors.Is(err, c.want) { t.Errorf("%+v: err = %v, want %v", c.sub, err, c.want) }
+    }
+    sub, err := s.Subscribe(Subscription{NodeID: section, URL: "https://203.0.113.7/hook", Secret: "s3cret"})
+    if err != nil || sub.Secret != "s3cret" || len(sub.Events) != 3 { t.Fatalf("sub = %+v, %v", sub, err) }
+    if got, _ := s.Subscription(sub.ID); got.Secret != "" { t.Error("secret listed") }
+    if err := s.Unsubscribe(sub.ID); err != nil { t.Fatal(err) }
+    if err := s.Unsubscribe(sub.ID); !errors.Is(err, ErrNotFound) { t.Errorf("second unsubscribe: %v", err) }
+}
+
+func TestInternalCallbacksRefused(t *testing.T) {
+    rc := newReceiver(t, 0)
+    s, _ := newService(t, conf.WebhooksConfig{AllowHosts: []string{}})
+    for _, u := range []string{"http://127.0.0.1/", "http://169.254.169.254/", rc.URL} {
+        if _, err := s.Subscribe(Subscription{NodeID: section, URL: u}); !errors.Is(err, ErrInvalid) { t.Errorf("%s: err = %v", u, err) }
+    }
+    // a name that resolved to a public address at subscribe time can be re-pointed
+    // at loopback later; delivery checks the address actually dialed
+    if _, err := s.Client.Post(rc.URL, "application/json", nil); err == nil { t.Error("delivery dialer reached loopback") }
+
+    open, _ := newService(t, conf.WebhooksConfig{AllowHosts: []string{"127.0.0.0/8"}})
+    if _, err := open.Subscribe(Subscription{NodeID: section, URL: rc.URL}); err != nil { t.Errorf("allowlisted: %v", err) }
+    if _, err := open.Subscribe(Subscription{NodeID: section, URL: "http://169.254.169.254/"}); !errors.Is(err, ErrInvalid) { t.Errorf("metadata allowed by loopback allowlist: %v", err) }
+    if _, err := New(conf.WebhooksConfig{AllowHosts: []string{"http://x/"}}, graphrepo.NewMemoryStore()); err == nil { t.Error("bad allow_hosts entry accepted") }
+}
+
+func TestVerifySignature(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    body := []byte(`{"id":"evt_1"}`)
+    sig := Sign("k", now, body)
+    if err := Verify("k", sig, body, now.Add(time.Minute), 5*time.Minute); err != nil { t.Fatal(err) }
+    for _, c := range []struct{ secret, header string; body []byte; at time.Time }{
+        {"other", sig, body, now},
+        {"k", sig, []byte(`{"id":"evt_2"}`), now},
+        {"k", sig, body, now.Add(time.Hour)},
+        {"k", "v1=00", body, now},
+        {"k", "", body, now},
+    } {
+        if err := Verify(c.secret, c.header, c.body, c.at, 5*time.Minute); !errors.Is(err, ErrBadSignature) { t.Errorf("%+v: err = %v", c, err) }
+    }
+}
@@ -0,0 +1,10 @@
+#!/usr/bin/env bash
+# Usage: scripts/import_leginfo.sh PUBINFO_DIR [CODES] [OUT]
+# PUBINFO_DIR is an extracted pubinfo archive (LAW_*_TBL.dat and .lob files).
+set -euo pipefail
+cd "$(dirname "$0")/.."
+DIR=${1:?pubinfo directory}
+CODES=${2:-CIV,PEN,CONS,BPC}
+OUT=${3:-ca.jsonl}
+echo "Importing $CODES from $DIR into $OUT"
+GOCACHE="$(pwd)/.gocache" go run ./cmd/ingest -source leginfo -codes "$CODES" -out "$OUT" "$DIR"